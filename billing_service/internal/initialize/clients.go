@@ -1,7 +1,12 @@
 package initialize
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"order-service-system/common/auth"
 	"order-service-system/proto/clients"
+	"order-service-system/sdk/ordersdk"
 
 	"go.uber.org/zap"
 )
@@ -11,21 +16,44 @@ type Clients struct {
 }
 
 type ClientsDeps struct {
-	Logger           *zap.Logger
-	OrderServiceHost string
+	Logger            *zap.Logger
+	OrderServiceHost  string
+	OrderClientConfig clients.OrderClientConfig
+	// ServiceName is the identity the service presents to order_service.
+	ServiceName string
+	Auth        auth.SignerConfiguration
+	// TLS is nil when order_service is called in plaintext.
+	TLS *tls.Config
 }
 
-func NewClients(deps ClientsDeps) *Clients {
+func NewClients(deps ClientsDeps) (*Clients, error) {
 	if deps.Logger == nil {
-		panic("logger must not be nil on <NewClients> of <initialize>")
+		return nil, errors.New("logger must not be nil on <NewClients> of <initialize>")
 	}
-	if deps.OrderServiceHost == "" {
-		panic("order service host must not be empty on <NewClients> of <initialize>")
+
+	var tokenSource ordersdk.TokenSource
+	if deps.Auth.Key != "" {
+		signer, err := auth.NewSigner(deps.ServiceName, deps.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("create service token signer: %w", err)
+		}
+		tokenSource = signer.Token
+	} else {
+		deps.Logger.Warn("service key is not set, calling order service without a token on <NewClients> of <initialize>")
 	}
-	return &Clients{
-		OrderClient: clients.NewOrderClient(clients.OrderClientDeps{
-			Logger:           deps.Logger,
-			OrderServiceHost: deps.OrderServiceHost,
-		}),
+
+	orderClient, err := clients.NewOrderClient(clients.OrderClientDeps{
+		Logger:           deps.Logger,
+		OrderServiceHost: deps.OrderServiceHost,
+		Config:           deps.OrderClientConfig,
+		TokenSource:      tokenSource,
+		TLS:              deps.TLS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create order client: %w", err)
 	}
+
+	return &Clients{
+		OrderClient: orderClient,
+	}, nil
 }
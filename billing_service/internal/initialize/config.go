@@ -2,7 +2,15 @@ package initialize
 
 import (
 	"log"
+	"order-service-system/common/auth"
+	"order-service-system/common/events"
+	"order-service-system/common/inbox"
+	"order-service-system/common/mongo"
 	"order-service-system/common/nats"
+	"order-service-system/common/telemetry"
+	"order-service-system/common/tenant"
+	"order-service-system/common/tlsconfig"
+	"order-service-system/proto/clients"
 
 	"github.com/caarlos0/env/v8"
 	"github.com/joho/godotenv"
@@ -11,11 +19,20 @@ import (
 type Config struct {
 	PaymentSuccessRate float64 `env:"PAYMENT_SUCCESS_RATE"`
 	OrderServiceHost   string  `env:"ORDER_SERVICE_HOST"`
+	HTTPURL            string  `env:"HTTP_URL" envDefault:":8080"`
+	OrderClient        clients.OrderClientConfig
+	Auth               auth.SignerConfiguration
+	TLS                tlsconfig.Configuration
+	Events             events.EncodingConfig
+	Inbox              inbox.Configuration
+	Tenants            tenant.Configuration
 	ExternalCfg        ExternalCfg
 }
 
 type ExternalCfg struct {
-	NatsConfig nats.Configuration
+	TelemetryConfig telemetry.Configuration
+	MongoConfig     mongo.Configuration
+	NatsConfig      nats.Configuration
 }
 
 func LoadConfig() (*Config, error) {
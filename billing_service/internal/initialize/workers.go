@@ -1,29 +1,47 @@
 package initialize
 
 import (
+	"order-service-system/billing_service/internal/repository/payments"
 	"order-service-system/billing_service/internal/workers/billing"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/inbox"
+	"order-service-system/common/tenant"
 
-	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
 type Workers struct {
-	BillingProcessor *billing.Processor
+	BillingProcessor      *billing.Processor
+	StatusResponder       *billing.StatusResponder
+	RefundProcessor       *billing.RefundProcessor
+	AmendmentProcessor    *billing.AmendmentProcessor
+	CancellationProcessor *billing.CancellationProcessor
 }
 
 type WorkersDeps struct {
 	Logger      *zap.Logger
 	Clients     *Clients
-	NatsConn    *nats.Conn
+	Bus         bus.Bus
+	Inbox       inbox.Inbox
+	Payments    *payments.Store
 	SuccessRate float64
+	Tenants     tenant.Settings[billing.TenantSettings]
+	Encoding    events.EncodingConfig
 }
 
 func NewWorkers(deps WorkersDeps) *Workers {
 	if deps.Logger == nil {
 		panic("logger must not be nil on <NewWorkers> of <initialize>")
 	}
-	if deps.NatsConn == nil {
-		panic("nats connection must not be nil on <NewWorkers> of <initialize>")
+	if deps.Bus == nil {
+		panic("bus must not be nil on <NewWorkers> of <initialize>")
+	}
+	if deps.Inbox == nil {
+		panic("inbox must not be nil on <NewWorkers> of <initialize>")
+	}
+	if deps.Payments == nil {
+		panic("payment store must not be nil on <NewWorkers> of <initialize>")
 	}
 	if deps.Clients == nil {
 		panic("clients must not be nil on <NewWorkers> of <initialize>")
@@ -31,9 +49,36 @@ func NewWorkers(deps WorkersDeps) *Workers {
 	return &Workers{
 		BillingProcessor: billing.NewProcessor(billing.Deps{
 			Logger:      deps.Logger,
-			NatsConn:    deps.NatsConn,
+			Bus:         deps.Bus,
+			Inbox:       deps.Inbox,
+			Payments:    deps.Payments,
 			OrderClient: deps.Clients.OrderClient,
 			SuccessRate: deps.SuccessRate,
+			Tenants:     deps.Tenants,
+			Encoding:    deps.Encoding,
+		}),
+		StatusResponder: billing.NewStatusResponder(billing.StatusResponderDeps{
+			Logger:   deps.Logger,
+			Bus:      deps.Bus,
+			Payments: deps.Payments,
+		}),
+		RefundProcessor: billing.NewRefundProcessor(billing.RefundDeps{
+			Logger:      deps.Logger,
+			Bus:         deps.Bus,
+			Inbox:       deps.Inbox,
+			Payments:    deps.Payments,
+			OrderClient: deps.Clients.OrderClient,
+			Encoding:    deps.Encoding,
+		}),
+		AmendmentProcessor: billing.NewAmendmentProcessor(billing.AmendmentDeps{
+			Logger:   deps.Logger,
+			Bus:      deps.Bus,
+			Payments: deps.Payments,
+		}),
+		CancellationProcessor: billing.NewCancellationProcessor(billing.CancellationDeps{
+			Logger:   deps.Logger,
+			Bus:      deps.Bus,
+			Payments: deps.Payments,
 		}),
 	}
 }
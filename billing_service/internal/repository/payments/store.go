@@ -0,0 +1,200 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order-service-system/common/events"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	paymentsCollection      = "billing_payment"
+	refundsCollection       = "billing_refund"
+	cancellationsCollection = "billing_cancellation"
+
+	// maxUpdateAttempts bounds the retries of Update when other replicas keep
+	// changing the same payment.
+	maxUpdateAttempts = 5
+)
+
+var ErrConflict = errors.New("payment was changed concurrently")
+
+// Payment is the last known outcome of the payment of an order. Amount and Version
+// follow order.amended events; Version is 0 until the order is amended.
+type Payment struct {
+	OrderID   string    `json:"order_id" bson:"_id"`
+	TenantID  string    `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
+	Outcome   string    `json:"outcome" bson:"outcome"`
+	Reason    string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	Amount    float64   `json:"amount,omitempty" bson:"amount,omitempty"`
+	Currency  string    `json:"currency,omitempty" bson:"currency,omitempty"`
+	Version   int64     `json:"version,omitempty" bson:"version,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Decided reports whether the order was charged or declined.
+func (p Payment) Decided() bool {
+	return p.Outcome == events.PaymentOutcomePaid || p.Outcome == events.PaymentOutcomeDeclined
+}
+
+// Refund is the outcome of the refund of a return.
+type Refund struct {
+	ReturnID  string    `json:"return_id" bson:"_id"`
+	TenantID  string    `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
+	OrderID   string    `json:"order_id" bson:"order_id"`
+	Amount    float64   `json:"amount" bson:"amount"`
+	Refunded  bool      `json:"refunded" bson:"refunded"`
+	Reason    string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// CancellationRefund is the outcome of the refund of items cancelled after payment.
+type CancellationRefund struct {
+	CancellationID string    `json:"cancellation_id" bson:"_id"`
+	TenantID       string    `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
+	OrderID        string    `json:"order_id" bson:"order_id"`
+	Amount         float64   `json:"amount" bson:"amount"`
+	Refunded       bool      `json:"refunded" bson:"refunded"`
+	Reason         string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// paymentDoc is the stored payment; Revision guards Update against concurrent writers.
+type paymentDoc struct {
+	Payment  `bson:",inline"`
+	Revision int64 `bson:"revision"`
+}
+
+// Store keeps payment outcomes by order id in Mongo, shared by all billing replicas,
+// so that any replica answers status queries and no replica charges an order twice.
+type Store struct {
+	payments      *mongo.Collection
+	refunds       *mongo.Collection
+	cancellations *mongo.Collection
+}
+
+func NewStore(db *mongo.Database) *Store {
+	if db == nil {
+		panic("mongo database must not be nil on <NewStore> of <Store>")
+	}
+	return &Store{
+		payments:      db.Collection(paymentsCollection),
+		refunds:       db.Collection(refundsCollection),
+		cancellations: db.Collection(cancellationsCollection),
+	}
+}
+
+// Update reads and changes the payment of the order. The change is written only if
+// no other replica changed the payment since it was read, otherwise it is applied
+// again to the fresh payment, so a charge in flight and an amendment cannot
+// overwrite each other.
+func (s *Store) Update(ctx context.Context, orderID string, fn func(payment *Payment, found bool)) (Payment, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		var doc paymentDoc
+		err := s.payments.FindOne(ctx, bson.M{"_id": orderID}).Decode(&doc)
+		found := err == nil
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return Payment{}, fmt.Errorf("update payment: %w", err)
+		}
+
+		revision := doc.Revision
+		fn(&doc.Payment, found)
+		doc.OrderID = orderID
+		doc.Revision = revision + 1
+
+		if !found {
+			_, err = s.payments.InsertOne(ctx, doc)
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			if err != nil {
+				return Payment{}, fmt.Errorf("update payment: %w", err)
+			}
+			return doc.Payment, nil
+		}
+
+		result, err := s.payments.ReplaceOne(ctx, bson.M{"_id": orderID, "revision": revision}, doc)
+		if err != nil {
+			return Payment{}, fmt.Errorf("update payment: %w", err)
+		}
+		if result.MatchedCount == 1 {
+			return doc.Payment, nil
+		}
+	}
+	return Payment{}, fmt.Errorf("update payment %s: %w", orderID, ErrConflict)
+}
+
+// Get returns false when billing has never seen the order.
+func (s *Store) Get(ctx context.Context, orderID string) (Payment, bool, error) {
+	payment, found, err := get[Payment](ctx, s.payments, orderID)
+	if err != nil {
+		return Payment{}, false, fmt.Errorf("read payment: %w", err)
+	}
+	return payment, found, nil
+}
+
+// SaveRefund stores the refund unless the return was refunded already; the first
+// decision is kept.
+func (s *Store) SaveRefund(ctx context.Context, refund Refund) error {
+	if err := insertOnce(ctx, s.refunds, refund.ReturnID, refund); err != nil {
+		return fmt.Errorf("save refund: %w", err)
+	}
+	return nil
+}
+
+// GetRefund returns false when the return has not been refunded yet.
+func (s *Store) GetRefund(ctx context.Context, returnID string) (Refund, bool, error) {
+	refund, found, err := get[Refund](ctx, s.refunds, returnID)
+	if err != nil {
+		return Refund{}, false, fmt.Errorf("read refund: %w", err)
+	}
+	return refund, found, nil
+}
+
+// SaveCancellationRefund stores the refund unless the cancellation was refunded
+// already; the first decision is kept.
+func (s *Store) SaveCancellationRefund(ctx context.Context, refund CancellationRefund) error {
+	if err := insertOnce(ctx, s.cancellations, refund.CancellationID, refund); err != nil {
+		return fmt.Errorf("save cancellation refund: %w", err)
+	}
+	return nil
+}
+
+// GetCancellationRefund returns false when the cancellation has not been refunded yet.
+func (s *Store) GetCancellationRefund(ctx context.Context, cancellationID string) (CancellationRefund, bool, error) {
+	refund, found, err := get[CancellationRefund](ctx, s.cancellations, cancellationID)
+	if err != nil {
+		return CancellationRefund{}, false, fmt.Errorf("read cancellation refund: %w", err)
+	}
+	return refund, found, nil
+}
+
+func insertOnce(ctx context.Context, collection *mongo.Collection, id string, value any) error {
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$setOnInsert": value},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// Другая реплика сохранила решение одновременно с нами.
+		return nil
+	}
+	return err
+}
+
+func get[T any](ctx context.Context, collection *mongo.Collection, id string) (T, bool, error) {
+	var value T
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&value)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	return value, true, nil
+}
@@ -0,0 +1,129 @@
+package billing
+
+import (
+	"context"
+	"order-service-system/billing_service/internal/repository/payments"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type AmendmentStore interface {
+	Update(ctx context.Context, orderID string, fn func(payment *payments.Payment, found bool)) (payments.Payment, error)
+}
+
+// AmendmentProcessor follows order.amended events. A charge that has not been made
+// yet uses the new amount; a charge already made for an older version is voided and
+// reissued with the new amount. Duplicate and stale events are dropped by version.
+type AmendmentProcessor struct {
+	logger   *zap.Logger
+	bus      bus.Bus
+	payments AmendmentStore
+}
+
+type AmendmentDeps struct {
+	Logger   *zap.Logger
+	Bus      bus.Bus
+	Payments AmendmentStore
+}
+
+func NewAmendmentProcessor(deps AmendmentDeps) *AmendmentProcessor {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewAmendmentProcessor> of <AmendmentProcessor>")
+	}
+	if deps.Bus == nil {
+		panic("bus must not be nil on <NewAmendmentProcessor> of <AmendmentProcessor>")
+	}
+	if deps.Payments == nil {
+		panic("payment store must not be nil on <NewAmendmentProcessor> of <AmendmentProcessor>")
+	}
+	return &AmendmentProcessor{
+		logger:   deps.Logger,
+		bus:      deps.Bus,
+		payments: deps.Payments,
+	}
+}
+
+func (receiver *AmendmentProcessor) Start(ctx context.Context) (bus.Subscription, error) {
+	sub, err := receiver.bus.Subscribe(ctx, events.TypeOrderAmended, queueBilling, receiver.handleMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver.logger.Info("listening for order.amended on <Start> of <AmendmentProcessor>",
+		zap.String("subject", events.TypeOrderAmended),
+		zap.String("queue", queueBilling),
+	)
+	return sub, nil
+}
+
+func (receiver *AmendmentProcessor) handleMessage(ctx context.Context, msg *bus.Message) error {
+	envelope, err := events.Unmarshal[events.OrderAmendedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode order.amended on <handleMessage> of <AmendmentProcessor>", zap.Error(err))
+		return nil
+	}
+	payload := envelope.Data
+	if payload.OrderID == "" || payload.Version <= 0 {
+		receiver.logger.Error("invalid payload on <handleMessage> of <AmendmentProcessor>", zap.Any("payload", payload))
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+
+	var (
+		previous payments.Payment
+		stale    bool
+	)
+	payment, err := receiver.payments.Update(ctx, payload.OrderID, func(payment *payments.Payment, found bool) {
+		previous = *payment
+		if payment.Version >= payload.Version {
+			stale = true
+			return
+		}
+		if !found {
+			// order.created ещё не обработан, списание пройдёт сразу на новую сумму.
+			payment.Outcome = events.PaymentOutcomeUnknown
+			payment.TenantID = tenant.FromContext(ctx)
+		}
+		payment.Amount = payload.TotalAmount
+		payment.Version = payload.Version
+		payment.UpdatedAt = time.Now().UTC()
+	})
+	if err != nil {
+		receiver.logger.Error("failed to save amended payment on <handleMessage> of <AmendmentProcessor>", zap.String("order_id", payload.OrderID), zap.Error(err))
+		return err
+	}
+	if stale {
+		receiver.logger.Info("skipping stale order.amended on <handleMessage> of <AmendmentProcessor>",
+			zap.String("order_id", payload.OrderID),
+			zap.Int64("version", payload.Version),
+			zap.Int64("payment_version", previous.Version))
+		return nil
+	}
+
+	if payment.Outcome == events.PaymentOutcomePaid {
+		oldAmount := previous.Amount
+		if oldAmount == 0 {
+			// Платежи, сохранённые до появления суммы.
+			oldAmount = payload.PreviousTotal
+		}
+		// Эмуляция: старое списание отменяется, новое на изменённую сумму всегда проходит.
+		paymentReissues.Inc()
+		receiver.logger.Info("payment reissued on <handleMessage> of <AmendmentProcessor>",
+			zap.String("order_id", payload.OrderID),
+			zap.Int64("version", payload.Version),
+			zap.Float64("voided_amount", oldAmount),
+			zap.Float64("amount", payment.Amount))
+		return nil
+	}
+
+	receiver.logger.Info("payment amount amended on <handleMessage> of <AmendmentProcessor>",
+		zap.String("order_id", payload.OrderID),
+		zap.String("outcome", payment.Outcome),
+		zap.Int64("version", payload.Version),
+		zap.Float64("amount", payment.Amount))
+	return nil
+}
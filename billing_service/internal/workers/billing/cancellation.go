@@ -0,0 +1,124 @@
+package billing
+
+import (
+	"context"
+	"order-service-system/billing_service/internal/repository/payments"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type CancellationStore interface {
+	Get(ctx context.Context, orderID string) (payments.Payment, bool, error)
+	GetCancellationRefund(ctx context.Context, cancellationID string) (payments.CancellationRefund, bool, error)
+	SaveCancellationRefund(ctx context.Context, refund payments.CancellationRefund) error
+}
+
+// CancellationProcessor refunds units cancelled from paid orders. The outcome is
+// stored by cancellation id, so a cancellation is refunded once however often the
+// order service repeats the request.
+type CancellationProcessor struct {
+	logger   *zap.Logger
+	bus      bus.Bus
+	payments CancellationStore
+}
+
+type CancellationDeps struct {
+	Logger   *zap.Logger
+	Bus      bus.Bus
+	Payments CancellationStore
+}
+
+func NewCancellationProcessor(deps CancellationDeps) *CancellationProcessor {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewCancellationProcessor> of <CancellationProcessor>")
+	}
+	if deps.Bus == nil {
+		panic("bus must not be nil on <NewCancellationProcessor> of <CancellationProcessor>")
+	}
+	if deps.Payments == nil {
+		panic("payment store must not be nil on <NewCancellationProcessor> of <CancellationProcessor>")
+	}
+	return &CancellationProcessor{
+		logger:   deps.Logger,
+		bus:      deps.Bus,
+		payments: deps.Payments,
+	}
+}
+
+func (receiver *CancellationProcessor) Start(ctx context.Context) (bus.Subscription, error) {
+	sub, err := receiver.bus.Subscribe(ctx, events.TypeOrderItemsCancelled, queueBilling, receiver.handleMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver.logger.Info("listening for order.items_cancelled on <Start> of <CancellationProcessor>",
+		zap.String("subject", events.TypeOrderItemsCancelled),
+		zap.String("queue", queueBilling),
+	)
+	return sub, nil
+}
+
+func (receiver *CancellationProcessor) handleMessage(ctx context.Context, msg *bus.Message) error {
+	envelope, err := events.Unmarshal[events.OrderItemsCancelledPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode order.items_cancelled on <handleMessage> of <CancellationProcessor>", zap.Error(err))
+		return nil
+	}
+	payload := envelope.Data
+	if payload.CancellationID == "" || payload.OrderID == "" {
+		receiver.logger.Error("invalid payload on <handleMessage> of <CancellationProcessor>", zap.Any("payload", payload))
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+
+	existing, found, err := receiver.payments.GetCancellationRefund(ctx, payload.CancellationID)
+	if err != nil {
+		receiver.logger.Error("failed to read cancellation refund on <handleMessage> of <CancellationProcessor>", zap.String("cancellation_id", payload.CancellationID), zap.Error(err))
+		return err
+	}
+	if found {
+		receiver.logger.Info("cancellation already refunded on <handleMessage> of <CancellationProcessor>",
+			zap.String("cancellation_id", existing.CancellationID),
+			zap.Bool("refunded", existing.Refunded))
+		return nil
+	}
+
+	payment, found, err := receiver.payments.Get(ctx, payload.OrderID)
+	if err != nil {
+		receiver.logger.Error("failed to read payment on <handleMessage> of <CancellationProcessor>", zap.String("order_id", payload.OrderID), zap.Error(err))
+		return err
+	}
+
+	refund := payments.CancellationRefund{
+		CancellationID: payload.CancellationID,
+		TenantID:       tenant.FromContext(ctx),
+		OrderID:        payload.OrderID,
+		Amount:         payload.Amount,
+		Refunded:       found && payment.Outcome == events.PaymentOutcomePaid,
+		UpdatedAt:      time.Now().UTC(),
+	}
+	result := refundRefunded
+	if !refund.Refunded {
+		refund.Reason, result = notPaidReason, refundFailed
+	}
+	if err := receiver.payments.SaveCancellationRefund(ctx, refund); err != nil {
+		receiver.logger.Error("failed to save cancellation refund on <handleMessage> of <CancellationProcessor>", zap.String("cancellation_id", payload.CancellationID), zap.Error(err))
+		return err
+	}
+	refundsTotal.WithLabelValues(result).Inc()
+	if refund.Refunded {
+		refundAmount.Add(refund.Amount)
+	}
+
+	receiver.logger.Info("cancellation refund decided on <handleMessage> of <CancellationProcessor>",
+		zap.String("cancellation_id", refund.CancellationID),
+		zap.String("order_id", refund.OrderID),
+		zap.Float64("amount", refund.Amount),
+		zap.Int("items", len(payload.Items)),
+		zap.Bool("refunded", refund.Refunded))
+	return nil
+}
@@ -0,0 +1,41 @@
+package billing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	paymentPaid     = "paid"
+	paymentDeclined = "declined"
+)
+
+var paymentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "billing_payments_total",
+	Help: "Number of processed payments by result (paid or declined).",
+}, []string{"result"})
+
+var paymentAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "billing_payment_amount_total",
+	Help: "Sum of processed payment amounts by result.",
+}, []string{"result"})
+
+const (
+	refundRefunded = "refunded"
+	refundFailed   = "failed"
+)
+
+var refundsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "billing_refunds_total",
+	Help: "Number of processed refunds by result (refunded or failed).",
+}, []string{"result"})
+
+var refundAmount = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "billing_refund_amount_total",
+	Help: "Sum of refunded amounts.",
+})
+
+var paymentReissues = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "billing_payment_reissues_total",
+	Help: "Number of charges voided and reissued because the order was amended after payment.",
+})
@@ -2,76 +2,109 @@ package billing
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"math/rand"
+	"order-service-system/billing_service/internal/repository/payments"
+	"order-service-system/common/bus"
 	"order-service-system/common/events"
-	"order-service-system/proto/clients"
+	"order-service-system/common/tenant"
+	"strings"
 	"time"
 
 	orderpb "order-service-system/proto/order"
 
-	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
 const (
-	subjectOrderCreated = "order.created"
+	subjectOrderCreated = events.TypeOrderCreated
 	queueBilling        = "billing-workers"
+	eventSource         = "billing-service"
+	declineReason       = "payment declined"
 )
 
+type Inbox interface {
+	Claim(ctx context.Context, id string) (bool, error)
+	Release(ctx context.Context, id string) error
+}
+
+type PaymentStore interface {
+	Get(ctx context.Context, orderID string) (payments.Payment, bool, error)
+	Update(ctx context.Context, orderID string, fn func(payment *payments.Payment, found bool)) (payments.Payment, error)
+}
+
+type OrderClient interface {
+	UpdateOrderStatus(ctx context.Context, orderID string, status orderpb.OrderStatus) (*orderpb.UpdateOrderStatusResponse, error)
+}
+
+// TenantSettings are the keys of the tenants file billing reads.
+type TenantSettings struct {
+	// PaymentSuccessRate replaces PAYMENT_SUCCESS_RATE for the tenant when set.
+	PaymentSuccessRate *float64 `json:"payment_success_rate"`
+	// Currencies are charged for the tenant; payments in other currencies are
+	// declined. Empty accepts any currency.
+	Currencies []string `json:"currencies"`
+}
+
 type Processor struct {
 	logger      *zap.Logger
-	natsConn    *nats.Conn
-	orderClient *clients.OrderClient
+	bus         bus.Bus
+	inbox       Inbox
+	payments    PaymentStore
+	orderClient OrderClient
 	successRate float64
+	tenants     tenant.Settings[TenantSettings]
+	encoding    events.EncodingConfig
 	rand        *rand.Rand
 }
 
 type Deps struct {
 	Logger      *zap.Logger
-	NatsConn    *nats.Conn
-	OrderClient *clients.OrderClient
+	Bus         bus.Bus
+	Inbox       Inbox
+	Payments    PaymentStore
+	OrderClient OrderClient
 	SuccessRate float64
+	// Tenants are empty when no tenants file is configured.
+	Tenants  tenant.Settings[TenantSettings]
+	Encoding events.EncodingConfig
 }
 
 func NewProcessor(deps Deps) *Processor {
 	if deps.Logger == nil {
 		panic("logger must not be nil on <NewProcessor> of <Processor>")
 	}
-	if deps.NatsConn == nil {
-		panic("nats connection must not be nil on <NewProcessor> of <Processor>")
+	if deps.Bus == nil {
+		panic("bus must not be nil on <NewProcessor> of <Processor>")
 	}
-	if deps.OrderClient == nil {
-		panic("order client must not be nil on <NewProcessor> of <Processor>")
+	if deps.Inbox == nil {
+		panic("inbox must not be nil on <NewProcessor> of <Processor>")
 	}
-
-	successRate := deps.SuccessRate
-	if successRate < 0 {
-		successRate = 0
+	if deps.Payments == nil {
+		panic("payment store must not be nil on <NewProcessor> of <Processor>")
 	}
-	if successRate > 1 {
-		successRate = 1
+	if deps.OrderClient == nil {
+		panic("order client must not be nil on <NewProcessor> of <Processor>")
 	}
 
 	return &Processor{
 		logger:      deps.Logger,
-		natsConn:    deps.NatsConn,
+		bus:         deps.Bus,
+		inbox:       deps.Inbox,
+		payments:    deps.Payments,
 		orderClient: deps.OrderClient,
-		successRate: successRate,
+		successRate: clamp(deps.SuccessRate),
+		tenants:     deps.Tenants,
+		encoding:    deps.Encoding,
 		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
-func (receiver *Processor) Start(ctx context.Context) (*nats.Subscription, error) {
-	sub, err := receiver.natsConn.QueueSubscribe(subjectOrderCreated, queueBilling, func(msg *nats.Msg) {
-		receiver.handleMessage(ctx, msg)
-	})
+func (receiver *Processor) Start(ctx context.Context) (bus.Subscription, error) {
+	sub, err := receiver.bus.Subscribe(ctx, subjectOrderCreated, queueBilling, receiver.handleMessage)
 	if err != nil {
 		return nil, err
 	}
-	if err := receiver.natsConn.Flush(); err != nil {
-		return nil, err
-	}
 
 	receiver.logger.Info("listening for order.created on <Start> of <Processor>",
 		zap.String("subject", subjectOrderCreated),
@@ -80,70 +113,206 @@ func (receiver *Processor) Start(ctx context.Context) (*nats.Subscription, error
 	return sub, nil
 }
 
-func (receiver *Processor) handleMessage(ctx context.Context, msg *nats.Msg) {
+func (receiver *Processor) handleMessage(ctx context.Context, msg *bus.Message) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	select {
 	case <-ctx.Done():
 		receiver.logger.Warn("context cancelled before processing on <handleMessage> of <Processor>")
-		return
+		return ctx.Err()
 	default:
 	}
 
-	var payload events.OrderCreatedPayload
-	if err := json.Unmarshal(msg.Data, &payload); err != nil {
+	envelope, err := events.Unmarshal[events.OrderCreatedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
 		receiver.logger.Error("failed to decode order.created on <handleMessage> of <Processor>", zap.Error(err))
-		return
+		return nil
 	}
+	payload := envelope.Data
 
 	if payload.OrderID == "" || payload.UserID == "" {
 		receiver.logger.Error("invalid payload on <handleMessage> of <Processor>", zap.Any("payload", payload))
-		return
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+	tenantID := tenant.FromContext(ctx)
+
+	eventID := events.ResolveID(envelope.ID, msg.Header.Get(events.HeaderMsgID), events.TypeOrderCreated, payload.OrderID)
+	claimed, err := receiver.inbox.Claim(ctx, eventID)
+	if err != nil {
+		receiver.logger.Error("failed to claim event on <handleMessage> of <Processor>", zap.String("event_id", eventID), zap.Error(err))
+		return err
+	}
+	if !claimed {
+		receiver.logger.Info("skipping duplicate order.created on <handleMessage> of <Processor>",
+			zap.String("event_id", eventID),
+			zap.String("order_id", payload.OrderID))
+		return nil
+	}
+
+	payment, err := receiver.payments.Update(ctx, payload.OrderID, func(payment *payments.Payment, found bool) {
+		if payment.Decided() {
+			return
+		}
+		payment.Outcome = events.PaymentOutcomeProcessing
+		payment.TenantID = tenantID
+		payment.Currency = payload.Currency
+		// Сумма из order.amended новее суммы из order.created.
+		if payment.Version == 0 {
+			payment.Amount = payload.TotalAmount
+		}
+		payment.UpdatedAt = time.Now().UTC()
+	})
+	if err != nil {
+		receiver.logger.Error("failed to save processing payment on <handleMessage> of <Processor>", zap.String("order_id", payload.OrderID), zap.Error(err))
+		if releaseErr := receiver.inbox.Release(context.WithoutCancel(ctx), eventID); releaseErr != nil {
+			receiver.logger.Error("failed to release event on <handleMessage> of <Processor>", zap.String("event_id", eventID), zap.Error(releaseErr))
+		}
+		return err
+	}
+	if payment.Decided() {
+		// Повторный order.created (например, от сверки заказов): деньги не списываем, только повторяем результат.
+		receiver.logger.Info("payment already decided on <handleMessage> of <Processor>",
+			zap.String("order_id", payload.OrderID),
+			zap.String("outcome", payment.Outcome))
+		receiver.publishResult(ctx, withAmount(envelope, payment), payment.Outcome == events.PaymentOutcomePaid, payment.Reason)
+		return nil
 	}
 
 	receiver.logger.Info("processing payment on <handleMessage> of <Processor>",
+		zap.String("event_id", eventID),
+		zap.Bool("legacy", envelope.Legacy),
+		zap.String("tenant_id", tenantID),
 		zap.String("order_id", payload.OrderID),
 		zap.String("user_id", payload.UserID),
-		zap.Float64("amount", payload.TotalAmount))
+		zap.Float64("amount", payment.Amount))
 
 	delay := 1000 + receiver.rand.Intn(1000) // 1000-2000ms
-	time.Sleep(time.Duration(delay) * time.Millisecond)
+	select {
+	case <-ctx.Done():
+		// платеж не проводился, повторная доставка должна быть обработана
+		if err := receiver.inbox.Release(context.WithoutCancel(ctx), eventID); err != nil {
+			receiver.logger.Error("failed to release event on <handleMessage> of <Processor>", zap.String("event_id", eventID), zap.Error(err))
+		}
+		receiver.logger.Warn("context cancelled before payment on <handleMessage> of <Processor>", zap.String("order_id", payload.OrderID))
+		return ctx.Err()
+	case <-time.After(time.Duration(delay) * time.Millisecond):
+	}
+
+	settings := receiver.tenants.For(tenantID)
+	reason := declineReason
+	success := receiver.rand.Float64() <= receiver.rate(settings)
+	if !accepts(settings.Currencies, payload.Currency) {
+		success, reason = false, fmt.Sprintf("currency %s is not accepted", payload.Currency)
+	}
+	result := paymentDeclined
+	if success {
+		result = paymentPaid
+	}
+	// Заказ могли изменить, пока шло списание: берём последнюю сумму.
+	payment, err = receiver.payments.Update(ctx, payload.OrderID, func(payment *payments.Payment, found bool) {
+		payment.Outcome, payment.Reason = events.PaymentOutcomeDeclined, reason
+		if success {
+			payment.Outcome, payment.Reason = events.PaymentOutcomePaid, ""
+		}
+		payment.UpdatedAt = time.Now().UTC()
+	})
+	if err != nil {
+		receiver.logger.Error("failed to save payment outcome on <handleMessage> of <Processor>", zap.String("order_id", payload.OrderID), zap.Error(err))
+		payment.Amount = payload.TotalAmount
+	}
+	paymentsTotal.WithLabelValues(result).Inc()
+	paymentAmount.WithLabelValues(result).Add(payment.Amount)
+
+	receiver.publishResult(ctx, withAmount(envelope, payment), success, reason)
+	return nil
+}
+
+// rate is the payment success rate of the tenant.
+func (receiver *Processor) rate(settings TenantSettings) float64 {
+	if settings.PaymentSuccessRate == nil {
+		return receiver.successRate
+	}
+	return clamp(*settings.PaymentSuccessRate)
+}
+
+func clamp(rate float64) float64 {
+	return min(max(rate, 0), 1)
+}
 
-	success := receiver.rand.Float64() <= receiver.successRate
-	receiver.publishResult(ctx, payload, success)
+// accepts reports whether the currency may be charged. Orders without a currency
+// were placed before currencies were configured and are always accepted.
+func accepts(currencies []string, currency string) bool {
+	if len(currencies) == 0 || currency == "" {
+		return true
+	}
+	for _, accepted := range currencies {
+		if strings.EqualFold(accepted, currency) {
+			return true
+		}
+	}
+	return false
 }
 
-func (receiver *Processor) publishResult(ctx context.Context, payload events.OrderCreatedPayload, success bool) {
+// withAmount reports the charged amount instead of the one the order was created with.
+func withAmount(created events.Envelope[events.OrderCreatedPayload], payment payments.Payment) events.Envelope[events.OrderCreatedPayload] {
+	if payment.Version > 0 {
+		created.Data.TotalAmount = payment.Amount
+	}
+	return created
+}
+
+func (receiver *Processor) publishResult(ctx context.Context, created events.Envelope[events.OrderCreatedPayload], success bool, reason string) {
+	payload := created.Data
 	status := orderpb.OrderStatus_FAILED
-	subject := "order.failed"
-	var event any
+	subject := events.TypeOrderFailed
+
+	correlationID := created.CorrelationID
+	if correlationID == "" {
+		correlationID = created.ID
+	}
+	if correlationID == "" {
+		correlationID = payload.OrderID
+	}
 
 	if success {
 		status = orderpb.OrderStatus_PAID
-		subject = "order.paid"
-		event = events.OrderPaidPayload{
+		subject = events.TypeOrderPaid
+	}
+	if reason == "" {
+		reason = declineReason
+	}
+	eventID := events.DeterministicID(subject, payload.OrderID)
+	contentType := receiver.encoding.ContentTypeFor(subject)
+
+	var data []byte
+	var err error
+	if success {
+		data, err = events.Marshal(contentType, events.NewEnvelope(eventSource, events.OrderPaidPayload{
 			OrderID:     payload.OrderID,
 			UserID:      payload.UserID,
 			TotalAmount: payload.TotalAmount,
 			PaidAt:      time.Now().Unix(),
-		}
+		}).WithID(eventID).WithCorrelationID(correlationID).WithTenant(created.TenantID))
 	} else {
-		event = events.OrderFailedPayload{
+		data, err = events.Marshal(contentType, events.NewEnvelope(eventSource, events.OrderFailedPayload{
 			OrderID:  payload.OrderID,
 			UserID:   payload.UserID,
-			Reason:   "payment declined",
+			Reason:   reason,
 			FailedAt: time.Now().Unix(),
-		}
+		}).WithID(eventID).WithCorrelationID(correlationID).WithTenant(created.TenantID))
 	}
-
-	data, err := json.Marshal(event)
 	if err != nil {
-		receiver.logger.Error("failed to marshal event on <publishResult> of <Processor>", zap.Error(err))
+		receiver.logger.Error("failed to encode event on <publishResult> of <Processor>", zap.Error(err))
 		return
 	}
 
-	if err := receiver.natsConn.Publish(subject, data); err != nil {
+	msg := bus.NewMessage(subject, data)
+	msg.Header.Set(events.HeaderContentType, contentType)
+	msg.Header.Set(events.HeaderMsgID, eventID)
+
+	if err := receiver.bus.Publish(ctx, msg); err != nil {
 		receiver.logger.Error("failed to publish billing event on <publishResult> of <Processor>",
 			zap.String("subject", subject),
 			zap.Error(err))
@@ -151,6 +320,8 @@ func (receiver *Processor) publishResult(ctx context.Context, payload events.Ord
 	} else {
 		receiver.logger.Info("published event on <publishResult> of <Processor>",
 			zap.String("subject", subject),
+			zap.String("event_id", eventID),
+			zap.String("correlation_id", correlationID),
 			zap.String("order_id", payload.OrderID))
 	}
 
@@ -0,0 +1,205 @@
+package billing
+
+import (
+	"context"
+	"order-service-system/billing_service/internal/repository/payments"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"time"
+
+	orderpb "order-service-system/proto/order"
+
+	"go.uber.org/zap"
+)
+
+const notPaidReason = "order was not paid"
+
+type RefundStore interface {
+	Get(ctx context.Context, orderID string) (payments.Payment, bool, error)
+	GetRefund(ctx context.Context, returnID string) (payments.Refund, bool, error)
+	SaveRefund(ctx context.Context, refund payments.Refund) error
+}
+
+type RefundClient interface {
+	RecordRefund(ctx context.Context, returnID string, refunded bool, reason string) (*orderpb.RecordRefundResponse, error)
+}
+
+// RefundProcessor refunds received returns. A return is refunded once: the outcome
+// is stored before it is reported, redeliveries only report it again.
+type RefundProcessor struct {
+	logger      *zap.Logger
+	bus         bus.Bus
+	inbox       Inbox
+	payments    RefundStore
+	orderClient RefundClient
+	encoding    events.EncodingConfig
+}
+
+type RefundDeps struct {
+	Logger      *zap.Logger
+	Bus         bus.Bus
+	Inbox       Inbox
+	Payments    RefundStore
+	OrderClient RefundClient
+	Encoding    events.EncodingConfig
+}
+
+func NewRefundProcessor(deps RefundDeps) *RefundProcessor {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewRefundProcessor> of <RefundProcessor>")
+	}
+	if deps.Bus == nil {
+		panic("bus must not be nil on <NewRefundProcessor> of <RefundProcessor>")
+	}
+	if deps.Inbox == nil {
+		panic("inbox must not be nil on <NewRefundProcessor> of <RefundProcessor>")
+	}
+	if deps.Payments == nil {
+		panic("payment store must not be nil on <NewRefundProcessor> of <RefundProcessor>")
+	}
+	if deps.OrderClient == nil {
+		panic("order client must not be nil on <NewRefundProcessor> of <RefundProcessor>")
+	}
+	return &RefundProcessor{
+		logger:      deps.Logger,
+		bus:         deps.Bus,
+		inbox:       deps.Inbox,
+		payments:    deps.Payments,
+		orderClient: deps.OrderClient,
+		encoding:    deps.Encoding,
+	}
+}
+
+func (receiver *RefundProcessor) Start(ctx context.Context) (bus.Subscription, error) {
+	sub, err := receiver.bus.Subscribe(ctx, events.TypeReturnReceived, queueBilling, receiver.handleMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver.logger.Info("listening for return.received on <Start> of <RefundProcessor>",
+		zap.String("subject", events.TypeReturnReceived),
+		zap.String("queue", queueBilling),
+	)
+	return sub, nil
+}
+
+func (receiver *RefundProcessor) handleMessage(ctx context.Context, msg *bus.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	envelope, err := events.Unmarshal[events.ReturnReceivedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode return.received on <handleMessage> of <RefundProcessor>", zap.Error(err))
+		return nil
+	}
+	payload := envelope.Data
+	if payload.ReturnID == "" || payload.OrderID == "" {
+		receiver.logger.Error("invalid payload on <handleMessage> of <RefundProcessor>", zap.Any("payload", payload))
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+
+	eventID := events.ResolveID(envelope.ID, msg.Header.Get(events.HeaderMsgID), events.TypeReturnReceived, payload.ReturnID)
+	claimed, err := receiver.inbox.Claim(ctx, eventID)
+	if err != nil {
+		receiver.logger.Error("failed to claim event on <handleMessage> of <RefundProcessor>", zap.String("event_id", eventID), zap.Error(err))
+		return err
+	}
+	if !claimed {
+		receiver.logger.Info("skipping duplicate return.received on <handleMessage> of <RefundProcessor>",
+			zap.String("event_id", eventID),
+			zap.String("return_id", payload.ReturnID))
+		return nil
+	}
+
+	refund, err := receiver.refund(ctx, payload)
+	if err == nil {
+		err = receiver.report(ctx, envelope, refund)
+	}
+	if err != nil {
+		if releaseErr := receiver.inbox.Release(context.WithoutCancel(ctx), eventID); releaseErr != nil {
+			receiver.logger.Error("failed to release event on <handleMessage> of <RefundProcessor>", zap.String("event_id", eventID), zap.Error(releaseErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// refund decides the refund of the return once; only paid orders are refunded.
+func (receiver *RefundProcessor) refund(ctx context.Context, payload events.ReturnReceivedPayload) (payments.Refund, error) {
+	refund, found, err := receiver.payments.GetRefund(ctx, payload.ReturnID)
+	if err != nil {
+		receiver.logger.Error("failed to read refund on <refund> of <RefundProcessor>", zap.String("return_id", payload.ReturnID), zap.Error(err))
+		return payments.Refund{}, err
+	}
+	if found {
+		return refund, nil
+	}
+
+	payment, found, err := receiver.payments.Get(ctx, payload.OrderID)
+	if err != nil {
+		receiver.logger.Error("failed to read payment on <refund> of <RefundProcessor>", zap.String("order_id", payload.OrderID), zap.Error(err))
+		return payments.Refund{}, err
+	}
+
+	refund = payments.Refund{
+		ReturnID:  payload.ReturnID,
+		TenantID:  tenant.FromContext(ctx),
+		OrderID:   payload.OrderID,
+		Amount:    payload.Amount,
+		Refunded:  found && payment.Outcome == events.PaymentOutcomePaid,
+		UpdatedAt: time.Now().UTC(),
+	}
+	result := refundRefunded
+	if !refund.Refunded {
+		refund.Reason, result = notPaidReason, refundFailed
+	}
+	if err := receiver.payments.SaveRefund(ctx, refund); err != nil {
+		receiver.logger.Error("failed to save refund on <refund> of <RefundProcessor>", zap.String("return_id", payload.ReturnID), zap.Error(err))
+		return payments.Refund{}, err
+	}
+	refundsTotal.WithLabelValues(result).Inc()
+	if refund.Refunded {
+		refundAmount.Add(refund.Amount)
+	}
+
+	receiver.logger.Info("refund decided on <refund> of <RefundProcessor>",
+		zap.String("return_id", refund.ReturnID),
+		zap.String("order_id", refund.OrderID),
+		zap.Float64("amount", refund.Amount),
+		zap.Bool("refunded", refund.Refunded))
+	return refund, nil
+}
+
+// report publishes return.refunded and records the outcome in the order service.
+func (receiver *RefundProcessor) report(ctx context.Context, received events.Envelope[events.ReturnReceivedPayload], refund payments.Refund) error {
+	payload := received.Data
+	if refund.Refunded {
+		eventID := events.DeterministicID(events.TypeReturnRefunded, refund.ReturnID)
+		contentType := receiver.encoding.ContentTypeFor(events.TypeReturnRefunded)
+		data, err := events.Marshal(contentType, events.NewEnvelope(eventSource, events.ReturnRefundedPayload{
+			ReturnID:   refund.ReturnID,
+			OrderID:    refund.OrderID,
+			UserID:     payload.UserID,
+			Amount:     refund.Amount,
+			RefundedAt: refund.UpdatedAt.Unix(),
+		}).WithID(eventID).WithCorrelationID(received.CorrelationID).WithTenant(tenant.FromContext(ctx)))
+		if err != nil {
+			receiver.logger.Error("failed to encode event on <report> of <RefundProcessor>", zap.Error(err))
+		} else {
+			msg := bus.NewMessage(events.TypeReturnRefunded, data)
+			msg.Header.Set(events.HeaderContentType, contentType)
+			msg.Header.Set(events.HeaderMsgID, eventID)
+			if err := receiver.bus.Publish(ctx, msg); err != nil {
+				receiver.logger.Error("failed to publish refund event on <report> of <RefundProcessor>", zap.String("return_id", refund.ReturnID), zap.Error(err))
+			}
+		}
+	}
+
+	if _, err := receiver.orderClient.RecordRefund(ctx, refund.ReturnID, refund.Refunded, refund.Reason); err != nil {
+		receiver.logger.Error("failed to record refund on <report> of <RefundProcessor>", zap.String("return_id", refund.ReturnID), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+
+	"go.uber.org/zap"
+)
+
+// StatusResponder answers payment status requests of the order service reconciliation.
+type StatusResponder struct {
+	logger   *zap.Logger
+	bus      bus.Bus
+	payments PaymentStore
+}
+
+type StatusResponderDeps struct {
+	Logger   *zap.Logger
+	Bus      bus.Bus
+	Payments PaymentStore
+}
+
+func NewStatusResponder(deps StatusResponderDeps) *StatusResponder {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewStatusResponder> of <StatusResponder>")
+	}
+	if deps.Bus == nil {
+		panic("bus must not be nil on <NewStatusResponder> of <StatusResponder>")
+	}
+	if deps.Payments == nil {
+		panic("payment store must not be nil on <NewStatusResponder> of <StatusResponder>")
+	}
+	return &StatusResponder{
+		logger:   deps.Logger,
+		bus:      deps.Bus,
+		payments: deps.Payments,
+	}
+}
+
+func (receiver *StatusResponder) Start(ctx context.Context) (bus.Subscription, error) {
+	sub, err := receiver.bus.Subscribe(ctx, events.SubjectPaymentStatus, queueBilling, receiver.handleRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver.logger.Info("answering payment status requests on <Start> of <StatusResponder>",
+		zap.String("subject", events.SubjectPaymentStatus))
+	return sub, nil
+}
+
+func (receiver *StatusResponder) handleRequest(ctx context.Context, msg *bus.Message) error {
+	var request events.PaymentStatusRequest
+	if err := json.Unmarshal(msg.Data, &request); err != nil || request.OrderID == "" {
+		receiver.logger.Error("invalid payment status request on <handleRequest> of <StatusResponder>", zap.Error(err))
+		return nil
+	}
+
+	payment, found, err := receiver.payments.Get(ctx, request.OrderID)
+	if err != nil {
+		// Без ответа запрашивающий получит таймаут и повторит запрос позже.
+		receiver.logger.Error("failed to read payment on <handleRequest> of <StatusResponder>", zap.String("order_id", request.OrderID), zap.Error(err))
+		return nil
+	}
+
+	reply := events.PaymentStatusReply{OrderID: request.OrderID, Outcome: events.PaymentOutcomeUnknown}
+	if found {
+		reply.Outcome = payment.Outcome
+		reply.Reason = payment.Reason
+		if payment.Decided() {
+			reply.DecidedAt = payment.UpdatedAt.Unix()
+		}
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		receiver.logger.Error("failed to encode payment status on <handleRequest> of <StatusResponder>", zap.Error(err))
+		return nil
+	}
+	if err := bus.Respond(ctx, receiver.bus, msg, data); err != nil {
+		receiver.logger.Warn("failed to reply to payment status request on <handleRequest> of <StatusResponder>",
+			zap.String("order_id", request.OrderID),
+			zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service-system/billing_service/internal/repository/payments"
+	"order-service-system/billing_service/internal/workers/billing"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type memoryInbox struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newMemoryInbox() *memoryInbox {
+	return &memoryInbox{seen: map[string]struct{}{}}
+}
+
+func (f *memoryInbox) Claim(_ context.Context, id string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.seen[id]; ok {
+		return false, nil
+	}
+	f.seen[id] = struct{}{}
+	return true, nil
+}
+
+func (f *memoryInbox) Release(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.seen, id)
+	return nil
+}
+
+type memoryPayments struct {
+	mu       sync.Mutex
+	payments map[string]payments.Payment
+	refunds  map[string]payments.Refund
+	cancels  map[string]payments.CancellationRefund
+}
+
+func newMemoryPayments() *memoryPayments {
+	return &memoryPayments{
+		payments: map[string]payments.Payment{},
+		refunds:  map[string]payments.Refund{},
+		cancels:  map[string]payments.CancellationRefund{},
+	}
+}
+
+func (f *memoryPayments) Get(_ context.Context, orderID string) (payments.Payment, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payment, ok := f.payments[orderID]
+	return payment, ok, nil
+}
+
+func (f *memoryPayments) Save(payment payments.Payment) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.payments[payment.OrderID] = payment
+	return nil
+}
+
+func (f *memoryPayments) Update(_ context.Context, orderID string, fn func(payment *payments.Payment, found bool)) (payments.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payment, ok := f.payments[orderID]
+	fn(&payment, ok)
+	payment.OrderID = orderID
+	f.payments[orderID] = payment
+	return payment, nil
+}
+
+func (f *memoryPayments) GetRefund(_ context.Context, returnID string) (payments.Refund, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	refund, ok := f.refunds[returnID]
+	return refund, ok, nil
+}
+
+func (f *memoryPayments) SaveRefund(_ context.Context, refund payments.Refund) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.refunds[refund.ReturnID]; !ok {
+		f.refunds[refund.ReturnID] = refund
+	}
+	return nil
+}
+
+func (f *memoryPayments) GetCancellationRefund(_ context.Context, cancellationID string) (payments.CancellationRefund, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	refund, ok := f.cancels[cancellationID]
+	return refund, ok, nil
+}
+
+func (f *memoryPayments) SaveCancellationRefund(_ context.Context, refund payments.CancellationRefund) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.cancels[refund.CancellationID]; !ok {
+		f.cancels[refund.CancellationID] = refund
+	}
+	return nil
+}
+
+type mockOrderClient struct {
+	mu      sync.Mutex
+	updates map[string]orderpb.OrderStatus
+	calls   int
+	refunds map[string]bool
+}
+
+func (f *mockOrderClient) RecordRefund(ctx context.Context, returnID string, refunded bool, reason string) (*orderpb.RecordRefundResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.refunds == nil {
+		f.refunds = map[string]bool{}
+	}
+	f.refunds[returnID] = refunded
+	f.calls++
+	return &orderpb.RecordRefundResponse{}, nil
+}
+
+func (f *mockOrderClient) UpdateOrderStatus(ctx context.Context, orderID string, status orderpb.OrderStatus) (*orderpb.UpdateOrderStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.updates == nil {
+		f.updates = map[string]orderpb.OrderStatus{}
+	}
+	f.updates[orderID] = status
+	f.calls++
+	return &orderpb.UpdateOrderStatusResponse{}, nil
+}
+
+func publishOrderCreated(t *testing.T, b bus.Publisher, orderID string) {
+	t.Helper()
+	envelope := events.NewEnvelope("order-service", events.OrderCreatedPayload{
+		OrderID:     orderID,
+		UserID:      "u1",
+		TotalAmount: 15,
+		CreatedAt:   time.Now().Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderCreated, orderID))
+
+	data, err := events.Encode(envelope)
+	require.NoError(t, err)
+
+	msg := bus.NewMessage(events.TypeOrderCreated, data)
+	msg.Header.Set(events.HeaderContentType, events.ContentTypeJSON)
+	msg.Header.Set(events.HeaderMsgID, envelope.ID)
+	require.NoError(t, b.Publish(context.Background(), msg))
+}
+
+func TestProcessor_PaysOnceAndPublishesResult(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+	ctx := context.Background()
+
+	processor := billing.NewProcessor(billing.Deps{
+		Logger:      zap.NewNop(),
+		Bus:         memory,
+		Inbox:       newMemoryInbox(),
+		Payments:    newMemoryPayments(),
+		OrderClient: orderClient,
+		SuccessRate: 1,
+	})
+
+	paid := make(chan events.Envelope[events.OrderPaidPayload], 2)
+	paidSub, err := memory.Subscribe(ctx, events.TypeOrderPaid, "", func(ctx context.Context, msg *bus.Message) error {
+		envelope, err := events.Unmarshal[events.OrderPaidPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+		require.NoError(t, err)
+		require.Equal(t, envelope.ID, msg.Header.Get(events.HeaderMsgID))
+		paid <- envelope
+		return nil
+	})
+	require.NoError(t, err)
+
+	sub, err := processor.Start(ctx)
+	require.NoError(t, err)
+
+	publishOrderCreated(t, memory, "o1")
+	publishOrderCreated(t, memory, "o1")
+
+	require.NoError(t, sub.Drain())
+	require.NoError(t, paidSub.Drain())
+	close(paid)
+
+	var published []events.Envelope[events.OrderPaidPayload]
+	for envelope := range paid {
+		published = append(published, envelope)
+	}
+	require.Len(t, published, 1)
+	require.Equal(t, "o1", published[0].Data.OrderID)
+	require.Equal(t, events.DeterministicID(events.TypeOrderPaid, "o1"), published[0].ID)
+	require.Equal(t, events.DeterministicID(events.TypeOrderCreated, "o1"), published[0].CorrelationID)
+
+	require.Equal(t, 1, orderClient.calls)
+	require.Equal(t, orderpb.OrderStatus_PAID, orderClient.updates["o1"])
+}
+
+func TestProcessor_DoesNotChargeDecidedOrderAgain(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+	store := newMemoryPayments()
+	require.NoError(t, store.Save(payments.Payment{OrderID: "o1", Outcome: events.PaymentOutcomeDeclined, Reason: "payment declined"}))
+
+	processor := billing.NewProcessor(billing.Deps{
+		Logger:      zap.NewNop(),
+		Bus:         memory,
+		Inbox:       newMemoryInbox(),
+		Payments:    store,
+		OrderClient: orderClient,
+		SuccessRate: 1,
+	})
+	sub, err := processor.Start(context.Background())
+	require.NoError(t, err)
+
+	publishOrderCreated(t, memory, "o1")
+	require.NoError(t, sub.Drain())
+
+	// Решение о платеже не меняется, повторяется только результат.
+	require.Equal(t, orderpb.OrderStatus_FAILED, orderClient.updates["o1"])
+	payment, _, _ := store.Get(context.Background(), "o1")
+	require.Equal(t, events.PaymentOutcomeDeclined, payment.Outcome)
+}
+
+func TestStatusResponder_AnswersPaymentOutcome(t *testing.T) {
+	memory := bus.NewMemory()
+	ctx := context.Background()
+	store := newMemoryPayments()
+	require.NoError(t, store.Save(payments.Payment{OrderID: "o1", Outcome: events.PaymentOutcomePaid, UpdatedAt: time.Unix(100, 0)}))
+
+	responder := billing.NewStatusResponder(billing.StatusResponderDeps{Logger: zap.NewNop(), Bus: memory, Payments: store})
+	sub, err := responder.Start(ctx)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	ask := func(orderID string) events.PaymentStatusReply {
+		data, err := json.Marshal(events.PaymentStatusRequest{OrderID: orderID})
+		require.NoError(t, err)
+		msg, err := memory.Request(ctx, bus.NewMessage(events.SubjectPaymentStatus, data))
+		require.NoError(t, err)
+		var reply events.PaymentStatusReply
+		require.NoError(t, json.Unmarshal(msg.Data, &reply))
+		return reply
+	}
+
+	require.Equal(t, events.PaymentStatusReply{OrderID: "o1", Outcome: events.PaymentOutcomePaid, DecidedAt: 100}, ask("o1"))
+	require.Equal(t, events.PaymentStatusReply{OrderID: "o2", Outcome: events.PaymentOutcomeUnknown}, ask("o2"))
+}
+
+func TestRefundProcessor_RefundsPaidOrdersOnce(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+	store := newMemoryPayments()
+	require.NoError(t, store.Save(payments.Payment{OrderID: "o1", Outcome: events.PaymentOutcomePaid}))
+	ctx := context.Background()
+
+	processor := billing.NewRefundProcessor(billing.RefundDeps{
+		Logger:      zap.NewNop(),
+		Bus:         memory,
+		Inbox:       newMemoryInbox(),
+		Payments:    store,
+		OrderClient: orderClient,
+	})
+
+	refunded := make(chan events.ReturnRefundedPayload, 2)
+	refundedSub, err := memory.Subscribe(ctx, events.TypeReturnRefunded, "", func(ctx context.Context, msg *bus.Message) error {
+		envelope, err := events.Unmarshal[events.ReturnRefundedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+		require.NoError(t, err)
+		refunded <- envelope.Data
+		return nil
+	})
+	require.NoError(t, err)
+
+	sub, err := processor.Start(ctx)
+	require.NoError(t, err)
+
+	publishReceived := func(returnID string, orderID string) {
+		envelope := events.NewEnvelope("order-service", events.ReturnReceivedPayload{
+			ReturnID: returnID,
+			OrderID:  orderID,
+			UserID:   "u1",
+			Amount:   20,
+		}).WithID(events.DeterministicID(events.TypeReturnReceived, returnID))
+		data, err := events.Encode(envelope)
+		require.NoError(t, err)
+		require.NoError(t, memory.Publish(ctx, bus.NewMessage(events.TypeReturnReceived, data)))
+	}
+	publishReceived("r1", "o1")
+	publishReceived("r1", "o1")
+	publishReceived("r2", "unpaid")
+
+	require.NoError(t, sub.Drain())
+	require.NoError(t, refundedSub.Drain())
+	close(refunded)
+
+	var published []events.ReturnRefundedPayload
+	for payload := range refunded {
+		published = append(published, payload)
+	}
+	require.Len(t, published, 1)
+	require.Equal(t, 20.0, published[0].Amount)
+
+	require.Equal(t, 2, orderClient.calls)
+	require.Equal(t, map[string]bool{"r1": true, "r2": false}, orderClient.refunds)
+	refund, _, _ := store.GetRefund(context.Background(), "r2")
+	require.Equal(t, "order was not paid", refund.Reason)
+}
+
+func TestAmendmentProcessor_ChargesLatestAmount(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+	store := newMemoryPayments()
+	ctx := context.Background()
+
+	processor := billing.NewProcessor(billing.Deps{
+		Logger:      zap.NewNop(),
+		Bus:         memory,
+		Inbox:       newMemoryInbox(),
+		Payments:    store,
+		OrderClient: orderClient,
+		SuccessRate: 1,
+	})
+	amendments := billing.NewAmendmentProcessor(billing.AmendmentDeps{Logger: zap.NewNop(), Bus: memory, Payments: store})
+
+	paid := make(chan events.OrderPaidPayload, 1)
+	paidSub, err := memory.Subscribe(ctx, events.TypeOrderPaid, "", func(ctx context.Context, msg *bus.Message) error {
+		envelope, err := events.Unmarshal[events.OrderPaidPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+		require.NoError(t, err)
+		paid <- envelope.Data
+		return nil
+	})
+	require.NoError(t, err)
+	amendSub, err := amendments.Start(ctx)
+	require.NoError(t, err)
+	sub, err := processor.Start(ctx)
+	require.NoError(t, err)
+
+	publishAmended := func(version int64, total float64) {
+		envelope := events.NewEnvelope("order-service", events.OrderAmendedPayload{
+			OrderID:       "o1",
+			UserID:        "u1",
+			Version:       version,
+			TotalAmount:   total,
+			PreviousTotal: 15,
+		})
+		data, err := events.Encode(envelope)
+		require.NoError(t, err)
+		require.NoError(t, memory.Publish(ctx, bus.NewMessage(events.TypeOrderAmended, data)))
+	}
+
+	// Изменение пришло раньше order.created: списывается уже новая сумма.
+	publishAmended(2, 9)
+	publishOrderCreated(t, memory, "o1")
+	require.NoError(t, sub.Drain())
+	require.Equal(t, 9.0, (<-paid).TotalAmount)
+
+	// После оплаты списание переоформляется, устаревшая версия игнорируется.
+	publishAmended(3, 4)
+	publishAmended(2, 9)
+	require.NoError(t, amendSub.Drain())
+	require.NoError(t, paidSub.Drain())
+
+	payment, _, _ := store.Get(context.Background(), "o1")
+	require.Equal(t, events.PaymentOutcomePaid, payment.Outcome)
+	require.Equal(t, 4.0, payment.Amount)
+	require.Equal(t, int64(3), payment.Version)
+}
+
+func TestCancellationProcessor_RefundsOnce(t *testing.T) {
+	memory := bus.NewMemory()
+	store := newMemoryPayments()
+	require.NoError(t, store.Save(payments.Payment{OrderID: "o1", Outcome: events.PaymentOutcomePaid}))
+	ctx := context.Background()
+
+	processor := billing.NewCancellationProcessor(billing.CancellationDeps{Logger: zap.NewNop(), Bus: memory, Payments: store})
+	sub, err := processor.Start(ctx)
+	require.NoError(t, err)
+
+	publishCancelled := func(cancellationID string, orderID string, amount float64) {
+		data, err := events.Encode(events.NewEnvelope("order-service", events.OrderItemsCancelledPayload{
+			CancellationID: cancellationID,
+			OrderID:        orderID,
+			UserID:         "u1",
+			Items:          []events.CancelledItem{{ProductID: "p1", Quantity: 1, Price: amount}},
+			Amount:         amount,
+		}))
+		require.NoError(t, err)
+		require.NoError(t, memory.Publish(ctx, bus.NewMessage(events.TypeOrderItemsCancelled, data)))
+	}
+	publishCancelled("c1", "o1", 10)
+	publishCancelled("c1", "o1", 99)
+	publishCancelled("c2", "unpaid", 5)
+	require.NoError(t, sub.Drain())
+
+	refund, found, _ := store.GetCancellationRefund(context.Background(), "c1")
+	require.True(t, found)
+	require.True(t, refund.Refunded)
+	require.Equal(t, 10.0, refund.Amount)
+	refund, _, _ = store.GetCancellationRefund(context.Background(), "c2")
+	require.False(t, refund.Refunded)
+	require.Equal(t, "order was not paid", refund.Reason)
+}
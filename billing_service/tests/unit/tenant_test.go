@@ -0,0 +1,76 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service-system/billing_service/internal/workers/billing"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func publishTenantOrderCreated(t *testing.T, b bus.Publisher, tenantID string, orderID string, currency string) {
+	t.Helper()
+	envelope := events.NewEnvelope("order-service", events.OrderCreatedPayload{
+		OrderID:     orderID,
+		UserID:      "u1",
+		TotalAmount: 15,
+		Currency:    currency,
+		CreatedAt:   time.Now().Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderCreated, orderID)).WithTenant(tenantID)
+
+	data, err := events.Encode(envelope)
+	require.NoError(t, err)
+
+	msg := bus.NewMessage(events.TypeOrderCreated, data)
+	msg.Header.Set(events.HeaderContentType, events.ContentTypeJSON)
+	msg.Header.Set(events.HeaderMsgID, envelope.ID)
+	require.NoError(t, b.Publish(context.Background(), msg))
+}
+
+func TestProcessor_AppliesTenantSettings(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+	store := newMemoryPayments()
+	never := 0.0
+
+	processor := billing.NewProcessor(billing.Deps{
+		Logger:      zap.NewNop(),
+		Bus:         memory,
+		Inbox:       newMemoryInbox(),
+		Payments:    store,
+		OrderClient: orderClient,
+		SuccessRate: 1,
+		Tenants: tenant.NewSettings(billing.TenantSettings{}, map[string]billing.TenantSettings{
+			"shop-a": {Currencies: []string{"EUR"}},
+			"shop-b": {PaymentSuccessRate: &never},
+		}),
+	})
+	sub, err := processor.Start(context.Background())
+	require.NoError(t, err)
+
+	publishTenantOrderCreated(t, memory, "", "o-default", "")
+	publishTenantOrderCreated(t, memory, "shop-a", "o-eur", "EUR")
+	publishTenantOrderCreated(t, memory, "shop-a", "o-usd", "USD")
+	publishTenantOrderCreated(t, memory, "shop-b", "o-declined", "")
+	require.NoError(t, sub.Drain())
+
+	require.Equal(t, orderpb.OrderStatus_PAID, orderClient.updates["o-default"])
+	require.Equal(t, orderpb.OrderStatus_PAID, orderClient.updates["o-eur"])
+	require.Equal(t, orderpb.OrderStatus_FAILED, orderClient.updates["o-usd"])
+	require.Equal(t, orderpb.OrderStatus_FAILED, orderClient.updates["o-declined"])
+
+	payment, _, _ := store.Get(context.Background(), "o-usd")
+	require.Equal(t, "shop-a", payment.TenantID)
+	require.Equal(t, "USD", payment.Currency)
+	require.Equal(t, "currency USD is not accepted", payment.Reason)
+
+	payment, _, _ = store.Get(context.Background(), "o-default")
+	require.Equal(t, tenant.Default, payment.TenantID)
+}
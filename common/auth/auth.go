@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+type PrincipalType string
+
+const (
+	PrincipalUser    PrincipalType = "user"
+	PrincipalService PrincipalType = "service"
+)
+
+// Principal is the authenticated caller. For users Subject is the user id, for
+// services it is the service name, e.g. "billing-service". Tenant is set for users
+// of a single storefront; services act in any tenant. Email is the contact email
+// of a user, when the token has one.
+type Principal struct {
+	Subject string
+	Type    PrincipalType
+	Tenant  string
+	Email   string
+}
+
+func (p Principal) IsService() bool {
+	return p.Type == PrincipalService
+}
+
+type Configuration struct {
+	Disabled bool   `env:"AUTH_DISABLED" envDefault:"false"`
+	JWKSFile string `env:"AUTH_JWKS_FILE"`
+	// StaticKeys are HMAC keys in the form kid:base64secret.
+	StaticKeys []string `env:"AUTH_STATIC_KEYS" envSeparator:","`
+	// ServiceKeys are HMAC keys in the form kid:base64secret shared with the services.
+	// Only tokens signed with them are service tokens; a principal_type=service claim
+	// signed with any other key is rejected.
+	ServiceKeys []string      `env:"AUTH_SERVICE_KEYS" envSeparator:","`
+	Issuer      string        `env:"AUTH_ISSUER"`
+	Audience    string        `env:"AUTH_AUDIENCE" envDefault:"order-service"`
+	Leeway      time.Duration `env:"AUTH_LEEWAY" envDefault:"30s"`
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the caller; ok is false when authentication is disabled.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Actor names the caller in audit trails: "user:<id>", "service:<name>" or
+// "anonymous" when authentication is disabled.
+func Actor(ctx context.Context) string {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok || principal.Subject == "" {
+		return "anonymous"
+	}
+	if principal.IsService() {
+		return "service:" + principal.Subject
+	}
+	return "user:" + principal.Subject
+}
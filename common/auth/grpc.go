@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicPrefixes are served without a token: health checks must work for probes.
+var publicPrefixes = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.",
+}
+
+// UnaryServerInterceptor authenticates the bearer token of every call and puts the
+// principal into the context. Calls already identified by a client certificate are
+// not asked for a token.
+func UnaryServerInterceptor(verifier *Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, verifier)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(verifier *Verifier) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := authenticate(stream.Context(), verifier)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &principalStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, verifier *Verifier) (context.Context, error) {
+	// Вызывающий уже опознан по клиентскому сертификату.
+	if _, ok := PrincipalFrom(ctx); ok {
+		return ctx, nil
+	}
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	principal, err := verifier.Verify(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, ErrInvalidToken.Error())
+	}
+	return WithPrincipal(ctx, principal), nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(value, " ")
+		if ok && strings.EqualFold(scheme, "bearer") && token != "" {
+			return strings.TrimSpace(token), nil
+		}
+	}
+	return "", ErrMissingToken
+}
+
+func isPublic(fullMethod string) bool {
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireService returns PermissionDenied unless the caller is one of the given services.
+// A caller without a principal is denied as well: it presented neither a token nor a
+// client certificate.
+func RequireService(ctx context.Context, services ...string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if ok && principal.IsService() {
+		for _, service := range services {
+			if principal.Subject == service {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.PermissionDenied, "caller is not allowed to call this method")
+}
+
+// UnaryRequirePrincipalInterceptor rejects calls that no earlier interceptor identified.
+// It guards servers that authenticate callers by client certificates alone, where a
+// call without a certificate would otherwise be treated as if authentication were off.
+func UnaryRequirePrincipalInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := requirePrincipal(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamRequirePrincipalInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := requirePrincipal(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func requirePrincipal(ctx context.Context, fullMethod string) error {
+	if isPublic(fullMethod) {
+		return nil
+	}
+	if _, ok := PrincipalFrom(ctx); !ok {
+		return status.Error(codes.Unauthenticated, "client certificate is required")
+	}
+	return nil
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// keySet maps key ids to verification keys: *rsa.PublicKey, *ecdsa.PublicKey or []byte for HMAC.
+type keySet map[string]any
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func loadJWKSFile(path string, keys keySet) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read jwks: %w", err)
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return fmt.Errorf("jwk %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return nil
+}
+
+func (jwk jsonWebKey) publicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.K, "="))
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// ParseStaticKey parses an HMAC key in the form kid:base64secret.
+func ParseStaticKey(value string) (string, []byte, error) {
+	kid, encoded, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok || kid == "" || encoded == "" {
+		return "", nil, fmt.Errorf("static key must be kid:base64secret")
+	}
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("decode static key %q: %w", kid, err)
+	}
+	return kid, secret, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// redactKey keeps the key id of a kid:base64secret key and hides the secret.
+func redactKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	kid, _, _ := strings.Cut(key, ":")
+	return kid + ":***"
+}
+
+func redactKeys(keys []string) []string {
+	redacted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		redacted = append(redacted, redactKey(key))
+	}
+	return redacted
+}
+
+// MarshalJSON hides the static and service keys when the configuration is logged.
+func (c Configuration) MarshalJSON() ([]byte, error) {
+	type plain Configuration
+	redacted := plain(c)
+	redacted.StaticKeys = redactKeys(c.StaticKeys)
+	redacted.ServiceKeys = redactKeys(c.ServiceKeys)
+	return json.Marshal(redacted)
+}
+
+// MarshalJSON hides the signing key when the configuration is logged.
+func (c SignerConfiguration) MarshalJSON() ([]byte, error) {
+	type plain SignerConfiguration
+	redacted := plain(c)
+	redacted.Key = redactKey(c.Key)
+	return json.Marshal(redacted)
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// ServiceFromCertificate derives the service identity from the SAN of a client
+// certificate: the last path segment of a URI SAN (spiffe://order-system/billing-service)
+// or, failing that, the first DNS SAN.
+func ServiceFromCertificate(cert *x509.Certificate) (string, bool) {
+	for _, uri := range cert.URIs {
+		if name := lastSegment(uri); name != "" {
+			return name, true
+		}
+	}
+	if len(cert.DNSNames) > 0 && cert.DNSNames[0] != "" {
+		return cert.DNSNames[0], true
+	}
+	return "", false
+}
+
+// UnaryPeerInterceptor puts the service principal of a verified client certificate
+// into the context. Calls without a certificate pass through unchanged.
+func UnaryPeerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withPeerPrincipal(ctx), req)
+	}
+}
+
+func StreamPeerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withPeerPrincipal(stream.Context())
+		if ctx == stream.Context() {
+			return handler(srv, stream)
+		}
+		return handler(srv, &principalStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func withPeerPrincipal(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	// VerifiedChains пуст, если сертификат не был предъявлен или не проверен.
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ctx
+	}
+	service, ok := ServiceFromCertificate(info.State.VerifiedChains[0][0])
+	if !ok {
+		return ctx
+	}
+	return WithPrincipal(ctx, Principal{Subject: service, Type: PrincipalService})
+}
+
+func lastSegment(uri *url.URL) string {
+	path := strings.Trim(uri.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[i+1:]
+	}
+	if path == "" {
+		return uri.Host
+	}
+	return path
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type SignerConfiguration struct {
+	// Key is the HMAC key shared with order_service in the form kid:base64secret.
+	Key      string        `env:"AUTH_SERVICE_KEY"`
+	Issuer   string        `env:"AUTH_ISSUER"`
+	Audience string        `env:"AUTH_AUDIENCE" envDefault:"order-service"`
+	TTL      time.Duration `env:"AUTH_SERVICE_TOKEN_TTL" envDefault:"5m"`
+}
+
+// Signer issues short-lived service tokens for calls between services and reuses
+// a token until it is close to expiry.
+type Signer struct {
+	subject  string
+	kid      string
+	secret   []byte
+	issuer   string
+	audience string
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewSigner(subject string, cfg SignerConfiguration) (*Signer, error) {
+	if subject == "" {
+		return nil, errors.New("signer subject is empty")
+	}
+	kid, secret, err := ParseStaticKey(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TTL <= 0 {
+		return nil, errors.New("service token ttl must be positive")
+	}
+
+	return &Signer{
+		subject:  subject,
+		kid:      kid,
+		secret:   secret,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.TTL,
+		now:      time.Now,
+	}, nil
+}
+
+func (s *Signer) Token(_ context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.token != "" && now.Add(s.ttl/5).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	expiresAt := now.Add(s.ttl)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   s.subject,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		PrincipalType: PrincipalService,
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = s.kid
+	signed, err := token.SignedString(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("sign service token: %w", err)
+	}
+
+	s.token, s.expiresAt = signed, expiresAt
+	return signed, nil
+}
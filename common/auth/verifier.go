@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Claims struct {
+	jwt.RegisteredClaims
+	PrincipalType PrincipalType `json:"principal_type,omitempty"`
+	Tenant        string        `json:"tenant,omitempty"`
+	Email         string        `json:"email,omitempty"`
+}
+
+// Verifier validates JWTs signed with the keys from a JWKS file or static HMAC keys.
+// Service tokens are accepted only when signed with one of the service keys, so a
+// user identity provider or a leaked development key cannot mint them.
+type Verifier struct {
+	keys        keySet
+	serviceKeys keySet
+	parser      *jwt.Parser
+}
+
+func NewVerifier(cfg Configuration) (*Verifier, error) {
+	keys := keySet{}
+	if cfg.JWKSFile != "" {
+		if err := loadJWKSFile(cfg.JWKSFile, keys); err != nil {
+			return nil, err
+		}
+	}
+	for _, value := range cfg.StaticKeys {
+		if value == "" {
+			continue
+		}
+		kid, secret, err := ParseStaticKey(value)
+		if err != nil {
+			return nil, err
+		}
+		keys[kid] = secret
+	}
+	serviceKeys := keySet{}
+	for _, value := range cfg.ServiceKeys {
+		if value == "" {
+			continue
+		}
+		kid, secret, err := ParseStaticKey(value)
+		if err != nil {
+			return nil, err
+		}
+		// Иначе по kid нельзя понять, каким ключом подписан токен.
+		if _, ok := keys[kid]; ok {
+			return nil, fmt.Errorf("service key %q has the kid of a user key", kid)
+		}
+		serviceKeys[kid] = secret
+	}
+	if len(keys) == 0 && len(serviceKeys) == 0 {
+		return nil, errors.New("no verification keys configured: set AUTH_JWKS_FILE, AUTH_STATIC_KEYS or AUTH_SERVICE_KEYS, or AUTH_DISABLED=true")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "HS256", "HS384", "HS512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &Verifier{keys: keys, serviceKeys: serviceKeys, parser: jwt.NewParser(options...)}, nil
+}
+
+func (v *Verifier) Verify(token string) (Principal, error) {
+	var claims Claims
+	var signedByService bool
+	keyFunc := func(token *jwt.Token) (any, error) {
+		key, service, err := v.keyFor(token)
+		signedByService = service
+		return key, err
+	}
+	if _, err := v.parser.ParseWithClaims(token, &claims, keyFunc); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: subject is empty", ErrInvalidToken)
+	}
+
+	principalType := claims.PrincipalType
+	if principalType == "" {
+		principalType = PrincipalUser
+		if signedByService {
+			principalType = PrincipalService
+		}
+	}
+	if principalType != PrincipalUser && principalType != PrincipalService {
+		return Principal{}, fmt.Errorf("%w: unknown principal type %q", ErrInvalidToken, principalType)
+	}
+	if (principalType == PrincipalService) != signedByService {
+		return Principal{}, fmt.Errorf("%w: %s token is signed with a key of another principal type", ErrInvalidToken, principalType)
+	}
+	return Principal{
+		Subject: claims.Subject,
+		Type:    principalType,
+		Tenant:  strings.ToLower(strings.TrimSpace(claims.Tenant)),
+		Email:   strings.ToLower(strings.TrimSpace(claims.Email)),
+	}, nil
+}
+
+// keyFor picks the key by kid, reports whether it is a service key and refuses
+// keys of a different family than the token algorithm, so an RSA public key can
+// never be used as an HMAC secret. A token without kid is accepted only when there
+// is a single user key and no service keys.
+func (v *Verifier) keyFor(token *jwt.Token) (any, bool, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, service := v.serviceKeys[kid]
+	ok := service
+	if !ok {
+		key, ok = v.keys[kid]
+	}
+	if !ok && kid == "" && len(v.keys) == 1 && len(v.serviceKeys) == 0 {
+		for _, only := range v.keys {
+			key, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if _, ok := key.([]byte); ok {
+			return key, service, nil
+		}
+	case *jwt.SigningMethodRSA:
+		if _, ok := key.(*rsa.PublicKey); ok {
+			return key, service, nil
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); ok {
+			return key, service, nil
+		}
+	}
+	return nil, false, fmt.Errorf("%w: key %q does not match algorithm %s", ErrUnknownKey, kid, token.Method.Alg())
+}
@@ -0,0 +1,99 @@
+package bus
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrClosed           = errors.New("bus is closed")
+	ErrInvalidSubject   = errors.New("invalid subject")
+	ErrSubscriptionDone = errors.New("subscription is closed")
+	ErrNoResponders     = errors.New("no responders for request")
+)
+
+type Header map[string][]string
+
+func (h Header) Get(key string) string {
+	if values := h[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (h Header) Set(key string, value string) {
+	h[key] = []string{value}
+}
+
+func (h Header) Add(key string, value string) {
+	h[key] = append(h[key], value)
+}
+
+func (h Header) Del(key string) {
+	delete(h, key)
+}
+
+func (h Header) clone() Header {
+	cloned := make(Header, len(h))
+	for k, v := range h {
+		cloned[k] = append([]string(nil), v...)
+	}
+	return cloned
+}
+
+type Message struct {
+	Subject string
+	// Reply is the subject the responder publishes the answer to; empty unless the message is a request.
+	Reply  string
+	Header Header
+	Data   []byte
+}
+
+func NewMessage(subject string, data []byte) *Message {
+	return &Message{
+		Subject: subject,
+		Header:  Header{},
+		Data:    data,
+	}
+}
+
+// Handler processes a delivered message. Returning nil acknowledges the message,
+// returning an error asks the transport to redeliver it when it supports redelivery.
+type Handler func(ctx context.Context, msg *Message) error
+
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+type Subscription interface {
+	Subject() string
+	Queue() string
+	IsValid() bool
+	Drain() error
+	Unsubscribe() error
+}
+
+// Subscriber delivers messages of the subject to the handler. Subscribers sharing
+// a non-empty queue group receive every message exactly once between them.
+type Subscriber interface {
+	Subscribe(ctx context.Context, subject string, queue string, handler Handler) (Subscription, error)
+}
+
+// Requester publishes a message and waits for the first reply until ctx is done.
+type Requester interface {
+	Request(ctx context.Context, msg *Message) (*Message, error)
+}
+
+// Respond publishes the answer to a request. Messages without a reply subject are ignored.
+func Respond(ctx context.Context, publisher Publisher, request *Message, data []byte) error {
+	if request == nil || request.Reply == "" {
+		return nil
+	}
+	return publisher.Publish(ctx, NewMessage(request.Reply, data))
+}
+
+type Bus interface {
+	Publisher
+	Subscriber
+	Requester
+}
@@ -0,0 +1,359 @@
+package bus
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type MemoryOption func(*Memory)
+
+// WithMaxDeliver sets how many times a message is handed to a handler that keeps failing.
+func WithMaxDeliver(n int) MemoryOption {
+	return func(m *Memory) {
+		if n > 0 {
+			m.maxDeliver = n
+		}
+	}
+}
+
+func WithRedeliveryDelay(d time.Duration) MemoryOption {
+	return func(m *Memory) {
+		if d >= 0 {
+			m.redeliveryDelay = d
+		}
+	}
+}
+
+// Memory is an in-process Bus with NATS-like semantics: wildcard subjects,
+// queue groups with round-robin delivery and ordered delivery per subscription.
+// Failed deliveries are retried up to the configured max deliver count.
+type Memory struct {
+	mu              sync.Mutex
+	subs            []*memorySubscription
+	cursors         map[string]int
+	closed          bool
+	maxDeliver      int
+	redeliveryDelay time.Duration
+	inboxes         atomic.Uint64
+}
+
+func NewMemory(opts ...MemoryOption) *Memory {
+	m := &Memory{
+		cursors:         map[string]int{},
+		maxDeliver:      1,
+		redeliveryDelay: 10 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (receiver *Memory) Publish(_ context.Context, msg *Message) error {
+	_, err := receiver.publish(msg)
+	return err
+}
+
+// Request subscribes to a unique inbox subject, publishes the message with it as
+// the reply subject and waits for the first answer.
+func (receiver *Memory) Request(ctx context.Context, msg *Message) (*Message, error) {
+	if msg == nil {
+		return nil, ErrInvalidSubject
+	}
+
+	replies := make(chan *Message, 1)
+	inbox := "_INBOX." + strconv.FormatUint(receiver.inboxes.Add(1), 10)
+	sub, err := receiver.Subscribe(ctx, inbox, "", func(_ context.Context, reply *Message) error {
+		select {
+		case replies <- reply:
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	request := copyMessage(msg)
+	request.Reply = inbox
+	delivered, err := receiver.publish(request)
+	if err != nil {
+		return nil, err
+	}
+	if delivered == 0 {
+		return nil, ErrNoResponders
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (receiver *Memory) publish(msg *Message) (int, error) {
+	if msg == nil || msg.Subject == "" || strings.ContainsAny(msg.Subject, "*>") {
+		return 0, ErrInvalidSubject
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	if receiver.closed {
+		return 0, ErrClosed
+	}
+
+	delivered := 0
+
+	groups := map[string][]*memorySubscription{}
+	var groupOrder []string
+	for _, sub := range receiver.subs {
+		if !sub.accepting() || !subjectMatches(sub.subject, msg.Subject) {
+			continue
+		}
+		if sub.queue == "" {
+			sub.enqueue(copyMessage(msg))
+			delivered++
+			continue
+		}
+		key := sub.subject + "\x00" + sub.queue
+		if _, ok := groups[key]; !ok {
+			groupOrder = append(groupOrder, key)
+		}
+		groups[key] = append(groups[key], sub)
+	}
+
+	for _, key := range groupOrder {
+		members := groups[key]
+		cursor := receiver.cursors[key]
+		receiver.cursors[key] = cursor + 1
+		members[cursor%len(members)].enqueue(copyMessage(msg))
+		delivered++
+	}
+	return delivered, nil
+}
+
+func (receiver *Memory) Subscribe(ctx context.Context, subject string, queue string, handler Handler) (Subscription, error) {
+	if subject == "" || handler == nil {
+		return nil, ErrInvalidSubject
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	if receiver.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &memorySubscription{
+		bus:     receiver,
+		ctx:     ctx,
+		subject: subject,
+		queue:   queue,
+		handler: handler,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	receiver.subs = append(receiver.subs, sub)
+	go sub.run()
+
+	return sub, nil
+}
+
+// Close stops every subscription without waiting for pending messages.
+func (receiver *Memory) Close() error {
+	receiver.mu.Lock()
+	receiver.closed = true
+	subs := receiver.subs
+	receiver.subs = nil
+	receiver.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.shutdown(false)
+	}
+	return nil
+}
+
+func (receiver *Memory) remove(sub *memorySubscription) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	for i, s := range receiver.subs {
+		if s == sub {
+			receiver.subs = append(receiver.subs[:i], receiver.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+type memorySubscription struct {
+	bus     *Memory
+	ctx     context.Context
+	subject string
+	queue   string
+	handler Handler
+
+	mu       sync.Mutex
+	pending  []*Message
+	draining bool
+	closed   bool
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (s *memorySubscription) Subject() string {
+	return s.subject
+}
+
+func (s *memorySubscription) Queue() string {
+	return s.queue
+}
+
+func (s *memorySubscription) IsValid() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.closed
+}
+
+// Drain stops accepting new messages and waits until the pending ones are handled.
+func (s *memorySubscription) Drain() error {
+	return s.shutdown(true)
+}
+
+func (s *memorySubscription) Unsubscribe() error {
+	return s.shutdown(false)
+}
+
+func (s *memorySubscription) shutdown(drain bool) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSubscriptionDone
+	}
+	s.draining = true
+	if !drain {
+		s.closed = true
+		s.pending = nil
+	}
+	s.mu.Unlock()
+
+	s.bus.remove(s)
+	if !drain {
+		s.once.Do(func() { close(s.stop) })
+	}
+	s.signal()
+	<-s.done
+
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySubscription) accepting() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.draining && !s.closed
+}
+
+func (s *memorySubscription) enqueue(msg *Message) {
+	s.mu.Lock()
+	s.pending = append(s.pending, msg)
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *memorySubscription) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *memorySubscription) next() (*Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 {
+		return nil, s.draining || s.closed
+	}
+	msg := s.pending[0]
+	s.pending = s.pending[1:]
+	return msg, false
+}
+
+func (s *memorySubscription) run() {
+	defer close(s.done)
+
+	for {
+		msg, finished := s.next()
+		if finished {
+			return
+		}
+		if msg == nil {
+			select {
+			case <-s.wake:
+			case <-s.stop:
+				return
+			}
+			continue
+		}
+		s.deliver(msg)
+	}
+}
+
+func (s *memorySubscription) deliver(msg *Message) {
+	for attempt := 1; ; attempt++ {
+		if err := s.handler(s.ctx, copyMessage(msg)); err == nil || attempt >= s.bus.maxDeliver {
+			return
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(s.bus.redeliveryDelay):
+		}
+	}
+}
+
+func copyMessage(msg *Message) *Message {
+	header := Header{}
+	if msg.Header != nil {
+		header = msg.Header.clone()
+	}
+	return &Message{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		Header:  header,
+		Data:    append([]byte(nil), msg.Data...),
+	}
+}
+
+// subjectMatches reports whether the subject matches the NATS style pattern,
+// where "*" matches a single token and ">" matches the remaining tokens.
+func subjectMatches(pattern string, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
@@ -0,0 +1,63 @@
+package bus
+
+import (
+	"context"
+	"order-service-system/common/metrics"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messagesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nats_messages_published_total",
+		Help: "Number of messages published to the bus by subject and result.",
+	}, []string{"subject", "result"})
+
+	messagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nats_messages_consumed_total",
+		Help: "Number of messages handled from the bus by subject, queue group and result.",
+	}, []string{"subject", "queue", "result"})
+
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nats_handler_duration_seconds",
+		Help:    "Time spent in message handlers by subject and queue group.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"subject", "queue"})
+)
+
+type metricsBus struct {
+	next Bus
+}
+
+// WithMetrics counts published and consumed messages and observes handler latency.
+func WithMetrics(next Bus) Bus {
+	return &metricsBus{next: next}
+}
+
+func (receiver *metricsBus) Publish(ctx context.Context, msg *Message) error {
+	err := receiver.next.Publish(ctx, msg)
+	if msg != nil {
+		messagesPublished.WithLabelValues(msg.Subject, metrics.Result(err)).Inc()
+	}
+	return err
+}
+
+func (receiver *metricsBus) Request(ctx context.Context, msg *Message) (*Message, error) {
+	reply, err := receiver.next.Request(ctx, msg)
+	if msg != nil {
+		messagesPublished.WithLabelValues(msg.Subject, metrics.Result(err)).Inc()
+	}
+	return reply, err
+}
+
+func (receiver *metricsBus) Subscribe(ctx context.Context, subject string, queue string, handler Handler) (Subscription, error) {
+	return receiver.next.Subscribe(ctx, subject, queue, func(ctx context.Context, msg *Message) error {
+		start := time.Now()
+		err := handler(ctx, msg)
+		handlerDuration.WithLabelValues(msg.Subject, queue).Observe(time.Since(start).Seconds())
+		messagesConsumed.WithLabelValues(msg.Subject, queue, metrics.Result(err)).Inc()
+		return err
+	})
+}
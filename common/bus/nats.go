@@ -0,0 +1,147 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+type NATS struct {
+	conn   *nats.Conn
+	logger *zap.Logger
+}
+
+func NewNATS(conn *nats.Conn, logger *zap.Logger) *NATS {
+	if conn == nil {
+		panic("nats connection must not be nil on <NewNATS> of <Bus>")
+	}
+	if logger == nil {
+		panic("logger must not be nil on <NewNATS> of <Bus>")
+	}
+	return &NATS{conn: conn, logger: logger}
+}
+
+func (receiver *NATS) Publish(_ context.Context, msg *Message) error {
+	if msg == nil || msg.Subject == "" {
+		return ErrInvalidSubject
+	}
+	return receiver.conn.PublishMsg(&nats.Msg{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		Header:  nats.Header(msg.Header),
+		Data:    msg.Data,
+	})
+}
+
+func (receiver *NATS) Request(ctx context.Context, msg *Message) (*Message, error) {
+	if msg == nil || msg.Subject == "" {
+		return nil, ErrInvalidSubject
+	}
+	reply, err := receiver.conn.RequestMsgWithContext(ctx, &nats.Msg{
+		Subject: msg.Subject,
+		Header:  nats.Header(msg.Header),
+		Data:    msg.Data,
+	})
+	if errors.Is(err, nats.ErrNoResponders) {
+		return nil, ErrNoResponders
+	}
+	if err != nil {
+		return nil, fmt.Errorf("request %s: %w", msg.Subject, err)
+	}
+
+	header := Header(reply.Header)
+	if header == nil {
+		header = Header{}
+	}
+	return &Message{Subject: reply.Subject, Header: header, Data: reply.Data}, nil
+}
+
+func (receiver *NATS) Subscribe(ctx context.Context, subject string, queue string, handler Handler) (Subscription, error) {
+	if subject == "" {
+		return nil, ErrInvalidSubject
+	}
+
+	callback := func(natsMsg *nats.Msg) {
+		msg := &Message{
+			Subject: natsMsg.Subject,
+			Reply:   natsMsg.Reply,
+			Header:  Header(natsMsg.Header),
+			Data:    natsMsg.Data,
+		}
+		if msg.Header == nil {
+			msg.Header = Header{}
+		}
+
+		err := handler(ctx, msg)
+		receiver.settle(natsMsg, err)
+	}
+
+	var sub *nats.Subscription
+	var err error
+	if queue == "" {
+		sub, err = receiver.conn.Subscribe(subject, callback)
+	} else {
+		sub, err = receiver.conn.QueueSubscribe(subject, queue, callback)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to %s: %w", subject, err)
+	}
+	if err := receiver.conn.Flush(); err != nil {
+		_ = sub.Unsubscribe()
+		return nil, fmt.Errorf("flush subscription to %s: %w", subject, err)
+	}
+
+	return &natsSubscription{sub: sub}, nil
+}
+
+// settle acknowledges JetStream deliveries. Core NATS has no redelivery, so a
+// handler error there is only reported.
+func (receiver *NATS) settle(natsMsg *nats.Msg, handlerErr error) {
+	if _, err := natsMsg.Metadata(); err != nil {
+		if handlerErr != nil {
+			receiver.logger.Warn("handler failed on core nats message on <settle> of <Bus>",
+				zap.String("subject", natsMsg.Subject),
+				zap.Error(handlerErr))
+		}
+		return
+	}
+
+	var err error
+	if handlerErr != nil {
+		err = natsMsg.Nak()
+	} else {
+		err = natsMsg.Ack()
+	}
+	if err != nil {
+		receiver.logger.Warn("failed to settle message on <settle> of <Bus>",
+			zap.String("subject", natsMsg.Subject),
+			zap.Error(err))
+	}
+}
+
+type natsSubscription struct {
+	sub *nats.Subscription
+}
+
+func (s *natsSubscription) Subject() string {
+	return s.sub.Subject
+}
+
+func (s *natsSubscription) Queue() string {
+	return s.sub.Queue
+}
+
+func (s *natsSubscription) IsValid() bool {
+	return s.sub.IsValid()
+}
+
+func (s *natsSubscription) Drain() error {
+	return s.sub.Drain()
+}
+
+func (s *natsSubscription) Unsubscribe() error {
+	return s.sub.Unsubscribe()
+}
@@ -0,0 +1,125 @@
+package bus
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "order-service-system/common/bus"
+
+// HeaderCarrier adapts message headers to the OpenTelemetry propagation API.
+type HeaderCarrier Header
+
+func (c HeaderCarrier) Get(key string) string {
+	return Header(c).Get(key)
+}
+
+func (c HeaderCarrier) Set(key string, value string) {
+	Header(c).Set(key, value)
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type tracingBus struct {
+	next Bus
+}
+
+// WithTracing injects the trace context into headers of published messages and
+// continues the trace in a consumer span for every delivered message.
+func WithTracing(next Bus) Bus {
+	return &tracingBus{next: next}
+}
+
+func (receiver *tracingBus) Publish(ctx context.Context, msg *Message) error {
+	if msg == nil {
+		return receiver.next.Publish(ctx, msg)
+	}
+	if msg.Header == nil {
+		msg.Header = Header{}
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "publish "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("nats"),
+			semconv.MessagingDestinationName(msg.Subject),
+			semconv.MessagingMessageBodySize(len(msg.Data)),
+		),
+	)
+	defer span.End()
+
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(msg.Header))
+
+	err := receiver.next.Publish(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (receiver *tracingBus) Request(ctx context.Context, msg *Message) (*Message, error) {
+	if msg == nil {
+		return receiver.next.Request(ctx, msg)
+	}
+	if msg.Header == nil {
+		msg.Header = Header{}
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "request "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("nats"),
+			semconv.MessagingDestinationName(msg.Subject),
+			semconv.MessagingMessageBodySize(len(msg.Data)),
+		),
+	)
+	defer span.End()
+
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(msg.Header))
+
+	reply, err := receiver.next.Request(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return reply, err
+}
+
+func (receiver *tracingBus) Subscribe(ctx context.Context, subject string, queue string, handler Handler) (Subscription, error) {
+	return receiver.next.Subscribe(ctx, subject, queue, func(ctx context.Context, msg *Message) error {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier(msg.Header))
+
+		ctx, span := otel.Tracer(tracerName).Start(ctx, "process "+msg.Subject,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				semconv.MessagingSystemKey.String("nats"),
+				semconv.MessagingDestinationName(msg.Subject),
+				semconv.MessagingMessageID(msg.Header.Get("Nats-Msg-Id")),
+				attribute.String("messaging.nats.queue", queue),
+			),
+		)
+		defer span.End()
+
+		err := handler(ctx, msg)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	})
+}
+
+var _ propagation.TextMapCarrier = HeaderCarrier{}
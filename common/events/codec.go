@@ -0,0 +1,52 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	HeaderContentType   = "Content-Type"
+	HeaderMsgID         = "Nats-Msg-Id"
+	ContentTypeJSON     = DataContentType
+	ContentTypeProtobuf = "application/protobuf"
+)
+
+var ErrUnsupportedContentType = errors.New("unsupported event content type")
+
+type EncodingConfig struct {
+	ProtobufSubjects []string `env:"EVENTS_PROTOBUF_SUBJECTS" envSeparator:","`
+}
+
+// ContentTypeFor returns the wire format a publisher should use for the subject.
+func (c EncodingConfig) ContentTypeFor(subject string) string {
+	if slices.Contains(c.ProtobufSubjects, subject) {
+		return ContentTypeProtobuf
+	}
+	return ContentTypeJSON
+}
+
+func Marshal[T Payload](contentType string, envelope Envelope[T]) ([]byte, error) {
+	switch contentType {
+	case ContentTypeJSON, "":
+		return Encode(envelope)
+	case ContentTypeProtobuf:
+		return encodeProto(envelope)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+}
+
+// Unmarshal decodes an event according to the Content-Type header of the message.
+// Messages without the header are treated as JSON so older publishers keep working.
+func Unmarshal[T Payload](contentType string, data []byte) (Envelope[T], error) {
+	switch contentType {
+	case ContentTypeJSON, "":
+		return Decode[T](data)
+	case ContentTypeProtobuf:
+		return decodeProto[T](data)
+	default:
+		return Envelope[T]{}, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+}
@@ -0,0 +1,262 @@
+package events
+
+import (
+	"fmt"
+
+	eventspb "order-service-system/proto/events"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func encodeProto[T Payload](envelope Envelope[T]) ([]byte, error) {
+	if envelope.ID == "" || envelope.Type == "" || envelope.Source == "" {
+		return nil, ErrMissingEnvelopeAttributes
+	}
+	if expected := envelope.Data.EventType(); envelope.Type != expected {
+		return nil, fmt.Errorf("%w: got %q, want %q", ErrUnexpectedType, envelope.Type, expected)
+	}
+
+	event := &eventspb.Event{
+		Attributes: &eventspb.EventAttributes{
+			SpecVersion:   envelope.SpecVersion,
+			Id:            envelope.ID,
+			Type:          envelope.Type,
+			Source:        envelope.Source,
+			Time:          timestamppb.New(envelope.Time),
+			SchemaVersion: int32(envelope.SchemaVersion),
+			CorrelationId: envelope.CorrelationID,
+			TenantId:      envelope.TenantID,
+		},
+	}
+
+	switch data := any(envelope.Data).(type) {
+	case OrderCreatedPayload:
+		event.Data = &eventspb.Event_OrderCreated{OrderCreated: &eventspb.OrderCreated{
+			OrderId:     data.OrderID,
+			UserId:      data.UserID,
+			TotalAmount: data.TotalAmount,
+			CreatedAt:   data.CreatedAt,
+			Currency:    data.Currency,
+		}}
+	case OrderPaidPayload:
+		event.Data = &eventspb.Event_OrderPaid{OrderPaid: &eventspb.OrderPaid{
+			OrderId:     data.OrderID,
+			UserId:      data.UserID,
+			TotalAmount: data.TotalAmount,
+			PaidAt:      data.PaidAt,
+		}}
+	case OrderFailedPayload:
+		event.Data = &eventspb.Event_OrderFailed{OrderFailed: &eventspb.OrderFailed{
+			OrderId:  data.OrderID,
+			UserId:   data.UserID,
+			Reason:   data.Reason,
+			FailedAt: data.FailedAt,
+		}}
+	case OrderShippedPayload:
+		event.Data = &eventspb.Event_OrderShipped{OrderShipped: &eventspb.OrderShipped{
+			OrderId:        data.OrderID,
+			UserId:         data.UserID,
+			Carrier:        data.Carrier,
+			TrackingNumber: data.TrackingNumber,
+			ShippedAt:      data.ShippedAt,
+		}}
+	case OrderDeliveredPayload:
+		event.Data = &eventspb.Event_OrderDelivered{OrderDelivered: &eventspb.OrderDelivered{
+			OrderId:     data.OrderID,
+			UserId:      data.UserID,
+			DeliveredAt: data.DeliveredAt,
+		}}
+	case OrderAmendedPayload:
+		event.Data = &eventspb.Event_OrderAmended{OrderAmended: &eventspb.OrderAmended{
+			OrderId:       data.OrderID,
+			UserId:        data.UserID,
+			Version:       data.Version,
+			TotalAmount:   data.TotalAmount,
+			PreviousTotal: data.PreviousTotal,
+			AmendedAt:     data.AmendedAt,
+		}}
+	case OrderItemsCancelledPayload:
+		items := make([]*eventspb.CancelledItem, 0, len(data.Items))
+		for _, item := range data.Items {
+			items = append(items, &eventspb.CancelledItem{ProductId: item.ProductID, Quantity: item.Quantity, Price: item.Price})
+		}
+		event.Data = &eventspb.Event_OrderItemsCancelled{OrderItemsCancelled: &eventspb.OrderItemsCancelled{
+			CancellationId: data.CancellationID,
+			OrderId:        data.OrderID,
+			UserId:         data.UserID,
+			Items:          items,
+			Amount:         data.Amount,
+			RemainingTotal: data.RemainingTotal,
+			Reason:         data.Reason,
+			CancelledAt:    data.CancelledAt,
+		}}
+	case ReturnApprovedPayload:
+		event.Data = &eventspb.Event_ReturnApproved{ReturnApproved: &eventspb.ReturnApproved{
+			ReturnId:   data.ReturnID,
+			OrderId:    data.OrderID,
+			UserId:     data.UserID,
+			ApprovedAt: data.ApprovedAt,
+		}}
+	case ReturnRejectedPayload:
+		event.Data = &eventspb.Event_ReturnRejected{ReturnRejected: &eventspb.ReturnRejected{
+			ReturnId:   data.ReturnID,
+			OrderId:    data.OrderID,
+			UserId:     data.UserID,
+			Reason:     data.Reason,
+			RejectedAt: data.RejectedAt,
+		}}
+	case ReturnReceivedPayload:
+		event.Data = &eventspb.Event_ReturnReceived{ReturnReceived: &eventspb.ReturnReceived{
+			ReturnId:   data.ReturnID,
+			OrderId:    data.OrderID,
+			UserId:     data.UserID,
+			Amount:     data.Amount,
+			ReceivedAt: data.ReceivedAt,
+		}}
+	case ReturnRefundedPayload:
+		event.Data = &eventspb.Event_ReturnRefunded{ReturnRefunded: &eventspb.ReturnRefunded{
+			ReturnId:   data.ReturnID,
+			OrderId:    data.OrderID,
+			UserId:     data.UserID,
+			Amount:     data.Amount,
+			RefundedAt: data.RefundedAt,
+		}}
+	default:
+		return nil, fmt.Errorf("%w: no protobuf mapping for %q", ErrUnsupportedContentType, envelope.Type)
+	}
+
+	return proto.Marshal(event)
+}
+
+func decodeProto[T Payload](data []byte) (Envelope[T], error) {
+	var event eventspb.Event
+	if err := proto.Unmarshal(data, &event); err != nil {
+		return Envelope[T]{}, err
+	}
+
+	attributes := event.GetAttributes()
+	if attributes.GetSpecVersion() != SpecVersion {
+		return Envelope[T]{}, fmt.Errorf("%w: %q", ErrUnsupportedSpecVersion, attributes.GetSpecVersion())
+	}
+	if int(attributes.GetSchemaVersion()) > SchemaVersion {
+		return Envelope[T]{}, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, attributes.GetSchemaVersion())
+	}
+
+	var payload Payload
+	switch data := event.GetData().(type) {
+	case *eventspb.Event_OrderCreated:
+		payload = OrderCreatedPayload{
+			OrderID:     data.OrderCreated.GetOrderId(),
+			UserID:      data.OrderCreated.GetUserId(),
+			TotalAmount: data.OrderCreated.GetTotalAmount(),
+			CreatedAt:   data.OrderCreated.GetCreatedAt(),
+			Currency:    data.OrderCreated.GetCurrency(),
+		}
+	case *eventspb.Event_OrderPaid:
+		payload = OrderPaidPayload{
+			OrderID:     data.OrderPaid.GetOrderId(),
+			UserID:      data.OrderPaid.GetUserId(),
+			TotalAmount: data.OrderPaid.GetTotalAmount(),
+			PaidAt:      data.OrderPaid.GetPaidAt(),
+		}
+	case *eventspb.Event_OrderFailed:
+		payload = OrderFailedPayload{
+			OrderID:  data.OrderFailed.GetOrderId(),
+			UserID:   data.OrderFailed.GetUserId(),
+			Reason:   data.OrderFailed.GetReason(),
+			FailedAt: data.OrderFailed.GetFailedAt(),
+		}
+	case *eventspb.Event_OrderShipped:
+		payload = OrderShippedPayload{
+			OrderID:        data.OrderShipped.GetOrderId(),
+			UserID:         data.OrderShipped.GetUserId(),
+			Carrier:        data.OrderShipped.GetCarrier(),
+			TrackingNumber: data.OrderShipped.GetTrackingNumber(),
+			ShippedAt:      data.OrderShipped.GetShippedAt(),
+		}
+	case *eventspb.Event_OrderDelivered:
+		payload = OrderDeliveredPayload{
+			OrderID:     data.OrderDelivered.GetOrderId(),
+			UserID:      data.OrderDelivered.GetUserId(),
+			DeliveredAt: data.OrderDelivered.GetDeliveredAt(),
+		}
+	case *eventspb.Event_OrderAmended:
+		payload = OrderAmendedPayload{
+			OrderID:       data.OrderAmended.GetOrderId(),
+			UserID:        data.OrderAmended.GetUserId(),
+			Version:       data.OrderAmended.GetVersion(),
+			TotalAmount:   data.OrderAmended.GetTotalAmount(),
+			PreviousTotal: data.OrderAmended.GetPreviousTotal(),
+			AmendedAt:     data.OrderAmended.GetAmendedAt(),
+		}
+	case *eventspb.Event_OrderItemsCancelled:
+		cancelled := data.OrderItemsCancelled
+		items := make([]CancelledItem, 0, len(cancelled.GetItems()))
+		for _, item := range cancelled.GetItems() {
+			items = append(items, CancelledItem{ProductID: item.GetProductId(), Quantity: item.GetQuantity(), Price: item.GetPrice()})
+		}
+		payload = OrderItemsCancelledPayload{
+			CancellationID: cancelled.GetCancellationId(),
+			OrderID:        cancelled.GetOrderId(),
+			UserID:         cancelled.GetUserId(),
+			Items:          items,
+			Amount:         cancelled.GetAmount(),
+			RemainingTotal: cancelled.GetRemainingTotal(),
+			Reason:         cancelled.GetReason(),
+			CancelledAt:    cancelled.GetCancelledAt(),
+		}
+	case *eventspb.Event_ReturnApproved:
+		payload = ReturnApprovedPayload{
+			ReturnID:   data.ReturnApproved.GetReturnId(),
+			OrderID:    data.ReturnApproved.GetOrderId(),
+			UserID:     data.ReturnApproved.GetUserId(),
+			ApprovedAt: data.ReturnApproved.GetApprovedAt(),
+		}
+	case *eventspb.Event_ReturnRejected:
+		payload = ReturnRejectedPayload{
+			ReturnID:   data.ReturnRejected.GetReturnId(),
+			OrderID:    data.ReturnRejected.GetOrderId(),
+			UserID:     data.ReturnRejected.GetUserId(),
+			Reason:     data.ReturnRejected.GetReason(),
+			RejectedAt: data.ReturnRejected.GetRejectedAt(),
+		}
+	case *eventspb.Event_ReturnReceived:
+		payload = ReturnReceivedPayload{
+			ReturnID:   data.ReturnReceived.GetReturnId(),
+			OrderID:    data.ReturnReceived.GetOrderId(),
+			UserID:     data.ReturnReceived.GetUserId(),
+			Amount:     data.ReturnReceived.GetAmount(),
+			ReceivedAt: data.ReturnReceived.GetReceivedAt(),
+		}
+	case *eventspb.Event_ReturnRefunded:
+		payload = ReturnRefundedPayload{
+			ReturnID:   data.ReturnRefunded.GetReturnId(),
+			OrderID:    data.ReturnRefunded.GetOrderId(),
+			UserID:     data.ReturnRefunded.GetUserId(),
+			Amount:     data.ReturnRefunded.GetAmount(),
+			RefundedAt: data.ReturnRefunded.GetRefundedAt(),
+		}
+	}
+
+	typed, ok := payload.(T)
+	if !ok || attributes.GetType() != typed.EventType() {
+		var zero T
+		return Envelope[T]{}, fmt.Errorf("%w: got %q, want %q", ErrUnexpectedType, attributes.GetType(), zero.EventType())
+	}
+
+	return Envelope[T]{
+		Attributes: Attributes{
+			SpecVersion:     attributes.GetSpecVersion(),
+			ID:              attributes.GetId(),
+			Type:            attributes.GetType(),
+			Source:          attributes.GetSource(),
+			Time:            attributes.GetTime().AsTime(),
+			SchemaVersion:   int(attributes.GetSchemaVersion()),
+			DataContentType: ContentTypeProtobuf,
+			CorrelationID:   attributes.GetCorrelationId(),
+			TenantID:        attributes.GetTenantId(),
+		},
+		Data: typed,
+	}, nil
+}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SpecVersion     = "1.0"
+	SchemaVersion   = 1
+	DataContentType = "application/json"
+)
+
+var (
+	ErrUnexpectedType            = errors.New("unexpected event type")
+	ErrUnsupportedSchemaVersion  = errors.New("unsupported event schema version")
+	ErrUnsupportedSpecVersion    = errors.New("unsupported event spec version")
+	ErrMissingEnvelopeAttributes = errors.New("event envelope attributes are missing")
+)
+
+// Payload is implemented by every event body that can travel inside an Envelope.
+type Payload interface {
+	EventType() string
+}
+
+// Attributes are the CloudEvents-style context attributes shared by every event.
+type Attributes struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Source          string    `json:"source"`
+	Time            time.Time `json:"time"`
+	SchemaVersion   int       `json:"schemaversion"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	CorrelationID   string    `json:"correlationid,omitempty"`
+	// TenantID is empty in events published before multi-tenancy; they belong to
+	// the default tenant.
+	TenantID string `json:"tenantid,omitempty"`
+}
+
+type Envelope[T Payload] struct {
+	Attributes
+	Data T `json:"data"`
+	// Legacy is set when the message was a bare payload without an envelope.
+	Legacy bool `json:"-"`
+}
+
+func NewEnvelope[T Payload](source string, payload T) Envelope[T] {
+	return Envelope[T]{
+		Attributes: Attributes{
+			SpecVersion:     SpecVersion,
+			ID:              uuid.NewString(),
+			Type:            payload.EventType(),
+			Source:          source,
+			Time:            time.Now().UTC(),
+			SchemaVersion:   SchemaVersion,
+			DataContentType: DataContentType,
+		},
+		Data: payload,
+	}
+}
+
+func (e Envelope[T]) WithID(id string) Envelope[T] {
+	if id != "" {
+		e.ID = id
+	}
+	return e
+}
+
+func (e Envelope[T]) WithCorrelationID(id string) Envelope[T] {
+	e.CorrelationID = id
+	return e
+}
+
+func (e Envelope[T]) WithTenant(id string) Envelope[T] {
+	e.TenantID = id
+	return e
+}
+
+func Encode[T Payload](envelope Envelope[T]) ([]byte, error) {
+	if envelope.ID == "" || envelope.Type == "" || envelope.Source == "" {
+		return nil, ErrMissingEnvelopeAttributes
+	}
+	if expected := envelope.Data.EventType(); envelope.Type != expected {
+		return nil, fmt.Errorf("%w: got %q, want %q", ErrUnexpectedType, envelope.Type, expected)
+	}
+	return json.Marshal(envelope)
+}
+
+// Decode parses an enveloped event. Bare payloads published before the envelope
+// was introduced are still accepted and reported with Legacy set.
+func Decode[T Payload](data []byte) (Envelope[T], error) {
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Envelope[T]{}, err
+	}
+
+	if probe.SpecVersion == "" {
+		return decodeLegacy[T](data)
+	}
+	if probe.SpecVersion != SpecVersion {
+		return Envelope[T]{}, fmt.Errorf("%w: %q", ErrUnsupportedSpecVersion, probe.SpecVersion)
+	}
+
+	var envelope Envelope[T]
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return Envelope[T]{}, err
+	}
+	if expected := envelope.Data.EventType(); envelope.Type != expected {
+		return Envelope[T]{}, fmt.Errorf("%w: got %q, want %q", ErrUnexpectedType, envelope.Type, expected)
+	}
+	if envelope.SchemaVersion > SchemaVersion {
+		return Envelope[T]{}, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, envelope.SchemaVersion)
+	}
+	return envelope, nil
+}
+
+func decodeLegacy[T Payload](data []byte) (Envelope[T], error) {
+	var payload T
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return Envelope[T]{}, err
+	}
+	return Envelope[T]{
+		Attributes: Attributes{
+			Type:            payload.EventType(),
+			DataContentType: DataContentType,
+		},
+		Data:   payload,
+		Legacy: true,
+	}, nil
+}
+
+var messageIDNamespace = uuid.MustParse("5b0e8a6c-3c1f-4d9e-9a57-0f7c1f3f8b21")
+
+// DeterministicID derives a stable event id from the event type and the business key,
+// so republishing the same fact always yields the same Nats-Msg-Id.
+func DeterministicID(eventType string, key string) string {
+	return uuid.NewSHA1(messageIDNamespace, []byte(eventType+":"+key)).String()
+}
+
+// ResolveID returns the id used for deduplication: the envelope id, the Nats-Msg-Id
+// of the message for legacy payloads, or an id derived from the business key.
+func ResolveID(envelopeID string, msgID string, eventType string, key string) string {
+	if envelopeID != "" {
+		return envelopeID
+	}
+	if msgID != "" {
+		return msgID
+	}
+	return DeterministicID(eventType, key)
+}
@@ -1,12 +1,27 @@
 package events
 
+const (
+	TypeOrderCreated   = "order.created"
+	TypeOrderPaid      = "order.paid"
+	TypeOrderFailed    = "order.failed"
+	TypeOrderShipped   = "order.shipped"
+	TypeOrderDelivered = "order.delivered"
+	TypeOrderAmended   = "order.amended"
+
+	TypeOrderItemsCancelled = "order.items_cancelled"
+)
+
 type OrderCreatedPayload struct {
 	OrderID     string  `json:"order_id"`
 	UserID      string  `json:"user_id"`
 	TotalAmount float64 `json:"total_amount"`
 	CreatedAt   int64   `json:"created_at"`
+	// Currency is empty for tenants without configured currencies.
+	Currency string `json:"currency,omitempty"`
 }
 
+func (OrderCreatedPayload) EventType() string { return TypeOrderCreated }
+
 type OrderPaidPayload struct {
 	OrderID     string  `json:"order_id"`
 	UserID      string  `json:"user_id"`
@@ -14,9 +29,66 @@ type OrderPaidPayload struct {
 	PaidAt      int64   `json:"paid_at"`
 }
 
+func (OrderPaidPayload) EventType() string { return TypeOrderPaid }
+
 type OrderFailedPayload struct {
 	OrderID  string `json:"order_id"`
 	UserID   string `json:"user_id"`
 	Reason   string `json:"reason"`
 	FailedAt int64  `json:"failed_at"`
 }
+
+func (OrderFailedPayload) EventType() string { return TypeOrderFailed }
+
+type OrderShippedPayload struct {
+	OrderID        string `json:"order_id"`
+	UserID         string `json:"user_id"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+	ShippedAt      int64  `json:"shipped_at"`
+}
+
+func (OrderShippedPayload) EventType() string { return TypeOrderShipped }
+
+type OrderDeliveredPayload struct {
+	OrderID     string `json:"order_id"`
+	UserID      string `json:"user_id"`
+	DeliveredAt int64  `json:"delivered_at"`
+}
+
+func (OrderDeliveredPayload) EventType() string { return TypeOrderDelivered }
+
+// OrderAmendedPayload carries the new total of a PENDING order; Version grows with
+// every amendment so consumers can drop stale ones.
+type OrderAmendedPayload struct {
+	OrderID       string  `json:"order_id"`
+	UserID        string  `json:"user_id"`
+	Version       int64   `json:"version"`
+	TotalAmount   float64 `json:"total_amount"`
+	PreviousTotal float64 `json:"previous_total"`
+	AmendedAt     int64   `json:"amended_at"`
+}
+
+func (OrderAmendedPayload) EventType() string { return TypeOrderAmended }
+
+type CancelledItem struct {
+	ProductID string  `json:"product_id"`
+	Quantity  int32   `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+// OrderItemsCancelledPayload asks billing to refund Amount. Items list the cancelled
+// units for an external inventory system; no service here releases stock.
+// CancellationID identifies the cancellation across redeliveries.
+type OrderItemsCancelledPayload struct {
+	CancellationID string          `json:"cancellation_id"`
+	OrderID        string          `json:"order_id"`
+	UserID         string          `json:"user_id"`
+	Items          []CancelledItem `json:"items"`
+	Amount         float64         `json:"amount"`
+	RemainingTotal float64         `json:"remaining_total"`
+	Reason         string          `json:"reason,omitempty"`
+	CancelledAt    int64           `json:"cancelled_at"`
+}
+
+func (OrderItemsCancelledPayload) EventType() string { return TypeOrderItemsCancelled }
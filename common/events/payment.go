@@ -0,0 +1,23 @@
+package events
+
+// SubjectPaymentStatus is answered by billing with the outcome of the payment of an order.
+const SubjectPaymentStatus = "billing.payment.status"
+
+const (
+	PaymentOutcomeUnknown    = "unknown"
+	PaymentOutcomeProcessing = "processing"
+	PaymentOutcomePaid       = "paid"
+	PaymentOutcomeDeclined   = "declined"
+)
+
+type PaymentStatusRequest struct {
+	OrderID string `json:"order_id"`
+}
+
+type PaymentStatusReply struct {
+	OrderID string `json:"order_id"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason,omitempty"`
+	// DecidedAt is unix seconds, zero while the outcome is not final.
+	DecidedAt int64 `json:"decided_at,omitempty"`
+}
@@ -0,0 +1,48 @@
+package events
+
+const (
+	TypeReturnApproved = "return.approved"
+	TypeReturnRejected = "return.rejected"
+	TypeReturnReceived = "return.received"
+	TypeReturnRefunded = "return.refunded"
+)
+
+type ReturnApprovedPayload struct {
+	ReturnID   string `json:"return_id"`
+	OrderID    string `json:"order_id"`
+	UserID     string `json:"user_id"`
+	ApprovedAt int64  `json:"approved_at"`
+}
+
+func (ReturnApprovedPayload) EventType() string { return TypeReturnApproved }
+
+type ReturnRejectedPayload struct {
+	ReturnID   string `json:"return_id"`
+	OrderID    string `json:"order_id"`
+	UserID     string `json:"user_id"`
+	Reason     string `json:"reason"`
+	RejectedAt int64  `json:"rejected_at"`
+}
+
+func (ReturnRejectedPayload) EventType() string { return TypeReturnRejected }
+
+// ReturnReceivedPayload asks billing to refund Amount for the returned items.
+type ReturnReceivedPayload struct {
+	ReturnID   string  `json:"return_id"`
+	OrderID    string  `json:"order_id"`
+	UserID     string  `json:"user_id"`
+	Amount     float64 `json:"amount"`
+	ReceivedAt int64   `json:"received_at"`
+}
+
+func (ReturnReceivedPayload) EventType() string { return TypeReturnReceived }
+
+type ReturnRefundedPayload struct {
+	ReturnID   string  `json:"return_id"`
+	OrderID    string  `json:"order_id"`
+	UserID     string  `json:"user_id"`
+	Amount     float64 `json:"amount"`
+	RefundedAt int64   `json:"refunded_at"`
+}
+
+func (ReturnRefundedPayload) EventType() string { return TypeReturnRefunded }
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"order-service-system/common/bus"
+
+	"github.com/nats-io/nats.go"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Mongo(db *mongo.Database) Check {
+	return func(ctx context.Context) error {
+		return db.Client().Ping(ctx, nil)
+	}
+}
+
+func NATS(conn *nats.Conn) Check {
+	return func(context.Context) error {
+		if status := conn.Status(); status != nats.CONNECTED {
+			return fmt.Errorf("nats connection is %s", status)
+		}
+		return nil
+	}
+}
+
+func Subscriptions(subs ...bus.Subscription) Check {
+	return func(context.Context) error {
+		for _, sub := range subs {
+			if !sub.IsValid() {
+				return fmt.Errorf("subscription to %s is not active", sub.Subject())
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusStarting     = "starting"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting down"
+)
+
+const (
+	stateStarting int32 = iota
+	stateRunning
+	stateShuttingDown
+)
+
+// Check reports whether a dependency is usable; a nil error means healthy.
+type Check func(ctx context.Context) error
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Checker aggregates readiness checks of a service. Liveness only reports that
+// the process is able to answer, readiness runs every registered check once the
+// service has started and until its shutdown begins.
+type Checker struct {
+	mu      sync.RWMutex
+	names   []string
+	checks  map[string]Check
+	timeout time.Duration
+	state   atomic.Int32
+}
+
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{
+		checks:  map[string]Check{},
+		timeout: timeout,
+	}
+}
+
+func (receiver *Checker) Register(name string, check Check) *Checker {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	if _, ok := receiver.checks[name]; !ok {
+		receiver.names = append(receiver.names, name)
+	}
+	receiver.checks[name] = check
+	return receiver
+}
+
+// Start marks the service as started, readiness is reported by the checks from now on.
+func (receiver *Checker) Start() {
+	receiver.state.CompareAndSwap(stateStarting, stateRunning)
+}
+
+// Shutdown marks the service as not ready, so that it is taken out of rotation
+// before its dependencies are closed.
+func (receiver *Checker) Shutdown() {
+	receiver.state.Store(stateShuttingDown)
+}
+
+func (receiver *Checker) Ready(ctx context.Context) (Report, bool) {
+	switch receiver.state.Load() {
+	case stateStarting:
+		return Report{Status: StatusStarting}, false
+	case stateShuttingDown:
+		return Report{Status: StatusShuttingDown}, false
+	}
+
+	receiver.mu.RLock()
+	names := append([]string(nil), receiver.names...)
+	checks := make(map[string]Check, len(receiver.checks))
+	for name, check := range receiver.checks {
+		checks[name] = check
+	}
+	receiver.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, receiver.timeout)
+	defer cancel()
+
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(names))}
+	ready := true
+	for i, name := range names {
+		if results[i] != nil {
+			report.Checks[name] = results[i].Error()
+			ready = false
+			continue
+		}
+		report.Checks[name] = StatusOK
+	}
+	if !ready {
+		report.Status = StatusUnavailable
+	}
+	return report, ready
+}
+
+// Watch periodically evaluates readiness and reports changes to onChange.
+func (receiver *Checker) Watch(ctx context.Context, interval time.Duration, onChange func(ready bool)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *bool
+	for {
+		_, ready := receiver.Ready(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if last == nil || *last != ready {
+			onChange(ready)
+			last = &ready
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (receiver *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+func (receiver *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report, ready := receiver.Ready(r.Context())
+		code := http.StatusOK
+		if !ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeReport(w, code, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// Probe queries the readiness endpoint of a locally running service. It is used
+// as the container health check, since distroless images have no curl or wget.
+func Probe(ctx context.Context, addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("parse http address: %w", err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+net.JoinHostPort(host, port)+"/readyz", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("service is not ready: %s", resp.Status)
+	}
+	return nil
+}
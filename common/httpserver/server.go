@@ -0,0 +1,59 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Deps struct {
+	Logger *zap.Logger
+}
+
+type Server struct {
+	logger *zap.Logger
+	mux    *http.ServeMux
+	server *http.Server
+}
+
+func New(deps Deps) (*Server, error) {
+	if deps.Logger == nil {
+		return nil, errors.New("logger is nil on <New>")
+	}
+	mux := http.NewServeMux()
+	return &Server{
+		logger: deps.Logger,
+		mux:    mux,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}, nil
+}
+
+func (receiver *Server) Handle(pattern string, handler http.Handler) *Server {
+	receiver.mux.Handle(pattern, handler)
+	return receiver
+}
+
+func (receiver *Server) Run(addr string) error {
+	receiver.logger.Info("starting http server on <Run> of <HTTP>", zap.String("host", addr))
+
+	receiver.server.Addr = addr
+	if err := receiver.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		receiver.logger.Error("listen error on <Run> of <HTTP>", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (receiver *Server) Stop(ctx context.Context) error {
+	if err := receiver.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	receiver.logger.Info("shutting down http server on <Stop> of <HTTP>")
+	return nil
+}
@@ -0,0 +1,84 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore shares processed event ids between the replicas of a service, so an
+// event redelivered to another replica of the queue group is still skipped.
+// Expired ids are removed by a TTL index.
+type MongoStore struct {
+	collection *mongo.Collection
+	window     time.Duration
+	now        func() time.Time
+}
+
+type processedDoc struct {
+	ID       string    `bson:"_id"`
+	ExpireAt time.Time `bson:"expire_at"`
+}
+
+// NewMongoStore keeps the inbox in the collection; every service needs its own,
+// since the same event is handled once by each service.
+func NewMongoStore(ctx context.Context, collection *mongo.Collection, cfg Configuration) (*MongoStore, error) {
+	if collection == nil {
+		return nil, errors.New("inbox collection is nil")
+	}
+	if cfg.Window <= 0 {
+		return nil, errors.New("inbox window must be positive")
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create inbox ttl index: %w", err)
+	}
+
+	return &MongoStore{collection: collection, window: cfg.Window, now: time.Now}, nil
+}
+
+// Claim atomically marks the event as processed. It returns false when the event
+// was already claimed within the window by any replica.
+func (s *MongoStore) Claim(ctx context.Context, id string) (bool, error) {
+	now := s.now().UTC()
+	expireAt := now.Add(s.window)
+
+	_, err := s.collection.InsertOne(ctx, processedDoc{ID: id, ExpireAt: expireAt})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Errorf("claim event: %w", err)
+	}
+
+	// TTL-индекс удаляет записи с задержкой: истёкшую запись забираем сами.
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "expire_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"expire_at": expireAt}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim event: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Release forgets the event so that a later redelivery is processed again.
+func (s *MongoStore) Release(ctx context.Context, id string) error {
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("release event: %w", err)
+	}
+	return nil
+}
+
+func (s *MongoStore) Ping(ctx context.Context) error {
+	return s.collection.Database().Client().Ping(ctx, nil)
+}
@@ -0,0 +1,173 @@
+package inbox
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+const (
+	processedBucket = "processed"
+
+	StoreBbolt = "bbolt"
+	StoreMongo = "mongo"
+)
+
+type Configuration struct {
+	// Store is mongo for an inbox shared by all replicas of the service; bbolt keeps
+	// the inbox in a local file and may only be used with a single replica.
+	Store  string        `env:"INBOX_STORE" envDefault:"mongo"`
+	Path   string        `env:"INBOX_PATH" envDefault:"inbox_bbolt.db"`
+	Window time.Duration `env:"INBOX_WINDOW" envDefault:"24h"`
+}
+
+// Inbox remembers processed event ids. Redeliveries of a queue group may go to any
+// replica, so the guarantee holds across replicas only for a shared inbox.
+type Inbox interface {
+	Claim(ctx context.Context, id string) (bool, error)
+	Release(ctx context.Context, id string) error
+	Ping(ctx context.Context) error
+}
+
+// Store remembers processed event ids for a configurable window so that
+// redelivered or republished messages are handled at most once by this process.
+// The file is local: with several replicas use MongoStore.
+type Store struct {
+	db     *bbolt.DB
+	window time.Duration
+	now    func() time.Time
+}
+
+func Open(cfg Configuration) (*Store, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("inbox path is empty")
+	}
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("inbox window must be positive")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create dir for inbox: %w", err)
+	}
+
+	db, err := bbolt.Open(cfg.Path, 0o644, &bbolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open inbox: %w", err)
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(processedBucket))
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("create bucket: %w", err)
+	}
+
+	return &Store{db: db, window: cfg.Window, now: time.Now}, nil
+}
+
+func (s *Store) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Claim atomically marks the event as processed. It returns false when the event
+// was already claimed within the window.
+func (s *Store) Claim(_ context.Context, id string) (bool, error) {
+	claimed := false
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(processedBucket))
+		now := s.now()
+		if v := b.Get([]byte(id)); v != nil && now.Sub(decodeTime(v)) < s.window {
+			return nil
+		}
+		claimed = true
+		return b.Put([]byte(id), encodeTime(now))
+	})
+	return claimed, err
+}
+
+// Release forgets the event so that a later redelivery is processed again.
+func (s *Store) Release(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(processedBucket)).Delete([]byte(id))
+	})
+}
+
+// Ping checks that the database is open and the inbox bucket is readable.
+func (s *Store) Ping(_ context.Context) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte(processedBucket)) == nil {
+			return fmt.Errorf("bucket %s not found", processedBucket)
+		}
+		return nil
+	})
+}
+
+func (s *Store) Purge() (int, error) {
+	purged := 0
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(processedBucket))
+		threshold := s.now().Add(-s.window)
+
+		var expired [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			if decodeTime(v).Before(threshold) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		purged = len(expired)
+		return nil
+	})
+	return purged, err
+}
+
+func (s *Store) StartPurging(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.Purge()
+			if err != nil {
+				logger.Warn("failed to purge inbox on <StartPurging> of <Inbox>", zap.Error(err))
+				continue
+			}
+			if purged > 0 {
+				logger.Debug("purged inbox on <StartPurging> of <Inbox>", zap.Int("purged", purged))
+			}
+		}
+	}
+}
+
+func encodeTime(t time.Time) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(t.UnixNano()))
+	return buf
+}
+
+func decodeTime(v []byte) time.Time {
+	if len(v) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
@@ -9,6 +9,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 type ConnectDeps struct {
@@ -22,7 +23,8 @@ func Connect(ctx context.Context, deps *ConnectDeps) (*mongo.Database, error) {
 	}
 
 	connectionOptions := options.Client().
-		ApplyURI(deps.Configuration.URL)
+		ApplyURI(deps.Configuration.URL).
+		SetMonitor(newMetricsMonitor(otelmongo.NewMonitor()))
 
 	ticker := time.NewTicker(1 * time.Second)
 	timeoutExceeded := time.After(deps.Timeout)
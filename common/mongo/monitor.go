@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"context"
+	"order-service-system/common/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "mongo_command_duration_seconds",
+	Help:    "Latency of MongoDB commands by command name and result.",
+	Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"command", "result"})
+
+// newMetricsMonitor records command latency and forwards every event to next.
+func newMetricsMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			if next != nil && next.Started != nil {
+				next.Started(ctx, evt)
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			commandDuration.WithLabelValues(evt.CommandName, metrics.ResultSuccess).Observe(evt.Duration.Seconds())
+			if next != nil && next.Succeeded != nil {
+				next.Succeeded(ctx, evt)
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			commandDuration.WithLabelValues(evt.CommandName, metrics.ResultError).Observe(evt.Duration.Seconds())
+			if next != nil && next.Failed != nil {
+				next.Failed(ctx, evt)
+			}
+		},
+	}
+}
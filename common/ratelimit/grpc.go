@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RetryAfterHeader carries the wait in whole seconds next to the RetryInfo detail.
+const RetryAfterHeader = "retry-after"
+
+var rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_server_rate_limited_total",
+	Help: "Total number of RPCs rejected by the rate limiter.",
+}, []string{"grpc_method"})
+
+// UnaryServerInterceptor rejects calls over the limit with ResourceExhausted and a
+// RetryInfo detail. It must run after authentication to see the principal.
+func UnaryServerInterceptor(limiter *Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+			return handler(ctx, req)
+		}
+
+		decision := limiter.Allow(ctx, info.FullMethod)
+		if decision.Allowed {
+			return handler(ctx, req)
+		}
+
+		rateLimited.WithLabelValues(info.FullMethod).Inc()
+		return nil, rejection(ctx, decision.RetryAfter)
+	}
+}
+
+func rejection(ctx context.Context, retryAfter time.Duration) error {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.FormatInt(max(seconds, 1), 10)))
+
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
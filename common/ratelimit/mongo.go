@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const rateLimitCollection = "rate_limits"
+
+// MongoStore shares buckets between replicas. It implements the token bucket as
+// GCRA: a bucket is a single "theoretical arrival time" updated atomically by one
+// findAndModify, so concurrent replicas cannot overspend it.
+type MongoStore struct {
+	collection *mongo.Collection
+	now        func() time.Time
+}
+
+type gcraState struct {
+	TAT     time.Time `bson:"tat"`
+	Allowed bool      `bson:"allowed"`
+}
+
+func NewMongoStore(ctx context.Context, db *mongo.Database) (*MongoStore, error) {
+	collection := db.Collection(rateLimitCollection)
+
+	// Простаивающие ключи удаляются TTL-индексом.
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expireAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create rate limit ttl index: %w", err)
+	}
+
+	return &MongoStore{collection: collection, now: time.Now}, nil
+}
+
+func (s *MongoStore) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	now := s.now().UTC().Truncate(time.Millisecond)
+	interval := limit.interval()
+	tolerance := interval * time.Duration(limit.Burst)
+
+	current := bson.M{"$ifNull": bson.A{"$tat", now}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"next": bson.M{"$add": bson.A{bson.M{"$max": bson.A{current, now}}, interval.Milliseconds()}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$lte": bson.A{bson.M{"$subtract": bson.A{"$next", now}}, tolerance.Milliseconds()}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"tat": bson.M{"$cond": bson.A{"$allowed", "$next", current}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"expireAt": bson.M{"$add": bson.A{"$tat", tolerance.Milliseconds()}},
+		}}},
+		{{Key: "$unset", Value: "next"}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var state gcraState
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&state)
+	if mongo.IsDuplicateKeyError(err) {
+		// Два upsert одного нового ключа: второй повторяет уже по существующему документу.
+		err = s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&state)
+	}
+	if err != nil {
+		return Decision{}, fmt.Errorf("update bucket: %w", err)
+	}
+
+	ahead := state.TAT.Sub(now)
+	if !state.Allowed {
+		return Decision{Allowed: false, RetryAfter: ahead + interval - tolerance}, nil
+	}
+	return Decision{Allowed: true, Remaining: int((tolerance - ahead) / interval)}, nil
+}
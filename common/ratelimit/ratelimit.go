@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"order-service-system/common/auth"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreMongo  = "mongo"
+
+	// APIKeyHeader identifies callers that come without a token.
+	APIKeyHeader = "x-api-key"
+)
+
+type Configuration struct {
+	Disabled bool `env:"RATE_LIMIT_DISABLED"`
+	// Default is the limit of every method as "rate:burst", rate in requests per second.
+	Default string `env:"RATE_LIMIT_DEFAULT" envDefault:"20:40"`
+	// Methods overrides the limit per method: "CreateOrder=2:5,ListOrders=10:20".
+	Methods []string `env:"RATE_LIMIT_METHODS" envSeparator:"," envDefault:"CreateOrder=2:5"`
+	// Exempt are service identities that are never limited.
+	Exempt []string `env:"RATE_LIMIT_EXEMPT" envSeparator:"," envDefault:"billing-service,notification-service"`
+	Store  string   `env:"RATE_LIMIT_STORE" envDefault:"memory"`
+}
+
+// Limit is a token bucket refilled with Rate tokens per second up to Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.Rate)
+}
+
+type Decision struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long to wait before the next call may pass.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Implementations shared between replicas make the limit global.
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+func ParseLimit(value string) (Limit, error) {
+	rate, burst, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must be rate:burst", value)
+	}
+	parsedRate, err := strconv.ParseFloat(rate, 64)
+	if err != nil || parsedRate <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate in limit %q", value)
+	}
+	parsedBurst, err := strconv.Atoi(burst)
+	if err != nil || parsedBurst <= 0 {
+		return Limit{}, fmt.Errorf("invalid burst in limit %q", value)
+	}
+	return Limit{Rate: parsedRate, Burst: parsedBurst}, nil
+}
+
+type Deps struct {
+	Logger        *zap.Logger
+	Store         Store
+	Configuration Configuration
+}
+
+// Limiter decides whether a call may pass. Buckets are keyed by the method and
+// the caller: the principal, else the API key, else the peer address.
+type Limiter struct {
+	logger       *zap.Logger
+	store        Store
+	defaultLimit Limit
+	methods      map[string]Limit
+	exempt       map[string]struct{}
+}
+
+func New(deps Deps) (*Limiter, error) {
+	if deps.Logger == nil {
+		return nil, errors.New("logger is nil on <New> of <ratelimit>")
+	}
+	if deps.Store == nil {
+		return nil, errors.New("store is nil on <New> of <ratelimit>")
+	}
+
+	defaultLimit, err := ParseLimit(deps.Configuration.Default)
+	if err != nil {
+		return nil, fmt.Errorf("parse RATE_LIMIT_DEFAULT: %w", err)
+	}
+
+	methods := make(map[string]Limit, len(deps.Configuration.Methods))
+	for _, entry := range deps.Configuration.Methods {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("method limit %q must be Method=rate:burst", entry)
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse RATE_LIMIT_METHODS: %w", err)
+		}
+		methods[strings.TrimSpace(method)] = limit
+	}
+
+	exempt := make(map[string]struct{}, len(deps.Configuration.Exempt))
+	for _, service := range deps.Configuration.Exempt {
+		if service = strings.TrimSpace(service); service != "" {
+			exempt[service] = struct{}{}
+		}
+	}
+
+	return &Limiter{
+		logger:       deps.Logger,
+		store:        deps.Store,
+		defaultLimit: defaultLimit,
+		methods:      methods,
+		exempt:       exempt,
+	}, nil
+}
+
+// Allow takes a token for the call. Store failures let the call through: the limiter
+// protects the service and must not take it down on its own.
+func (receiver *Limiter) Allow(ctx context.Context, fullMethod string) Decision {
+	caller, exempt := receiver.caller(ctx)
+	if exempt {
+		return Decision{Allowed: true}
+	}
+
+	limit := receiver.limitFor(fullMethod)
+	decision, err := receiver.store.Allow(ctx, fullMethod+"|"+caller, limit)
+	if err != nil {
+		receiver.logger.Warn("rate limit store failed on <Allow> of <Limiter>", zap.String("method", fullMethod), zap.Error(err))
+		return Decision{Allowed: true}
+	}
+	return decision
+}
+
+func (receiver *Limiter) limitFor(fullMethod string) Limit {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if limit, ok := receiver.methods[name]; ok {
+		return limit
+	}
+	return receiver.defaultLimit
+}
+
+func (receiver *Limiter) caller(ctx context.Context) (string, bool) {
+	if principal, ok := auth.PrincipalFrom(ctx); ok {
+		if principal.IsService() {
+			_, exempt := receiver.exempt[principal.Subject]
+			return "service:" + principal.Subject, exempt
+		}
+		return "user:" + principal.Subject, false
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(APIKeyHeader); len(keys) > 0 && keys[0] != "" {
+		// Сам ключ в хранилище не попадает.
+		sum := sha256.Sum256([]byte(keys[0]))
+		return "key:" + hex.EncodeToString(sum[:8]), false
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return "ip:" + host, false
+	}
+	return "anonymous", false
+}
@@ -0,0 +1,86 @@
+package tenant
+
+import (
+	"context"
+	"order-service-system/common/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor scopes every call to the tenant from the x-tenant-id
+// metadata. It must run after authentication: a user may act only in the tenant
+// of the token, users without the claim only in the default tenant; services pick
+// the tenant by the header. allowed rejects unknown tenants; nil allows any.
+func UnaryServerInterceptor(allowed func(id string) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := resolve(ctx, allowed)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(allowed func(id string) bool) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := resolve(stream.Context(), allowed)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &tenantStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// UnaryClientInterceptor sends the tenant of the context with every call, so the
+// services act on behalf of the tenant of the event they handle.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if !IsAll(ctx) && len(md.Get(MetadataKey)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, MetadataKey, FromContext(ctx))
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func resolve(ctx context.Context, allowed func(id string) bool) (context.Context, error) {
+	var requested string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataKey); len(values) > 0 {
+			requested = values[0]
+		}
+	}
+	id, err := Normalize(requested)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if principal, ok := auth.PrincipalFrom(ctx); ok && !principal.IsService() {
+		// Покупатель не может переключиться на чужую витрину заголовком, даже если
+		// в токене нет витрины: один и тот же id пользователя бывает в разных витринах.
+		pinned := principal.Tenant
+		if pinned == "" {
+			pinned = Default
+		}
+		if requested != "" && id != pinned {
+			return nil, status.Error(codes.PermissionDenied, "tenant does not match the token")
+		}
+		id = pinned
+	}
+	if allowed != nil && !allowed(id) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown tenant %q", id)
+	}
+	return WithTenant(ctx, id), nil
+}
+
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
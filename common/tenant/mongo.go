@@ -0,0 +1,23 @@
+package tenant
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MongoFilter restricts the filter to the tenant of the context; contexts of
+// WithAllTenants see every tenant. Documents stored before multi-tenancy have no
+// tenant_id and belong to Default.
+func MongoFilter(ctx context.Context, filter bson.M) bson.M {
+	if IsAll(ctx) {
+		return filter
+	}
+	id := FromContext(ctx)
+	if id == Default {
+		filter["tenant_id"] = bson.M{"$in": bson.A{id, nil}}
+	} else {
+		filter["tenant_id"] = id
+	}
+	return filter
+}
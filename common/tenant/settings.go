@@ -0,0 +1,84 @@
+package tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Configuration points to the JSON file with the settings of the tenants, shared by
+// all services: {"shop-a": {"currencies": ["EUR"], "payment_success_rate": 0.9}}.
+// Every service reads only the keys it knows.
+type Configuration struct {
+	File string `env:"TENANTS_FILE"`
+}
+
+// Settings are the per-tenant values of T. A tenant inherits every value it does
+// not set from the defaults, which must not contain maps.
+type Settings[T any] struct {
+	defaults T
+	tenants  map[string]T
+}
+
+func NewSettings[T any](defaults T, tenants map[string]T) Settings[T] {
+	return Settings[T]{defaults: defaults, tenants: tenants}
+}
+
+// LoadSettings reads the file of the configuration; without a file every tenant
+// gets the defaults.
+func LoadSettings[T any](cfg Configuration, defaults T) (Settings[T], error) {
+	settings := Settings[T]{defaults: defaults, tenants: map[string]T{}}
+	if cfg.File == "" {
+		return settings, nil
+	}
+
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return Settings[T]{}, fmt.Errorf("read tenants file: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Settings[T]{}, fmt.Errorf("parse tenants file: %w", err)
+	}
+	for name, value := range raw {
+		id, err := Normalize(name)
+		if err != nil {
+			return Settings[T]{}, err
+		}
+		tenant := defaults
+		if err := json.Unmarshal(value, &tenant); err != nil {
+			return Settings[T]{}, fmt.Errorf("parse settings of tenant %q: %w", id, err)
+		}
+		settings.tenants[id] = tenant
+	}
+	return settings, nil
+}
+
+// For returns the settings of the tenant.
+func (s Settings[T]) For(id string) T {
+	if settings, ok := s.tenants[id]; ok {
+		return settings
+	}
+	return s.defaults
+}
+
+// Known reports whether the tenant may be served: any tenant when the file lists
+// none, otherwise Default and the listed ones.
+func (s Settings[T]) Known(id string) bool {
+	if len(s.tenants) == 0 || id == Default {
+		return true
+	}
+	_, ok := s.tenants[id]
+	return ok
+}
+
+// Tenants lists the configured tenants in order.
+func (s Settings[T]) Tenants() []string {
+	ids := make([]string, 0, len(s.tenants))
+	for id := range s.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,81 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Default is the tenant of calls and events without a tenant id, including
+	// everything stored before multi-tenancy.
+	Default = "default"
+	// MetadataKey is the gRPC metadata key (and X-Tenant-Id HTTP header) of the tenant id.
+	MetadataKey = "x-tenant-id"
+)
+
+var ErrInvalidTenant = errors.New("invalid tenant id")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Normalize lowercases the id and maps an empty one to Default.
+func Normalize(id string) (string, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return Default, nil
+	}
+	if !idPattern.MatchString(id) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTenant, id)
+	}
+	return id, nil
+}
+
+type scope struct {
+	id  string
+	all bool
+}
+
+type scopeKey struct{}
+
+// WithTenant scopes the context to the tenant; an empty id means Default.
+func WithTenant(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = Default
+	}
+	return context.WithValue(ctx, scopeKey{}, scope{id: id})
+}
+
+// WithAllTenants lets background workers see the data of every tenant. They must
+// scope the context to the tenant of each record before acting on it.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope{all: true})
+}
+
+// FromContext returns the tenant of the context, Default when it has none.
+func FromContext(ctx context.Context) string {
+	s, ok := ctx.Value(scopeKey{}).(scope)
+	if !ok || s.id == "" {
+		return Default
+	}
+	return s.id
+}
+
+// IsAll reports whether the context was created by WithAllTenants.
+func IsAll(ctx context.Context) bool {
+	s, _ := ctx.Value(scopeKey{}).(scope)
+	return s.all
+}
+
+// Matches reports whether a record of the tenant is visible in the context.
+// Records stored before multi-tenancy have no tenant and belong to Default.
+func Matches(ctx context.Context, id string) bool {
+	if IsAll(ctx) {
+		return true
+	}
+	if id == "" {
+		id = Default
+	}
+	return id == FromContext(ctx)
+}
@@ -0,0 +1,239 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"order-service-system/common/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testStaticKey  = "test:c2VjcmV0LWtleS1mb3ItdGVzdHM="
+	testServiceKey = "svc:c2VydmljZS1rZXktZm9yLXRlc3Rz"
+)
+
+func signHMAC(t *testing.T, claims auth.Claims) string {
+	t.Helper()
+	return signHMACWith(t, testStaticKey, claims)
+}
+
+func signHMACWith(t *testing.T, key string, claims auth.Claims) string {
+	t.Helper()
+	kid, secret, err := auth.ParseStaticKey(key)
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(secret)
+	require.NoError(t, err)
+	return signed
+}
+
+func userClaims(subject string, expiresIn time.Duration) auth.Claims {
+	return auth.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{"order-service"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+	}}
+}
+
+func newStaticVerifier(t *testing.T) *auth.Verifier {
+	t.Helper()
+	verifier, err := auth.NewVerifier(auth.Configuration{
+		StaticKeys:  []string{testStaticKey},
+		ServiceKeys: []string{testServiceKey},
+		Audience:    "order-service",
+	})
+	require.NoError(t, err)
+	return verifier
+}
+
+func TestVerifier_StaticKeys(t *testing.T) {
+	verifier := newStaticVerifier(t)
+
+	principal, err := verifier.Verify(signHMAC(t, userClaims("user-1", time.Minute)))
+	require.NoError(t, err)
+	require.Equal(t, auth.Principal{Subject: "user-1", Type: auth.PrincipalUser}, principal)
+
+	_, err = verifier.Verify(signHMAC(t, userClaims("user-1", -time.Hour)))
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	wrongAudience := userClaims("user-1", time.Minute)
+	wrongAudience.Audience = jwt.ClaimStrings{"another-service"}
+	_, err = verifier.Verify(signHMAC(t, wrongAudience))
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	_, err = auth.NewVerifier(auth.Configuration{})
+	require.Error(t, err)
+}
+
+func TestVerifier_JWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "rsa-1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	verifier, err := auth.NewVerifier(auth.Configuration{JWKSFile: path, Audience: "order-service"})
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, userClaims("user-2", time.Minute))
+	token.Header["kid"] = "rsa-1"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	principal, err := verifier.Verify(signed)
+	require.NoError(t, err)
+	require.Equal(t, "user-2", principal.Subject)
+
+	// Подпись HMAC публичным ключом RSA не должна приниматься.
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims("admin", time.Minute))
+	forged.Header["kid"] = "rsa-1"
+	forgedSigned, err := forged.SignedString(publicDER)
+	require.NoError(t, err)
+	_, err = verifier.Verify(forgedSigned)
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestSigner_IssuesServiceTokens(t *testing.T) {
+	signer, err := auth.NewSigner("billing-service", auth.SignerConfiguration{
+		Key:      testServiceKey,
+		Audience: "order-service",
+		TTL:      time.Minute,
+	})
+	require.NoError(t, err)
+
+	token, err := signer.Token(context.Background())
+	require.NoError(t, err)
+	again, err := signer.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, token, again)
+
+	principal, err := newStaticVerifier(t).Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, auth.Principal{Subject: "billing-service", Type: auth.PrincipalService}, principal)
+}
+
+func TestVerifier_ServiceIdentityComesFromKey(t *testing.T) {
+	verifier := newStaticVerifier(t)
+	serviceClaims := func() auth.Claims {
+		claims := userClaims("billing-service", time.Minute)
+		claims.PrincipalType = auth.PrincipalService
+		return claims
+	}
+
+	// Пользовательский ключ не может выпустить сервисный токен.
+	_, err := verifier.Verify(signHMACWith(t, testStaticKey, serviceClaims()))
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	principal, err := verifier.Verify(signHMACWith(t, testServiceKey, serviceClaims()))
+	require.NoError(t, err)
+	require.Equal(t, auth.PrincipalService, principal.Type)
+
+	// Токен без типа, подписанный сервисным ключом, остаётся сервисным.
+	principal, err = verifier.Verify(signHMACWith(t, testServiceKey, userClaims("billing-service", time.Minute)))
+	require.NoError(t, err)
+	require.Equal(t, auth.PrincipalService, principal.Type)
+
+	explicitUser := userClaims("user-1", time.Minute)
+	explicitUser.PrincipalType = auth.PrincipalUser
+	_, err = verifier.Verify(signHMACWith(t, testServiceKey, explicitUser))
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	_, err = auth.NewVerifier(auth.Configuration{StaticKeys: []string{testStaticKey}, ServiceKeys: []string{"test:b3RoZXI="}})
+	require.Error(t, err)
+}
+
+func TestConfiguration_RedactsKeysInLogs(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buffer), zap.InfoLevel))
+	config := struct {
+		Auth    auth.Configuration
+		Service auth.SignerConfiguration
+	}{
+		Auth:    auth.Configuration{StaticKeys: []string{testStaticKey}, ServiceKeys: []string{testServiceKey}, Audience: "order-service"},
+		Service: auth.SignerConfiguration{Key: "billing:c2VydmljZS1zZWNyZXQ=", TTL: time.Minute},
+	}
+
+	logger.Info("service start", zap.Any("config", config))
+
+	require.Contains(t, buffer.String(), `"StaticKeys":["test:***"]`)
+	require.Contains(t, buffer.String(), `"ServiceKeys":["svc:***"]`)
+	require.Contains(t, buffer.String(), `"Key":"billing:***"`)
+	require.Contains(t, buffer.String(), `"Audience":"order-service"`)
+	require.NotContains(t, buffer.String(), "c2VydmljZS1zZWNyZXQ=")
+	require.NotContains(t, buffer.String(), "c2VjcmV0LWtleS1mb3ItdGVzdHM=")
+	require.NotContains(t, buffer.String(), "c2VydmljZS1rZXktZm9yLXRlc3Rz")
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := auth.UnaryServerInterceptor(newStaticVerifier(t))
+	handler := func(ctx context.Context, req any) (any, error) {
+		principal, ok := auth.PrincipalFrom(ctx)
+		if !ok {
+			return "anonymous", nil
+		}
+		return principal.Subject, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.OrderService/GetOrder"}
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer garbage"))
+	_, err = interceptor(ctx, nil, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+signHMAC(t, userClaims("user-1", time.Minute))))
+	subject, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "user-1", subject)
+
+	subject, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "anonymous", subject)
+}
+
+func TestRequireService(t *testing.T) {
+	ctx := context.Background()
+
+	// Вызывающий без токена и сертификата не считается сервисом.
+	require.Equal(t, codes.PermissionDenied, status.Code(auth.RequireService(ctx, "billing-service")))
+
+	user := auth.WithPrincipal(ctx, auth.Principal{Subject: "billing-service", Type: auth.PrincipalUser})
+	require.Equal(t, codes.PermissionDenied, status.Code(auth.RequireService(user, "billing-service")))
+
+	service := auth.WithPrincipal(ctx, auth.Principal{Subject: "billing-service", Type: auth.PrincipalService})
+	require.NoError(t, auth.RequireService(service, "billing-service"))
+	require.Equal(t, codes.PermissionDenied, status.Code(auth.RequireService(service, "notification-service")))
+}
@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"order-service-system/common/bus"
+
+	"github.com/stretchr/testify/require"
+)
+
+func collect(t *testing.T, b bus.Subscriber, subject string, queue string) (bus.Subscription, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []string
+	sub, err := b.Subscribe(context.Background(), subject, queue, func(ctx context.Context, msg *bus.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, msg.Subject+":"+string(msg.Data))
+		return nil
+	})
+	require.NoError(t, err)
+	return sub, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+func TestMemoryBus_FanOutAndWildcards(t *testing.T) {
+	memory := bus.NewMemory()
+	ctx := context.Background()
+
+	exact, exactReceived := collect(t, memory, "order.paid", "")
+	single, singleReceived := collect(t, memory, "order.*", "")
+	tail, tailReceived := collect(t, memory, "order.>", "")
+
+	require.NoError(t, memory.Publish(ctx, bus.NewMessage("order.paid", []byte("1"))))
+	require.NoError(t, memory.Publish(ctx, bus.NewMessage("order.failed", []byte("2"))))
+	require.NoError(t, memory.Publish(ctx, bus.NewMessage("order.item.cancelled", []byte("3"))))
+
+	for _, sub := range []bus.Subscription{exact, single, tail} {
+		require.NoError(t, sub.Drain())
+		require.False(t, sub.IsValid())
+	}
+
+	require.Equal(t, []string{"order.paid:1"}, exactReceived())
+	require.Equal(t, []string{"order.paid:1", "order.failed:2"}, singleReceived())
+	require.Equal(t, []string{"order.paid:1", "order.failed:2", "order.item.cancelled:3"}, tailReceived())
+}
+
+func TestMemoryBus_QueueGroupDeliversOnce(t *testing.T) {
+	memory := bus.NewMemory()
+	ctx := context.Background()
+
+	first, firstReceived := collect(t, memory, "order.created", "billing")
+	second, secondReceived := collect(t, memory, "order.created", "billing")
+	other, otherReceived := collect(t, memory, "order.created", "audit")
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, memory.Publish(ctx, bus.NewMessage("order.created", []byte{byte('a' + i)})))
+	}
+
+	require.NoError(t, first.Drain())
+	require.NoError(t, second.Drain())
+	require.NoError(t, other.Drain())
+
+	require.Len(t, firstReceived(), 2)
+	require.Len(t, secondReceived(), 2)
+	require.Len(t, otherReceived(), 4)
+}
+
+func TestMemoryBus_RedeliversFailedMessages(t *testing.T) {
+	memory := bus.NewMemory(bus.WithMaxDeliver(3), bus.WithRedeliveryDelay(time.Millisecond))
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	sub, err := memory.Subscribe(ctx, "order.created", "", func(ctx context.Context, msg *bus.Message) error {
+		require.Equal(t, "v", msg.Header.Get("k"))
+		if attempts.Add(1) < 3 {
+			return errors.New("try again")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	msg := bus.NewMessage("order.created", []byte("x"))
+	msg.Header.Set("k", "v")
+	require.NoError(t, memory.Publish(ctx, msg))
+	require.NoError(t, sub.Drain())
+
+	require.Equal(t, int32(3), attempts.Load())
+}
+
+func TestMemoryBus_ClosedAndInvalid(t *testing.T) {
+	memory := bus.NewMemory()
+	ctx := context.Background()
+
+	require.ErrorIs(t, memory.Publish(ctx, bus.NewMessage("order.*", nil)), bus.ErrInvalidSubject)
+
+	sub, _ := collect(t, memory, "order.created", "")
+	require.NoError(t, sub.Unsubscribe())
+	require.ErrorIs(t, sub.Unsubscribe(), bus.ErrSubscriptionDone)
+
+	require.NoError(t, memory.Close())
+	require.ErrorIs(t, memory.Publish(ctx, bus.NewMessage("order.created", nil)), bus.ErrClosed)
+	_, err := memory.Subscribe(ctx, "order.created", "", func(ctx context.Context, msg *bus.Message) error { return nil })
+	require.ErrorIs(t, err, bus.ErrClosed)
+}
+
+func TestMemoryBus_RequestReply(t *testing.T) {
+	memory := bus.NewMemory()
+	defer memory.Close()
+	ctx := context.Background()
+
+	_, err := memory.Request(ctx, bus.NewMessage("billing.payment.status", []byte("o1")))
+	require.ErrorIs(t, err, bus.ErrNoResponders)
+
+	for _, name := range []string{"a", "b"} {
+		_, err := memory.Subscribe(ctx, "billing.payment.status", "billing", func(ctx context.Context, msg *bus.Message) error {
+			return bus.Respond(ctx, memory, msg, append([]byte(name+":"), msg.Data...))
+		})
+		require.NoError(t, err)
+	}
+
+	first, err := memory.Request(ctx, bus.NewMessage("billing.payment.status", []byte("o1")))
+	require.NoError(t, err)
+	second, err := memory.Request(ctx, bus.NewMessage("billing.payment.status", []byte("o2")))
+	require.NoError(t, err)
+	// Запросы распределяются внутри queue group, как обычные сообщения.
+	require.ElementsMatch(t, []string{"a:o1", "b:o2"}, []string{string(first.Data), string(second.Data)})
+
+	_, err = memory.Subscribe(ctx, "silent", "", func(context.Context, *bus.Message) error { return nil })
+	require.NoError(t, err)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = memory.Request(timeoutCtx, bus.NewMessage("silent", nil))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
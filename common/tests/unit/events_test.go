@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"order-service-system/common/events"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelope_EncodeDecodeRoundTrip(t *testing.T) {
+	envelope := events.NewEnvelope("order-service", events.OrderCreatedPayload{
+		OrderID:     "o1",
+		UserID:      "u1",
+		TotalAmount: 10.5,
+		CreatedAt:   100,
+	}).WithCorrelationID("c1")
+
+	data, err := events.Encode(envelope)
+	require.NoError(t, err)
+
+	decoded, err := events.Decode[events.OrderCreatedPayload](data)
+	require.NoError(t, err)
+	require.False(t, decoded.Legacy)
+	require.Equal(t, envelope.ID, decoded.ID)
+	require.Equal(t, events.TypeOrderCreated, decoded.Type)
+	require.Equal(t, "order-service", decoded.Source)
+	require.Equal(t, "c1", decoded.CorrelationID)
+	require.Equal(t, events.SchemaVersion, decoded.SchemaVersion)
+	require.Equal(t, envelope.Data, decoded.Data)
+}
+
+func TestEnvelope_DecodeLegacyPayload(t *testing.T) {
+	data, err := json.Marshal(events.OrderPaidPayload{OrderID: "o1", UserID: "u1", TotalAmount: 3, PaidAt: 7})
+	require.NoError(t, err)
+
+	decoded, err := events.Decode[events.OrderPaidPayload](data)
+	require.NoError(t, err)
+	require.True(t, decoded.Legacy)
+	require.Equal(t, events.TypeOrderPaid, decoded.Type)
+	require.Empty(t, decoded.ID)
+	require.Equal(t, "o1", decoded.Data.OrderID)
+	require.Equal(t, int64(7), decoded.Data.PaidAt)
+}
+
+func TestEnvelope_DecodeRejectsMismatches(t *testing.T) {
+	data, err := events.Encode(events.NewEnvelope("billing-service", events.OrderFailedPayload{OrderID: "o1"}))
+	require.NoError(t, err)
+
+	_, err = events.Decode[events.OrderPaidPayload](data)
+	require.ErrorIs(t, err, events.ErrUnexpectedType)
+
+	future := events.NewEnvelope("billing-service", events.OrderFailedPayload{OrderID: "o1"})
+	future.SchemaVersion = events.SchemaVersion + 1
+	data, err = events.Encode(future)
+	require.NoError(t, err)
+
+	_, err = events.Decode[events.OrderFailedPayload](data)
+	require.ErrorIs(t, err, events.ErrUnsupportedSchemaVersion)
+
+	_, err = events.Encode(events.Envelope[events.OrderFailedPayload]{})
+	require.ErrorIs(t, err, events.ErrMissingEnvelopeAttributes)
+}
+
+func TestCodec_ProtobufRoundTrip(t *testing.T) {
+	envelope := events.NewEnvelope("billing-service", events.OrderPaidPayload{
+		OrderID:     "o1",
+		UserID:      "u1",
+		TotalAmount: 42,
+		PaidAt:      1700000000,
+	}).WithCorrelationID("c1")
+
+	data, err := events.Marshal(events.ContentTypeProtobuf, envelope)
+	require.NoError(t, err)
+
+	decoded, err := events.Unmarshal[events.OrderPaidPayload](events.ContentTypeProtobuf, data)
+	require.NoError(t, err)
+	require.Equal(t, envelope.ID, decoded.ID)
+	require.Equal(t, "c1", decoded.CorrelationID)
+	require.Equal(t, events.ContentTypeProtobuf, decoded.DataContentType)
+	require.True(t, envelope.Time.Equal(decoded.Time))
+	require.Equal(t, envelope.Data, decoded.Data)
+
+	_, err = events.Unmarshal[events.OrderFailedPayload](events.ContentTypeProtobuf, data)
+	require.ErrorIs(t, err, events.ErrUnexpectedType)
+}
+
+func TestCodec_ProtobufRoundTripWithItems(t *testing.T) {
+	envelope := events.NewEnvelope("order-service", events.OrderItemsCancelledPayload{
+		CancellationID: "c1",
+		OrderID:        "o1",
+		UserID:         "u1",
+		Items:          []events.CancelledItem{{ProductID: "p1", Quantity: 2, Price: 5}, {ProductID: "p2", Quantity: 1, Price: 3}},
+		Amount:         13,
+		RemainingTotal: 7,
+		CancelledAt:    1700000000,
+	})
+
+	data, err := events.Marshal(events.ContentTypeProtobuf, envelope)
+	require.NoError(t, err)
+	decoded, err := events.Unmarshal[events.OrderItemsCancelledPayload](events.ContentTypeProtobuf, data)
+	require.NoError(t, err)
+	require.Equal(t, envelope.Data, decoded.Data)
+}
+
+func TestCodec_MissingContentTypeFallsBackToJSON(t *testing.T) {
+	data, err := events.Encode(events.NewEnvelope("order-service", events.OrderCreatedPayload{OrderID: "o1"}))
+	require.NoError(t, err)
+
+	decoded, err := events.Unmarshal[events.OrderCreatedPayload]("", data)
+	require.NoError(t, err)
+	require.Equal(t, "o1", decoded.Data.OrderID)
+
+	_, err = events.Unmarshal[events.OrderCreatedPayload]("text/plain", data)
+	require.ErrorIs(t, err, events.ErrUnsupportedContentType)
+}
+
+func TestEncodingConfig_ContentTypeFor(t *testing.T) {
+	cfg := events.EncodingConfig{ProtobufSubjects: []string{events.TypeOrderCreated}}
+
+	require.Equal(t, events.ContentTypeProtobuf, cfg.ContentTypeFor(events.TypeOrderCreated))
+	require.Equal(t, events.ContentTypeJSON, cfg.ContentTypeFor(events.TypeOrderPaid))
+}
@@ -0,0 +1,81 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"order-service-system/common/bus"
+	"order-service-system/common/health"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Readiness(t *testing.T) {
+	var mongoErr error
+	checker := health.New(0).
+		Register("mongo", func(context.Context) error { return mongoErr }).
+		Register("nats", func(context.Context) error { return nil })
+
+	_, ready := checker.Ready(context.Background())
+	require.False(t, ready, "not ready before start")
+
+	checker.Start()
+	report, ready := checker.Ready(context.Background())
+	require.True(t, ready)
+	require.Equal(t, health.StatusOK, report.Checks["mongo"])
+
+	mongoErr = errors.New("server selection timeout")
+	report, ready = checker.Ready(context.Background())
+	require.False(t, ready)
+	require.Equal(t, health.StatusUnavailable, report.Status)
+	require.Equal(t, "server selection timeout", report.Checks["mongo"])
+	require.Equal(t, health.StatusOK, report.Checks["nats"])
+
+	mongoErr = nil
+	checker.Shutdown()
+	report, ready = checker.Ready(context.Background())
+	require.False(t, ready)
+	require.Equal(t, health.StatusShuttingDown, report.Status)
+}
+
+func TestChecker_Handlers(t *testing.T) {
+	checker := health.New(0).Register("broken", func(context.Context) error { return errors.New("down") })
+	checker.Start()
+
+	recorder := httptest.NewRecorder()
+	checker.LivenessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"broken":"down"`)
+}
+
+func TestProbe(t *testing.T) {
+	checker := health.New(0)
+	server := httptest.NewServer(checker.ReadinessHandler())
+	defer server.Close()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	require.Error(t, health.Probe(context.Background(), addr))
+
+	checker.Start()
+	require.NoError(t, health.Probe(context.Background(), addr))
+}
+
+func TestSubscriptionsCheck(t *testing.T) {
+	memory := bus.NewMemory()
+	sub, err := memory.Subscribe(context.Background(), "order.created", "billing", func(context.Context, *bus.Message) error { return nil })
+	require.NoError(t, err)
+
+	check := health.Subscriptions(sub)
+	require.NoError(t, check(context.Background()))
+
+	require.NoError(t, sub.Drain())
+	require.Error(t, check(context.Background()))
+}
@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"order-service-system/common/events"
+	"order-service-system/common/inbox"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInbox(t *testing.T, window time.Duration) *inbox.Store {
+	t.Helper()
+	store, err := inbox.Open(inbox.Configuration{
+		Path:   filepath.Join(t.TempDir(), "inbox.db"),
+		Window: window,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestInbox_ClaimIsAtMostOnce(t *testing.T) {
+	store := newTestInbox(t, time.Hour)
+
+	claimed, err := store.Claim(context.Background(), "e1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	claimed, err = store.Claim(context.Background(), "e1")
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	claimed, err = store.Claim(context.Background(), "e2")
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func TestInbox_ReleaseAllowsReprocessing(t *testing.T) {
+	store := newTestInbox(t, time.Hour)
+
+	claimed, err := store.Claim(context.Background(), "e1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	require.NoError(t, store.Release(context.Background(), "e1"))
+
+	claimed, err = store.Claim(context.Background(), "e1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func TestInbox_WindowExpiry(t *testing.T) {
+	store := newTestInbox(t, 20*time.Millisecond)
+
+	claimed, err := store.Claim(context.Background(), "e1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	time.Sleep(30 * time.Millisecond)
+
+	purged, err := store.Purge()
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	claimed, err = store.Claim(context.Background(), "e1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func TestDeterministicID_IsStable(t *testing.T) {
+	require.Equal(t,
+		events.DeterministicID(events.TypeOrderCreated, "o1"),
+		events.DeterministicID(events.TypeOrderCreated, "o1"),
+	)
+	require.NotEqual(t,
+		events.DeterministicID(events.TypeOrderCreated, "o1"),
+		events.DeterministicID(events.TypeOrderPaid, "o1"),
+	)
+	require.Equal(t, "e1", events.ResolveID("e1", "m1", events.TypeOrderPaid, "o1"))
+	require.Equal(t, "m1", events.ResolveID("", "m1", events.TypeOrderPaid, "o1"))
+	require.Equal(t, events.DeterministicID(events.TypeOrderPaid, "o1"), events.ResolveID("", "", events.TypeOrderPaid, "o1"))
+}
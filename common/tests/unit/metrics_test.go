@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"order-service-system/common/bus"
+	"order-service-system/common/metrics"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsBus_CountsMessages(t *testing.T) {
+	instrumented := bus.WithMetrics(bus.NewMemory())
+
+	sub, err := instrumented.Subscribe(context.Background(), "metrics.test", "workers", func(ctx context.Context, msg *bus.Message) error {
+		if string(msg.Data) == "fail" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, instrumented.Publish(context.Background(), bus.NewMessage("metrics.test", []byte("ok"))))
+	require.NoError(t, instrumented.Publish(context.Background(), bus.NewMessage("metrics.test", []byte("fail"))))
+	require.NoError(t, sub.Drain())
+
+	body := scrape(t)
+	require.Contains(t, body, `nats_messages_published_total{result="success",subject="metrics.test"} 2`)
+	require.Contains(t, body, `nats_messages_consumed_total{queue="workers",result="success",subject="metrics.test"} 1`)
+	require.Contains(t, body, `nats_messages_consumed_total{queue="workers",result="error",subject="metrics.test"} 1`)
+	require.Contains(t, body, `nats_handler_duration_seconds_count{queue="workers",subject="metrics.test"} 2`)
+}
+
+func scrape(t *testing.T) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	return string(body)
+}
@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service-system/common/auth"
+	"order-service-system/common/ratelimit"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const createOrderMethod = "/order.OrderService/CreateOrder"
+
+func newTestLimiter(t *testing.T, now func() time.Time) *ratelimit.Limiter {
+	t.Helper()
+	limiter, err := ratelimit.New(ratelimit.Deps{
+		Logger: zap.NewNop(),
+		Store:  ratelimit.NewMemoryStoreWithClock(now),
+		Configuration: ratelimit.Configuration{
+			Default: "10:10",
+			Methods: []string{"CreateOrder=1:2"},
+			Exempt:  []string{"billing-service"},
+		},
+	})
+	require.NoError(t, err)
+	return limiter
+}
+
+func userContext(subject string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Subject: subject, Type: auth.PrincipalUser})
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("0.5:3")
+	require.NoError(t, err)
+	require.Equal(t, ratelimit.Limit{Rate: 0.5, Burst: 3}, limit)
+
+	for _, value := range []string{"", "5", "0:1", "1:0", "x:1"} {
+		_, err := ratelimit.ParseLimit(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestLimiter_TokenBucketPerUserAndMethod(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := newTestLimiter(t, func() time.Time { return now })
+
+	alice := userContext("alice")
+	require.True(t, limiter.Allow(alice, createOrderMethod).Allowed)
+	require.True(t, limiter.Allow(alice, createOrderMethod).Allowed)
+
+	denied := limiter.Allow(alice, createOrderMethod)
+	require.False(t, denied.Allowed)
+	require.Equal(t, time.Second, denied.RetryAfter)
+
+	// Другой пользователь и другой метод считаются отдельно.
+	require.True(t, limiter.Allow(userContext("bob"), createOrderMethod).Allowed)
+	require.True(t, limiter.Allow(alice, "/order.OrderService/GetOrder").Allowed)
+
+	now = now.Add(time.Second)
+	require.True(t, limiter.Allow(alice, createOrderMethod).Allowed)
+	require.False(t, limiter.Allow(alice, createOrderMethod).Allowed)
+}
+
+func TestLimiter_CallerKeys(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := newTestLimiter(t, func() time.Time { return now })
+
+	billing := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "billing-service", Type: auth.PrincipalService})
+	for range 10 {
+		require.True(t, limiter.Allow(billing, createOrderMethod).Allowed)
+	}
+
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(ratelimit.APIKeyHeader, key))
+	}
+	require.True(t, limiter.Allow(withKey("k1"), createOrderMethod).Allowed)
+	require.True(t, limiter.Allow(withKey("k1"), createOrderMethod).Allowed)
+	require.False(t, limiter.Allow(withKey("k1"), createOrderMethod).Allowed)
+	require.True(t, limiter.Allow(withKey("k2"), createOrderMethod).Allowed)
+}
+
+func TestRateLimitInterceptor_ResourceExhausted(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	interceptor := ratelimit.UnaryServerInterceptor(newTestLimiter(t, func() time.Time { return now }))
+	info := &grpc.UnaryServerInfo{FullMethod: createOrderMethod}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	ctx := userContext("alice")
+	for range 2 {
+		_, err := interceptor(ctx, nil, info, handler)
+		require.NoError(t, err)
+	}
+
+	_, err := interceptor(ctx, nil, info, handler)
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	retry, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Equal(t, time.Second, retry.GetRetryDelay().AsDuration())
+}
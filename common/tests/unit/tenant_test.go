@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"order-service-system/common/auth"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func resolveTenant(t *testing.T, ctx context.Context, allowed func(string) bool) (string, error) {
+	t.Helper()
+	var resolved string
+	_, err := tenant.UnaryServerInterceptor(allowed)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/order.OrderService/GetOrder"},
+		func(ctx context.Context, req any) (any, error) {
+			resolved = tenant.FromContext(ctx)
+			return nil, nil
+		})
+	return resolved, err
+}
+
+func withTenantHeader(ctx context.Context, id string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(tenant.MetadataKey, id))
+}
+
+func TestTenantInterceptor_ResolvesTenant(t *testing.T) {
+	id, err := resolveTenant(t, context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, tenant.Default, id)
+
+	id, err = resolveTenant(t, withTenantHeader(context.Background(), " Shop-A "), nil)
+	require.NoError(t, err)
+	require.Equal(t, "shop-a", id)
+
+	_, err = resolveTenant(t, withTenantHeader(context.Background(), "shop a/../b"), nil)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = resolveTenant(t, withTenantHeader(context.Background(), "shop-x"), func(id string) bool { return id == "shop-a" })
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestTenantInterceptor_PinsUserToTokenTenant(t *testing.T) {
+	user := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Type: auth.PrincipalUser, Tenant: "shop-a"})
+
+	id, err := resolveTenant(t, user, nil)
+	require.NoError(t, err)
+	require.Equal(t, "shop-a", id)
+
+	_, err = resolveTenant(t, withTenantHeader(user, "shop-b"), nil)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Пользователь без витрины в токене работает только в витрине по умолчанию.
+	claimless := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Type: auth.PrincipalUser})
+	id, err = resolveTenant(t, claimless, nil)
+	require.NoError(t, err)
+	require.Equal(t, tenant.Default, id)
+
+	id, err = resolveTenant(t, withTenantHeader(claimless, "Default"), nil)
+	require.NoError(t, err)
+	require.Equal(t, tenant.Default, id)
+
+	_, err = resolveTenant(t, withTenantHeader(claimless, "shop-b"), nil)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Сервисы действуют от имени витрины обрабатываемого события.
+	service := auth.WithPrincipal(withTenantHeader(context.Background(), "shop-b"), auth.Principal{Subject: "billing-service", Type: auth.PrincipalService})
+	id, err = resolveTenant(t, service, nil)
+	require.NoError(t, err)
+	require.Equal(t, "shop-b", id)
+}
+
+func TestTenantClientInterceptor_SendsTenant(t *testing.T) {
+	var sent []string
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		sent = md.Get(tenant.MetadataKey)
+		return nil
+	}
+	interceptor := tenant.UnaryClientInterceptor()
+
+	require.NoError(t, interceptor(tenant.WithTenant(context.Background(), "shop-a"), "/m", nil, nil, nil, invoker))
+	require.Equal(t, []string{"shop-a"}, sent)
+
+	require.NoError(t, interceptor(tenant.WithAllTenants(context.Background()), "/m", nil, nil, nil, invoker))
+	require.Empty(t, sent)
+}
+
+func TestTenantMongoFilter_IsolatesTenants(t *testing.T) {
+	filter := tenant.MongoFilter(tenant.WithTenant(context.Background(), "shop-a"), bson.M{"_id": "order-1"})
+	require.Equal(t, bson.M{"_id": "order-1", "tenant_id": "shop-a"}, filter)
+
+	// Заказы, созданные до мультитенантности, принадлежат витрине по умолчанию.
+	filter = tenant.MongoFilter(context.Background(), bson.M{"_id": "order-1"})
+	require.Equal(t, bson.M{"_id": "order-1", "tenant_id": bson.M{"$in": bson.A{tenant.Default, nil}}}, filter)
+
+	filter = tenant.MongoFilter(tenant.WithAllTenants(context.Background()), bson.M{"status": "PENDING"})
+	require.Equal(t, bson.M{"status": "PENDING"}, filter)
+
+	require.True(t, tenant.Matches(context.Background(), ""))
+	require.False(t, tenant.Matches(tenant.WithTenant(context.Background(), "shop-a"), ""))
+}
+
+func TestTenantSettings_MergesOverDefaults(t *testing.T) {
+	type settings struct {
+		Rate       float64  `json:"payment_success_rate"`
+		Currencies []string `json:"currencies"`
+	}
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"Shop-A": {"currencies": ["EUR"], "templates": {"order.paid": "ignored"}},
+		"shop-b": {"payment_success_rate": 0.5}
+	}`), 0o600))
+
+	loaded, err := tenant.LoadSettings(tenant.Configuration{File: path}, settings{Rate: 0.8})
+	require.NoError(t, err)
+	require.Equal(t, []string{"shop-a", "shop-b"}, loaded.Tenants())
+	require.Equal(t, settings{Rate: 0.8, Currencies: []string{"EUR"}}, loaded.For("shop-a"))
+	require.Equal(t, settings{Rate: 0.5}, loaded.For("shop-b"))
+	require.Equal(t, settings{Rate: 0.8}, loaded.For(tenant.Default))
+	require.True(t, loaded.Known(tenant.Default))
+	require.False(t, loaded.Known("shop-c"))
+
+	empty, err := tenant.LoadSettings(tenant.Configuration{}, settings{Rate: 0.8})
+	require.NoError(t, err)
+	require.True(t, empty.Known("shop-c"))
+}
+
+func TestEnvelope_CarriesTenant(t *testing.T) {
+	envelope := events.NewEnvelope("order-service", events.OrderCreatedPayload{OrderID: "order-1", Currency: "EUR"}).WithTenant("shop-a")
+
+	for _, contentType := range []string{events.ContentTypeJSON, events.ContentTypeProtobuf} {
+		data, err := events.Marshal(contentType, envelope)
+		require.NoError(t, err)
+		decoded, err := events.Unmarshal[events.OrderCreatedPayload](contentType, data)
+		require.NoError(t, err)
+		require.Equal(t, "shop-a", decoded.TenantID, contentType)
+		require.Equal(t, "EUR", decoded.Data.Currency, contentType)
+	}
+}
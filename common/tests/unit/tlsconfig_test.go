@@ -0,0 +1,228 @@
+package unit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"order-service-system/common/auth"
+	"order-service-system/common/tlsconfig"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a leaf certificate and its key into dir and returns their paths.
+func (ca testCA) issue(t *testing.T, dir, name string, dnsNames []string, uris ...string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     dnsNames,
+	}
+	for _, raw := range uris {
+		uri, err := url.Parse(raw)
+		require.NoError(t, err)
+		template.URIs = append(template.URIs, uri)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+var fileGeneration atomic.Int64
+
+// writeFile moves the modification time forward on every write so that rotation
+// is noticed regardless of the file system clock resolution.
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	modTime := time.Now().Add(time.Duration(fileGeneration.Add(1)) * time.Second)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// startTLSServer serves grpc.health.v1 with TLS and reports the principal of every call.
+func startTLSServer(t *testing.T, reloader *tlsconfig.Reloader) (string, <-chan auth.Principal) {
+	t.Helper()
+	principals := make(chan auth.Principal, 16)
+	capture := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		principal, _ := auth.PrincipalFrom(ctx)
+		principals <- principal
+		return handler(ctx, req)
+	}
+
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(reloader.ServerConfig())),
+		grpc.ChainUnaryInterceptor(auth.UnaryPeerInterceptor(), capture),
+	)
+	healthpb.RegisterHealthServer(server, grpchealth.NewServer())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), principals
+}
+
+func checkHealth(addr string, reloader *tlsconfig.Reloader, anonymous bool) error {
+	config := reloader.ClientConfig("order-service")
+	if anonymous {
+		config = reloader.AnonymousClientConfig("order-service")
+	}
+	connection, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(connection).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestReloader_MutualTLSIdentity(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+
+	serverCert, serverKey := ca.issue(t, dir, "server", []string{"order-service"})
+	clientCert, clientKey := ca.issue(t, dir, "client", []string{"billing"}, "spiffe://order-system/billing-service")
+
+	serverReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{
+		CertFile: serverCert, KeyFile: serverKey, CAFile: caFile, ClientAuth: tlsconfig.ClientAuthOptional,
+	})
+	require.NoError(t, err)
+	clientReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{CertFile: clientCert, KeyFile: clientKey, CAFile: caFile})
+	require.NoError(t, err)
+
+	addr, principals := startTLSServer(t, serverReloader)
+
+	require.NoError(t, checkHealth(addr, clientReloader, false))
+	require.Equal(t, auth.Principal{Subject: "billing-service", Type: auth.PrincipalService}, <-principals)
+
+	// Без клиентского сертификата вызов проходит, но без сервисной идентичности.
+	require.NoError(t, checkHealth(addr, clientReloader, true))
+	require.Equal(t, auth.Principal{}, <-principals)
+}
+
+func TestReloader_RequireClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	serverCert, serverKey := ca.issue(t, dir, "server", []string{"order-service"})
+
+	serverReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{
+		CertFile: serverCert, KeyFile: serverKey, CAFile: caFile, ClientAuth: tlsconfig.ClientAuthRequire,
+	})
+	require.NoError(t, err)
+	require.True(t, serverReloader.RequiresClientCert())
+	clientReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{CAFile: caFile})
+	require.NoError(t, err)
+
+	addr, _ := startTLSServer(t, serverReloader)
+	require.Error(t, checkHealth(addr, clientReloader, false))
+}
+
+func TestReloader_PicksUpRotatedCertificates(t *testing.T) {
+	dir := t.TempDir()
+	oldCA, newCA := newTestCA(t), newTestCA(t)
+	serverCAFile := filepath.Join(dir, "server-ca.crt")
+	clientCAFile := filepath.Join(dir, "client-ca.crt")
+	writeFile(t, serverCAFile, oldCA.pem)
+	writeFile(t, clientCAFile, oldCA.pem)
+	serverCert, serverKey := oldCA.issue(t, dir, "server", []string{"order-service"})
+
+	serverReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{
+		CertFile: serverCert, KeyFile: serverKey, CAFile: serverCAFile, ReloadInterval: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	clientReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{CAFile: clientCAFile, ReloadInterval: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go serverReloader.Start(ctx, zap.NewNop())
+	go clientReloader.Start(ctx, zap.NewNop())
+
+	addr, _ := startTLSServer(t, serverReloader)
+	require.NoError(t, checkHealth(addr, clientReloader, false))
+
+	// Сервер переходит на сертификат нового CA, которому клиент ещё не доверяет.
+	newCA.issue(t, dir, "server", []string{"order-service"})
+	require.Eventually(t, func() bool { return checkHealth(addr, clientReloader, false) != nil }, 2*time.Second, 20*time.Millisecond)
+
+	writeFile(t, clientCAFile, append(append([]byte{}, oldCA.pem...), newCA.pem...))
+	require.Eventually(t, func() bool { return checkHealth(addr, clientReloader, false) == nil }, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestServiceFromCertificate(t *testing.T) {
+	uri, _ := url.Parse("spiffe://order-system/ns/prod/notification-service")
+	service, ok := auth.ServiceFromCertificate(&x509.Certificate{URIs: []*url.URL{uri}, DNSNames: []string{"ignored"}})
+	require.True(t, ok)
+	require.Equal(t, "notification-service", service)
+
+	service, ok = auth.ServiceFromCertificate(&x509.Certificate{DNSNames: []string{"billing-service"}})
+	require.True(t, ok)
+	require.Equal(t, "billing-service", service)
+
+	_, ok = auth.ServiceFromCertificate(&x509.Certificate{})
+	require.False(t, ok)
+}
@@ -0,0 +1,64 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"order-service-system/common/bus"
+	"order-service-system/common/telemetry"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingBus_PropagatesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	traced := bus.WithTracing(bus.NewMemory())
+
+	var consumed trace.SpanContext
+	sub, err := traced.Subscribe(context.Background(), "order.created", "billing", func(ctx context.Context, msg *bus.Message) error {
+		consumed = trace.SpanContextFromContext(ctx)
+		require.NotEmpty(t, msg.Header.Get("traceparent"))
+		return nil
+	})
+	require.NoError(t, err)
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "CreateOrder")
+	require.NoError(t, traced.Publish(ctx, bus.NewMessage("order.created", []byte("{}"))))
+	root.End()
+	require.NoError(t, sub.Drain())
+
+	require.True(t, consumed.IsValid())
+	require.Equal(t, root.SpanContext().TraceID(), consumed.TraceID())
+
+	names := map[string]trace.SpanKind{}
+	for _, span := range recorder.Ended() {
+		names[span.Name()] = span.SpanKind()
+	}
+	require.Equal(t, trace.SpanKindProducer, names["publish order.created"])
+	require.Equal(t, trace.SpanKindConsumer, names["process order.created"])
+}
+
+func TestTelemetrySetup_Exporters(t *testing.T) {
+	ctx := context.Background()
+
+	shutdown, err := telemetry.Setup(ctx, "test-service", telemetry.Configuration{Exporter: telemetry.ExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+
+	shutdown, err = telemetry.Setup(ctx, "test-service", telemetry.Configuration{Exporter: telemetry.ExporterStdout, SampleRatio: 1})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+
+	_, err = telemetry.Setup(ctx, "test-service", telemetry.Configuration{Exporter: "zipkin"})
+	require.Error(t, err)
+}
@@ -0,0 +1,252 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+type Configuration struct {
+	CertFile string `env:"TLS_CERT_FILE"`
+	KeyFile  string `env:"TLS_KEY_FILE"`
+	CAFile   string `env:"TLS_CA_FILE"`
+	// ClientAuth is the server policy for client certificates: none, optional or require.
+	ClientAuth string `env:"TLS_CLIENT_AUTH" envDefault:"optional"`
+	// ServerName overrides the name checked in the server certificate on the client side.
+	ServerName     string        `env:"TLS_SERVER_NAME"`
+	ReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" envDefault:"30s"`
+}
+
+// Enabled reports whether TLS is configured at all; a client may use only a CA file.
+func (c Configuration) Enabled() bool {
+	return c.CertFile != "" || c.CAFile != ""
+}
+
+// Reloader keeps the certificate, key and CA bundle loaded from disk and picks up
+// rotated files without a restart. Every handshake uses the current material.
+type Reloader struct {
+	cfg Configuration
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+func NewReloader(cfg Configuration) (*Reloader, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	switch cfg.ClientAuth {
+	case "", ClientAuthNone, ClientAuthOptional, ClientAuthRequire:
+	default:
+		return nil, fmt.Errorf("unknown TLS_CLIENT_AUTH %q", cfg.ClientAuth)
+	}
+
+	r := &Reloader{cfg: cfg}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start polls the files and reloads them when they change until ctx is done.
+func (r *Reloader) Start(ctx context.Context, logger *zap.Logger) {
+	interval := r.cfg.ReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			if err != nil {
+				// Оставляем предыдущие сертификаты, пока файлы не станут консистентными.
+				logger.Warn("failed to reload certificates on <Start> of <Reloader>", zap.Error(err))
+				continue
+			}
+			if reloaded {
+				logger.Info("certificates reloaded on <Start> of <Reloader>")
+			}
+		}
+	}
+}
+
+// RequiresClientCert reports whether the server rejects clients without a certificate.
+func (r *Reloader) RequiresClientCert() bool {
+	return r.cfg.ClientAuth == ClientAuthRequire && r.cfg.CAFile != ""
+}
+
+// ServerConfig is the TLS config of a gRPC server. Client certificates are verified
+// against the CA bundle according to the configured ClientAuth policy.
+func (r *Reloader) ServerConfig() *tls.Config {
+	clientAuth := tls.NoClientCert
+	switch r.cfg.ClientAuth {
+	case ClientAuthOptional, "":
+		clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	if r.cfg.CAFile == "" {
+		clientAuth = tls.NoClientCert
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			if r.cert == nil {
+				return nil, errors.New("server certificate is not configured")
+			}
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientCAs:    r.pool,
+				ClientAuth:   clientAuth,
+			}, nil
+		},
+	}
+}
+
+// ClientConfig is the TLS config of a client. It presents the client certificate
+// when one is configured and verifies the server against the current CA bundle.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return r.clientConfig(serverName, true)
+}
+
+// AnonymousClientConfig verifies the server but never presents a client certificate,
+// so the connection carries no service identity of its own.
+func (r *Reloader) AnonymousClientConfig(serverName string) *tls.Config {
+	return r.clientConfig(serverName, false)
+}
+
+func (r *Reloader) clientConfig(serverName string, presentCert bool) *tls.Config {
+	if r.cfg.ServerName != "" {
+		serverName = r.cfg.ServerName
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Проверка выполняется в VerifyConnection, чтобы использовать актуальный CA после ротации.
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			if !presentCert || r.cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return r.cert, nil
+		},
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return r.verifyServer(state, serverName)
+		},
+	}
+}
+
+// ServerName returns the first DNS name of the own certificate.
+func (r *Reloader) ServerName() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cert == nil || r.cert.Leaf == nil || len(r.cert.Leaf.DNSNames) == 0 {
+		return ""
+	}
+	return r.cert.Leaf.DNSNames[0]
+}
+
+func (r *Reloader) verifyServer(state tls.ConnectionState, serverName string) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+
+	options := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		options.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(options)
+	return err
+}
+
+func (r *Reloader) reload() (bool, error) {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile}
+	modTimes := map[string]time.Time{}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, fmt.Errorf("stat %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	r.mu.RLock()
+	changed := r.modTimes == nil
+	for file, modTime := range modTimes {
+		if !r.modTimes[file].Equal(modTime) {
+			changed = true
+		}
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	var cert *tls.Certificate
+	if r.cfg.CertFile != "" {
+		loaded, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return false, fmt.Errorf("load key pair: %w", err)
+		}
+		if loaded.Leaf == nil {
+			if loaded.Leaf, err = x509.ParseCertificate(loaded.Certificate[0]); err != nil {
+				return false, fmt.Errorf("parse certificate: %w", err)
+			}
+		}
+		cert = &loaded
+	}
+
+	var pool *x509.CertPool
+	if r.cfg.CAFile != "" {
+		data, err := os.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return false, fmt.Errorf("read ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return false, fmt.Errorf("no certificates in %s", r.cfg.CAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.modTimes = cert, pool, modTimes
+	r.mu.Unlock()
+	return true, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"order-service-system/common/health"
 	"order-service-system/notification_service/internal/app"
 	"order-service-system/notification_service/internal/initialize"
 	"os"
@@ -24,6 +25,15 @@ func main() {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
 
+	// Distroless образ без curl: docker healthcheck вызывает бинарник с аргументом healthcheck.
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		if err := health.Probe(context.Background(), config.HTTPURL); err != nil {
+			fmt.Printf("Healthcheck failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -2,11 +2,22 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"order-service-system/common/bus"
 	"order-service-system/common/closer"
+	"order-service-system/common/health"
+	"order-service-system/common/httpserver"
+	"order-service-system/common/inbox"
+	"order-service-system/common/metrics"
+	"order-service-system/common/mongo"
 	"order-service-system/common/nats"
+	"order-service-system/common/telemetry"
+	"order-service-system/common/tenant"
+	"order-service-system/common/tlsconfig"
 	"order-service-system/notification_service/internal/initialize"
+	"order-service-system/notification_service/internal/workers/notifier"
 	"time"
 
 	"go.uber.org/zap"
@@ -26,20 +37,104 @@ func Run(ctx context.Context, config initialize.Config, logger *zap.Logger) erro
 
 	shutdownGroup := closer.NewCloserGroup()
 
+	shutdownTracing, err := telemetry.Setup(ctx, "notification-service", config.ExternalCfg.TelemetryConfig)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracing: %w", err)
+	}
+	shutdownGroup.Add(closer.CloserFunc(shutdownTracing))
+
+	checker := health.New(2 * time.Second)
+
+	httpServer, err := httpserver.New(httpserver.Deps{Logger: logger})
+	if err != nil {
+		return fmt.Errorf("failed initialize http server: %w", err)
+	}
+	httpServer.
+		Handle("/metrics", metrics.Handler()).
+		Handle("/healthz", checker.LivenessHandler()).
+		Handle("/readyz", checker.ReadinessHandler())
+
+	go func() {
+		if err := httpServer.Run(config.HTTPURL); err != nil {
+			logger.Error("http server failed on <Run> of <app>", zap.Error(err))
+			cancel()
+		}
+	}()
+	shutdownGroup.Add(closer.CloserFunc(httpServer.Stop))
+
 	natsConn, err := nats.Connect(config.ExternalCfg.NatsConfig)
 	if err != nil {
 		return fmt.Errorf("failed to connect nats: %w", err)
 	}
 
-	clients := initialize.NewClients(initialize.ClientsDeps{
-		Logger:           logger,
-		OrderServiceHost: config.OrderServiceHost,
+	var inboxStore inbox.Inbox
+	switch config.Inbox.Store {
+	case inbox.StoreMongo:
+		// Повторная доставка может попасть на любую реплику, поэтому inbox общий.
+		mongoDB, err := mongo.Connect(ctx, &mongo.ConnectDeps{
+			Configuration: &config.ExternalCfg.MongoConfig,
+			Timeout:       10 * time.Second,
+		})
+		if err != nil {
+			return fmt.Errorf("failed connection to db: %w", err)
+		}
+		shutdownGroup.Add(closer.CloserFunc(mongoDB.Client().Disconnect))
+		if inboxStore, err = inbox.NewMongoStore(ctx, mongoDB.Collection("notification_inbox"), config.Inbox); err != nil {
+			return fmt.Errorf("failed to open inbox: %w", err)
+		}
+	case inbox.StoreBbolt:
+		localInbox, err := inbox.Open(config.Inbox)
+		if err != nil {
+			return fmt.Errorf("failed to open inbox: %w", err)
+		}
+		logger.Warn("inbox is local to this replica, run a single notification replica on <Run> of <app>")
+		go localInbox.StartPurging(ctx, time.Minute, logger)
+		shutdownGroup.Add(closer.CloserFunc(func(ctx context.Context) error {
+			return localInbox.Close()
+		}))
+		inboxStore = localInbox
+	default:
+		return fmt.Errorf("unknown INBOX_STORE %q", config.Inbox.Store)
+	}
+
+	var clientTLS *tls.Config
+	if config.TLS.Enabled() {
+		reloader, err := tlsconfig.NewReloader(config.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to load certificates: %w", err)
+		}
+		go reloader.Start(ctx, logger)
+		clientTLS = reloader.ClientConfig("order-service")
+	}
+
+	clients, err := initialize.NewClients(initialize.ClientsDeps{
+		Logger:            logger,
+		OrderServiceHost:  config.OrderServiceHost,
+		OrderClientConfig: config.OrderClient,
+		ServiceName:       "notification-service",
+		Auth:              config.Auth,
+		TLS:               clientTLS,
 	})
+	if err != nil {
+		return fmt.Errorf("failed initialize clients: %w", err)
+	}
+	shutdownGroup.Add(closer.CloserFunc(clients.OrderClient.Close))
+
+	tenants, err := tenant.LoadSettings(config.Tenants, notifier.TenantSettings{})
+	if err != nil {
+		return fmt.Errorf("invalid TENANTS_FILE: %w", err)
+	}
+	templates, err := notifier.NewTemplates(tenants)
+	if err != nil {
+		return fmt.Errorf("invalid notification templates in TENANTS_FILE: %w", err)
+	}
 
 	workers := initialize.NewWorkers(initialize.WorkersDeps{
-		Logger:   logger,
-		Clients:  clients,
-		NatsConn: natsConn,
+		Logger:    logger,
+		Clients:   clients,
+		Bus:       bus.WithMetrics(bus.WithTracing(bus.NewNATS(natsConn, logger))),
+		Inbox:     inboxStore,
+		Templates: templates,
 	})
 
 	subscriptions, err := workers.Notifier.Start(ctx)
@@ -47,6 +142,12 @@ func Run(ctx context.Context, config initialize.Config, logger *zap.Logger) erro
 		return fmt.Errorf("failed to subscribe to events: %w", err)
 	}
 
+	checker.
+		Register("nats", health.NATS(natsConn)).
+		Register("subscriptions", health.Subscriptions(subscriptions...)).
+		Register("inbox", inboxStore.Ping).
+		Start()
+
 	shutdownGroup.Add(closer.CloserFunc(func(ctx context.Context) error {
 		return natsConn.Drain()
 	}))
@@ -59,6 +160,8 @@ func Run(ctx context.Context, config initialize.Config, logger *zap.Logger) erro
 
 	<-ctx.Done()
 
+	checker.Shutdown()
+
 	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer timeoutCancel()
 	if err := shutdownGroup.Call(timeoutCtx); err != nil {
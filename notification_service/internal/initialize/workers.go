@@ -1,9 +1,10 @@
 package initialize
 
 import (
+	"order-service-system/common/bus"
+	"order-service-system/common/inbox"
 	"order-service-system/notification_service/internal/workers/notifier"
 
-	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
@@ -12,17 +13,23 @@ type Workers struct {
 }
 
 type WorkersDeps struct {
-	Logger   *zap.Logger
-	NatsConn *nats.Conn
-	Clients  *Clients
+	Logger  *zap.Logger
+	Bus     bus.Subscriber
+	Inbox   inbox.Inbox
+	Clients *Clients
+	// Templates are optional: without them notifications use the default texts.
+	Templates *notifier.Templates
 }
 
 func NewWorkers(deps WorkersDeps) *Workers {
 	if deps.Logger == nil {
 		panic("logger must not be nil on <NewWorkers> of <initialize>")
 	}
-	if deps.NatsConn == nil {
-		panic("nats connection must not be nil on <NewWorkers> of <initialize>")
+	if deps.Bus == nil {
+		panic("bus must not be nil on <NewWorkers> of <initialize>")
+	}
+	if deps.Inbox == nil {
+		panic("inbox must not be nil on <NewWorkers> of <initialize>")
 	}
 	if deps.Clients == nil {
 		panic("clients must not be nil on <NewWorkers> of <initialize>")
@@ -30,8 +37,10 @@ func NewWorkers(deps WorkersDeps) *Workers {
 	return &Workers{
 		Notifier: notifier.New(notifier.Deps{
 			Logger:      deps.Logger,
-			NatsConn:    deps.NatsConn,
+			Subscriber:  deps.Bus,
+			Inbox:       deps.Inbox,
 			OrderClient: deps.Clients.OrderClient,
+			Templates:   deps.Templates,
 		}),
 	}
 }
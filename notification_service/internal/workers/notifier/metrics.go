@@ -0,0 +1,19 @@
+package notifier
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	outcomeDelivered = "delivered"
+	outcomeDuplicate = "duplicate"
+	outcomeFailed    = "failed"
+	outcomeInvalid   = "invalid"
+	outcomeStale     = "stale"
+)
+
+var deliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_deliveries_total",
+	Help: "Number of notifications by event type and delivery outcome.",
+}, []string{"event", "outcome"})
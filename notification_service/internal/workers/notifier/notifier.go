@@ -2,123 +2,310 @@ package notifier
 
 import (
 	"context"
-	"encoding/json"
+	"order-service-system/common/bus"
 	"order-service-system/common/events"
-	"order-service-system/proto/clients"
+	"order-service-system/common/tenant"
 
 	orderpb "order-service-system/proto/order"
 
-	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	subjectOrderPaid   = "order.paid"
-	subjectOrderFailed = "order.failed"
-	queueNotification  = "notification-workers"
+	subjectOrderPaid      = events.TypeOrderPaid
+	subjectOrderFailed    = events.TypeOrderFailed
+	subjectOrderShipped   = events.TypeOrderShipped
+	subjectOrderDelivered = events.TypeOrderDelivered
+	subjectItemsCancelled = events.TypeOrderItemsCancelled
+	subjectReturnApproved = events.TypeReturnApproved
+	subjectReturnRejected = events.TypeReturnRejected
+	subjectReturnRefunded = events.TypeReturnRefunded
+	queueNotification     = "notification-workers"
 )
 
+type Inbox interface {
+	Claim(ctx context.Context, id string) (bool, error)
+	Release(ctx context.Context, id string) error
+}
+
+type OrderClient interface {
+	UpdateOrderStatus(ctx context.Context, orderID string, status orderpb.OrderStatus) (*orderpb.UpdateOrderStatusResponse, error)
+}
+
 type Notifier struct {
 	logger      *zap.Logger
-	natsConn    *nats.Conn
-	orderClient *clients.OrderClient
+	subscriber  bus.Subscriber
+	inbox       Inbox
+	orderClient OrderClient
+	templates   *Templates
 }
 
 type Deps struct {
 	Logger      *zap.Logger
-	NatsConn    *nats.Conn
-	OrderClient *clients.OrderClient
+	Subscriber  bus.Subscriber
+	Inbox       Inbox
+	OrderClient OrderClient
+	// Templates are the notification texts of the tenants; nil renders the default ones.
+	Templates *Templates
 }
 
 func New(deps Deps) *Notifier {
 	if deps.Logger == nil {
 		panic("logger must not be nil on <New> of <Notifier>")
 	}
-	if deps.NatsConn == nil {
-		panic("nats connection must not be nil on <New> of <Notifier>")
+	if deps.Subscriber == nil {
+		panic("subscriber must not be nil on <New> of <Notifier>")
+	}
+	if deps.Inbox == nil {
+		panic("inbox must not be nil on <New> of <Notifier>")
 	}
 	if deps.OrderClient == nil {
 		panic("order client must not be nil on <New> of <Notifier>")
 	}
+	if deps.Templates == nil {
+		deps.Templates = &Templates{}
+	}
 	return &Notifier{
 		logger:      deps.Logger,
-		natsConn:    deps.NatsConn,
+		subscriber:  deps.Subscriber,
+		inbox:       deps.Inbox,
 		orderClient: deps.OrderClient,
+		templates:   deps.Templates,
 	}
 }
 
-func (receiver *Notifier) Start(ctx context.Context) ([]*nats.Subscription, error) {
-	subPaid, err := receiver.natsConn.QueueSubscribe(subjectOrderPaid, queueNotification, func(msg *nats.Msg) {
-		receiver.handlePaid(ctx, msg)
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	subFailed, err := receiver.natsConn.QueueSubscribe(subjectOrderFailed, queueNotification, func(msg *nats.Msg) {
-		receiver.handleFailed(ctx, msg)
-	})
-	if err != nil {
-		return nil, err
+func (receiver *Notifier) Start(ctx context.Context) ([]bus.Subscription, error) {
+	handlers := []struct {
+		subject string
+		handler bus.Handler
+	}{
+		{subjectOrderPaid, receiver.handlePaid},
+		{subjectOrderFailed, receiver.handleFailed},
+		{subjectOrderShipped, receiver.handleShipped},
+		{subjectOrderDelivered, receiver.handleDelivered},
+		{subjectItemsCancelled, receiver.handleItemsCancelled},
+		{subjectReturnApproved, receiver.handleReturnApproved},
+		{subjectReturnRejected, receiver.handleReturnRejected},
+		{subjectReturnRefunded, receiver.handleReturnRefunded},
 	}
 
-	if err := receiver.natsConn.Flush(); err != nil {
-		return nil, err
+	subscriptions := make([]bus.Subscription, 0, len(handlers))
+	for _, h := range handlers {
+		sub, err := receiver.subscriber.Subscribe(ctx, h.subject, queueNotification, h.handler)
+		if err != nil {
+			for _, subscribed := range subscriptions {
+				_ = subscribed.Unsubscribe()
+			}
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
 	}
 
-	receiver.logger.Info("listening for payment events on <Start> of <Notifier>",
+	receiver.logger.Info("listening for order events on <Start> of <Notifier>",
 		zap.String("paid", subjectOrderPaid),
 		zap.String("failed", subjectOrderFailed),
+		zap.String("shipped", subjectOrderShipped),
+		zap.String("delivered", subjectOrderDelivered),
+		zap.String("items_cancelled", subjectItemsCancelled),
+		zap.Strings("returns", []string{subjectReturnApproved, subjectReturnRejected, subjectReturnRefunded}),
 		zap.String("queue", queueNotification),
 	)
-	return []*nats.Subscription{subPaid, subFailed}, nil
+	return subscriptions, nil
 }
 
-func (receiver *Notifier) handlePaid(ctx context.Context, msg *nats.Msg) {
+func (receiver *Notifier) handlePaid(ctx context.Context, msg *bus.Message) error {
 	select {
 	case <-ctx.Done():
-		return
+		return ctx.Err()
 	default:
 	}
 
-	var payload events.OrderPaidPayload
-	if err := json.Unmarshal(msg.Data, &payload); err != nil {
+	envelope, err := events.Unmarshal[events.OrderPaidPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
 		receiver.logger.Error("failed to decode paid payload on <handlePaid> of <Notifier>", zap.Error(err))
-		return
+		deliveriesTotal.WithLabelValues(events.TypeOrderPaid, outcomeInvalid).Inc()
+		return nil
 	}
+	payload := envelope.Data
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
 
-	if _, err := receiver.orderClient.UpdateOrderStatus(ctx, payload.OrderID, orderpb.OrderStatus_PAID); err != nil {
+	eventID := events.ResolveID(envelope.ID, msg.Header.Get(events.HeaderMsgID), events.TypeOrderPaid, payload.OrderID)
+	if claimed, err := receiver.claim(ctx, eventID, payload.OrderID); !claimed {
+		if err == nil {
+			deliveriesTotal.WithLabelValues(events.TypeOrderPaid, outcomeDuplicate).Inc()
+		}
+		return err
+	}
+
+	if _, err := receiver.orderClient.UpdateOrderStatus(ctx, payload.OrderID, orderpb.OrderStatus_PAID); status.Code(err) == codes.FailedPrecondition {
+		// Повторная доставка не поможет: заказ уже истёк, отменён или получил другой результат.
+		receiver.logger.Warn("order was resolved before the payment result on <handlePaid> of <Notifier>", zap.String("order_id", payload.OrderID), zap.Error(err))
+		deliveriesTotal.WithLabelValues(events.TypeOrderPaid, outcomeStale).Inc()
+		return nil
+	} else if err != nil {
 		receiver.logger.Error("failed to update order status on <handlePaid> of <Notifier>", zap.String("order_id", payload.OrderID), zap.Error(err))
-		return
+		deliveriesTotal.WithLabelValues(events.TypeOrderPaid, outcomeFailed).Inc()
+		receiver.release(ctx, eventID)
+		return err
 	}
+	deliveriesTotal.WithLabelValues(events.TypeOrderPaid, outcomeDelivered).Inc()
 
 	receiver.logger.Info("notified user about payment on <handlePaid> of <Notifier>",
+		zap.String("event_id", eventID),
+		zap.String("correlation_id", envelope.CorrelationID),
+		zap.String("tenant_id", tenant.FromContext(ctx)),
 		zap.String("order_id", payload.OrderID),
 		zap.String("user_id", payload.UserID),
+		zap.String("message", receiver.render(ctx, payload)),
 	)
+	return nil
 }
 
-func (receiver *Notifier) handleFailed(ctx context.Context, msg *nats.Msg) {
+func (receiver *Notifier) handleFailed(ctx context.Context, msg *bus.Message) error {
 	select {
 	case <-ctx.Done():
-		return
+		return ctx.Err()
 	default:
 	}
 
-	var payload events.OrderFailedPayload
-	if err := json.Unmarshal(msg.Data, &payload); err != nil {
+	envelope, err := events.Unmarshal[events.OrderFailedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
 		receiver.logger.Error("failed to decode failed payload on <handleFailed> of <Notifier>", zap.Error(err))
-		return
+		deliveriesTotal.WithLabelValues(events.TypeOrderFailed, outcomeInvalid).Inc()
+		return nil
 	}
+	payload := envelope.Data
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
 
-	if _, err := receiver.orderClient.UpdateOrderStatus(ctx, payload.OrderID, orderpb.OrderStatus_FAILED); err != nil {
+	eventID := events.ResolveID(envelope.ID, msg.Header.Get(events.HeaderMsgID), events.TypeOrderFailed, payload.OrderID)
+	if claimed, err := receiver.claim(ctx, eventID, payload.OrderID); !claimed {
+		if err == nil {
+			deliveriesTotal.WithLabelValues(events.TypeOrderFailed, outcomeDuplicate).Inc()
+		}
+		return err
+	}
+
+	if _, err := receiver.orderClient.UpdateOrderStatus(ctx, payload.OrderID, orderpb.OrderStatus_FAILED); status.Code(err) == codes.FailedPrecondition {
+		// Повторная доставка не поможет: заказ уже истёк, отменён или получил другой результат.
+		receiver.logger.Warn("order was resolved before the payment result on <handleFailed> of <Notifier>", zap.String("order_id", payload.OrderID), zap.Error(err))
+		deliveriesTotal.WithLabelValues(events.TypeOrderFailed, outcomeStale).Inc()
+		return nil
+	} else if err != nil {
 		receiver.logger.Error("failed to update order status on <handleFailed> of <Notifier>", zap.String("order_id", payload.OrderID), zap.Error(err))
-		return
+		deliveriesTotal.WithLabelValues(events.TypeOrderFailed, outcomeFailed).Inc()
+		receiver.release(ctx, eventID)
+		return err
 	}
+	deliveriesTotal.WithLabelValues(events.TypeOrderFailed, outcomeDelivered).Inc()
 
 	receiver.logger.Info("notified user about failure on <handleFailed> of <Notifier>",
+		zap.String("event_id", eventID),
+		zap.String("correlation_id", envelope.CorrelationID),
+		zap.String("tenant_id", tenant.FromContext(ctx)),
 		zap.String("order_id", payload.OrderID),
 		zap.String("user_id", payload.UserID),
 		zap.String("reason", payload.Reason),
+		zap.String("message", receiver.render(ctx, payload)),
+	)
+	return nil
+}
+
+// Доставка и возвраты только уведомляют пользователя: статусы меняют сервисы доставки и billing.
+func (receiver *Notifier) handleShipped(ctx context.Context, msg *bus.Message) error {
+	return notify(ctx, receiver, msg, func(p events.OrderShippedPayload) (string, string) { return p.OrderID, p.UserID })
+}
+
+func (receiver *Notifier) handleDelivered(ctx context.Context, msg *bus.Message) error {
+	return notify(ctx, receiver, msg, func(p events.OrderDeliveredPayload) (string, string) { return p.OrderID, p.UserID })
+}
+
+func (receiver *Notifier) handleItemsCancelled(ctx context.Context, msg *bus.Message) error {
+	return notify(ctx, receiver, msg, func(p events.OrderItemsCancelledPayload) (string, string) { return p.OrderID, p.UserID })
+}
+
+func (receiver *Notifier) handleReturnApproved(ctx context.Context, msg *bus.Message) error {
+	return notify(ctx, receiver, msg, func(p events.ReturnApprovedPayload) (string, string) { return p.OrderID, p.UserID })
+}
+
+func (receiver *Notifier) handleReturnRejected(ctx context.Context, msg *bus.Message) error {
+	return notify(ctx, receiver, msg, func(p events.ReturnRejectedPayload) (string, string) { return p.OrderID, p.UserID })
+}
+
+func (receiver *Notifier) handleReturnRefunded(ctx context.Context, msg *bus.Message) error {
+	return notify(ctx, receiver, msg, func(p events.ReturnRefundedPayload) (string, string) { return p.OrderID, p.UserID })
+}
+
+// notify claims the event and logs the rendered notification; recipient extracts
+// the order and the user from the payload.
+func notify[T events.Payload](ctx context.Context, receiver *Notifier, msg *bus.Message, recipient func(T) (string, string)) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	var zero T
+	eventType := zero.EventType()
+	envelope, err := events.Unmarshal[T](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode payload on <notify> of <Notifier>", zap.String("event", eventType), zap.Error(err))
+		deliveriesTotal.WithLabelValues(eventType, outcomeInvalid).Inc()
+		return nil
+	}
+
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+	orderID, userID := recipient(envelope.Data)
+
+	eventID := events.ResolveID(envelope.ID, msg.Header.Get(events.HeaderMsgID), eventType, orderID)
+	if claimed, err := receiver.claim(ctx, eventID, orderID); !claimed {
+		if err == nil {
+			deliveriesTotal.WithLabelValues(eventType, outcomeDuplicate).Inc()
+		}
+		return err
+	}
+	deliveriesTotal.WithLabelValues(eventType, outcomeDelivered).Inc()
+
+	receiver.logger.Info("notified user on <notify> of <Notifier>",
+		zap.String("event", eventType),
+		zap.String("event_id", eventID),
+		zap.String("correlation_id", envelope.CorrelationID),
+		zap.String("tenant_id", tenant.FromContext(ctx)),
+		zap.String("order_id", orderID),
+		zap.String("user_id", userID),
+		zap.String("message", receiver.render(ctx, envelope.Data)),
 	)
+	return nil
+}
+
+func (receiver *Notifier) render(ctx context.Context, payload events.Payload) string {
+	text, err := receiver.templates.Render(tenant.FromContext(ctx), payload)
+	if err != nil {
+		receiver.logger.Error("failed to render notification on <render> of <Notifier>",
+			zap.String("event", payload.EventType()),
+			zap.String("tenant_id", tenant.FromContext(ctx)),
+			zap.Error(err))
+	}
+	return text
+}
+
+func (receiver *Notifier) claim(ctx context.Context, eventID string, orderID string) (bool, error) {
+	claimed, err := receiver.inbox.Claim(ctx, eventID)
+	if err != nil {
+		receiver.logger.Error("failed to claim event on <claim> of <Notifier>", zap.String("event_id", eventID), zap.Error(err))
+		return false, err
+	}
+	if !claimed {
+		receiver.logger.Info("skipping duplicate event on <claim> of <Notifier>",
+			zap.String("event_id", eventID),
+			zap.String("order_id", orderID))
+	}
+	return claimed, nil
+}
+
+func (receiver *Notifier) release(ctx context.Context, eventID string) {
+	if err := receiver.inbox.Release(context.WithoutCancel(ctx), eventID); err != nil {
+		receiver.logger.Error("failed to release event on <release> of <Notifier>", zap.String("event_id", eventID), zap.Error(err))
+	}
 }
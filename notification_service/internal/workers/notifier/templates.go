@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"fmt"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var templates = template.Must(template.New("notifications").Funcs(template.FuncMap{
+	"date": func(unix int64) string { return time.Unix(unix, 0).UTC().Format("02.01.2006 15:04 UTC") },
+}).Parse(`
+{{define "order.paid"}}Заказ {{.OrderID}} оплачен на сумму {{printf "%.2f" .TotalAmount}}.{{end}}
+{{define "order.failed"}}Не удалось оплатить заказ {{.OrderID}}: {{.Reason}}.{{end}}
+{{define "order.shipped"}}Заказ {{.OrderID}} передан в службу доставки {{.Carrier}} {{date .ShippedAt}}. Трек-номер: {{.TrackingNumber}}.{{end}}
+{{define "order.delivered"}}Заказ {{.OrderID}} доставлен {{date .DeliveredAt}}.{{end}}
+{{define "order.items_cancelled"}}Из заказа {{.OrderID}} отменено: {{range $i, $item := .Items}}{{if $i}}, {{end}}{{$item.ProductID}} × {{$item.Quantity}}{{end}}. К возврату {{printf "%.2f" .Amount}}.{{end}}
+{{define "return.approved"}}Возврат {{.ReturnID}} по заказу {{.OrderID}} одобрен. Отправьте товары на склад.{{end}}
+{{define "return.rejected"}}Возврат {{.ReturnID}} по заказу {{.OrderID}} отклонён: {{.Reason}}.{{end}}
+{{define "return.refunded"}}По возврату {{.ReturnID}} возвращено {{printf "%.2f" .Amount}}.{{end}}
+`))
+
+// TenantSettings are the keys of the tenants file the notification service reads.
+type TenantSettings struct {
+	// Templates replace the texts of the listed event types, e.g.
+	// {"order.paid": "Shop A: order {{.OrderID}} is paid."}.
+	Templates map[string]string `json:"templates"`
+}
+
+// Templates render notifications with the texts of the tenant of the event.
+type Templates struct {
+	tenants map[string]*template.Template
+}
+
+// NewTemplates parses the texts of every configured tenant on top of the default ones.
+func NewTemplates(settings tenant.Settings[TenantSettings]) (*Templates, error) {
+	parsed := &Templates{tenants: map[string]*template.Template{}}
+	for _, id := range settings.Tenants() {
+		overrides := settings.For(id).Templates
+		if len(overrides) == 0 {
+			continue
+		}
+		tenantTemplates, err := templates.Clone()
+		if err != nil {
+			return nil, err
+		}
+		for eventType, text := range overrides {
+			if _, err := tenantTemplates.New(eventType).Parse(text); err != nil {
+				return nil, fmt.Errorf("parse %s template of tenant %q: %w", eventType, id, err)
+			}
+		}
+		parsed.tenants[id] = tenantTemplates
+	}
+	return parsed, nil
+}
+
+// Render builds the text of the notification about the event for the tenant.
+func (t *Templates) Render(tenantID string, payload events.Payload) (string, error) {
+	set, ok := t.tenants[tenantID]
+	if !ok {
+		set = templates
+	}
+	var text strings.Builder
+	if err := set.ExecuteTemplate(&text, payload.EventType(), payload); err != nil {
+		return "", fmt.Errorf("render %s notification: %w", payload.EventType(), err)
+	}
+	return text.String(), nil
+}
+
+// Render builds the text of the notification about the event with the default texts.
+func Render(payload events.Payload) (string, error) {
+	return (&Templates{}).Render(tenant.Default, payload)
+}
@@ -0,0 +1,246 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"order-service-system/notification_service/internal/workers/notifier"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type memoryInbox struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newMemoryInbox() *memoryInbox {
+	return &memoryInbox{seen: map[string]struct{}{}}
+}
+
+func (f *memoryInbox) Claim(_ context.Context, id string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.seen[id]; ok {
+		return false, nil
+	}
+	f.seen[id] = struct{}{}
+	return true, nil
+}
+
+func (f *memoryInbox) Release(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.seen, id)
+	return nil
+}
+
+type mockOrderClient struct {
+	mu       sync.Mutex
+	failures int
+	// rejection is returned on every call when set, like an order service that refuses the change.
+	rejection error
+	calls     int
+	statuses  []orderpb.OrderStatus
+	tenants   []string
+}
+
+func (f *mockOrderClient) UpdateOrderStatus(ctx context.Context, orderID string, status orderpb.OrderStatus) (*orderpb.UpdateOrderStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.rejection != nil {
+		return nil, f.rejection
+	}
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("order service unavailable")
+	}
+	f.statuses = append(f.statuses, status)
+	f.tenants = append(f.tenants, tenant.FromContext(ctx))
+	return &orderpb.UpdateOrderStatusResponse{}, nil
+}
+
+func publish[T events.Payload](t *testing.T, b bus.Publisher, subject string, payload T, contentType string) {
+	t.Helper()
+	envelope := events.NewEnvelope("billing-service", payload)
+	data, err := events.Marshal(contentType, envelope)
+	require.NoError(t, err)
+
+	msg := bus.NewMessage(subject, data)
+	msg.Header.Set(events.HeaderContentType, contentType)
+	msg.Header.Set(events.HeaderMsgID, envelope.ID)
+	require.NoError(t, b.Publish(context.Background(), msg))
+}
+
+func TestNotifier_UpdatesStatusOncePerEvent(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+
+	n := notifier.New(notifier.Deps{
+		Logger:      zap.NewNop(),
+		Subscriber:  memory,
+		Inbox:       newMemoryInbox(),
+		OrderClient: orderClient,
+	})
+	subs, err := n.Start(context.Background())
+	require.NoError(t, err)
+
+	paid := events.OrderPaidPayload{OrderID: "o1", UserID: "u1", TotalAmount: 5, PaidAt: time.Now().Unix()}
+	envelope := events.NewEnvelope("billing-service", paid).WithID(events.DeterministicID(events.TypeOrderPaid, "o1"))
+	data, err := events.Encode(envelope)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, memory.Publish(context.Background(), bus.NewMessage(events.TypeOrderPaid, data)))
+	}
+	publish(t, memory, events.TypeOrderFailed, events.OrderFailedPayload{OrderID: "o2", UserID: "u1", Reason: "declined"}, events.ContentTypeProtobuf)
+
+	for _, sub := range subs {
+		require.NoError(t, sub.Drain())
+	}
+
+	require.ElementsMatch(t, []orderpb.OrderStatus{orderpb.OrderStatus_PAID, orderpb.OrderStatus_FAILED}, orderClient.statuses)
+}
+
+func TestNotifier_ReleasesEventWhenUpdateFails(t *testing.T) {
+	memory := bus.NewMemory(bus.WithMaxDeliver(2), bus.WithRedeliveryDelay(time.Millisecond))
+	orderClient := &mockOrderClient{failures: 1}
+
+	n := notifier.New(notifier.Deps{
+		Logger:      zap.NewNop(),
+		Subscriber:  memory,
+		Inbox:       newMemoryInbox(),
+		OrderClient: orderClient,
+	})
+	subs, err := n.Start(context.Background())
+	require.NoError(t, err)
+
+	publish(t, memory, events.TypeOrderPaid, events.OrderPaidPayload{OrderID: "o1", UserID: "u1"}, events.ContentTypeJSON)
+
+	for _, sub := range subs {
+		require.NoError(t, sub.Drain())
+	}
+
+	require.Equal(t, []orderpb.OrderStatus{orderpb.OrderStatus_PAID}, orderClient.statuses)
+}
+
+func TestNotifier_AcksPaymentResultForResolvedOrder(t *testing.T) {
+	memory := bus.NewMemory(bus.WithMaxDeliver(3), bus.WithRedeliveryDelay(time.Millisecond))
+	orderClient := &mockOrderClient{rejection: status.Error(codes.FailedPrecondition, "order status does not allow this change")}
+
+	n := notifier.New(notifier.Deps{
+		Logger:      zap.NewNop(),
+		Subscriber:  memory,
+		Inbox:       newMemoryInbox(),
+		OrderClient: orderClient,
+	})
+	subs, err := n.Start(context.Background())
+	require.NoError(t, err)
+
+	publish(t, memory, events.TypeOrderPaid, events.OrderPaidPayload{OrderID: "o1", UserID: "u1"}, events.ContentTypeJSON)
+
+	for _, sub := range subs {
+		require.NoError(t, sub.Drain())
+	}
+
+	// Заказ уже истёк: событие подтверждается, а не доставляется повторно.
+	require.Equal(t, 1, orderClient.calls)
+}
+
+func TestNotifier_ShippingEventsOnlyNotify(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+	inbox := newMemoryInbox()
+
+	n := notifier.New(notifier.Deps{
+		Logger:      zap.NewNop(),
+		Subscriber:  memory,
+		Inbox:       inbox,
+		OrderClient: orderClient,
+	})
+	subs, err := n.Start(context.Background())
+	require.NoError(t, err)
+	require.Len(t, subs, 8)
+
+	shippedAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC).Unix()
+	shipped := events.OrderShippedPayload{OrderID: "o1", UserID: "u1", Carrier: "dhl", TrackingNumber: "TRACK1", ShippedAt: shippedAt}
+	publish(t, memory, events.TypeOrderShipped, shipped, events.ContentTypeProtobuf)
+	publish(t, memory, events.TypeOrderDelivered, events.OrderDeliveredPayload{OrderID: "o1", UserID: "u1", DeliveredAt: shippedAt}, events.ContentTypeJSON)
+
+	for _, sub := range subs {
+		require.NoError(t, sub.Drain())
+	}
+
+	require.Empty(t, orderClient.statuses)
+	require.Len(t, inbox.seen, 2)
+
+	text, err := notifier.Render(shipped)
+	require.NoError(t, err)
+	require.Equal(t, "Заказ o1 передан в службу доставки dhl 01.05.2024 10:30 UTC. Трек-номер: TRACK1.", text)
+
+	text, err = notifier.Render(events.OrderItemsCancelledPayload{
+		OrderID: "o1",
+		Items:   []events.CancelledItem{{ProductID: "p1", Quantity: 2}, {ProductID: "p2", Quantity: 1}},
+		Amount:  25,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Из заказа o1 отменено: p1 × 2, p2 × 1. К возврату 25.00.", text)
+}
+
+func TestNotifier_UsesTenantOfEvent(t *testing.T) {
+	memory := bus.NewMemory()
+	orderClient := &mockOrderClient{}
+
+	templates, err := notifier.NewTemplates(tenant.NewSettings(notifier.TenantSettings{}, map[string]notifier.TenantSettings{
+		"shop-a": {Templates: map[string]string{events.TypeOrderPaid: "Shop A: заказ {{.OrderID}} оплачен."}},
+	}))
+	require.NoError(t, err)
+
+	n := notifier.New(notifier.Deps{
+		Logger:      zap.NewNop(),
+		Subscriber:  memory,
+		Inbox:       newMemoryInbox(),
+		OrderClient: orderClient,
+		Templates:   templates,
+	})
+	subs, err := n.Start(context.Background())
+	require.NoError(t, err)
+
+	paid := events.OrderPaidPayload{OrderID: "o1", UserID: "u1", TotalAmount: 5}
+	data, err := events.Encode(events.NewEnvelope("billing-service", paid).WithTenant("shop-a"))
+	require.NoError(t, err)
+	require.NoError(t, memory.Publish(context.Background(), bus.NewMessage(events.TypeOrderPaid, data)))
+
+	for _, sub := range subs {
+		require.NoError(t, sub.Drain())
+	}
+	// Статус меняется от имени витрины события.
+	require.Equal(t, []string{"shop-a"}, orderClient.tenants)
+
+	text, err := templates.Render("shop-a", paid)
+	require.NoError(t, err)
+	require.Equal(t, "Shop A: заказ o1 оплачен.", text)
+
+	text, err = templates.Render("shop-a", events.OrderFailedPayload{OrderID: "o1", Reason: "declined"})
+	require.NoError(t, err)
+	require.Equal(t, "Не удалось оплатить заказ o1: declined.", text)
+
+	text, err = templates.Render(tenant.Default, paid)
+	require.NoError(t, err)
+	require.Equal(t, "Заказ o1 оплачен на сумму 5.00.", text)
+
+	_, err = notifier.NewTemplates(tenant.NewSettings(notifier.TenantSettings{}, map[string]notifier.TenantSettings{
+		"shop-b": {Templates: map[string]string{events.TypeOrderPaid: "{{.OrderID"}},
+	}))
+	require.Error(t, err)
+}
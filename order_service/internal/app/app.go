@@ -2,13 +2,26 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"order-service-system/common/auth"
+	"order-service-system/common/bus"
 	"order-service-system/common/closer"
+	"order-service-system/common/health"
+	"order-service-system/common/httpserver"
+	"order-service-system/common/metrics"
 	"order-service-system/common/mongo"
 	"order-service-system/common/nats"
+	"order-service-system/common/ratelimit"
+	"order-service-system/common/telemetry"
+	"order-service-system/common/tenant"
+	"order-service-system/common/tlsconfig"
 	"order-service-system/order_service/internal/initialize"
+	"order-service-system/order_service/internal/repository/bboltdb"
 	"order-service-system/order_service/internal/server"
+	"order-service-system/order_service/internal/service/cart_service"
+	"order-service-system/order_service/internal/service/order_service"
 	"time"
 
 	"go.uber.org/zap"
@@ -28,6 +41,31 @@ func Run(ctx context.Context, config initialize.Config, logger *zap.Logger) erro
 
 	shutdownGroup := closer.NewCloserGroup()
 
+	shutdownTracing, err := telemetry.Setup(ctx, "order-service", config.ExternalCfg.TelemetryConfig)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracing: %w", err)
+	}
+	shutdownGroup.Add(closer.CloserFunc(shutdownTracing))
+
+	checker := health.New(2 * time.Second)
+
+	httpServer, err := httpserver.New(httpserver.Deps{Logger: logger})
+	if err != nil {
+		return fmt.Errorf("failed initialize http server: %w", err)
+	}
+	httpServer.
+		Handle("/metrics", metrics.Handler()).
+		Handle("/healthz", checker.LivenessHandler()).
+		Handle("/readyz", checker.ReadinessHandler())
+
+	go func() {
+		if err := httpServer.Run(config.HTTPURL); err != nil {
+			logger.Error("http server failed on <Run> of <app>", zap.Error(err))
+			cancel()
+		}
+	}()
+	shutdownGroup.Add(closer.CloserFunc(httpServer.Stop))
+
 	mongoDB, err := mongo.Connect(ctx, &mongo.ConnectDeps{
 		Configuration: &config.ExternalCfg.MongoConfig,
 		Timeout:       10 * time.Second,
@@ -48,35 +86,131 @@ func Run(ctx context.Context, config initialize.Config, logger *zap.Logger) erro
 		return fmt.Errorf("failed initialize repositories: %w", err)
 	}
 
+	if err := bboltdb.RegisterMetrics(repositories.BboltDBStore); err != nil {
+		return fmt.Errorf("failed to register outbox metrics: %w", err)
+	}
+
+	eventBus := bus.WithMetrics(bus.WithTracing(bus.NewNATS(natsConn, logger)))
 	clients := initialize.NewClients(initialize.ClientsDeps{
 		Logger:     logger,
-		Conn:       natsConn,
+		Publisher:  eventBus,
+		Requester:  eventBus,
 		BboltStore: repositories.BboltDBStore,
+		Encoding:   config.Events,
 	})
 
+	promos, err := cart_service.ParsePromoCodes(config.Cart.PromoCodes)
+	if err != nil {
+		return fmt.Errorf("invalid CART_PROMO_CODES: %w", err)
+	}
+
+	tenants, err := tenant.LoadSettings(config.Tenants, order_service.TenantSettings{})
+	if err != nil {
+		return fmt.Errorf("invalid TENANTS_FILE: %w", err)
+	}
+
 	services := initialize.NewServices(initialize.ServicesDeps{
 		Logger:       logger,
 		Clients:      clients,
 		Repositories: repositories,
+		Cart:         config.Cart,
+		Promos:       promos,
+		Tenants:      tenants,
 	})
 
 	workers := initialize.NewWorkers(initialize.WorkersDeps{
 		Logger:       logger,
 		Clients:      clients,
 		Repositories: repositories,
+		Services:     services,
+		Subscriber:   eventBus,
+		Reconcile:    config.Reconcile,
+		Schedule:     config.Subscriptions,
+		Release:      config.Scheduled,
 	})
 
 	rpcControllers := initialize.NewRpcControllers(initialize.RpcControllersDeps{
 		Services: services,
 	})
 
-	serverGRPC, err := server.NewGRPC(server.DepsGRPC{Logger: logger})
+	var verifier *auth.Verifier
+	if config.Auth.Disabled {
+		logger.Warn("authentication is disabled on <Run> of <app>")
+	} else if verifier, err = auth.NewVerifier(config.Auth); err != nil {
+		return fmt.Errorf("failed initialize auth: %w", err)
+	}
+
+	var serverTLS, gatewayTLS *tls.Config
+	gatewayEnabled := true
+	if config.TLS.Enabled() {
+		reloader, err := tlsconfig.NewReloader(config.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to load certificates: %w", err)
+		}
+		go reloader.Start(ctx, logger)
+		serverTLS = reloader.ServerConfig()
+		gatewayTLS = reloader.AnonymousClientConfig(reloader.ServerName())
+		if verifier == nil && !reloader.RequiresClientCert() {
+			return errors.New("TLS_CLIENT_AUTH=require and TLS_CA_FILE must be set when TLS is used without authentication")
+		}
+		// Шлюз не предъявляет сертификат, иначе REST-запросы получили бы права сервиса.
+		if reloader.RequiresClientCert() {
+			gatewayEnabled = false
+			logger.Warn("REST gateway is disabled because client certificates are required on <Run> of <app>")
+		}
+	}
+
+	var limiter *ratelimit.Limiter
+	if config.RateLimit.Disabled {
+		logger.Warn("rate limiting is disabled on <Run> of <app>")
+	} else {
+		var store ratelimit.Store
+		switch config.RateLimit.Store {
+		case ratelimit.StoreMemory:
+			store = ratelimit.NewMemoryStore()
+		case ratelimit.StoreMongo:
+			if store, err = ratelimit.NewMongoStore(ctx, mongoDB); err != nil {
+				return fmt.Errorf("failed initialize rate limit store: %w", err)
+			}
+		default:
+			return fmt.Errorf("unknown RATE_LIMIT_STORE %q", config.RateLimit.Store)
+		}
+		limiter, err = ratelimit.New(ratelimit.Deps{
+			Logger:        logger,
+			Store:         store,
+			Configuration: config.RateLimit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed initialize rate limiter: %w", err)
+		}
+	}
+
+	serverGRPC, err := server.NewGRPC(server.DepsGRPC{
+		Logger:             logger,
+		Verifier:           verifier,
+		StatusUpdaters:     config.StatusUpdaters,
+		FulfilmentServices: config.FulfilmentServices,
+		ReturnManagers:     config.ReturnManagers,
+		SupportServices:    config.SupportServices,
+		AnalyticsServices:  config.AnalyticsServices,
+		TLS:                serverTLS,
+		RateLimiter:        limiter,
+		KnownTenant:        tenants.Known,
+	})
 	if err != nil {
 		return fmt.Errorf("failed initialize gRPC server: %w", err)
 	}
 
 	serverGRPC.Register(rpcControllers)
 	go workers.RepublisherWC.Start(ctx, 3*time.Second)
+	go workers.ReconcilerWC.Start(ctx)
+	go workers.SchedulerWC.Start(ctx)
+	go workers.ReleaserWC.Start(ctx)
+
+	rollupSubscriptions, err := workers.RollupWC.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to order events: %w", err)
+	}
 
 	go func() {
 		if err := serverGRPC.Run(config.GrpcURL); err != nil {
@@ -85,10 +219,39 @@ func Run(ctx context.Context, config initialize.Config, logger *zap.Logger) erro
 		}
 	}()
 
+	if gatewayEnabled {
+		gateway, err := server.NewGateway(ctx, server.DepsGateway{
+			Logger:  logger,
+			GrpcURL: config.GrpcURL,
+			TLS:     gatewayTLS,
+		})
+		if err != nil {
+			return fmt.Errorf("failed initialize REST gateway: %w", err)
+		}
+		httpServer.
+			Handle("/v1/", gateway).
+			Handle("/openapi.json", gateway.OpenAPIHandler())
+		shutdownGroup.Add(closer.CloserFunc(gateway.Close))
+	}
+
+	checker.
+		Register("mongo", health.Mongo(mongoDB)).
+		Register("nats", health.NATS(natsConn)).
+		Register("bbolt", repositories.BboltDBStore.Ping).
+		Register("subscriptions", health.Subscriptions(rollupSubscriptions...)).
+		Start()
+	go checker.Watch(ctx, 5*time.Second, serverGRPC.SetServing)
+
 	shutdownGroup.Add(closer.CloserFunc(serverGRPC.Stop))
 	shutdownGroup.Add(closer.CloserFunc(func(ctx context.Context) error {
 		return natsConn.Drain()
 	}))
+	for _, sub := range rollupSubscriptions {
+		subscription := sub
+		shutdownGroup.Add(closer.CloserFunc(func(ctx context.Context) error {
+			return subscription.Drain()
+		}))
+	}
 	shutdownGroup.Add(closer.CloserFunc(mongoDB.Client().Disconnect))
 	shutdownGroup.Add(closer.CloserFunc(func(ctx context.Context) error {
 		return repositories.BboltDBStore.Close()
@@ -96,6 +259,9 @@ func Run(ctx context.Context, config initialize.Config, logger *zap.Logger) erro
 
 	<-ctx.Done()
 
+	checker.Shutdown()
+	serverGRPC.SetServing(false)
+
 	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer timeoutCancel()
 	if err := shutdownGroup.Call(timeoutCtx); err != nil {
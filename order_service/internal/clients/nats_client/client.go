@@ -1,59 +1,89 @@
 package nats_client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"order-service-system/common/bus"
 	"order-service-system/common/events"
+	"order-service-system/common/tenant"
 	"order-service-system/order_service/internal/models"
 	"order-service-system/order_service/internal/repository/bboltdb"
+	orderpb "order-service-system/proto/order"
 
-	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
+const eventSource = "order-service"
+
 type Client struct {
-	conn       *nats.Conn
+	publisher  bus.Publisher
+	requester  bus.Requester
 	logger     *zap.Logger
 	bboltStore *bboltdb.Store
+	encoding   events.EncodingConfig
 }
 
 type Deps struct {
 	Logger     *zap.Logger
-	Conn       *nats.Conn
+	Publisher  bus.Publisher
+	Requester  bus.Requester
 	BboltStore *bboltdb.Store
+	Encoding   events.EncodingConfig
 }
 
 func NewClient(deps Deps) *Client {
 	if deps.Logger == nil {
 		panic("logger must not be nil on <NewClient> of <NatsClient>")
 	}
-	if deps.Conn == nil {
-		panic("nats connection must not be nil on <NewClient> of <NatsClient>")
+	if deps.Publisher == nil {
+		panic("publisher must not be nil on <NewClient> of <NatsClient>")
+	}
+	if deps.Requester == nil {
+		panic("requester must not be nil on <NewClient> of <NatsClient>")
 	}
 	if deps.BboltStore == nil {
 		panic("bbolt store must not be nil on <NewClient> of <NatsClient>")
 	}
 	return &Client{
 		logger:     deps.Logger,
-		conn:       deps.Conn,
+		publisher:  deps.Publisher,
+		requester:  deps.Requester,
 		bboltStore: deps.BboltStore,
+		encoding:   deps.Encoding,
 	}
 }
 
-func (receiver *Client) PublishOrderCreated(event models.OrderCreatedEvent) error {
-	payload := events.OrderCreatedPayload{
+func (receiver *Client) PublishOrderCreated(ctx context.Context, event models.OrderCreatedEvent) error {
+	eventID := event.EventID
+	if eventID == "" {
+		eventID = events.DeterministicID(events.TypeOrderCreated, event.OrderID)
+	}
+
+	if event.TenantID == "" {
+		event.TenantID = tenant.FromContext(ctx)
+	}
+
+	envelope := events.NewEnvelope(eventSource, events.OrderCreatedPayload{
 		OrderID:     event.OrderID,
 		UserID:      event.UserID,
 		TotalAmount: event.TotalAmount,
 		CreatedAt:   event.CreatedAt.Unix(),
-	}
+		Currency:    event.Currency,
+	}).WithID(eventID).WithTenant(event.TenantID)
+	envelope = envelope.WithCorrelationID(envelope.ID)
 
-	data, err := json.Marshal(payload)
+	contentType := receiver.encoding.ContentTypeFor(events.TypeOrderCreated)
+	data, err := events.Marshal(contentType, envelope)
 	if err != nil {
-		return fmt.Errorf("marshal payload: %w", err)
+		return fmt.Errorf("encode event: %w", err)
 	}
 
-	if err := receiver.conn.Publish("order.created", data); err != nil {
+	msg := bus.NewMessage(events.TypeOrderCreated, data)
+	msg.Header.Set(events.HeaderContentType, contentType)
+	msg.Header.Set(events.HeaderMsgID, envelope.ID)
+
+	if err := receiver.publisher.Publish(ctx, msg); err != nil {
 		if saveErr := receiver.bboltStore.Save(event); saveErr != nil {
 			receiver.logger.Error("failed to persist event to bboltDB on <PublishOrderCreated> of <NatsClient>",
 				zap.Error(saveErr),
@@ -64,9 +94,188 @@ func (receiver *Client) PublishOrderCreated(event models.OrderCreatedEvent) erro
 	}
 
 	receiver.logger.Info("published event on <PublishOrderCreated> of <NatsClient>",
-		zap.String("subject", "order.created"),
+		zap.String("subject", events.TypeOrderCreated),
+		zap.String("event_id", envelope.ID),
+		zap.String("content_type", contentType),
+		zap.String("tenant_id", event.TenantID),
 		zap.String("order_id", event.OrderID),
 		zap.Time("created_at", event.CreatedAt),
 	)
 	return nil
 }
+
+func (receiver *Client) PublishOrderShipped(ctx context.Context, event models.OrderShippedEvent) error {
+	envelope := events.NewEnvelope(eventSource, events.OrderShippedPayload{
+		OrderID:        event.OrderID,
+		UserID:         event.UserID,
+		Carrier:        event.Carrier,
+		TrackingNumber: event.TrackingNumber,
+		ShippedAt:      event.ShippedAt.Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderShipped, event.OrderID)).
+		WithCorrelationID(events.DeterministicID(events.TypeOrderCreated, event.OrderID))
+
+	return publishEnvelope(ctx, receiver, events.TypeOrderShipped, envelope)
+}
+
+func (receiver *Client) PublishOrderDelivered(ctx context.Context, event models.OrderDeliveredEvent) error {
+	envelope := events.NewEnvelope(eventSource, events.OrderDeliveredPayload{
+		OrderID:     event.OrderID,
+		UserID:      event.UserID,
+		DeliveredAt: event.DeliveredAt.Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderDelivered, event.OrderID)).
+		WithCorrelationID(events.DeterministicID(events.TypeOrderCreated, event.OrderID))
+
+	return publishEnvelope(ctx, receiver, events.TypeOrderDelivered, envelope)
+}
+
+// PublishOrderAmended publishes the new total of the order; every version gets its
+// own event id.
+func (receiver *Client) PublishOrderAmended(ctx context.Context, event models.OrderAmendedEvent) error {
+	envelope := events.NewEnvelope(eventSource, events.OrderAmendedPayload{
+		OrderID:       event.OrderID,
+		UserID:        event.UserID,
+		Version:       event.Version,
+		TotalAmount:   event.TotalAmount,
+		PreviousTotal: event.PreviousTotal,
+		AmendedAt:     event.AmendedAt.Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderAmended, fmt.Sprintf("%s/%d", event.OrderID, event.Version))).
+		WithCorrelationID(events.DeterministicID(events.TypeOrderCreated, event.OrderID))
+
+	return publishEnvelope(ctx, receiver, events.TypeOrderAmended, envelope)
+}
+
+// PublishOrderPaid publishes the payment result under the id billing gives it, so
+// consumers that already got it from billing skip it.
+func (receiver *Client) PublishOrderPaid(ctx context.Context, event models.OrderPaidEvent) error {
+	envelope := events.NewEnvelope(eventSource, events.OrderPaidPayload{
+		OrderID:     event.OrderID,
+		UserID:      event.UserID,
+		TotalAmount: event.TotalAmount,
+		PaidAt:      event.PaidAt.Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderPaid, event.OrderID)).
+		WithCorrelationID(events.DeterministicID(events.TypeOrderCreated, event.OrderID))
+
+	return publishEnvelope(ctx, receiver, events.TypeOrderPaid, envelope)
+}
+
+// PublishOrderFailed publishes the payment result under the id billing gives it.
+func (receiver *Client) PublishOrderFailed(ctx context.Context, event models.OrderFailedEvent) error {
+	envelope := events.NewEnvelope(eventSource, events.OrderFailedPayload{
+		OrderID:  event.OrderID,
+		UserID:   event.UserID,
+		Reason:   event.Reason,
+		FailedAt: event.FailedAt.Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderFailed, event.OrderID)).
+		WithCorrelationID(events.DeterministicID(events.TypeOrderCreated, event.OrderID))
+
+	return publishEnvelope(ctx, receiver, events.TypeOrderFailed, envelope)
+}
+
+// PublishOrderItemsCancelled asks billing to refund the cancelled units. There is no
+// inventory service in this system: the units in the event are the only hand-off
+// for releasing stock, nothing here releases it.
+func (receiver *Client) PublishOrderItemsCancelled(ctx context.Context, event models.OrderItemsCancelledEvent) error {
+	cancellation := event.Cancellation
+	items := make([]events.CancelledItem, 0, len(cancellation.Items))
+	for _, item := range cancellation.Items {
+		items = append(items, events.CancelledItem{ProductID: item.ProductID, Quantity: item.Quantity, Price: item.Price})
+	}
+	envelope := events.NewEnvelope(eventSource, events.OrderItemsCancelledPayload{
+		CancellationID: cancellation.CancellationID,
+		OrderID:        event.OrderID,
+		UserID:         event.UserID,
+		Items:          items,
+		Amount:         cancellation.Amount,
+		RemainingTotal: event.RemainingTotal,
+		Reason:         cancellation.Reason,
+		CancelledAt:    cancellation.At.Unix(),
+	}).WithID(events.DeterministicID(events.TypeOrderItemsCancelled, cancellation.CancellationID)).
+		WithCorrelationID(events.DeterministicID(events.TypeOrderCreated, event.OrderID))
+
+	return publishEnvelope(ctx, receiver, events.TypeOrderItemsCancelled, envelope)
+}
+
+// PublishReturnStatus publishes the event of the current status of the return.
+func (receiver *Client) PublishReturnStatus(ctx context.Context, ret models.Return) error {
+	at := ret.UpdatedAt.Unix()
+	correlationID := events.DeterministicID(events.TypeOrderCreated, ret.OrderID)
+
+	switch ret.Status {
+	case orderpb.ReturnStatus_RETURN_APPROVED.String():
+		return publishEnvelope(ctx, receiver, events.TypeReturnApproved, events.NewEnvelope(eventSource, events.ReturnApprovedPayload{
+			ReturnID:   ret.ReturnID,
+			OrderID:    ret.OrderID,
+			UserID:     ret.UserID,
+			ApprovedAt: at,
+		}).WithID(events.DeterministicID(events.TypeReturnApproved, ret.ReturnID)).WithCorrelationID(correlationID))
+	case orderpb.ReturnStatus_RETURN_REJECTED.String():
+		var reason string
+		if len(ret.History) > 0 {
+			reason = ret.History[len(ret.History)-1].Comment
+		}
+		return publishEnvelope(ctx, receiver, events.TypeReturnRejected, events.NewEnvelope(eventSource, events.ReturnRejectedPayload{
+			ReturnID:   ret.ReturnID,
+			OrderID:    ret.OrderID,
+			UserID:     ret.UserID,
+			Reason:     reason,
+			RejectedAt: at,
+		}).WithID(events.DeterministicID(events.TypeReturnRejected, ret.ReturnID)).WithCorrelationID(correlationID))
+	case orderpb.ReturnStatus_RETURN_RECEIVED.String():
+		return publishEnvelope(ctx, receiver, events.TypeReturnReceived, events.NewEnvelope(eventSource, events.ReturnReceivedPayload{
+			ReturnID:   ret.ReturnID,
+			OrderID:    ret.OrderID,
+			UserID:     ret.UserID,
+			Amount:     ret.RefundAmount,
+			ReceivedAt: at,
+		}).WithID(events.DeterministicID(events.TypeReturnReceived, ret.ReturnID)).WithCorrelationID(correlationID))
+	default:
+		return fmt.Errorf("no event for return status %q", ret.Status)
+	}
+}
+
+// publishEnvelope publishes a fulfilment, payment result or return event in the tenant of the context.
+// Unlike order.created these events are not kept in the outbox: the caller reports
+// the failure and the next status change can be retried by the fulfilment service.
+func publishEnvelope[T events.Payload](ctx context.Context, receiver *Client, subject string, envelope events.Envelope[T]) error {
+	envelope = envelope.WithTenant(tenant.FromContext(ctx))
+	contentType := receiver.encoding.ContentTypeFor(subject)
+	data, err := events.Marshal(contentType, envelope)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	msg := bus.NewMessage(subject, data)
+	msg.Header.Set(events.HeaderContentType, contentType)
+	msg.Header.Set(events.HeaderMsgID, envelope.ID)
+
+	if err := receiver.publisher.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+
+	receiver.logger.Info("published event on <publishEnvelope> of <NatsClient>",
+		zap.String("subject", subject),
+		zap.String("event_id", envelope.ID),
+		zap.String("content_type", contentType),
+		zap.String("tenant_id", envelope.TenantID),
+	)
+	return nil
+}
+
+// QueryPaymentStatus asks billing for the payment outcome of the order.
+func (receiver *Client) QueryPaymentStatus(ctx context.Context, orderID string) (events.PaymentStatusReply, error) {
+	data, err := json.Marshal(events.PaymentStatusRequest{OrderID: orderID})
+	if err != nil {
+		return events.PaymentStatusReply{}, fmt.Errorf("encode request: %w", err)
+	}
+
+	msg, err := receiver.requester.Request(ctx, bus.NewMessage(events.SubjectPaymentStatus, data))
+	if err != nil {
+		return events.PaymentStatusReply{}, fmt.Errorf("request payment status: %w", err)
+	}
+
+	var reply events.PaymentStatusReply
+	if err := json.Unmarshal(msg.Data, &reply); err != nil {
+		return events.PaymentStatusReply{}, fmt.Errorf("decode reply: %w", err)
+	}
+	return reply, nil
+}
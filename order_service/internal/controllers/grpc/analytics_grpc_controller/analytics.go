@@ -0,0 +1,30 @@
+package analytics_grpc_controller
+
+import (
+	"context"
+	"order-service-system/order_service/internal/service/analytics_service"
+	orderpb "order-service-system/proto/order"
+)
+
+type AnalyticsController struct {
+	orderpb.UnimplementedAnalyticsServiceServer
+	analyticsService *analytics_service.AnalyticsService
+}
+
+type Deps struct {
+	AnalyticsService *analytics_service.AnalyticsService
+}
+
+func NewAnalyticsController(deps Deps) *AnalyticsController {
+	if deps.AnalyticsService == nil {
+		panic("analytics service must not be nil on <NewAnalyticsController> of <AnalyticsController>")
+	}
+
+	return &AnalyticsController{
+		analyticsService: deps.AnalyticsService,
+	}
+}
+
+func (receiver *AnalyticsController) GetOrderAnalytics(ctx context.Context, req *orderpb.GetOrderAnalyticsRequest) (*orderpb.GetOrderAnalyticsResponse, error) {
+	return receiver.analyticsService.GetOrderAnalytics(ctx, req)
+}
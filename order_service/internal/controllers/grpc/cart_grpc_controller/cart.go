@@ -0,0 +1,82 @@
+package cart_grpc_controller
+
+import (
+	"context"
+	"order-service-system/order_service/internal/service/cart_service"
+	orderpb "order-service-system/proto/order"
+)
+
+type CartController struct {
+	orderpb.UnimplementedCartServiceServer
+	cartService *cart_service.CartService
+}
+
+type Deps struct {
+	CartService *cart_service.CartService
+}
+
+func NewCartController(deps Deps) *CartController {
+	if deps.CartService == nil {
+		panic("cart service must not be nil on <NewCartController> of <CartController>")
+	}
+
+	return &CartController{
+		cartService: deps.CartService,
+	}
+}
+
+func (receiver *CartController) CreateCart(ctx context.Context, req *orderpb.CreateCartRequest) (*orderpb.CreateCartResponse, error) {
+	cart, err := receiver.cartService.CreateCart(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.CreateCartResponse{Cart: cart}, nil
+}
+
+func (receiver *CartController) GetCart(ctx context.Context, req *orderpb.GetCartRequest) (*orderpb.GetCartResponse, error) {
+	cart, err := receiver.cartService.GetCart(ctx, req.GetCartId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.GetCartResponse{Cart: cart}, nil
+}
+
+func (receiver *CartController) AddCartItem(ctx context.Context, req *orderpb.AddCartItemRequest) (*orderpb.AddCartItemResponse, error) {
+	cart, err := receiver.cartService.AddCartItem(ctx, req.GetCartId(), req.GetItem())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.AddCartItemResponse{Cart: cart}, nil
+}
+
+func (receiver *CartController) UpdateCartItem(ctx context.Context, req *orderpb.UpdateCartItemRequest) (*orderpb.UpdateCartItemResponse, error) {
+	cart, err := receiver.cartService.UpdateCartItem(ctx, req.GetCartId(), req.GetProductId(), req.GetQuantity())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.UpdateCartItemResponse{Cart: cart}, nil
+}
+
+func (receiver *CartController) RemoveCartItem(ctx context.Context, req *orderpb.RemoveCartItemRequest) (*orderpb.RemoveCartItemResponse, error) {
+	cart, err := receiver.cartService.RemoveCartItem(ctx, req.GetCartId(), req.GetProductId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.RemoveCartItemResponse{Cart: cart}, nil
+}
+
+func (receiver *CartController) ApplyPromo(ctx context.Context, req *orderpb.ApplyPromoRequest) (*orderpb.ApplyPromoResponse, error) {
+	cart, err := receiver.cartService.ApplyPromo(ctx, req.GetCartId(), req.GetPromoCode())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.ApplyPromoResponse{Cart: cart}, nil
+}
+
+func (receiver *CartController) Checkout(ctx context.Context, req *orderpb.CheckoutRequest) (*orderpb.CheckoutResponse, error) {
+	order, cart, err := receiver.cartService.Checkout(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.CheckoutResponse{Order: order, Cart: cart}, nil
+}
@@ -3,25 +3,39 @@ package order_grpc_controller
 import (
 	"context"
 	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/service/return_service"
+	"order-service-system/order_service/internal/service/search_service"
 	orderpb "order-service-system/proto/order"
 )
 
 type OrderController struct {
 	orderpb.UnimplementedOrderServiceServer
-	orderService *order_service.OrderService
+	orderService  *order_service.OrderService
+	returnService *return_service.ReturnService
+	searchService *search_service.SearchService
 }
 
 type Deps struct {
-	OrderService *order_service.OrderService
+	OrderService  *order_service.OrderService
+	ReturnService *return_service.ReturnService
+	SearchService *search_service.SearchService
 }
 
 func NewOrderController(deps Deps) *OrderController {
 	if deps.OrderService == nil {
 		panic("order service must not be nil on <NewOrderController> of <OrderController>")
 	}
+	if deps.ReturnService == nil {
+		panic("return service must not be nil on <NewOrderController> of <OrderController>")
+	}
+	if deps.SearchService == nil {
+		panic("search service must not be nil on <NewOrderController> of <OrderController>")
+	}
 
 	return &OrderController{
-		orderService: deps.OrderService,
+		orderService:  deps.OrderService,
+		returnService: deps.ReturnService,
+		searchService: deps.SearchService,
 	}
 }
 
@@ -41,6 +55,14 @@ func (receiver *OrderController) GetOrder(ctx context.Context, req *orderpb.GetO
 	return &orderpb.GetOrderResponse{Order: order}, nil
 }
 
+func (receiver *OrderController) GetOrderByNumber(ctx context.Context, req *orderpb.GetOrderByNumberRequest) (*orderpb.GetOrderByNumberResponse, error) {
+	order, err := receiver.orderService.GetOrderByNumber(ctx, req.GetOrderNumber())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.GetOrderByNumberResponse{Order: order}, nil
+}
+
 func (receiver *OrderController) UpdateOrderStatus(ctx context.Context, req *orderpb.UpdateOrderStatusRequest) (*orderpb.UpdateOrderStatusResponse, error) {
 	order, err := receiver.orderService.UpdateOrderStatus(ctx, req.GetOrderId(), req.GetStatus())
 	if err != nil {
@@ -48,3 +70,95 @@ func (receiver *OrderController) UpdateOrderStatus(ctx context.Context, req *ord
 	}
 	return &orderpb.UpdateOrderStatusResponse{Order: order}, nil
 }
+
+func (receiver *OrderController) MarkShipped(ctx context.Context, req *orderpb.MarkShippedRequest) (*orderpb.MarkShippedResponse, error) {
+	order, err := receiver.orderService.MarkShipped(ctx, req.GetOrderId(), req.GetCarrier(), req.GetTrackingNumber())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.MarkShippedResponse{Order: order}, nil
+}
+
+func (receiver *OrderController) AmendOrder(ctx context.Context, req *orderpb.AmendOrderRequest) (*orderpb.AmendOrderResponse, error) {
+	order, err := receiver.orderService.AmendOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.AmendOrderResponse{Order: order}, nil
+}
+
+func (receiver *OrderController) CancelItems(ctx context.Context, req *orderpb.CancelItemsRequest) (*orderpb.CancelItemsResponse, error) {
+	order, cancellation, err := receiver.orderService.CancelItems(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.CancelItemsResponse{Order: order, Cancellation: cancellation}, nil
+}
+
+func (receiver *OrderController) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	orders, nextPageToken, err := receiver.orderService.ListOrders(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.ListOrdersResponse{Orders: orders, NextPageToken: nextPageToken}, nil
+}
+
+func (receiver *OrderController) SearchOrders(ctx context.Context, req *orderpb.SearchOrdersRequest) (*orderpb.SearchOrdersResponse, error) {
+	return receiver.searchService.SearchOrders(ctx, req)
+}
+
+func (receiver *OrderController) RequestReturn(ctx context.Context, req *orderpb.RequestReturnRequest) (*orderpb.RequestReturnResponse, error) {
+	ret, err := receiver.returnService.RequestReturn(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.RequestReturnResponse{Return: ret}, nil
+}
+
+func (receiver *OrderController) GetReturn(ctx context.Context, req *orderpb.GetReturnRequest) (*orderpb.GetReturnResponse, error) {
+	ret, err := receiver.returnService.GetReturn(ctx, req.GetReturnId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.GetReturnResponse{Return: ret}, nil
+}
+
+func (receiver *OrderController) ListReturns(ctx context.Context, req *orderpb.ListReturnsRequest) (*orderpb.ListReturnsResponse, error) {
+	returns, err := receiver.returnService.ListReturns(ctx, req.GetOrderId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.ListReturnsResponse{Returns: returns}, nil
+}
+
+func (receiver *OrderController) ApproveReturn(ctx context.Context, req *orderpb.ApproveReturnRequest) (*orderpb.ApproveReturnResponse, error) {
+	ret, err := receiver.returnService.ApproveReturn(ctx, req.GetReturnId(), req.GetComment())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.ApproveReturnResponse{Return: ret}, nil
+}
+
+func (receiver *OrderController) RejectReturn(ctx context.Context, req *orderpb.RejectReturnRequest) (*orderpb.RejectReturnResponse, error) {
+	ret, err := receiver.returnService.RejectReturn(ctx, req.GetReturnId(), req.GetReason())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.RejectReturnResponse{Return: ret}, nil
+}
+
+func (receiver *OrderController) MarkReturnReceived(ctx context.Context, req *orderpb.MarkReturnReceivedRequest) (*orderpb.MarkReturnReceivedResponse, error) {
+	ret, err := receiver.returnService.MarkReturnReceived(ctx, req.GetReturnId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.MarkReturnReceivedResponse{Return: ret}, nil
+}
+
+func (receiver *OrderController) RecordRefund(ctx context.Context, req *orderpb.RecordRefundRequest) (*orderpb.RecordRefundResponse, error) {
+	ret, err := receiver.returnService.RecordRefund(ctx, req.GetReturnId(), req.GetRefunded(), req.GetReason())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.RecordRefundResponse{Return: ret}, nil
+}
@@ -0,0 +1,74 @@
+package subscription_grpc_controller
+
+import (
+	"context"
+	"order-service-system/order_service/internal/service/subscription_service"
+	orderpb "order-service-system/proto/order"
+)
+
+type SubscriptionController struct {
+	orderpb.UnimplementedSubscriptionServiceServer
+	subscriptionService *subscription_service.SubscriptionService
+}
+
+type Deps struct {
+	SubscriptionService *subscription_service.SubscriptionService
+}
+
+func NewSubscriptionController(deps Deps) *SubscriptionController {
+	if deps.SubscriptionService == nil {
+		panic("subscription service must not be nil on <NewSubscriptionController> of <SubscriptionController>")
+	}
+
+	return &SubscriptionController{
+		subscriptionService: deps.SubscriptionService,
+	}
+}
+
+func (receiver *SubscriptionController) CreateSubscription(ctx context.Context, req *orderpb.CreateSubscriptionRequest) (*orderpb.CreateSubscriptionResponse, error) {
+	subscription, err := receiver.subscriptionService.CreateSubscription(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.CreateSubscriptionResponse{Subscription: subscription}, nil
+}
+
+func (receiver *SubscriptionController) GetSubscription(ctx context.Context, req *orderpb.GetSubscriptionRequest) (*orderpb.GetSubscriptionResponse, error) {
+	subscription, err := receiver.subscriptionService.GetSubscription(ctx, req.GetSubscriptionId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.GetSubscriptionResponse{Subscription: subscription}, nil
+}
+
+func (receiver *SubscriptionController) ListSubscriptions(ctx context.Context, req *orderpb.ListSubscriptionsRequest) (*orderpb.ListSubscriptionsResponse, error) {
+	subscriptions, err := receiver.subscriptionService.ListSubscriptions(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.ListSubscriptionsResponse{Subscriptions: subscriptions}, nil
+}
+
+func (receiver *SubscriptionController) PauseSubscription(ctx context.Context, req *orderpb.PauseSubscriptionRequest) (*orderpb.PauseSubscriptionResponse, error) {
+	subscription, err := receiver.subscriptionService.PauseSubscription(ctx, req.GetSubscriptionId(), req.GetReason())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.PauseSubscriptionResponse{Subscription: subscription}, nil
+}
+
+func (receiver *SubscriptionController) ResumeSubscription(ctx context.Context, req *orderpb.ResumeSubscriptionRequest) (*orderpb.ResumeSubscriptionResponse, error) {
+	subscription, err := receiver.subscriptionService.ResumeSubscription(ctx, req.GetSubscriptionId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.ResumeSubscriptionResponse{Subscription: subscription}, nil
+}
+
+func (receiver *SubscriptionController) CancelSubscription(ctx context.Context, req *orderpb.CancelSubscriptionRequest) (*orderpb.CancelSubscriptionResponse, error) {
+	subscription, err := receiver.subscriptionService.CancelSubscription(ctx, req.GetSubscriptionId(), req.GetReason())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.CancelSubscriptionResponse{Subscription: subscription}, nil
+}
@@ -1,10 +1,11 @@
 package initialize
 
 import (
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
 	"order-service-system/order_service/internal/clients/nats_client"
 	"order-service-system/order_service/internal/repository/bboltdb"
 
-	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
@@ -14,16 +15,21 @@ type Clients struct {
 
 type ClientsDeps struct {
 	Logger     *zap.Logger
-	Conn       *nats.Conn
+	Publisher  bus.Publisher
+	Requester  bus.Requester
 	BboltStore *bboltdb.Store
+	Encoding   events.EncodingConfig
 }
 
 func NewClients(deps ClientsDeps) *Clients {
 	if deps.Logger == nil {
 		panic("logger must not be nil on <NewClients> of <initialize>")
 	}
-	if deps.Conn == nil {
-		panic("nats connection must not be nil on <NewClients> of <initialize>")
+	if deps.Publisher == nil {
+		panic("publisher must not be nil on <NewClients> of <initialize>")
+	}
+	if deps.Requester == nil {
+		panic("requester must not be nil on <NewClients> of <initialize>")
 	}
 	if deps.BboltStore == nil {
 		panic("bbolt store must not be nil on <NewClients> of <initialize>")
@@ -31,8 +37,10 @@ func NewClients(deps ClientsDeps) *Clients {
 	return &Clients{
 		NatsClient: nats_client.NewClient(nats_client.Deps{
 			Logger:     deps.Logger,
-			Conn:       deps.Conn,
+			Publisher:  deps.Publisher,
+			Requester:  deps.Requester,
 			BboltStore: deps.BboltStore,
+			Encoding:   deps.Encoding,
 		}),
 	}
 }
@@ -2,21 +2,52 @@ package initialize
 
 import (
 	"log"
+	"order-service-system/common/auth"
+	"order-service-system/common/events"
 	"order-service-system/common/mongo"
 	"order-service-system/common/nats"
+	"order-service-system/common/ratelimit"
+	"order-service-system/common/telemetry"
+	"order-service-system/common/tenant"
+	"order-service-system/common/tlsconfig"
+	"order-service-system/order_service/internal/service/cart_service"
+	"order-service-system/order_service/internal/workers/reconciler"
+	"order-service-system/order_service/internal/workers/releaser"
+	"order-service-system/order_service/internal/workers/scheduler"
 
 	"github.com/caarlos0/env/v8"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	GrpcURL     string `env:"GRPC_URL"`
-	ExternalCfg ExternalCfg
+	GrpcURL string `env:"GRPC_URL"`
+	HTTPURL string `env:"HTTP_URL" envDefault:":8080"`
+	Auth    auth.Configuration
+	// StatusUpdaters are the service identities allowed to call UpdateOrderStatus.
+	StatusUpdaters []string `env:"AUTH_STATUS_UPDATERS" envSeparator:"," envDefault:"billing-service,notification-service"`
+	// FulfilmentServices may ship orders and move them through PROCESSING and DELIVERED.
+	FulfilmentServices []string `env:"AUTH_FULFILMENT_SERVICES" envSeparator:"," envDefault:"fulfilment-service"`
+	// ReturnManagers approve and reject return requests.
+	ReturnManagers []string `env:"AUTH_RETURN_MANAGERS" envSeparator:"," envDefault:"support-service"`
+	// SupportServices search orders of all users of a tenant.
+	SupportServices []string `env:"AUTH_SUPPORT_SERVICES" envSeparator:"," envDefault:"support-service"`
+	// AnalyticsServices read order analytics of a tenant.
+	AnalyticsServices []string `env:"AUTH_ANALYTICS_SERVICES" envSeparator:"," envDefault:"finance-service"`
+	TLS               tlsconfig.Configuration
+	RateLimit         ratelimit.Configuration
+	Reconcile         reconciler.Configuration
+	Cart              cart_service.Configuration
+	Subscriptions     scheduler.Configuration
+	Scheduled         releaser.Configuration
+	Events            events.EncodingConfig
+	Tenants           tenant.Configuration
+	ExternalCfg       ExternalCfg
 }
 
 type ExternalCfg struct {
-	MongoConfig mongo.Configuration
-	NatsConfig  nats.Configuration
+	TelemetryConfig telemetry.Configuration
+	MongoConfig     mongo.Configuration
+	NatsConfig      nats.Configuration
 }
 
 func LoadConfig() (*Config, error) {
@@ -1,7 +1,10 @@
 package initialize
 
 import (
+	"order-service-system/order_service/internal/controllers/grpc/analytics_grpc_controller"
+	"order-service-system/order_service/internal/controllers/grpc/cart_grpc_controller"
 	"order-service-system/order_service/internal/controllers/grpc/order_grpc_controller"
+	"order-service-system/order_service/internal/controllers/grpc/subscription_grpc_controller"
 )
 
 type RpcControllersDeps struct {
@@ -9,13 +12,27 @@ type RpcControllersDeps struct {
 }
 
 type RpcControllers struct {
-	OrderController *order_grpc_controller.OrderController
+	OrderController        *order_grpc_controller.OrderController
+	CartController         *cart_grpc_controller.CartController
+	SubscriptionController *subscription_grpc_controller.SubscriptionController
+	AnalyticsController    *analytics_grpc_controller.AnalyticsController
 }
 
 func NewRpcControllers(deps RpcControllersDeps) *RpcControllers {
 	return &RpcControllers{
 		OrderController: order_grpc_controller.NewOrderController(order_grpc_controller.Deps{
-			OrderService: deps.Services.OrderServices,
+			OrderService:  deps.Services.OrderServices,
+			ReturnService: deps.Services.ReturnServices,
+			SearchService: deps.Services.SearchServices,
+		}),
+		CartController: cart_grpc_controller.NewCartController(cart_grpc_controller.Deps{
+			CartService: deps.Services.CartServices,
+		}),
+		SubscriptionController: subscription_grpc_controller.NewSubscriptionController(subscription_grpc_controller.Deps{
+			SubscriptionService: deps.Services.SubscriptionServices,
+		}),
+		AnalyticsController: analytics_grpc_controller.NewAnalyticsController(analytics_grpc_controller.Deps{
+			AnalyticsService: deps.Services.AnalyticsServices,
 		}),
 	}
 }
@@ -2,15 +2,27 @@ package initialize
 
 import (
 	"context"
+	"order-service-system/order_service/internal/repository/analytics_repository"
 	"order-service-system/order_service/internal/repository/bboltdb"
+	"order-service-system/order_service/internal/repository/cart_repository"
+	"order-service-system/order_service/internal/repository/counter_repository"
 	"order-service-system/order_service/internal/repository/order_repository"
+	"order-service-system/order_service/internal/repository/return_repository"
+	"order-service-system/order_service/internal/repository/search_repository"
+	"order-service-system/order_service/internal/repository/subscription_repository"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Repositories struct {
-	OrderRepository *order_repository.OrderRepository
-	BboltDBStore    *bboltdb.Store
+	OrderRepository        *order_repository.OrderRepository
+	ReturnRepository       *return_repository.ReturnRepository
+	CartRepository         *cart_repository.CartRepository
+	SubscriptionRepository *subscription_repository.SubscriptionRepository
+	SearchRepository       *search_repository.SearchRepository
+	AnalyticsRepository    *analytics_repository.AnalyticsRepository
+	CounterRepository      *counter_repository.CounterRepository
+	BboltDBStore           *bboltdb.Store
 }
 
 type RepositoriesDeps struct {
@@ -28,13 +40,58 @@ func NewRepositories(ctx context.Context, deps RepositoriesDeps) (*Repositories,
 		return nil, err
 	}
 
+	returnRepo, err := return_repository.NewReturnRepository(ctx, return_repository.Deps{
+		Collection: deps.MongoDB.Collection("return"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cartRepo, err := cart_repository.NewCartRepository(ctx, cart_repository.Deps{
+		Collection: deps.MongoDB.Collection("cart"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionRepo, err := subscription_repository.NewSubscriptionRepository(ctx, subscription_repository.Deps{
+		Collection: deps.MongoDB.Collection("subscription"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	searchRepo, err := search_repository.NewSearchRepository(ctx, search_repository.Deps{
+		Collection: deps.MongoDB.Collection("order"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	analyticsRepo, err := analytics_repository.NewAnalyticsRepository(ctx, analytics_repository.Deps{
+		Orders:  deps.MongoDB.Collection("order"),
+		Returns: deps.MongoDB.Collection("return"),
+		Rollups: deps.MongoDB.Collection("order_rollup"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	bboltDBStore, err := bboltdb.Create()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Repositories{
-		OrderRepository: orderRepo,
-		BboltDBStore:    bboltDBStore,
+		OrderRepository:        orderRepo,
+		ReturnRepository:       returnRepo,
+		CartRepository:         cartRepo,
+		SubscriptionRepository: subscriptionRepo,
+		SearchRepository:       searchRepo,
+		AnalyticsRepository:    analyticsRepo,
+		CounterRepository: counter_repository.NewCounterRepository(counter_repository.Deps{
+			Collection: deps.MongoDB.Collection("counter"),
+		}),
+		BboltDBStore: bboltDBStore,
 	}, nil
 }
@@ -1,19 +1,35 @@
 package initialize
 
 import (
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/service/analytics_service"
+	"order-service-system/order_service/internal/service/cart_service"
 	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/service/return_service"
+	"order-service-system/order_service/internal/service/search_service"
+	"order-service-system/order_service/internal/service/subscription_service"
 
 	"go.uber.org/zap"
 )
 
 type Services struct {
-	OrderServices *order_service.OrderService
+	OrderServices        *order_service.OrderService
+	ReturnServices       *return_service.ReturnService
+	CartServices         *cart_service.CartService
+	SubscriptionServices *subscription_service.SubscriptionService
+	SearchServices       *search_service.SearchService
+	AnalyticsServices    *analytics_service.AnalyticsService
 }
 
 type ServicesDeps struct {
 	Logger       *zap.Logger
 	Repositories *Repositories
 	Clients      *Clients
+	Cart         cart_service.Configuration
+	// Promos are the parsed Cart.PromoCodes.
+	Promos  map[string]models.Promo
+	Tenants tenant.Settings[order_service.TenantSettings]
 }
 
 func NewServices(deps ServicesDeps) *Services {
@@ -26,11 +42,40 @@ func NewServices(deps ServicesDeps) *Services {
 	if deps.Clients == nil {
 		panic("clients must not be nil on <NewServices> of <initialize>")
 	}
+	orderService := order_service.NewOrderService(order_service.Deps{
+		Logger:     deps.Logger,
+		OrderRepo:  deps.Repositories.OrderRepository,
+		Counters:   deps.Repositories.CounterRepository,
+		NatsClient: deps.Clients.NatsClient,
+		Tenants:    deps.Tenants,
+	})
+
 	return &Services{
-		OrderServices: order_service.NewOrderService(order_service.Deps{
+		OrderServices: orderService,
+		ReturnServices: return_service.NewReturnService(return_service.Deps{
 			Logger:     deps.Logger,
+			ReturnRepo: deps.Repositories.ReturnRepository,
 			OrderRepo:  deps.Repositories.OrderRepository,
 			NatsClient: deps.Clients.NatsClient,
 		}),
+		CartServices: cart_service.NewCartService(cart_service.Deps{
+			Logger:   deps.Logger,
+			CartRepo: deps.Repositories.CartRepository,
+			Orders:   orderService,
+			Promos:   deps.Promos,
+			TTL:      deps.Cart.TTL,
+		}),
+		SubscriptionServices: subscription_service.NewSubscriptionService(subscription_service.Deps{
+			Logger:           deps.Logger,
+			SubscriptionRepo: deps.Repositories.SubscriptionRepository,
+		}),
+		SearchServices: search_service.NewSearchService(search_service.Deps{
+			Logger:  deps.Logger,
+			Backend: deps.Repositories.SearchRepository,
+		}),
+		AnalyticsServices: analytics_service.NewAnalyticsService(analytics_service.Deps{
+			Logger:  deps.Logger,
+			Backend: deps.Repositories.AnalyticsRepository,
+		}),
 	}
 }
@@ -1,19 +1,33 @@
 package initialize
 
 import (
+	"order-service-system/common/bus"
+	"order-service-system/order_service/internal/workers/reconciler"
+	"order-service-system/order_service/internal/workers/releaser"
 	"order-service-system/order_service/internal/workers/republisher"
+	"order-service-system/order_service/internal/workers/rollup"
+	"order-service-system/order_service/internal/workers/scheduler"
 
 	"go.uber.org/zap"
 )
 
 type Workers struct {
 	RepublisherWC *republisher.Republisher
+	ReconcilerWC  *reconciler.Reconciler
+	SchedulerWC   *scheduler.Scheduler
+	ReleaserWC    *releaser.Releaser
+	RollupWC      *rollup.Updater
 }
 
 type WorkersDeps struct {
 	Logger       *zap.Logger
 	Clients      *Clients
 	Repositories *Repositories
+	Services     *Services
+	Subscriber   bus.Subscriber
+	Reconcile    reconciler.Configuration
+	Schedule     scheduler.Configuration
+	Release      releaser.Configuration
 }
 
 func NewWorkers(deps WorkersDeps) *Workers {
@@ -26,11 +40,41 @@ func NewWorkers(deps WorkersDeps) *Workers {
 	if deps.Clients == nil {
 		panic("clients must not be nil on <NewWorkers> of <initialize>")
 	}
+	if deps.Services == nil {
+		panic("services must not be nil on <NewWorkers> of <initialize>")
+	}
+	if deps.Subscriber == nil {
+		panic("subscriber must not be nil on <NewWorkers> of <initialize>")
+	}
 	return &Workers{
 		RepublisherWC: republisher.NewRepublisher(republisher.Deps{
 			Logger:     deps.Logger,
 			BboltStore: deps.Repositories.BboltDBStore,
 			NatsClient: deps.Clients.NatsClient,
 		}),
+		ReconcilerWC: reconciler.NewReconciler(reconciler.Deps{
+			Logger:        deps.Logger,
+			OrderRepo:     deps.Repositories.OrderRepository,
+			NatsClient:    deps.Clients.NatsClient,
+			Configuration: deps.Reconcile,
+		}),
+		SchedulerWC: scheduler.NewScheduler(scheduler.Deps{
+			Logger:           deps.Logger,
+			SubscriptionRepo: deps.Repositories.SubscriptionRepository,
+			Orders:           deps.Services.OrderServices,
+			OrderRepo:        deps.Repositories.OrderRepository,
+			Configuration:    deps.Schedule,
+		}),
+		ReleaserWC: releaser.NewReleaser(releaser.Deps{
+			Logger:        deps.Logger,
+			OrderRepo:     deps.Repositories.OrderRepository,
+			NatsClient:    deps.Clients.NatsClient,
+			Configuration: deps.Release,
+		}),
+		RollupWC: rollup.NewUpdater(rollup.Deps{
+			Logger:     deps.Logger,
+			Subscriber: deps.Subscriber,
+			Store:      deps.Repositories.AnalyticsRepository,
+		}),
 	}
 }
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	AnalyticsByDay    = "day"
+	AnalyticsByWeek   = "week"
+	AnalyticsByStatus = "status"
+	AnalyticsByUser   = "user"
+)
+
+// Kinds of order events counted in the daily rollups; an order is counted once per
+// kind, its cancellations and refunds once per cancellation and return.
+const (
+	RollupCreated   = "created"
+	RollupPaid      = "paid"
+	RollupFailed    = "failed"
+	RollupCancelled = "cancelled"
+	RollupRefunded  = "refunded"
+)
+
+// PaidStatuses are the statuses of orders whose payment succeeded.
+var PaidStatuses = []string{"PAID", "PROCESSING", "SHIPPED", "DELIVERED"}
+
+func IsPaidStatus(status string) bool {
+	return slices.Contains(PaidStatuses, status)
+}
+
+type AnalyticsQuery struct {
+	From     time.Time
+	To       time.Time
+	GroupBy  string
+	Currency string
+	// Limit bounds the user buckets.
+	Limit int
+}
+
+// AnalyticsMetrics are the additive counters of a bucket; rates are derived from them.
+type AnalyticsMetrics struct {
+	OrdersCount    int64   `bson:"orders_count"`
+	GrossRevenue   float64 `bson:"gross_revenue"`
+	PaidOrders     int64   `bson:"paid_orders"`
+	FailedPayments int64   `bson:"failed_payments"`
+}
+
+func (m AnalyticsMetrics) Add(other AnalyticsMetrics) AnalyticsMetrics {
+	return AnalyticsMetrics{
+		OrdersCount:    m.OrdersCount + other.OrdersCount,
+		GrossRevenue:   m.GrossRevenue + other.GrossRevenue,
+		PaidOrders:     m.PaidOrders + other.PaidOrders,
+		FailedPayments: m.FailedPayments + other.FailedPayments,
+	}
+}
+
+func (m AnalyticsMetrics) PaymentFailureRate() float64 {
+	if outcomes := m.PaidOrders + m.FailedPayments; outcomes > 0 {
+		return float64(m.FailedPayments) / float64(outcomes)
+	}
+	return 0
+}
+
+func (m AnalyticsMetrics) AverageOrderValue() float64 {
+	if m.PaidOrders > 0 {
+		return m.GrossRevenue / float64(m.PaidOrders)
+	}
+	return 0
+}
+
+// AnalyticsBucket has the key of its grouping set: PeriodStart, Status or UserID.
+// Totals have only Currency.
+type AnalyticsBucket struct {
+	PeriodStart time.Time
+	Status      string
+	UserID      string
+	Currency    string
+	Metrics     AnalyticsMetrics
+}
+
+type AnalyticsResult struct {
+	Buckets []AnalyticsBucket
+	Totals  []AnalyticsBucket
+}
+
+// RollupDelta changes the daily rollup of the tenant of the context.
+type RollupDelta struct {
+	Day      time.Time
+	Currency string
+	Metrics  AnalyticsMetrics
+}
+
+// DayOf returns the UTC midnight of the day of t.
+func DayOf(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// WeekOf returns the UTC midnight of the Monday of the week of t.
+func WeekOf(t time.Time) time.Time {
+	day := DayOf(t)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
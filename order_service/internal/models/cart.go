@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Cart is built by the customer before the order. ExpiresAt is moved forward by
+// every change; Mongo removes the cart once it has passed.
+type Cart struct {
+	CartID    string      `bson:"cart_id"`
+	TenantID  string      `bson:"tenant_id,omitempty"`
+	UserID    string      `bson:"user_id"`
+	Items     []OrderItem `bson:"items"`
+	PromoCode string      `bson:"promo_code,omitempty"`
+	Status    string      `bson:"status"`
+	Version   int64       `bson:"version"`
+
+	// OrderID and ShippingAddress are fixed when the checkout starts, so that a
+	// repeated checkout creates the same order.
+	OrderID         string           `bson:"order_id,omitempty"`
+	ShippingAddress *ShippingAddress `bson:"shipping_address,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Promo is the discount of a promo code: Percent of the subtotal or a fixed Amount.
+type Promo struct {
+	Code    string
+	Percent float64
+	Amount  float64
+}
+
+// CartTotals are the prices of a cart with the promo code applied. Items carry the
+// discounted prices, so Total is exactly the sum of the items.
+type CartTotals struct {
+	Items    []OrderItem
+	Subtotal float64
+	Discount float64
+	Total    float64
+}
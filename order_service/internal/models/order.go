@@ -6,25 +6,210 @@ import (
 )
 
 type Order struct {
-	OrderID     string      `bson:"order_id"`
+	OrderID string `bson:"order_id"`
+	// TenantID is empty for orders created before multi-tenancy; they belong to the
+	// default tenant.
+	TenantID string `bson:"tenant_id,omitempty"`
+	// Number is the human-readable id customers tell support, e.g. ORD-2026-000123.
+	Number      string      `bson:"number,omitempty"`
 	UserID      string      `bson:"user_id"`
 	Items       []OrderItem `bson:"items"`
 	TotalAmount float64     `bson:"total_amount"`
-	Status      string      `bson:"status"`
-	CreatedAt   time.Time   `bson:"created_at"`
-	UpdatedAt   time.Time   `bson:"updated_at"`
+	Currency    string      `bson:"currency,omitempty"`
+	// Email is the lowercased contact email support searches by.
+	Email     string    `bson:"email,omitempty"`
+	Status    string    `bson:"status"`
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+
+	// StatusReason explains a status set by the service itself, e.g. EXPIRED.
+	StatusReason    string           `bson:"status_reason,omitempty"`
+	ShippingAddress *ShippingAddress `bson:"shipping_address,omitempty"`
+	Shipment        *Shipment        `bson:"shipment,omitempty"`
+
+	// Version starts at 1 and is bumped by every amendment or cancellation of items;
+	// orders created before versioning have 0.
+	Version       int64              `bson:"version"`
+	Amendments    []OrderAmendment   `bson:"amendments,omitempty"`
+	Cancellations []ItemCancellation `bson:"cancellations,omitempty"`
+
+	// CartID is set for orders created by the checkout of a cart. The discount of
+	// the promo code is already spread over the item prices.
+	CartID    string  `bson:"cart_id,omitempty"`
+	PromoCode string  `bson:"promo_code,omitempty"`
+	Discount  float64 `bson:"discount,omitempty"`
+	// SubscriptionID is set for orders created by a subscription run.
+	SubscriptionID string `bson:"subscription_id,omitempty"`
+
+	// ScheduledAt is set for orders placed in advance; they stay SCHEDULED until
+	// the releaser submits them for payment and sets SubmittedAt.
+	ScheduledAt time.Time `bson:"scheduled_at,omitempty"`
+	SubmittedAt time.Time `bson:"submitted_at,omitempty"`
+}
+
+// PendingSince is when the order started waiting for its payment. Orders created
+// before scheduling existed have no SubmittedAt.
+func (o Order) PendingSince() time.Time {
+	if o.SubmittedAt.IsZero() {
+		return o.CreatedAt
+	}
+	return o.SubmittedAt
+}
+
+// ItemCancellation records units cancelled by one CancelItems call; Amount is
+// refunded by billing.
+type ItemCancellation struct {
+	CancellationID string          `bson:"cancellation_id"`
+	Items          []CancelledItem `bson:"items"`
+	Amount         float64         `bson:"amount"`
+	Reason         string          `bson:"reason,omitempty"`
+	Actor          string          `bson:"actor"`
+	At             time.Time       `bson:"at"`
+}
+
+type CancelledItem struct {
+	ProductID string  `bson:"product_id"`
+	Quantity  int32   `bson:"quantity"`
+	Price     float64 `bson:"price"`
+}
+
+// OrderAmendment records what an AmendOrder call changed.
+type OrderAmendment struct {
+	Version       int64        `bson:"version"`
+	Actor         string       `bson:"actor"`
+	Changes       []ItemChange `bson:"changes"`
+	PreviousTotal float64      `bson:"previous_total"`
+	NewTotal      float64      `bson:"new_total"`
+	At            time.Time    `bson:"at"`
+}
+
+type ItemChange struct {
+	ProductID   string  `bson:"product_id"`
+	OldQuantity int32   `bson:"old_quantity"`
+	NewQuantity int32   `bson:"new_quantity"`
+	Price       float64 `bson:"price"`
+}
+
+type ShippingAddress struct {
+	Recipient  string `bson:"recipient"`
+	Line1      string `bson:"line1"`
+	Line2      string `bson:"line2,omitempty"`
+	City       string `bson:"city"`
+	PostalCode string `bson:"postal_code,omitempty"`
+	Country    string `bson:"country"`
+	Phone      string `bson:"phone,omitempty"`
+}
+
+type Shipment struct {
+	Carrier        string    `bson:"carrier"`
+	TrackingNumber string    `bson:"tracking_number"`
+	ShippedAt      time.Time `bson:"shipped_at"`
+	DeliveredAt    time.Time `bson:"delivered_at,omitempty"`
+}
+
+// StatusChange is a guarded status transition; Shipment and DeliveredAt are
+// stored together with the status when set.
+type StatusChange struct {
+	Status      string
+	Shipment    *Shipment
+	DeliveredAt time.Time
 }
 
 type OrderItem struct {
 	ProductID string  `bson:"product_id"`
 	Quantity  int32   `bson:"quantity"`
 	Price     float64 `bson:"price"`
+
+	// CancelledQuantity is the part of Quantity cancelled after payment; Status is
+	// empty for lines without cancellations.
+	CancelledQuantity int32  `bson:"cancelled_quantity,omitempty"`
+	Status            string `bson:"status,omitempty"`
 }
 
+// ActiveQuantity is the number of units that are still ordered.
+func (item OrderItem) ActiveQuantity() int32 {
+	return item.Quantity - item.CancelledQuantity
+}
+
+// OrderCursor points at the last order of a page; orders are listed by created_at
+// and order_id descending.
+type OrderCursor struct {
+	CreatedAt time.Time
+	OrderID   string
+}
+
+type ListOrdersFilter struct {
+	UserID string
+	Status string
+	Limit  int
+	After  *OrderCursor
+}
+
+type OrderShippedEvent struct {
+	OrderID        string
+	UserID         string
+	Carrier        string
+	TrackingNumber string
+	ShippedAt      time.Time
+}
+
+type OrderDeliveredEvent struct {
+	OrderID     string
+	UserID      string
+	DeliveredAt time.Time
+}
+
+type OrderAmendedEvent struct {
+	OrderID       string
+	UserID        string
+	Version       int64
+	TotalAmount   float64
+	PreviousTotal float64
+	AmendedAt     time.Time
+}
+
+// OrderPaidEvent and OrderFailedEvent report payment results the service resolved
+// itself; billing publishes the rest.
+type OrderPaidEvent struct {
+	OrderID     string
+	UserID      string
+	TotalAmount float64
+	PaidAt      time.Time
+}
+
+type OrderFailedEvent struct {
+	OrderID  string
+	UserID   string
+	Reason   string
+	FailedAt time.Time
+}
+
+type OrderItemsCancelledEvent struct {
+	OrderID        string
+	UserID         string
+	Cancellation   ItemCancellation
+	RemainingTotal float64
+}
+
+// OrderOrigin is set for orders created by the service itself, from a cart or a
+// subscription. OrderID is chosen by the caller, so a repeated call finds the order.
+type OrderOrigin struct {
+	OrderID        string
+	CartID         string
+	SubscriptionID string
+	PromoCode      string
+	Discount       float64
+}
+
+// OrderCreatedEvent is kept in the outbox until published, so it carries the tenant
+// of the order itself.
 type OrderCreatedEvent struct {
+	EventID     string
+	TenantID    string
 	OrderID     string
 	UserID      string
 	TotalAmount float64
+	Currency    string
 	CreatedAt   time.Time
 }
 
@@ -33,4 +218,20 @@ var AllowedStatuses = map[orderpb.OrderStatus]struct{}{
 	orderpb.OrderStatus_PAID:      {},
 	orderpb.OrderStatus_CANCELLED: {},
 	orderpb.OrderStatus_FAILED:    {},
+	// SHIPPED устанавливается только через MarkShipped.
+	orderpb.OrderStatus_PROCESSING: {},
+	orderpb.OrderStatus_DELIVERED:  {},
+}
+
+// CancellableStatuses allow cancelling single items: the order is paid but not shipped yet.
+var CancellableStatuses = []string{
+	orderpb.OrderStatus_PAID.String(),
+	orderpb.OrderStatus_PROCESSING.String(),
+}
+
+// FulfilmentStatuses follow PAID; payment results must not move an order back from them.
+var FulfilmentStatuses = []string{
+	orderpb.OrderStatus_PROCESSING.String(),
+	orderpb.OrderStatus_SHIPPED.String(),
+	orderpb.OrderStatus_DELIVERED.String(),
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	orderpb "order-service-system/proto/order"
+	"time"
+)
+
+// Return is a request to return items of a delivered order (RMA). History keeps
+// every status change in the order it happened.
+type Return struct {
+	ReturnID     string               `bson:"return_id"`
+	TenantID     string               `bson:"tenant_id,omitempty"`
+	OrderID      string               `bson:"order_id"`
+	UserID       string               `bson:"user_id"`
+	Items        []ReturnItem         `bson:"items"`
+	Reason       string               `bson:"reason"`
+	Status       string               `bson:"status"`
+	RefundAmount float64              `bson:"refund_amount"`
+	History      []ReturnHistoryEntry `bson:"history"`
+	CreatedAt    time.Time            `bson:"created_at"`
+	UpdatedAt    time.Time            `bson:"updated_at"`
+	// Sequence numbers the returns of the order from 1 and is unique per order, so
+	// of two requests checked against the same returns only one is stored. Returns
+	// created before it have 0.
+	Sequence int64 `bson:"sequence,omitempty"`
+}
+
+type ReturnItem struct {
+	ProductID string `bson:"product_id"`
+	Quantity  int32  `bson:"quantity"`
+}
+
+type ReturnHistoryEntry struct {
+	Status  string    `bson:"status"`
+	Actor   string    `bson:"actor"`
+	Comment string    `bson:"comment,omitempty"`
+	At      time.Time `bson:"at"`
+}
+
+// ClosedReturnStatuses do not hold items of the order; they can be requested again.
+var ClosedReturnStatuses = []string{
+	orderpb.ReturnStatus_RETURN_REJECTED.String(),
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// OrderSearchQuery holds the criteria of a support search; they are combined with
+// AND. Status narrows the hits but not the status counts.
+type OrderSearchQuery struct {
+	Text      string
+	Number    string
+	ProductID string
+	Email     string
+	MinAmount float64
+	// MaxAmount 0 means no upper bound.
+	MaxAmount float64
+	Status    string
+	Offset    int
+	Limit     int
+}
+
+type OrderSearchResult struct {
+	Hits []OrderSearchHit
+	// StatusCounts counts the matching orders by status regardless of Status.
+	StatusCounts map[string]int64
+	Total        int64
+}
+
+type OrderSearchHit struct {
+	Order      Order
+	Highlights []SearchHighlight
+	// Score is the text relevance; 0 without Text.
+	Score float64
+}
+
+type SearchHighlight struct {
+	Field   string
+	Snippet string
+}
+
+const (
+	HighlightOrderNumber = "order_number"
+	HighlightProductID   = "product_id"
+	HighlightEmail       = "email"
+)
+
+// TextTerms splits the text into the words a text index matches.
+func TextTerms(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Highlight marks the parts of the order that matched the query with <em></em>,
+// for backends that do not highlight themselves.
+func (q OrderSearchQuery) Highlight(order Order) []SearchHighlight {
+	terms := TextTerms(q.Text)
+	var highlights []SearchHighlight
+	add := func(field string, value string, needles ...string) {
+		if snippet, ok := mark(value, needles); ok {
+			highlights = append(highlights, SearchHighlight{Field: field, Snippet: snippet})
+		}
+	}
+
+	add(HighlightOrderNumber, order.Number, append([]string{q.Number}, terms...)...)
+	seen := map[string]bool{}
+	for _, item := range order.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		seen[item.ProductID] = true
+		if q.ProductID != "" && item.ProductID == q.ProductID {
+			add(HighlightProductID, item.ProductID, item.ProductID)
+			continue
+		}
+		add(HighlightProductID, item.ProductID, terms...)
+	}
+	if q.Email != "" && strings.EqualFold(order.Email, q.Email) {
+		add(HighlightEmail, order.Email, order.Email)
+	} else {
+		add(HighlightEmail, order.Email, terms...)
+	}
+	return highlights
+}
+
+// mark wraps every case-insensitive occurrence of the needles in the value.
+func mark(value string, needles []string) (string, bool) {
+	quoted := make([]string, 0, len(needles))
+	for _, needle := range needles {
+		if needle = strings.TrimSpace(needle); needle != "" {
+			quoted = append(quoted, regexp.QuoteMeta(needle))
+		}
+	}
+	if value == "" || len(quoted) == 0 {
+		return "", false
+	}
+	// Длинные совпадения раньше коротких, чтобы «000123» не разбивалось на «123».
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	pattern := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	if !pattern.MatchString(value) {
+		return "", false
+	}
+	return pattern.ReplaceAllString(value, "<em>$0</em>"), true
+}
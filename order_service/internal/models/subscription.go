@@ -0,0 +1,66 @@
+package models
+
+import (
+	orderpb "order-service-system/proto/order"
+	"time"
+)
+
+// Subscription repeats the template order every IntervalCount periods from StartsAt.
+// CurrentPeriod is the period billed next; it moves on once its order is paid.
+type Subscription struct {
+	SubscriptionID  string           `bson:"subscription_id"`
+	TenantID        string           `bson:"tenant_id,omitempty"`
+	UserID          string           `bson:"user_id"`
+	Items           []OrderItem      `bson:"items"`
+	ShippingAddress *ShippingAddress `bson:"shipping_address,omitempty"`
+	Period          string           `bson:"period"`
+	IntervalCount   int32            `bson:"interval_count"`
+	StartsAt        time.Time        `bson:"starts_at"`
+	EndsAt          time.Time        `bson:"ends_at,omitempty"`
+	Status          string           `bson:"status"`
+	StatusReason    string           `bson:"status_reason,omitempty"`
+
+	CurrentPeriod  int64     `bson:"current_period"`
+	NextRunAt      time.Time `bson:"next_run_at"`
+	FailedAttempts int32     `bson:"failed_attempts"`
+	// PendingOrderID is the order of the current period that waits for its payment.
+	PendingOrderID string            `bson:"pending_order_id,omitempty"`
+	Runs           []SubscriptionRun `bson:"runs,omitempty"`
+
+	Version   int64     `bson:"version"`
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+type SubscriptionRun struct {
+	Period      int64     `bson:"period"`
+	Attempt     int32     `bson:"attempt"`
+	OrderID     string    `bson:"order_id"`
+	OrderStatus string    `bson:"order_status"`
+	At          time.Time `bson:"at"`
+}
+
+// RunAt is the scheduled time of the period. Periods are counted from StartsAt, so
+// the schedule does not drift with retries or pauses.
+func (s Subscription) RunAt(period int64) time.Time {
+	n := int(period) * int(s.IntervalCount)
+	switch s.Period {
+	case orderpb.SubscriptionPeriod_DAILY.String():
+		return s.StartsAt.AddDate(0, 0, n)
+	case orderpb.SubscriptionPeriod_WEEKLY.String():
+		return s.StartsAt.AddDate(0, 0, 7*n)
+	default:
+		return s.StartsAt.AddDate(0, n, 0)
+	}
+}
+
+// Ended reports whether the period starts after EndsAt.
+func (s Subscription) Ended(period int64) bool {
+	return !s.EndsAt.IsZero() && s.RunAt(period).After(s.EndsAt)
+}
+
+// SchedulableSubscriptionStatuses are the statuses in which the scheduler creates orders.
+var SchedulableSubscriptionStatuses = []string{
+	orderpb.SubscriptionStatus_SUBSCRIPTION_ACTIVE.String(),
+	orderpb.SubscriptionStatus_SUBSCRIPTION_PAST_DUE.String(),
+}
@@ -4,4 +4,8 @@ import "github.com/pkg/errors"
 
 var (
 	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the order is not in a status the change can be applied to.
+	ErrConflict = errors.New("status conflict")
+	// ErrAlreadyExists is returned when a document with the same id is stored already.
+	ErrAlreadyExists = errors.New("already exists")
 )
@@ -0,0 +1,254 @@
+package analytics_repository
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	orderpb "order-service-system/proto/order"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AnalyticsRepository keeps daily rollups of order metrics per tenant and currency
+// and aggregates them, or the orders themselves, into analytics buckets.
+type AnalyticsRepository struct {
+	orders  *mongo.Collection
+	returns *mongo.Collection
+	rollups *mongo.Collection
+}
+
+type Deps struct {
+	Orders *mongo.Collection
+	// Returns are read for the refunds taken off the revenue of their orders.
+	Returns *mongo.Collection
+	// Rollups keep the daily metrics together with the events counted in them.
+	Rollups *mongo.Collection
+}
+
+func NewAnalyticsRepository(ctx context.Context, deps Deps) (*AnalyticsRepository, error) {
+	if deps.Orders == nil {
+		panic("orders collection must not be nil on <NewAnalyticsRepository> of <AnalyticsRepository>")
+	}
+	if deps.Returns == nil {
+		panic("returns collection must not be nil on <NewAnalyticsRepository> of <AnalyticsRepository>")
+	}
+	if deps.Rollups == nil {
+		panic("rollups collection must not be nil on <NewAnalyticsRepository> of <AnalyticsRepository>")
+	}
+
+	rollupIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "day", Value: 1}, {Key: "currency", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := deps.Rollups.Indexes().CreateOne(ctx, rollupIndexModel); err != nil {
+		return nil, err
+	}
+	createdIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: 1}},
+	}
+	if _, err := deps.Orders.Indexes().CreateOne(ctx, createdIndexModel); err != nil {
+		return nil, err
+	}
+
+	return &AnalyticsRepository{
+		orders:  deps.Orders,
+		returns: deps.Returns,
+		rollups: deps.Rollups,
+	}, nil
+}
+
+// Order returns the order of the tenant of the context; false means it is unknown.
+func (receiver *AnalyticsRepository) Order(ctx context.Context, orderID string) (models.Order, bool, error) {
+	var order models.Order
+	err := receiver.orders.FindOne(ctx, tenant.MongoFilter(ctx, bson.M{"order_id": orderID})).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Order{}, false, nil
+	}
+	if err != nil {
+		return models.Order{}, false, err
+	}
+	return order, true, nil
+}
+
+// Apply adds the delta to the daily rollup of the tenant of the context, creating the
+// rollup on the first order of the day. The key of the event is stored in the same
+// rollup by the same update, so an event is counted at most once and false is
+// returned for a redelivered or re-emitted one.
+func (receiver *AnalyticsRepository) Apply(ctx context.Context, key string, delta models.RollupDelta) (bool, error) {
+	if tenant.IsAll(ctx) {
+		return false, errors.New("rollups are kept per tenant")
+	}
+	filter := bson.M{
+		"tenant_id": tenant.FromContext(ctx),
+		"day":       models.DayOf(delta.Day),
+		"currency":  delta.Currency,
+		"counted":   bson.M{"$ne": key},
+	}
+	update := bson.M{
+		"$inc": bson.M{
+			"orders_count":    delta.Metrics.OrdersCount,
+			"gross_revenue":   delta.Metrics.GrossRevenue,
+			"paid_orders":     delta.Metrics.PaidOrders,
+			"failed_payments": delta.Metrics.FailedPayments,
+		},
+		"$push": bson.M{"counted": key},
+		"$set":  bson.M{"updated_at": time.Now().UTC()},
+	}
+
+	result, err := receiver.rollups.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// Роллап дня уже есть: событие в нём учтено или его только что создала другая реплика.
+		result, err = receiver.rollups.UpdateOne(ctx, filter, update)
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1 || result.UpsertedCount == 1, nil
+}
+
+type bucketDoc struct {
+	ID struct {
+		Period   time.Time `bson:"period"`
+		Status   string    `bson:"status"`
+		UserID   string    `bson:"user_id"`
+		Currency string    `bson:"currency"`
+	} `bson:"_id"`
+	models.AnalyticsMetrics `bson:",inline"`
+}
+
+type analyticsFacets struct {
+	Buckets []bucketDoc `bson:"buckets"`
+	Totals  []bucketDoc `bson:"totals"`
+}
+
+// AggregateRollups sums the daily rollups of the range by day or by week.
+func (receiver *AnalyticsRepository) AggregateRollups(ctx context.Context, query models.AnalyticsQuery) (models.AnalyticsResult, error) {
+	match := bson.M{"day": bson.M{"$gte": query.From, "$lt": query.To}}
+	if query.Currency != "" {
+		match["currency"] = query.Currency
+	}
+	match = tenant.MongoFilter(ctx, match)
+
+	var period any = "$day"
+	if query.GroupBy == models.AnalyticsByWeek {
+		period = bson.M{"$dateTrunc": bson.M{"date": "$day", "unit": "week", "startOfWeek": "monday"}}
+	}
+	sum := func(field string) bson.M { return bson.M{"$sum": "$" + field} }
+	metrics := bson.M{
+		"orders_count":    sum("orders_count"),
+		"gross_revenue":   sum("gross_revenue"),
+		"paid_orders":     sum("paid_orders"),
+		"failed_payments": sum("failed_payments"),
+	}
+
+	return receiver.aggregate(ctx, receiver.rollups, mongo.Pipeline{{{Key: "$match", Value: match}}}, bson.M{"period": period, "currency": "$currency"}, metrics,
+		bson.D{{Key: "_id.period", Value: 1}, {Key: "_id.currency", Value: 1}}, 0)
+}
+
+// AggregateOrders groups the orders created in the range by their current status
+// or by user. Scheduled orders are not submitted yet and are left out, like in the rollups.
+// The revenue of an order is its total after item cancellations less its refunded returns.
+func (receiver *AnalyticsRepository) AggregateOrders(ctx context.Context, query models.AnalyticsQuery) (models.AnalyticsResult, error) {
+	match := bson.M{
+		"created_at": bson.M{"$gte": query.From, "$lt": query.To},
+		"status":     bson.M{"$ne": "SCHEDULED"},
+	}
+	if query.Currency != "" {
+		match["currency"] = query.Currency
+	}
+	match = tenant.MongoFilter(ctx, match)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": receiver.returns.Name(),
+			"let":  bson.M{"order_id": "$order_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{
+					"$expr":  bson.M{"$eq": bson.A{"$order_id", "$$order_id"}},
+					"status": orderpb.ReturnStatus_RETURN_REFUNDED.String(),
+				}},
+				bson.M{"$project": bson.M{"_id": 0, "refund_amount": 1}},
+			},
+			"as": "refunds",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"refunded": bson.M{"$sum": "$refunds.refund_amount"}}}},
+	}
+
+	paid := bson.M{"$in": bson.A{"$status", models.PaidStatuses}}
+	revenue := bson.M{"$subtract": bson.A{"$total_amount", "$refunded"}}
+	metrics := bson.M{
+		"orders_count":    bson.M{"$sum": 1},
+		"gross_revenue":   bson.M{"$sum": bson.M{"$cond": bson.A{paid, revenue, 0}}},
+		"paid_orders":     bson.M{"$sum": bson.M{"$cond": bson.A{paid, 1, 0}}},
+		"failed_payments": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", "FAILED"}}, 1, 0}}},
+	}
+	currency := bson.M{"$ifNull": bson.A{"$currency", ""}}
+
+	if query.GroupBy == models.AnalyticsByUser {
+		return receiver.aggregate(ctx, receiver.orders, pipeline, bson.M{"user_id": "$user_id", "currency": currency}, metrics,
+			bson.D{{Key: "gross_revenue", Value: -1}, {Key: "orders_count", Value: -1}, {Key: "_id.user_id", Value: 1}, {Key: "_id.currency", Value: 1}}, query.Limit)
+	}
+	return receiver.aggregate(ctx, receiver.orders, pipeline, bson.M{"status": "$status", "currency": currency}, metrics,
+		bson.D{{Key: "_id.status", Value: 1}, {Key: "_id.currency", Value: 1}}, 0)
+}
+
+// aggregate groups the documents left by the pipeline into buckets and per-currency
+// totals in one pipeline, so totals also include the buckets cut off by limit.
+func (receiver *AnalyticsRepository) aggregate(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline, key bson.M, metrics bson.M, sort bson.D, limit int) (models.AnalyticsResult, error) {
+	group := func(id any) bson.M {
+		stage := bson.M{"_id": id}
+		for field, accumulator := range metrics {
+			stage[field] = accumulator
+		}
+		return bson.M{"$group": stage}
+	}
+	buckets := bson.A{group(key), bson.M{"$sort": sort}}
+	if limit > 0 {
+		buckets = append(buckets, bson.M{"$limit": limit})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"buckets": buckets,
+		"totals": bson.A{
+			group(bson.M{"currency": key["currency"]}),
+			bson.M{"$sort": bson.M{"_id.currency": 1}},
+		},
+	}}})
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.AnalyticsResult{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var facets []analyticsFacets
+	if err := cursor.All(ctx, &facets); err != nil {
+		return models.AnalyticsResult{}, err
+	}
+
+	var result models.AnalyticsResult
+	if len(facets) == 0 {
+		return result, nil
+	}
+	for _, doc := range facets[0].Buckets {
+		result.Buckets = append(result.Buckets, doc.bucket())
+	}
+	for _, doc := range facets[0].Totals {
+		result.Totals = append(result.Totals, doc.bucket())
+	}
+	return result, nil
+}
+
+func (doc bucketDoc) bucket() models.AnalyticsBucket {
+	return models.AnalyticsBucket{
+		PeriodStart: doc.ID.Period,
+		Status:      doc.ID.Status,
+		UserID:      doc.ID.UserID,
+		Currency:    doc.ID.Currency,
+		Metrics:     doc.AnalyticsMetrics,
+	}
+}
@@ -0,0 +1,19 @@
+package bboltdb
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterMetrics exposes the number of events waiting in the outbox for republishing.
+func RegisterMetrics(store *Store) error {
+	return prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "order_outbox_backlog",
+		Help: "Number of order.created events stored in bbolt waiting to be republished.",
+	}, func() float64 {
+		count, err := store.Count()
+		if err != nil {
+			return -1
+		}
+		return float64(count)
+	}))
+}
@@ -1,6 +1,7 @@
 package bboltdb
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -80,3 +81,22 @@ func (s *Store) Delete(key string) error {
 		return b.Delete([]byte(key))
 	})
 }
+
+func (s *Store) Count() (int, error) {
+	count := 0
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		count = tx.Bucket([]byte(orderCreatedBucket)).Stats().KeyN
+		return nil
+	})
+	return count, err
+}
+
+// Ping checks that the database is open and the outbox bucket is readable.
+func (s *Store) Ping(_ context.Context) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte(orderCreatedBucket)) == nil {
+			return fmt.Errorf("bucket %s not found", orderCreatedBucket)
+		}
+		return nil
+	})
+}
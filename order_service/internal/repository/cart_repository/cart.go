@@ -0,0 +1,151 @@
+package cart_repository
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	orderpb "order-service-system/proto/order"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CartRepository struct {
+	collection *mongo.Collection
+}
+
+type Deps struct {
+	Collection *mongo.Collection
+}
+
+func NewCartRepository(ctx context.Context, deps Deps) (*CartRepository, error) {
+	if deps.Collection == nil {
+		panic("collection must not be nil on <NewCartRepository> of <CartRepository>")
+	}
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "cart_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	// Брошенные корзины удаляет сама Mongo, как только expires_at прошёл.
+	ttlIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := deps.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{indexModel, ttlIndexModel}); err != nil {
+		return nil, err
+	}
+
+	return &CartRepository{
+		collection: deps.Collection,
+	}, nil
+}
+
+// Create stores the cart in the tenant of the context.
+func (receiver *CartRepository) Create(ctx context.Context, cart models.Cart) error {
+	if !tenant.IsAll(ctx) {
+		cart.TenantID = tenant.FromContext(ctx)
+	}
+	_, err := receiver.collection.InsertOne(ctx, cart)
+	if mongo.IsDuplicateKeyError(err) {
+		return pj_errors.ErrAlreadyExists
+	}
+	return err
+}
+
+// Get does not return expired carts that the TTL monitor has not removed yet.
+func (receiver *CartRepository) Get(ctx context.Context, cartID string) (models.Cart, error) {
+	var doc models.Cart
+	err := receiver.collection.FindOne(ctx, tenant.MongoFilter(ctx, bson.M{
+		"cart_id":    cartID,
+		"expires_at": bson.M{"$gt": time.Now().UTC()},
+	})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Cart{}, pj_errors.ErrNotFound
+	}
+	return doc, err
+}
+
+// Save stores the items and the promo code of an active cart that still has the
+// expected version. ErrConflict means the cart was changed or checked out meanwhile.
+func (receiver *CartRepository) Save(ctx context.Context, cart models.Cart, expectedVersion int64) (models.Cart, error) {
+	return receiver.update(ctx,
+		bson.M{"cart_id": cart.CartID, "status": orderpb.CartStatus_CART_ACTIVE.String(), "version": expectedVersion},
+		bson.M{
+			"$set": bson.M{
+				"items":      cart.Items,
+				"promo_code": cart.PromoCode,
+				"updated_at": cart.UpdatedAt,
+				"expires_at": cart.ExpiresAt,
+			},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+}
+
+// StartCheckout freezes an active cart with the expected version and fixes the id
+// of its order and the shipping address.
+func (receiver *CartRepository) StartCheckout(ctx context.Context, cartID string, expectedVersion int64, orderID string, address *models.ShippingAddress, expiresAt time.Time) (models.Cart, error) {
+	return receiver.update(ctx,
+		bson.M{"cart_id": cartID, "status": orderpb.CartStatus_CART_ACTIVE.String(), "version": expectedVersion},
+		bson.M{
+			"$set": bson.M{
+				"status":           orderpb.CartStatus_CART_CHECKING_OUT.String(),
+				"order_id":         orderID,
+				"shipping_address": address,
+				"updated_at":       time.Now().UTC(),
+				"expires_at":       expiresAt,
+			},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+}
+
+// ReleaseCheckout makes the cart editable again after its order was rejected.
+func (receiver *CartRepository) ReleaseCheckout(ctx context.Context, cartID string, orderID string) (models.Cart, error) {
+	return receiver.update(ctx,
+		bson.M{"cart_id": cartID, "status": orderpb.CartStatus_CART_CHECKING_OUT.String(), "order_id": orderID},
+		bson.M{
+			"$set":   bson.M{"status": orderpb.CartStatus_CART_ACTIVE.String(), "updated_at": time.Now().UTC()},
+			"$unset": bson.M{"order_id": "", "shipping_address": ""},
+			"$inc":   bson.M{"version": 1},
+		},
+	)
+}
+
+// CompleteCheckout marks the cart as checked out once its order is stored.
+func (receiver *CartRepository) CompleteCheckout(ctx context.Context, cartID string, orderID string) (models.Cart, error) {
+	return receiver.update(ctx,
+		bson.M{"cart_id": cartID, "status": orderpb.CartStatus_CART_CHECKING_OUT.String(), "order_id": orderID},
+		bson.M{
+			"$set": bson.M{"status": orderpb.CartStatus_CART_CHECKED_OUT.String(), "updated_at": time.Now().UTC()},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+}
+
+func (receiver *CartRepository) update(ctx context.Context, filter bson.M, update bson.M) (models.Cart, error) {
+	var doc models.Cart
+	res := receiver.collection.FindOneAndUpdate(ctx, tenant.MongoFilter(ctx, filter), update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	if err := res.Err(); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return models.Cart{}, err
+		}
+		// Корзина есть, но её статус (или версия) не подходит.
+		if _, getErr := receiver.Get(ctx, filter["cart_id"].(string)); getErr == nil {
+			return models.Cart{}, pj_errors.ErrConflict
+		}
+		return models.Cart{}, pj_errors.ErrNotFound
+	}
+	if err := res.Decode(&doc); err != nil {
+		return models.Cart{}, err
+	}
+	return doc, nil
+}
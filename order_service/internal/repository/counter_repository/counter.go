@@ -0,0 +1,48 @@
+package counter_repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CounterRepository struct {
+	collection *mongo.Collection
+}
+
+type Deps struct {
+	Collection *mongo.Collection
+}
+
+type counter struct {
+	Name  string `bson:"_id"`
+	Value int64  `bson:"value"`
+}
+
+func NewCounterRepository(deps Deps) *CounterRepository {
+	if deps.Collection == nil {
+		panic("collection must not be nil on <NewCounterRepository> of <CounterRepository>")
+	}
+
+	return &CounterRepository{
+		collection: deps.Collection,
+	}
+}
+
+// Next atomically increments the named counter and returns the new value; the
+// first call for a name returns 1. A value taken by a failed operation is not
+// returned again.
+func (receiver *CounterRepository) Next(ctx context.Context, name string) (int64, error) {
+	var doc counter
+	err := receiver.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"value": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+	return doc.Value, nil
+}
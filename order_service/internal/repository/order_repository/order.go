@@ -3,8 +3,11 @@ package order_repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"order-service-system/common/tenant"
 	"order-service-system/order_service/internal/models"
 	"order-service-system/order_service/internal/pj_errors"
+	orderpb "order-service-system/proto/order"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -31,7 +34,29 @@ func NewOrderRepository(ctx context.Context, deps Deps) (*OrderRepository, error
 		Keys:    bson.D{{Key: "order_id", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	}
-	if _, err := deps.Collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+	listIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "order_id", Value: -1}},
+	}
+	staleIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+	}
+	// Номера заказов уникальны в пределах витрины.
+	numberIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "number", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"number": bson.M{"$exists": true}}),
+	}
+	submittedIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "submitted_at", Value: 1}},
+	}
+	scheduledIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "status", Value: 1}, {Key: "scheduled_at", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+	indexModels := []mongo.IndexModel{indexModel, numberIndexModel, listIndexModel, staleIndexModel, submittedIndexModel, scheduledIndexModel}
+	if err := dropGlobalNumberIndex(ctx, deps.Collection); err != nil {
+		return nil, err
+	}
+	if _, err := deps.Collection.Indexes().CreateMany(ctx, indexModels); err != nil {
 		return nil, err
 	}
 
@@ -40,36 +65,245 @@ func NewOrderRepository(ctx context.Context, deps Deps) (*OrderRepository, error
 	}, nil
 }
 
+// IsGlobalNumberIndex reports whether the index is the unique index on the number
+// alone that orders had before tenants. Counters of different tenants issue the same
+// numbers, so with this index the first order of a second tenant is rejected.
+func IsGlobalNumberIndex(spec mongo.IndexSpecification) bool {
+	if spec.Unique == nil || !*spec.Unique {
+		return false
+	}
+	keys, err := spec.KeysDocument.Elements()
+	return err == nil && len(keys) == 1 && keys[0].Key() == "number"
+}
+
+// dropGlobalNumberIndex migrates databases created before tenants; other indexes on
+// the number are left alone.
+func dropGlobalNumberIndex(ctx context.Context, collection *mongo.Collection) error {
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return fmt.Errorf("list order indexes: %w", err)
+	}
+	for _, spec := range specs {
+		if !IsGlobalNumberIndex(*spec) {
+			continue
+		}
+		_, err := collection.Indexes().DropOne(ctx, spec.Name)
+		var commandErr mongo.CommandError
+		// Другая реплика удалила индекс одновременно с нами.
+		if errors.As(err, &commandErr) && commandErr.Name == "IndexNotFound" {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("drop order index %s: %w", spec.Name, err)
+		}
+	}
+	return nil
+}
+
+// Create returns ErrAlreadyExists when an order with the same id is stored already.
+// The order is stored in the tenant of the context.
 func (receiver *OrderRepository) Create(ctx context.Context, order models.Order) error {
+	if !tenant.IsAll(ctx) {
+		order.TenantID = tenant.FromContext(ctx)
+	}
 	_, err := receiver.collection.InsertOne(ctx, order)
+	if mongo.IsDuplicateKeyError(err) {
+		return pj_errors.ErrAlreadyExists
+	}
 	return err
 }
 
 func (receiver *OrderRepository) Get(ctx context.Context, orderID string) (models.Order, error) {
 	var doc models.Order
-	err := receiver.collection.FindOne(ctx, bson.M{"order_id": orderID}).Decode(&doc)
+	err := receiver.collection.FindOne(ctx, tenant.MongoFilter(ctx, bson.M{"order_id": orderID})).Decode(&doc)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return models.Order{}, pj_errors.ErrNotFound
 	}
 	return doc, err
 }
 
+func (receiver *OrderRepository) GetByNumber(ctx context.Context, number string) (models.Order, error) {
+	var doc models.Order
+	err := receiver.collection.FindOne(ctx, tenant.MongoFilter(ctx, bson.M{"number": number})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Order{}, pj_errors.ErrNotFound
+	}
+	return doc, err
+}
+
+// UpdateStatus sets a status that is not a payment result. Orders that are already in
+// fulfilment are not touched and ErrConflict is returned.
 func (receiver *OrderRepository) UpdateStatus(ctx context.Context, orderID string, status string) (models.Order, error) {
+	return receiver.update(ctx,
+		bson.M{"order_id": orderID, "status": bson.M{"$nin": models.FulfilmentStatuses}},
+		bson.M{
+			"$set":   bson.M{"status": status, "updated_at": time.Now().UTC()},
+			"$unset": bson.M{"status_reason": ""},
+		},
+	)
+}
+
+// Transition applies the change only if the order is in one of the from statuses,
+// otherwise it returns ErrConflict.
+func (receiver *OrderRepository) Transition(ctx context.Context, orderID string, from []string, change models.StatusChange) (models.Order, error) {
+	set := bson.M{"status": change.Status, "updated_at": time.Now().UTC()}
+	if change.Shipment != nil {
+		set["shipment"] = change.Shipment
+	}
+	if !change.DeliveredAt.IsZero() {
+		set["shipment.delivered_at"] = change.DeliveredAt
+	}
+
+	return receiver.update(ctx,
+		bson.M{"order_id": orderID, "status": bson.M{"$in": from}},
+		bson.M{"$set": set, "$unset": bson.M{"status_reason": ""}},
+	)
+}
+
+// Amend replaces the items of a PENDING order that still has the expected version
+// and bumps the version. ErrConflict means the order was paid or amended meanwhile.
+func (receiver *OrderRepository) Amend(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, amendment models.OrderAmendment) (models.Order, error) {
+	return receiver.update(ctx,
+		bson.M{"order_id": orderID, "status": orderpb.OrderStatus_PENDING.String(), "version": versionFilter(expectedVersion)},
+		bson.M{
+			"$set":  bson.M{"items": items, "total_amount": amendment.NewTotal, "version": amendment.Version, "updated_at": amendment.At},
+			"$push": bson.M{"amendments": amendment},
+		},
+	)
+}
+
+// CancelItems stores the items with cancelled units and the remaining total of a
+// paid, not yet shipped order that still has the expected version. An order without
+// remaining units is moved to status. ErrConflict means the order changed meanwhile.
+func (receiver *OrderRepository) CancelItems(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, total float64, status string, cancellation models.ItemCancellation) (models.Order, error) {
+	return receiver.update(ctx,
+		bson.M{"order_id": orderID, "status": bson.M{"$in": models.CancellableStatuses}, "version": versionFilter(expectedVersion)},
+		bson.M{
+			"$set":  bson.M{"items": items, "total_amount": total, "status": status, "updated_at": cancellation.At},
+			"$inc":  bson.M{"version": 1},
+			"$push": bson.M{"cancellations": cancellation},
+		},
+	)
+}
+
+func versionFilter(expectedVersion int64) any {
+	if expectedVersion == 0 {
+		// У заказов, созданных до появления версий, поля нет.
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return expectedVersion
+}
+
+func (receiver *OrderRepository) update(ctx context.Context, filter bson.M, update bson.M) (models.Order, error) {
 	var doc models.Order
-	res := receiver.collection.FindOneAndUpdate(ctx,
-		bson.M{"order_id": orderID},
-		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now().UTC()}},
+	res := receiver.collection.FindOneAndUpdate(ctx, tenant.MongoFilter(ctx, filter), update,
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
 
 	if err := res.Err(); err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return models.Order{}, pj_errors.ErrNotFound
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return models.Order{}, err
 		}
-		return models.Order{}, err
+		// Заказ есть, но его статус (или версия) не подходит.
+		if _, getErr := receiver.Get(ctx, filter["order_id"].(string)); getErr == nil {
+			return models.Order{}, pj_errors.ErrConflict
+		}
+		return models.Order{}, pj_errors.ErrNotFound
 	}
 	if err := res.Decode(&doc); err != nil {
 		return models.Order{}, err
 	}
 	return doc, nil
 }
+
+func (receiver *OrderRepository) List(ctx context.Context, filter models.ListOrdersFilter) ([]models.Order, error) {
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.After != nil {
+		query["$or"] = bson.A{
+			bson.M{"created_at": bson.M{"$lt": filter.After.CreatedAt}},
+			bson.M{"created_at": filter.After.CreatedAt, "order_id": bson.M{"$lt": filter.After.OrderID}},
+		}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "order_id", Value: -1}}).
+		SetLimit(int64(filter.Limit))
+
+	cursor, err := receiver.collection.Find(ctx, tenant.MongoFilter(ctx, query), findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []models.Order
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// ListStale returns orders that are still in the status and were submitted for
+// payment before the threshold, oldest first.
+func (receiver *OrderRepository) ListStale(ctx context.Context, status string, submittedBefore time.Time, limit int) ([]models.Order, error) {
+	return receiver.find(ctx, bson.M{
+		"status": status,
+		"$or": bson.A{
+			bson.M{"submitted_at": bson.M{"$lt": submittedBefore}},
+			// Заказы, созданные до появления submitted_at.
+			bson.M{"submitted_at": bson.M{"$exists": false}, "created_at": bson.M{"$lt": submittedBefore}},
+		},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(int64(limit)))
+}
+
+// ListScheduled returns SCHEDULED orders whose time is not after dueBefore, most
+// overdue first.
+func (receiver *OrderRepository) ListScheduled(ctx context.Context, dueBefore time.Time, limit int) ([]models.Order, error) {
+	return receiver.find(ctx, bson.M{
+		"status":       orderpb.OrderStatus_SCHEDULED.String(),
+		"scheduled_at": bson.M{"$lte": dueBefore},
+	}, options.Find().SetSort(bson.D{{Key: "scheduled_at", Value: 1}}).SetLimit(int64(limit)))
+}
+
+// Release moves a SCHEDULED order to PENDING. ErrConflict means the order was
+// released by another replica or cancelled meanwhile.
+func (receiver *OrderRepository) Release(ctx context.Context, orderID string, submittedAt time.Time) (models.Order, error) {
+	return receiver.update(ctx,
+		bson.M{"order_id": orderID, "status": orderpb.OrderStatus_SCHEDULED.String()},
+		bson.M{"$set": bson.M{
+			"status":       orderpb.OrderStatus_PENDING.String(),
+			"submitted_at": submittedAt,
+			"updated_at":   submittedAt,
+		}},
+	)
+}
+
+func (receiver *OrderRepository) find(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]models.Order, error) {
+	cursor, err := receiver.collection.Find(ctx, tenant.MongoFilter(ctx, filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []models.Order
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// TransitionStatus moves the order to the new status only if it is still in the
+// expected one. It returns false when the order has already moved on.
+func (receiver *OrderRepository) TransitionStatus(ctx context.Context, orderID string, from string, to string, reason string) (bool, error) {
+	res, err := receiver.collection.UpdateOne(ctx,
+		tenant.MongoFilter(ctx, bson.M{"order_id": orderID, "status": from}),
+		bson.M{"$set": bson.M{"status": to, "status_reason": reason, "updated_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
@@ -0,0 +1,114 @@
+package return_repository
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReturnRepository struct {
+	collection *mongo.Collection
+}
+
+type Deps struct {
+	Collection *mongo.Collection
+}
+
+func NewReturnRepository(ctx context.Context, deps Deps) (*ReturnRepository, error) {
+	if deps.Collection == nil {
+		panic("collection must not be nil on <NewReturnRepository> of <ReturnRepository>")
+	}
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "return_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	orderIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "created_at", Value: 1}},
+	}
+	sequenceIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "sequence", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"sequence": bson.M{"$exists": true}}),
+	}
+	if _, err := deps.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{indexModel, orderIndexModel, sequenceIndexModel}); err != nil {
+		return nil, err
+	}
+
+	return &ReturnRepository{
+		collection: deps.Collection,
+	}, nil
+}
+
+// Create stores the return in the tenant of the context. It returns ErrAlreadyExists
+// when the order has a return with the same sequence already.
+func (receiver *ReturnRepository) Create(ctx context.Context, ret models.Return) error {
+	if !tenant.IsAll(ctx) {
+		ret.TenantID = tenant.FromContext(ctx)
+	}
+	_, err := receiver.collection.InsertOne(ctx, ret)
+	if mongo.IsDuplicateKeyError(err) {
+		return pj_errors.ErrAlreadyExists
+	}
+	return err
+}
+
+func (receiver *ReturnRepository) Get(ctx context.Context, returnID string) (models.Return, error) {
+	var doc models.Return
+	err := receiver.collection.FindOne(ctx, tenant.MongoFilter(ctx, bson.M{"return_id": returnID})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Return{}, pj_errors.ErrNotFound
+	}
+	return doc, err
+}
+
+// ListByOrder returns the returns of the order, oldest first.
+func (receiver *ReturnRepository) ListByOrder(ctx context.Context, orderID string) ([]models.Return, error) {
+	cursor, err := receiver.collection.Find(ctx, tenant.MongoFilter(ctx, bson.M{"order_id": orderID}),
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	returns := []models.Return{}
+	if err := cursor.All(ctx, &returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// Transition moves the return to entry.Status and appends the entry to its history
+// if the return is in one of the from statuses, otherwise it returns ErrConflict.
+func (receiver *ReturnRepository) Transition(ctx context.Context, returnID string, from []string, entry models.ReturnHistoryEntry) (models.Return, error) {
+	var doc models.Return
+	res := receiver.collection.FindOneAndUpdate(ctx,
+		tenant.MongoFilter(ctx, bson.M{"return_id": returnID, "status": bson.M{"$in": from}}),
+		bson.M{
+			"$set":  bson.M{"status": entry.Status, "updated_at": entry.At},
+			"$push": bson.M{"history": entry},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	if err := res.Err(); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return models.Return{}, err
+		}
+		if _, getErr := receiver.Get(ctx, returnID); getErr == nil {
+			return models.Return{}, pj_errors.ErrConflict
+		}
+		return models.Return{}, pj_errors.ErrNotFound
+	}
+	if err := res.Decode(&doc); err != nil {
+		return models.Return{}, err
+	}
+	return doc, nil
+}
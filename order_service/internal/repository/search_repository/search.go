@@ -0,0 +1,170 @@
+package search_repository
+
+import (
+	"context"
+	"fmt"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxSearchTime bounds a search: a fragment of an order number cannot seek the
+// index and may scan all the number keys of the tenant.
+const maxSearchTime = 5 * time.Second
+
+// SearchRepository searches the order collection with Mongo indexes: a text index
+// for free-text queries and compound indexes per tenant for exact criteria.
+type SearchRepository struct {
+	collection *mongo.Collection
+}
+
+type Deps struct {
+	// Collection is the order collection.
+	Collection *mongo.Collection
+}
+
+func NewSearchRepository(ctx context.Context, deps Deps) (*SearchRepository, error) {
+	if deps.Collection == nil {
+		panic("collection must not be nil on <NewSearchRepository> of <SearchRepository>")
+	}
+
+	// В коллекции может быть только один текстовый индекс. Язык none отключает
+	// стемминг: номера, id товаров и email ищутся как есть.
+	textIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "number", Value: "text"}, {Key: "items.product_id", Value: "text"}, {Key: "email", Value: "text"}},
+		Options: options.Index().
+			SetName("order_search_text").
+			SetDefaultLanguage("none").
+			SetWeights(bson.D{{Key: "number", Value: 10}, {Key: "email", Value: 5}, {Key: "items.product_id", Value: 1}}),
+	}
+	productIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "items.product_id", Value: 1}, {Key: "created_at", Value: -1}},
+	}
+	emailIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "email", Value: 1}, {Key: "created_at", Value: -1}},
+	}
+	amountIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "total_amount", Value: 1}},
+	}
+	indexModels := []mongo.IndexModel{textIndexModel, productIndexModel, emailIndexModel, amountIndexModel}
+	if _, err := deps.Collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return nil, err
+	}
+
+	return &SearchRepository{
+		collection: deps.Collection,
+	}, nil
+}
+
+type searchFacets struct {
+	Hits []struct {
+		models.Order `bson:",inline"`
+		Score        float64 `bson:"search_score"`
+	} `bson:"hits"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+	Statuses []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	} `bson:"statuses"`
+}
+
+// Search finds the orders of the tenant of the context in one aggregation: hits,
+// their total and counts by status are facets of the same match.
+func (receiver *SearchRepository) Search(ctx context.Context, query models.OrderSearchQuery) (models.OrderSearchResult, error) {
+	match := bson.M{}
+	if query.Text != "" {
+		match["$text"] = bson.M{"$search": query.Text}
+	}
+	if query.Number != "" {
+		// Номера хранятся в верхнем регистре, поэтому регулярное выражение без (?i).
+		// Часть номера не привязана к началу, поэтому Mongo перебирает все ключи
+		// индекса (tenant_id, number) витрины, а не ищет по нему; перебор ограничен maxSearchTime.
+		match["number"] = bson.M{"$regex": regexp.QuoteMeta(strings.ToUpper(query.Number))}
+	}
+	if query.ProductID != "" {
+		match["items.product_id"] = query.ProductID
+	}
+	if query.Email != "" {
+		match["email"] = strings.ToLower(query.Email)
+	}
+	if query.MinAmount > 0 || query.MaxAmount > 0 {
+		amount := bson.M{"$gte": query.MinAmount}
+		if query.MaxAmount > 0 {
+			amount["$lte"] = query.MaxAmount
+		}
+		match["total_amount"] = amount
+	}
+	match = tenant.MongoFilter(ctx, match)
+
+	hitsMatch := bson.M{}
+	if query.Status != "" {
+		hitsMatch["status"] = query.Status
+	}
+	sort := bson.D{{Key: "created_at", Value: -1}, {Key: "order_id", Value: -1}}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	if query.Text != "" {
+		sort = append(bson.D{{Key: "search_score", Value: -1}}, sort...)
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"search_score": bson.M{"$meta": "textScore"}}}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"hits": bson.A{
+			bson.M{"$match": hitsMatch},
+			bson.M{"$sort": sort},
+			bson.M{"$skip": query.Offset},
+			bson.M{"$limit": query.Limit},
+		},
+		"total": bson.A{
+			bson.M{"$match": hitsMatch},
+			bson.M{"$count": "count"},
+		},
+		"statuses": bson.A{
+			bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+		},
+	}}})
+
+	cursor, err := receiver.collection.Aggregate(ctx, pipeline, options.Aggregate().SetMaxTime(maxSearchTime))
+	if err != nil {
+		return models.OrderSearchResult{}, searchError(err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []searchFacets
+	if err := cursor.All(ctx, &facets); err != nil {
+		return models.OrderSearchResult{}, searchError(err)
+	}
+
+	result := models.OrderSearchResult{StatusCounts: map[string]int64{}}
+	if len(facets) == 0 {
+		return result, nil
+	}
+	for _, hit := range facets[0].Hits {
+		result.Hits = append(result.Hits, models.OrderSearchHit{
+			Order:      hit.Order,
+			Highlights: query.Highlight(hit.Order),
+			Score:      hit.Score,
+		})
+	}
+	if len(facets[0].Total) > 0 {
+		result.Total = facets[0].Total[0].Count
+	}
+	for _, status := range facets[0].Statuses {
+		result.StatusCounts[status.Status] = status.Count
+	}
+	return result, nil
+}
+
+// searchError reports a search cut off by maxSearchTime as context.DeadlineExceeded.
+func searchError(err error) error {
+	if mongo.IsTimeout(err) {
+		return fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+	}
+	return err
+}
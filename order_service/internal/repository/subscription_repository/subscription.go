@@ -0,0 +1,126 @@
+package subscription_repository
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SubscriptionRepository struct {
+	collection *mongo.Collection
+}
+
+type Deps struct {
+	Collection *mongo.Collection
+}
+
+func NewSubscriptionRepository(ctx context.Context, deps Deps) (*SubscriptionRepository, error) {
+	if deps.Collection == nil {
+		panic("collection must not be nil on <NewSubscriptionRepository> of <SubscriptionRepository>")
+	}
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "subscription_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	userIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	}
+	dueIndexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_run_at", Value: 1}},
+	}
+	pendingIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "pending_order_id", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+	if _, err := deps.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{indexModel, userIndexModel, dueIndexModel, pendingIndexModel}); err != nil {
+		return nil, err
+	}
+
+	return &SubscriptionRepository{
+		collection: deps.Collection,
+	}, nil
+}
+
+// Create stores the subscription in the tenant of the context.
+func (receiver *SubscriptionRepository) Create(ctx context.Context, subscription models.Subscription) error {
+	if !tenant.IsAll(ctx) {
+		subscription.TenantID = tenant.FromContext(ctx)
+	}
+	_, err := receiver.collection.InsertOne(ctx, subscription)
+	return err
+}
+
+func (receiver *SubscriptionRepository) Get(ctx context.Context, subscriptionID string) (models.Subscription, error) {
+	var doc models.Subscription
+	err := receiver.collection.FindOne(ctx, tenant.MongoFilter(ctx, bson.M{"subscription_id": subscriptionID})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Subscription{}, pj_errors.ErrNotFound
+	}
+	return doc, err
+}
+
+// ListByUser returns the subscriptions of the user, newest first.
+func (receiver *SubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]models.Subscription, error) {
+	return receiver.find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+}
+
+// ListDue returns schedulable subscriptions without an unpaid order whose next run
+// is not after now, most overdue first.
+func (receiver *SubscriptionRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	return receiver.find(ctx, bson.M{
+		"status":           bson.M{"$in": models.SchedulableSubscriptionStatuses},
+		"pending_order_id": bson.M{"$exists": false},
+		"next_run_at":      bson.M{"$lte": now},
+	}, options.Find().SetSort(bson.D{{Key: "next_run_at", Value: 1}}).SetLimit(int64(limit)))
+}
+
+// ListAwaitingPayment returns subscriptions whose last order is not paid yet.
+func (receiver *SubscriptionRepository) ListAwaitingPayment(ctx context.Context, limit int) ([]models.Subscription, error) {
+	return receiver.find(ctx, bson.M{"pending_order_id": bson.M{"$exists": true}},
+		options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}).SetLimit(int64(limit)),
+	)
+}
+
+// Replace stores the subscription if it still has the expected version and bumps
+// the version. ErrConflict means the subscription was changed meanwhile.
+func (receiver *SubscriptionRepository) Replace(ctx context.Context, subscription models.Subscription, expectedVersion int64) (models.Subscription, error) {
+	subscription.Version = expectedVersion + 1
+	res, err := receiver.collection.ReplaceOne(ctx,
+		tenant.MongoFilter(ctx, bson.M{"subscription_id": subscription.SubscriptionID, "version": expectedVersion}),
+		subscription,
+	)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	if res.MatchedCount == 0 {
+		if _, getErr := receiver.Get(ctx, subscription.SubscriptionID); getErr == nil {
+			return models.Subscription{}, pj_errors.ErrConflict
+		}
+		return models.Subscription{}, pj_errors.ErrNotFound
+	}
+	return subscription, nil
+}
+
+func (receiver *SubscriptionRepository) find(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]models.Subscription, error) {
+	cursor, err := receiver.collection.Find(ctx, tenant.MongoFilter(ctx, filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	subscriptions := []models.Subscription{}
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
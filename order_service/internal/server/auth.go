@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"order-service-system/common/auth"
+	"order-service-system/proto/order"
+	"slices"
+
+	"google.golang.org/grpc"
+)
+
+// authorizationInterceptor restricts methods that change order state on behalf of
+// the payment, fulfilment and returns flows, the search across users and the
+// analytics, to the configured service identities. Ownership of orders and returns is checked in the
+// service layer.
+func authorizationInterceptor(statusUpdaters []string, fulfilmentServices []string, returnManagers []string, supportServices []string, analyticsServices []string) grpc.UnaryServerInterceptor {
+	restricted := map[string][]string{
+		order.OrderService_UpdateOrderStatus_FullMethodName:     append(slices.Clone(statusUpdaters), fulfilmentServices...),
+		order.OrderService_MarkShipped_FullMethodName:           fulfilmentServices,
+		order.OrderService_ApproveReturn_FullMethodName:         returnManagers,
+		order.OrderService_RejectReturn_FullMethodName:          returnManagers,
+		order.OrderService_MarkReturnReceived_FullMethodName:    fulfilmentServices,
+		order.OrderService_RecordRefund_FullMethodName:          statusUpdaters,
+		order.OrderService_SearchOrders_FullMethodName:          supportServices,
+		order.AnalyticsService_GetOrderAnalytics_FullMethodName: analyticsServices,
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if services, ok := restricted[info.FullMethod]; ok {
+			if err := auth.RequireService(ctx, services...); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
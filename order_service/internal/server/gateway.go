@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"order-service-system/common/tenant"
+	"order-service-system/proto/openapi"
+	"order-service-system/proto/order"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type DepsGateway struct {
+	Logger  *zap.Logger
+	GrpcURL string
+	// TLS must be set when the gRPC server listens with TLS. It must not present a
+	// client certificate, otherwise REST callers would act as the gateway's service.
+	TLS *tls.Config
+}
+
+// Gateway translates REST/JSON requests into gRPC calls. It goes through the gRPC
+// server over loopback, so every interceptor applies to REST traffic as well.
+type Gateway struct {
+	logger     *zap.Logger
+	mux        *runtime.ServeMux
+	connection *grpc.ClientConn
+}
+
+// ErrorBody is the JSON body of every failed REST request.
+type ErrorBody struct {
+	Error ErrorStatus `json:"error"`
+}
+
+type ErrorStatus struct {
+	Code    int               `json:"code"`
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+func NewGateway(ctx context.Context, deps DepsGateway) (*Gateway, error) {
+	if deps.Logger == nil {
+		return nil, errors.New("logger is nil on <NewGateway>")
+	}
+
+	endpoint, err := loopbackAddress(deps.GrpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("resolve grpc address: %w", err)
+	}
+
+	transport := insecure.NewCredentials()
+	if deps.TLS != nil {
+		transport = credentials.NewTLS(deps.TLS)
+	}
+
+	connection, err := grpc.NewClient(endpoint,
+		grpc.WithTransportCredentials(transport),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create gateway connection: %w", err)
+	}
+
+	gateway := &Gateway{
+		logger:     deps.Logger,
+		connection: connection,
+	}
+	gateway.mux = runtime.NewServeMux(
+		runtime.WithErrorHandler(gateway.handleError),
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+	)
+
+	if err := order.RegisterOrderServiceHandler(ctx, gateway.mux, connection); err != nil {
+		_ = connection.Close()
+		return nil, fmt.Errorf("register order service handler: %w", err)
+	}
+	if err := order.RegisterCartServiceHandler(ctx, gateway.mux, connection); err != nil {
+		_ = connection.Close()
+		return nil, fmt.Errorf("register cart service handler: %w", err)
+	}
+	if err := order.RegisterSubscriptionServiceHandler(ctx, gateway.mux, connection); err != nil {
+		_ = connection.Close()
+		return nil, fmt.Errorf("register subscription service handler: %w", err)
+	}
+	if err := order.RegisterAnalyticsServiceHandler(ctx, gateway.mux, connection); err != nil {
+		_ = connection.Close()
+		return nil, fmt.Errorf("register analytics service handler: %w", err)
+	}
+	return gateway, nil
+}
+
+func (receiver *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.mux.ServeHTTP(w, r)
+}
+
+// OpenAPIHandler serves the OpenAPI document of the REST API.
+func (receiver *Gateway) OpenAPIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openapi.OrderService)
+	})
+}
+
+func (receiver *Gateway) Close(_ context.Context) error {
+	return receiver.connection.Close()
+}
+
+func (receiver *Gateway) handleError(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	httpStatus := runtime.HTTPStatusFromCode(st.Code())
+
+	body := ErrorBody{Error: ErrorStatus{
+		Code:    httpStatus,
+		Status:  code.Code_name[int32(st.Code())],
+		Message: st.Message(),
+	}}
+	for _, detail := range st.Details() {
+		if retry, ok := detail.(*errdetails.RetryInfo); ok {
+			seconds := int64(math.Ceil(retry.GetRetryDelay().AsDuration().Seconds()))
+			w.Header().Set("Retry-After", strconv.FormatInt(max(seconds, 1), 10))
+		}
+	}
+	for _, detail := range st.Proto().GetDetails() {
+		raw, marshalErr := protojson.Marshal(detail)
+		if marshalErr != nil {
+			continue
+		}
+		body.Error.Details = append(body.Error.Details, raw)
+	}
+
+	if httpStatus >= http.StatusInternalServerError {
+		receiver.logger.Error("request failed on <handleError> of <Gateway>", zap.String("path", r.URL.Path), zap.Error(err))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// headerMatcher passes the X-Tenant-Id header as is, in addition to the headers
+// forwarded by default.
+func headerMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, tenant.MetadataKey) {
+		return tenant.MetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// loopbackAddress turns a listen address such as ":50051" or "0.0.0.0:50051"
+// into an address the gateway can dial.
+func loopbackAddress(listenAddr string) (string, error) {
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
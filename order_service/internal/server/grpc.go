@@ -2,8 +2,13 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net"
+	"order-service-system/common/auth"
+	"order-service-system/common/metrics"
+	"order-service-system/common/ratelimit"
+	"order-service-system/common/tenant"
 	"order-service-system/order_service/internal/initialize"
 	"order-service-system/proto/order"
 	"time"
@@ -11,17 +16,35 @@ import (
 	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpczap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	grpcrecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type DepsGRPC struct {
 	Logger *zap.Logger
+	// Verifier is nil when authentication is disabled.
+	Verifier           *auth.Verifier
+	StatusUpdaters     []string
+	FulfilmentServices []string
+	ReturnManagers     []string
+	SupportServices    []string
+	AnalyticsServices  []string
+	// TLS is nil when the server listens in plaintext.
+	TLS *tls.Config
+	// RateLimiter is nil when rate limiting is disabled.
+	RateLimiter *ratelimit.Limiter
+	// KnownTenant rejects calls of unknown tenants; nil accepts any tenant.
+	KnownTenant func(id string) bool
 }
 
 type GRPC struct {
 	logger *zap.Logger
 	grpc   *grpc.Server
+	health *grpchealth.Server
 }
 
 func NewGRPC(deps DepsGRPC) (*GRPC, error) {
@@ -29,18 +52,60 @@ func NewGRPC(deps DepsGRPC) (*GRPC, error) {
 		return nil, errors.New("logger is nil on <NewGRPC>")
 	}
 
-	grpcStreamInterceptor := grpc.StreamInterceptor(grpcmiddleware.ChainStreamServer(
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		metrics.StreamServerInterceptor(),
 		grpczap.StreamServerInterceptor(deps.Logger),
 		grpcrecovery.StreamServerInterceptor(),
-	))
-
-	grpcUnaryInterceptor := grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(
+	}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		metrics.UnaryServerInterceptor(),
 		grpczap.UnaryServerInterceptor(deps.Logger),
 		grpcrecovery.UnaryServerInterceptor(),
-	))
+	}
+	if deps.TLS != nil {
+		streamInterceptors = append(streamInterceptors, auth.StreamPeerInterceptor())
+		unaryInterceptors = append(unaryInterceptors, auth.UnaryPeerInterceptor())
+	}
+	if deps.Verifier != nil {
+		streamInterceptors = append(streamInterceptors, auth.StreamServerInterceptor(deps.Verifier))
+		unaryInterceptors = append(unaryInterceptors, auth.UnaryServerInterceptor(deps.Verifier))
+	} else if deps.TLS != nil {
+		// Без JWT вызывающего опознаёт только сертификат; анонимный вызов получил бы доступ ко всем заказам.
+		streamInterceptors = append(streamInterceptors, auth.StreamRequirePrincipalInterceptor())
+		unaryInterceptors = append(unaryInterceptors, auth.UnaryRequirePrincipalInterceptor())
+	}
+	streamInterceptors = append(streamInterceptors, tenant.StreamServerInterceptor(deps.KnownTenant))
+	unaryInterceptors = append(unaryInterceptors, tenant.UnaryServerInterceptor(deps.KnownTenant))
+	if deps.TLS != nil || deps.Verifier != nil {
+		unaryInterceptors = append(unaryInterceptors, authorizationInterceptor(deps.StatusUpdaters, deps.FulfilmentServices, deps.ReturnManagers, deps.SupportServices, deps.AnalyticsServices))
+	}
+	if deps.RateLimiter != nil {
+		unaryInterceptors = append(unaryInterceptors, ratelimit.UnaryServerInterceptor(deps.RateLimiter))
+	}
+
+	grpcStreamInterceptor := grpc.StreamInterceptor(grpcmiddleware.ChainStreamServer(streamInterceptors...))
+	grpcUnaryInterceptor := grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(unaryInterceptors...))
+
+	options := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpcStreamInterceptor,
+		grpcUnaryInterceptor,
+		grpc.ConnectionTimeout(5 * time.Second),
+	}
+	if deps.TLS != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(deps.TLS)))
+	}
+
+	grpcServer := grpc.NewServer(options...)
+
+	// Пока зависимости не проверены, сервер считается не готовым.
+	healthServer := grpchealth.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
 
 	return &GRPC{
-		grpc:   grpc.NewServer(grpcStreamInterceptor, grpcUnaryInterceptor, grpc.ConnectionTimeout(5*time.Second)),
+		grpc:   grpcServer,
+		health: healthServer,
 		logger: deps.Logger,
 	}, nil
 }
@@ -55,7 +120,24 @@ func (receiver *GRPC) Run(addr string) error {
 	return nil
 }
 
+// SetServing updates the grpc.health.v1 status of the server and of every registered service.
+func (receiver *GRPC) SetServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	receiver.health.SetServingStatus("", status)
+	for name := range receiver.grpc.GetServiceInfo() {
+		if name != healthpb.Health_ServiceDesc.ServiceName {
+			receiver.health.SetServingStatus(name, status)
+		}
+	}
+	receiver.logger.Info("serving status changed on <SetServing> of <GRPC>", zap.String("status", status.String()))
+}
+
 func (receiver *GRPC) Stop(_ context.Context) error {
+	receiver.health.Shutdown()
 	receiver.grpc.GracefulStop()
 	receiver.logger.Info("shutting down grpc server on <Stop> of <GRPC>")
 
@@ -64,15 +146,23 @@ func (receiver *GRPC) Stop(_ context.Context) error {
 
 func (receiver *GRPC) Register(controllers *initialize.RpcControllers) *GRPC {
 	order.RegisterOrderServiceServer(receiver.grpc, controllers.OrderController)
+	order.RegisterCartServiceServer(receiver.grpc, controllers.CartController)
+	order.RegisterSubscriptionServiceServer(receiver.grpc, controllers.SubscriptionController)
+	order.RegisterAnalyticsServiceServer(receiver.grpc, controllers.AnalyticsController)
 	// another...
 	return receiver
 }
 
+// Serve accepts connections on an existing listener, e.g. in tests.
+func (receiver *GRPC) Serve(listener net.Listener) error {
+	return receiver.grpc.Serve(listener)
+}
+
 func (receiver *GRPC) listen(address string) error {
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		return err
 	}
 
-	return receiver.grpc.Serve(listener)
+	return receiver.Serve(listener)
 }
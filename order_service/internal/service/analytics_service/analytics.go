@@ -0,0 +1,187 @@
+package analytics_service
+
+import (
+	"context"
+	"order-service-system/order_service/internal/models"
+	orderpb "order-service-system/proto/order"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultUserLimit = 20
+	maxUserLimit     = 100
+	day              = 24 * time.Hour
+)
+
+// Rollups are small, so they may be read for long ranges; status and user buckets
+// scan the orders and are limited harder.
+var maxRange = map[string]time.Duration{
+	models.AnalyticsByDay:    366 * day,
+	models.AnalyticsByWeek:   2 * 366 * day,
+	models.AnalyticsByStatus: 92 * day,
+	models.AnalyticsByUser:   92 * day,
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type AnalyticsService struct {
+	logger  *zap.Logger
+	backend Backend
+}
+
+type Deps struct {
+	Logger  *zap.Logger
+	Backend Backend
+}
+
+// Backend aggregates in the tenant of the context: day and week buckets from the
+// daily rollups, status and user buckets from the orders.
+type Backend interface {
+	AggregateRollups(ctx context.Context, query models.AnalyticsQuery) (models.AnalyticsResult, error)
+	AggregateOrders(ctx context.Context, query models.AnalyticsQuery) (models.AnalyticsResult, error)
+}
+
+func NewAnalyticsService(deps Deps) *AnalyticsService {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewAnalyticsService> of <AnalyticsService>")
+	}
+	if deps.Backend == nil {
+		panic("backend must not be nil on <NewAnalyticsService> of <AnalyticsService>")
+	}
+	return &AnalyticsService{
+		logger:  deps.Logger,
+		backend: deps.Backend,
+	}
+}
+
+func (receiver *AnalyticsService) GetOrderAnalytics(ctx context.Context, req *orderpb.GetOrderAnalyticsRequest) (*orderpb.GetOrderAnalyticsResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	query, err := queryFromProto(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.AnalyticsResult
+	switch query.GroupBy {
+	case models.AnalyticsByDay, models.AnalyticsByWeek:
+		result, err = receiver.backend.AggregateRollups(ctx, query)
+	default:
+		result, err = receiver.backend.AggregateOrders(ctx, query)
+	}
+	if err != nil {
+		receiver.logger.Error("failed to aggregate order analytics on <GetOrderAnalytics> of <AnalyticsService>",
+			zap.String("group_by", query.GroupBy), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to aggregate order analytics: %v", err)
+	}
+
+	response := &orderpb.GetOrderAnalyticsResponse{}
+	for _, bucket := range result.Buckets {
+		response.Buckets = append(response.Buckets, bucketToProto(bucket))
+	}
+	if query.GroupBy == models.AnalyticsByStatus {
+		// Статусы идут в порядке enum в proto, а не по алфавиту.
+		slices.SortStableFunc(response.Buckets, func(a, b *orderpb.OrderAnalyticsBucket) int {
+			return int(a.Status) - int(b.Status)
+		})
+	}
+	for _, total := range result.Totals {
+		response.Totals = append(response.Totals, bucketToProto(total))
+	}
+	return response, nil
+}
+
+func queryFromProto(req *orderpb.GetOrderAnalyticsRequest) (models.AnalyticsQuery, error) {
+	if req.From == nil || req.To == nil {
+		return models.AnalyticsQuery{}, status.Error(codes.InvalidArgument, "from and to are required")
+	}
+	if err := req.From.CheckValid(); err != nil {
+		return models.AnalyticsQuery{}, status.Error(codes.InvalidArgument, "invalid from")
+	}
+	if err := req.To.CheckValid(); err != nil {
+		return models.AnalyticsQuery{}, status.Error(codes.InvalidArgument, "invalid to")
+	}
+	query := models.AnalyticsQuery{
+		From:     req.From.AsTime(),
+		To:       req.To.AsTime(),
+		Currency: strings.ToUpper(strings.TrimSpace(req.Currency)),
+		Limit:    int(req.Limit),
+	}
+	if !query.To.After(query.From) {
+		return models.AnalyticsQuery{}, status.Error(codes.InvalidArgument, "to must be after from")
+	}
+	if query.Currency != "" && !currencyPattern.MatchString(query.Currency) {
+		return models.AnalyticsQuery{}, status.Error(codes.InvalidArgument, "currency must be an ISO 4217 code")
+	}
+
+	switch req.GroupBy {
+	case orderpb.AnalyticsGroupBy_GROUP_BY_DAY:
+		query.GroupBy = models.AnalyticsByDay
+		query.From, query.To = models.DayOf(query.From), ceil(query.To, models.DayOf, day)
+	case orderpb.AnalyticsGroupBy_GROUP_BY_WEEK:
+		query.GroupBy = models.AnalyticsByWeek
+		query.From, query.To = models.WeekOf(query.From), ceil(query.To, models.WeekOf, 7*day)
+	case orderpb.AnalyticsGroupBy_GROUP_BY_STATUS:
+		query.GroupBy = models.AnalyticsByStatus
+	case orderpb.AnalyticsGroupBy_GROUP_BY_USER:
+		query.GroupBy = models.AnalyticsByUser
+	default:
+		return models.AnalyticsQuery{}, status.Error(codes.InvalidArgument, "group_by is required")
+	}
+	if req.To.AsTime().Sub(req.From.AsTime()) > maxRange[query.GroupBy] {
+		return models.AnalyticsQuery{}, status.Errorf(codes.InvalidArgument, "range must be at most %d days for %s buckets",
+			int(maxRange[query.GroupBy]/day), query.GroupBy)
+	}
+
+	if query.Limit < 0 {
+		return models.AnalyticsQuery{}, status.Error(codes.InvalidArgument, "limit must be non-negative")
+	}
+	if query.GroupBy != models.AnalyticsByUser {
+		query.Limit = 0
+	} else if query.Limit == 0 {
+		query.Limit = defaultUserLimit
+	} else if query.Limit > maxUserLimit {
+		query.Limit = maxUserLimit
+	}
+	return query, nil
+}
+
+// ceil rounds t up to the start of the next period unless it is at a period start already.
+func ceil(t time.Time, floor func(time.Time) time.Time, period time.Duration) time.Time {
+	start := floor(t)
+	if start.Equal(t) {
+		return start
+	}
+	return start.Add(period)
+}
+
+func bucketToProto(bucket models.AnalyticsBucket) *orderpb.OrderAnalyticsBucket {
+	result := &orderpb.OrderAnalyticsBucket{
+		UserId:   bucket.UserID,
+		Currency: bucket.Currency,
+		Metrics: &orderpb.OrderMetrics{
+			OrdersCount:        bucket.Metrics.OrdersCount,
+			GrossRevenue:       bucket.Metrics.GrossRevenue,
+			PaidOrders:         bucket.Metrics.PaidOrders,
+			FailedPayments:     bucket.Metrics.FailedPayments,
+			PaymentFailureRate: bucket.Metrics.PaymentFailureRate(),
+			AverageOrderValue:  bucket.Metrics.AverageOrderValue(),
+		},
+	}
+	if !bucket.PeriodStart.IsZero() {
+		result.PeriodStart = timestamppb.New(bucket.PeriodStart)
+	}
+	if bucket.Status != "" {
+		result.Status = orderpb.OrderStatus(orderpb.OrderStatus_value[bucket.Status])
+	}
+	return result
+}
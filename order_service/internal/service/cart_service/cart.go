@@ -0,0 +1,387 @@
+package cart_service
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/auth"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/utils"
+	orderpb "order-service-system/proto/order"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxChangeAttempts bounds the retries of a cart change that lost a race with
+// another change of the same cart.
+const maxChangeAttempts = 3
+
+type Configuration struct {
+	// TTL is how long a cart is kept after its last change.
+	TTL time.Duration `env:"CART_TTL" envDefault:"72h"`
+	// PromoCodes are CODE:PERCENT% or CODE:AMOUNT, e.g. WELCOME10:10%,MINUS5:5.
+	PromoCodes []string `env:"CART_PROMO_CODES" envSeparator:","`
+}
+
+type CartService struct {
+	logger   *zap.Logger
+	cartRepo CartRepository
+	orders   OrderCreator
+	promos   map[string]models.Promo
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+type Deps struct {
+	Logger   *zap.Logger
+	CartRepo CartRepository
+	Orders   OrderCreator
+	// Promos are the result of ParsePromoCodes.
+	Promos map[string]models.Promo
+	TTL    time.Duration
+	// Now is time.Now when nil.
+	Now func() time.Time
+}
+
+type CartRepository interface {
+	Create(ctx context.Context, cart models.Cart) error
+	Get(ctx context.Context, cartID string) (models.Cart, error)
+	Save(ctx context.Context, cart models.Cart, expectedVersion int64) (models.Cart, error)
+	StartCheckout(ctx context.Context, cartID string, expectedVersion int64, orderID string, address *models.ShippingAddress, expiresAt time.Time) (models.Cart, error)
+	ReleaseCheckout(ctx context.Context, cartID string, orderID string) (models.Cart, error)
+	CompleteCheckout(ctx context.Context, cartID string, orderID string) (models.Cart, error)
+}
+
+// OrderCreator is the OrderService; checkout creates orders only through it.
+type OrderCreator interface {
+	CreateOrderFrom(ctx context.Context, req *orderpb.CreateOrderRequest, origin models.OrderOrigin) (*orderpb.Order, error)
+	GetOrder(ctx context.Context, orderID string) (*orderpb.Order, error)
+}
+
+func NewCartService(deps Deps) *CartService {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewCartService> of <CartService>")
+	}
+	if deps.CartRepo == nil {
+		panic("cart repo must not be nil on <NewCartService> of <CartService>")
+	}
+	if deps.Orders == nil {
+		panic("orders must not be nil on <NewCartService> of <CartService>")
+	}
+	ttl := deps.TTL
+	if ttl <= 0 {
+		ttl = 72 * time.Hour
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &CartService{
+		logger:   deps.Logger,
+		cartRepo: deps.CartRepo,
+		orders:   deps.Orders,
+		promos:   deps.Promos,
+		ttl:      ttl,
+		now:      now,
+	}
+}
+
+func (receiver *CartService) CreateCart(ctx context.Context, req *orderpb.CreateCartRequest) (*orderpb.Cart, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	userID, err := ownerFor(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	var items []models.OrderItem
+	for _, item := range req.Items {
+		if items, err = addItem(items, item); err != nil {
+			return nil, err
+		}
+	}
+
+	now := receiver.now().UTC()
+	doc := models.Cart{
+		CartID:    uuid.NewString(),
+		UserID:    userID,
+		Items:     items,
+		Status:    orderpb.CartStatus_CART_ACTIVE.String(),
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(receiver.ttl),
+	}
+	if err := receiver.cartRepo.Create(ctx, doc); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to persist cart: %v", err)
+	}
+	return receiver.toProto(doc), nil
+}
+
+func (receiver *CartService) GetCart(ctx context.Context, cartID string) (*orderpb.Cart, error) {
+	doc, err := receiver.load(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	return receiver.toProto(doc), nil
+}
+
+func (receiver *CartService) AddCartItem(ctx context.Context, cartID string, item *orderpb.OrderItem) (*orderpb.Cart, error) {
+	if item == nil {
+		return nil, status.Error(codes.InvalidArgument, "item is required")
+	}
+	return receiver.change(ctx, cartID, func(cart *models.Cart) error {
+		items, err := addItem(cart.Items, item)
+		cart.Items = items
+		return err
+	})
+}
+
+func (receiver *CartService) UpdateCartItem(ctx context.Context, cartID string, productID string, quantity int32) (*orderpb.Cart, error) {
+	if productID == "" {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	if quantity < 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must not be negative")
+	}
+	return receiver.change(ctx, cartID, func(cart *models.Cart) error {
+		index := slices.IndexFunc(cart.Items, func(item models.OrderItem) bool {
+			return item.ProductID == productID
+		})
+		if index < 0 {
+			return status.Errorf(codes.InvalidArgument, "product %q is not in the cart", productID)
+		}
+		if quantity == 0 {
+			cart.Items = slices.Delete(cart.Items, index, index+1)
+			return nil
+		}
+		cart.Items[index].Quantity = quantity
+		return nil
+	})
+}
+
+// RemoveCartItem does nothing if the product is not in the cart.
+func (receiver *CartService) RemoveCartItem(ctx context.Context, cartID string, productID string) (*orderpb.Cart, error) {
+	if productID == "" {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	return receiver.change(ctx, cartID, func(cart *models.Cart) error {
+		cart.Items = slices.DeleteFunc(cart.Items, func(item models.OrderItem) bool {
+			return item.ProductID == productID
+		})
+		return nil
+	})
+}
+
+func (receiver *CartService) ApplyPromo(ctx context.Context, cartID string, promoCode string) (*orderpb.Cart, error) {
+	code := normalizeCode(promoCode)
+	if _, ok := receiver.promos[code]; code != "" && !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown promo code %q", promoCode)
+	}
+	return receiver.change(ctx, cartID, func(cart *models.Cart) error {
+		cart.PromoCode = code
+		return nil
+	})
+}
+
+// Checkout creates the order of the cart in three steps that can all be repeated:
+// the cart is frozen with a new order id, the order is created with this id and the
+// cart is marked as checked out. A repeated call continues from the step where the
+// previous one stopped, so the cart never produces a second order.
+func (receiver *CartService) Checkout(ctx context.Context, req *orderpb.CheckoutRequest) (*orderpb.Order, *orderpb.Cart, error) {
+	if req == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	cart, err := receiver.load(ctx, req.CartId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch cart.Status {
+	case orderpb.CartStatus_CART_CHECKED_OUT.String():
+		order, err := receiver.orders.GetOrder(ctx, cart.OrderID)
+		if err != nil {
+			return nil, nil, err
+		}
+		checkoutsTotal.WithLabelValues(resultRepeated).Inc()
+		return order, receiver.toProto(cart), nil
+	case orderpb.CartStatus_CART_ACTIVE.String():
+		if len(cart.Items) == 0 {
+			return nil, nil, status.Error(codes.FailedPrecondition, "cart is empty")
+		}
+		cart, err = receiver.cartRepo.StartCheckout(ctx, cart.CartID, cart.Version, uuid.NewString(),
+			utils.ShippingAddressFromProto(req.ShippingAddress), receiver.now().UTC().Add(receiver.ttl))
+		if errors.Is(err, pj_errors.ErrConflict) {
+			return nil, nil, status.Error(codes.Aborted, "cart was changed concurrently, retry the checkout")
+		}
+		if err != nil {
+			return nil, nil, cartError(err)
+		}
+	}
+
+	promo := receiver.promo(cart)
+	totals := price(cart.Items, promo)
+	origin := models.OrderOrigin{
+		OrderID:  cart.OrderID,
+		CartID:   cart.CartID,
+		Discount: totals.Discount,
+	}
+	if promo != nil {
+		origin.PromoCode = promo.Code
+	}
+	items := make([]*orderpb.OrderItem, 0, len(totals.Items))
+	for _, item := range totals.Items {
+		items = append(items, &orderpb.OrderItem{ProductId: item.ProductID, Quantity: item.Quantity, Price: item.Price})
+	}
+
+	order, err := receiver.orders.CreateOrderFrom(ctx, &orderpb.CreateOrderRequest{
+		UserId:          cart.UserID,
+		Items:           items,
+		ShippingAddress: utils.ConvertShippingAddressToProto(cart.ShippingAddress),
+	}, origin)
+	if err != nil {
+		// Заказ отклонён (например, неверный адрес), корзину снова можно менять.
+		if status.Code(err) == codes.InvalidArgument {
+			if _, releaseErr := receiver.cartRepo.ReleaseCheckout(ctx, cart.CartID, cart.OrderID); releaseErr != nil {
+				receiver.logger.Warn("failed to release cart on <Checkout> of <CartService>",
+					zap.String("cart_id", cart.CartID),
+					zap.Error(releaseErr),
+				)
+			}
+			checkoutsTotal.WithLabelValues(resultRejected).Inc()
+		}
+		return nil, nil, err
+	}
+
+	completed, err := receiver.cartRepo.CompleteCheckout(ctx, cart.CartID, cart.OrderID)
+	if errors.Is(err, pj_errors.ErrConflict) {
+		// Параллельный checkout той же корзины успел первым.
+		completed, err = receiver.cartRepo.Get(ctx, cart.CartID)
+	}
+	if err != nil {
+		return nil, nil, cartError(err)
+	}
+	checkoutsTotal.WithLabelValues(resultCreated).Inc()
+	return order, receiver.toProto(completed), nil
+}
+
+// change applies fn to an active cart and stores it with a new expiry. A change that
+// lost the race with another one is applied again to the fresh cart.
+func (receiver *CartService) change(ctx context.Context, cartID string, fn func(cart *models.Cart) error) (*orderpb.Cart, error) {
+	for attempt := 1; ; attempt++ {
+		cart, err := receiver.load(ctx, cartID)
+		if err != nil {
+			return nil, err
+		}
+		if cart.Status != orderpb.CartStatus_CART_ACTIVE.String() {
+			return nil, status.Error(codes.FailedPrecondition, "cart is checked out and cannot be changed")
+		}
+
+		expectedVersion := cart.Version
+		if err := fn(&cart); err != nil {
+			return nil, err
+		}
+		now := receiver.now().UTC()
+		cart.UpdatedAt = now
+		cart.ExpiresAt = now.Add(receiver.ttl)
+
+		saved, err := receiver.cartRepo.Save(ctx, cart, expectedVersion)
+		if errors.Is(err, pj_errors.ErrConflict) && attempt < maxChangeAttempts {
+			continue
+		}
+		if errors.Is(err, pj_errors.ErrConflict) {
+			return nil, status.Error(codes.Aborted, "cart was changed concurrently, retry")
+		}
+		if err != nil {
+			return nil, cartError(err)
+		}
+		return receiver.toProto(saved), nil
+	}
+}
+
+func (receiver *CartService) load(ctx context.Context, cartID string) (models.Cart, error) {
+	if cartID == "" {
+		return models.Cart{}, status.Error(codes.InvalidArgument, "cart_id is required")
+	}
+	doc, err := receiver.cartRepo.Get(ctx, cartID)
+	if err != nil {
+		return models.Cart{}, cartError(err)
+	}
+	// Чужая корзина неотличима от несуществующей.
+	if !canAccess(ctx, doc.UserID) {
+		return models.Cart{}, status.Error(codes.NotFound, "cart not found")
+	}
+	return doc, nil
+}
+
+// promo returns nil when the cart has no promo code or the code was withdrawn.
+func (receiver *CartService) promo(cart models.Cart) *models.Promo {
+	promo, ok := receiver.promos[cart.PromoCode]
+	if cart.PromoCode == "" || !ok {
+		return nil
+	}
+	return &promo
+}
+
+func (receiver *CartService) toProto(cart models.Cart) *orderpb.Cart {
+	return utils.ConvertCartToProto(cart, price(cart.Items, receiver.promo(cart)))
+}
+
+// addItem adds the quantity to the line of the product; the price of the line is
+// replaced by the latest one.
+func addItem(items []models.OrderItem, item *orderpb.OrderItem) ([]models.OrderItem, error) {
+	if item.ProductId == "" {
+		return items, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	if item.Quantity <= 0 {
+		return items, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+	if item.Price < 0 {
+		return items, status.Error(codes.InvalidArgument, "price must be non-negative")
+	}
+	index := slices.IndexFunc(items, func(line models.OrderItem) bool {
+		return line.ProductID == item.ProductId
+	})
+	if index < 0 {
+		return append(items, models.OrderItem{
+			ProductID: item.ProductId,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		}), nil
+	}
+	items[index].Quantity += item.Quantity
+	items[index].Price = item.Price
+	return items, nil
+}
+
+func cartError(err error) error {
+	if errors.Is(err, pj_errors.ErrNotFound) {
+		return status.Error(codes.NotFound, "cart not found")
+	}
+	return status.Errorf(codes.Internal, "failed to update cart: %v", err)
+}
+
+func ownerFor(ctx context.Context, requestedUserID string) (string, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || principal.IsService() {
+		return requestedUserID, nil
+	}
+	if requestedUserID != "" && requestedUserID != principal.Subject {
+		return "", status.Error(codes.PermissionDenied, "carts of another user are not accessible")
+	}
+	return principal.Subject, nil
+}
+
+func canAccess(ctx context.Context, ownerID string) bool {
+	principal, ok := auth.PrincipalFrom(ctx)
+	return !ok || principal.IsService() || principal.Subject == ownerID
+}
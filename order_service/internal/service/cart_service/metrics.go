@@ -0,0 +1,17 @@
+package cart_service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	resultCreated  = "created"
+	resultRepeated = "repeated"
+	resultRejected = "rejected"
+)
+
+var checkoutsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_cart_checkouts_total",
+	Help: "Cart checkouts by result.",
+}, []string{"result"})
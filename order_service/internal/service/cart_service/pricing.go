@@ -0,0 +1,94 @@
+package cart_service
+
+import (
+	"fmt"
+	"math"
+	"order-service-system/order_service/internal/models"
+	"strconv"
+	"strings"
+)
+
+// ParsePromoCodes reads promo codes in the form CODE:10% (percent of the subtotal) or
+// CODE:5 (fixed amount). Codes are case-insensitive.
+func ParsePromoCodes(specs []string) (map[string]models.Promo, error) {
+	promos := make(map[string]models.Promo, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		code, value, ok := strings.Cut(spec, ":")
+		code = normalizeCode(code)
+		if !ok || code == "" {
+			return nil, fmt.Errorf("promo %q: expected CODE:VALUE", spec)
+		}
+		if _, exists := promos[code]; exists {
+			return nil, fmt.Errorf("promo %q is listed twice", code)
+		}
+
+		promo := models.Promo{Code: code}
+		percent := strings.HasSuffix(value, "%")
+		number, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("promo %q: %w", code, err)
+		}
+		switch {
+		case number <= 0:
+			return nil, fmt.Errorf("promo %q: discount must be positive", code)
+		case percent && number > 100:
+			return nil, fmt.Errorf("promo %q: percent must not exceed 100", code)
+		case percent:
+			promo.Percent = number
+		default:
+			promo.Amount = number
+		}
+		promos[code] = promo
+	}
+	return promos, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// price spreads the discount of the promo over the item prices in proportion to
+// them, so that an order created from the items keeps the discount through
+// amendments, cancellations and returns.
+func price(items []models.OrderItem, promo *models.Promo) models.CartTotals {
+	var subtotal float64
+	for _, item := range items {
+		subtotal += float64(item.Quantity) * item.Price
+	}
+	subtotal = roundCents(subtotal)
+
+	var discount float64
+	if promo != nil {
+		discount = promo.Amount
+		if promo.Percent > 0 {
+			discount = subtotal * promo.Percent / 100
+		}
+		discount = min(discount, subtotal)
+	}
+	factor := 1.0
+	if subtotal > 0 {
+		factor = (subtotal - discount) / subtotal
+	}
+
+	totals := models.CartTotals{
+		Items:    make([]models.OrderItem, 0, len(items)),
+		Subtotal: subtotal,
+	}
+	for _, item := range items {
+		item.Price = roundCents(item.Price * factor)
+		totals.Items = append(totals.Items, item)
+		totals.Total += float64(item.Quantity) * item.Price
+	}
+	totals.Total = roundCents(totals.Total)
+	// После округления цен скидка может отличаться от заявленной на копейки.
+	totals.Discount = roundCents(subtotal - totals.Total)
+	return totals
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
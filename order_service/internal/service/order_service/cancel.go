@@ -0,0 +1,160 @@
+package order_service
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/auth"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/utils"
+	orderpb "order-service-system/proto/order"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CancelItems cancels units of a paid order that is not shipped yet. A repeated call
+// with a known cancellation id does not cancel anything and only publishes the refund
+// request again. Stock is not released here: order.items_cancelled is the only
+// hand-off to inventory.
+func (receiver *OrderService) CancelItems(ctx context.Context, req *orderpb.CancelItemsRequest) (*orderpb.Order, *orderpb.ItemCancellation, error) {
+	if req == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	if req.OrderId == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	if len(req.Items) == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "items are required")
+	}
+	var products []string
+	quantities := make(map[string]int32, len(req.Items))
+	for _, item := range req.Items {
+		if item.ProductId == "" {
+			return nil, nil, status.Error(codes.InvalidArgument, "product_id is required")
+		}
+		if item.Quantity <= 0 {
+			return nil, nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+		}
+		if _, ok := quantities[item.ProductId]; ok {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "product %q is listed twice", item.ProductId)
+		}
+		products = append(products, item.ProductId)
+		quantities[item.ProductId] = item.Quantity
+	}
+
+	doc, err := receiver.orderRepo.Get(ctx, req.OrderId)
+	if err != nil {
+		return nil, nil, statusUpdateError(err)
+	}
+	if !canAccess(ctx, doc.UserID) {
+		return nil, nil, status.Error(codes.NotFound, "order not found")
+	}
+
+	if req.CancellationId != "" {
+		index := slices.IndexFunc(doc.Cancellations, func(cancellation models.ItemCancellation) bool {
+			return cancellation.CancellationID == req.CancellationId
+		})
+		if index >= 0 {
+			cancellation := doc.Cancellations[index]
+			receiver.publishItemsCancelled(ctx, doc, cancellation)
+			return utils.ConvertToProto(doc), utils.ConvertCancellationToProto(cancellation), nil
+		}
+	}
+
+	if !slices.Contains(models.CancellableStatuses, doc.Status) {
+		return nil, nil, status.Error(codes.FailedPrecondition, "only PAID or PROCESSING orders can be cancelled partially")
+	}
+
+	cancellation := models.ItemCancellation{
+		CancellationID: req.CancellationId,
+		Reason:         req.Reason,
+		Actor:          auth.Actor(ctx),
+		At:             time.Now().UTC(),
+	}
+	if cancellation.CancellationID == "" {
+		cancellation.CancellationID = uuid.NewString()
+	}
+
+	items := slices.Clone(doc.Items)
+	for _, productID := range products {
+		left := quantities[productID]
+		found := false
+		for i := range items {
+			if items[i].ProductID != productID {
+				continue
+			}
+			found = true
+			cancelled := min(left, items[i].ActiveQuantity())
+			if cancelled == 0 {
+				continue
+			}
+			items[i].CancelledQuantity += cancelled
+			left -= cancelled
+			cancellation.Items = append(cancellation.Items, models.CancelledItem{
+				ProductID: productID,
+				Quantity:  cancelled,
+				Price:     items[i].Price,
+			})
+			cancellation.Amount += float64(cancelled) * items[i].Price
+		}
+		if !found {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "product %q is not in the order", productID)
+		}
+		if left > 0 {
+			return nil, nil, status.Errorf(codes.FailedPrecondition, "only %d units of product %q can be cancelled", quantities[productID]-left, productID)
+		}
+	}
+
+	var (
+		remaining      float64
+		remainingUnits int32
+	)
+	for i := range items {
+		switch {
+		case items[i].CancelledQuantity == 0:
+		case items[i].ActiveQuantity() == 0:
+			items[i].Status = orderpb.ItemStatus_ITEM_CANCELLED.String()
+		default:
+			items[i].Status = orderpb.ItemStatus_ITEM_PARTIALLY_CANCELLED.String()
+		}
+		remaining += float64(items[i].ActiveQuantity()) * items[i].Price
+		remainingUnits += items[i].ActiveQuantity()
+	}
+	orderStatus := doc.Status
+	if remainingUnits == 0 {
+		orderStatus = orderpb.OrderStatus_CANCELLED.String()
+	}
+
+	updated, err := receiver.orderRepo.CancelItems(ctx, doc.OrderID, doc.Version, items, remaining, orderStatus, cancellation)
+	if errors.Is(err, pj_errors.ErrConflict) {
+		return nil, nil, status.Error(codes.Aborted, "order was changed concurrently, reload it and retry")
+	}
+	if err != nil {
+		return nil, nil, statusUpdateError(err)
+	}
+
+	receiver.publishItemsCancelled(ctx, updated, cancellation)
+	return utils.ConvertToProto(updated), utils.ConvertCancellationToProto(cancellation), nil
+}
+
+func (receiver *OrderService) publishItemsCancelled(ctx context.Context, doc models.Order, cancellation models.ItemCancellation) {
+	if err := receiver.natsClient.PublishOrderItemsCancelled(ctx, models.OrderItemsCancelledEvent{
+		OrderID:        doc.OrderID,
+		UserID:         doc.UserID,
+		Cancellation:   cancellation,
+		RemainingTotal: doc.TotalAmount,
+	}); err != nil {
+		// Повторный вызов с тем же cancellation_id опубликует событие ещё раз.
+		receiver.logger.Warn("failed to publish event on <CancelItems> of <OrderService>",
+			zap.String("subject", "order.items_cancelled"),
+			zap.String("order_id", doc.OrderID),
+			zap.String("cancellation_id", cancellation.CancellationID),
+			zap.Error(err),
+		)
+	}
+}
@@ -3,10 +3,16 @@ package order_service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/mail"
+	"order-service-system/common/auth"
+	"order-service-system/common/tenant"
 	"order-service-system/order_service/internal/models"
 	"order-service-system/order_service/internal/pj_errors"
 	"order-service-system/order_service/internal/utils"
 	orderpb "order-service-system/proto/order"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,24 +24,50 @@ import (
 type OrderService struct {
 	logger     *zap.Logger
 	orderRepo  OrderRepository
+	counters   Counters
 	natsClient OrderEventsPublisher
+	tenants    tenant.Settings[TenantSettings]
 }
 
 type Deps struct {
 	Logger     *zap.Logger
 	OrderRepo  OrderRepository
+	Counters   Counters
 	NatsClient OrderEventsPublisher
+	// Tenants are empty when no tenants file is configured.
+	Tenants tenant.Settings[TenantSettings]
+}
+
+// TenantSettings are the keys of the tenants file the order service reads.
+type TenantSettings struct {
+	// Currencies are accepted by the tenant, the first one is the default. Orders
+	// of a tenant without currencies have no currency.
+	Currencies []string `json:"currencies"`
 }
 
 // только для unit тестов нужны
 type OrderRepository interface {
 	Create(ctx context.Context, order models.Order) error
 	Get(ctx context.Context, orderID string) (models.Order, error)
+	GetByNumber(ctx context.Context, number string) (models.Order, error)
 	UpdateStatus(ctx context.Context, orderID string, status string) (models.Order, error)
+	Transition(ctx context.Context, orderID string, from []string, change models.StatusChange) (models.Order, error)
+	Amend(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, amendment models.OrderAmendment) (models.Order, error)
+	CancelItems(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, total float64, status string, cancellation models.ItemCancellation) (models.Order, error)
+	List(ctx context.Context, filter models.ListOrdersFilter) ([]models.Order, error)
+}
+
+// Counters issue the sequence numbers of order numbers.
+type Counters interface {
+	Next(ctx context.Context, name string) (int64, error)
 }
 
 type OrderEventsPublisher interface {
-	PublishOrderCreated(event models.OrderCreatedEvent) error
+	PublishOrderCreated(ctx context.Context, event models.OrderCreatedEvent) error
+	PublishOrderShipped(ctx context.Context, event models.OrderShippedEvent) error
+	PublishOrderDelivered(ctx context.Context, event models.OrderDeliveredEvent) error
+	PublishOrderAmended(ctx context.Context, event models.OrderAmendedEvent) error
+	PublishOrderItemsCancelled(ctx context.Context, event models.OrderItemsCancelledEvent) error
 }
 
 func NewOrderService(deps Deps) *OrderService {
@@ -45,21 +77,44 @@ func NewOrderService(deps Deps) *OrderService {
 	if deps.OrderRepo == nil {
 		panic("order repo must not be nil on <NewOrderService> of <OrderService>")
 	}
+	if deps.Counters == nil {
+		panic("counters must not be nil on <NewOrderService> of <OrderService>")
+	}
 	if deps.NatsClient == nil {
 		panic("nats client must not be nil on <NewOrderService> of <OrderService>")
 	}
 	return &OrderService{
 		logger:     deps.Logger,
 		orderRepo:  deps.OrderRepo,
+		counters:   deps.Counters,
 		natsClient: deps.NatsClient,
+		tenants:    deps.Tenants,
 	}
 }
 
 func (receiver *OrderService) CreateOrder(ctx context.Context, req *orderpb.CreateOrderRequest) (*orderpb.Order, error) {
+	return receiver.createOrder(ctx, req, nil)
+}
+
+// CreateOrderFrom creates the order of a cart checkout or a subscription run under
+// the order id chosen by the caller. If the order is stored already, it is returned
+// instead of a second one.
+func (receiver *OrderService) CreateOrderFrom(ctx context.Context, req *orderpb.CreateOrderRequest, origin models.OrderOrigin) (*orderpb.Order, error) {
+	if origin.OrderID == "" || (origin.CartID == "" && origin.SubscriptionID == "") {
+		return nil, status.Error(codes.Internal, "order origin has no order id or source")
+	}
+	return receiver.createOrder(ctx, req, &origin)
+}
+
+func (receiver *OrderService) createOrder(ctx context.Context, req *orderpb.CreateOrderRequest, origin *models.OrderOrigin) (*orderpb.Order, error) {
 	if req == nil {
 		return nil, status.Error(codes.InvalidArgument, "request is required")
 	}
-	if req.UserId == "" {
+	userID, err := ownerFor(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
 		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
 	if len(req.Items) == 0 {
@@ -86,23 +141,80 @@ func (receiver *OrderService) CreateOrder(ctx context.Context, req *orderpb.Crea
 		total += float64(item.Quantity) * item.Price
 	}
 
+	address, err := ShippingAddressFromProto(req.ShippingAddress)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := receiver.currency(ctx, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	email, err := contactEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	doc := models.Order{
-		OrderID:     uuid.NewString(),
-		UserID:      req.UserId,
-		Items:       items,
-		TotalAmount: total,
-		Status:      orderpb.OrderStatus_PENDING.String(),
-		CreatedAt:   time.Now(),
+		OrderID:         uuid.NewString(),
+		TenantID:        tenant.FromContext(ctx),
+		UserID:          userID,
+		Items:           items,
+		TotalAmount:     total,
+		Currency:        currency,
+		Email:           email,
+		Status:          orderpb.OrderStatus_PENDING.String(),
+		CreatedAt:       now,
+		SubmittedAt:     now,
+		ShippingAddress: address,
+		Version:         1,
+	}
+	if req.ScheduledAt != nil {
+		if err := req.ScheduledAt.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid scheduled_at: %v", err)
+		}
+		if scheduledAt := req.ScheduledAt.AsTime(); scheduledAt.After(now) {
+			doc.Status = orderpb.OrderStatus_SCHEDULED.String()
+			doc.ScheduledAt = scheduledAt.UTC()
+			doc.SubmittedAt = time.Time{}
+		}
+	}
+	if doc.Number, err = receiver.orderNumber(ctx, now); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to allocate order number: %v", err)
+	}
+	if origin != nil {
+		doc.OrderID = origin.OrderID
+		doc.CartID = origin.CartID
+		doc.SubscriptionID = origin.SubscriptionID
+		doc.PromoCode = origin.PromoCode
+		doc.Discount = origin.Discount
 	}
 
-	if err := receiver.orderRepo.Create(ctx, doc); err != nil {
+	err = receiver.orderRepo.Create(ctx, doc)
+	if origin != nil && errors.Is(err, pj_errors.ErrAlreadyExists) {
+		// Повторный вызов: заказ уже сохранён, а событие могло не уйти.
+		doc, err = receiver.orderRepo.Get(ctx, origin.OrderID)
+		if err == nil && (doc.CartID != origin.CartID || doc.SubscriptionID != origin.SubscriptionID) {
+			return nil, status.Errorf(codes.Internal, "order %q has another origin", doc.OrderID)
+		}
+		if err == nil && doc.Status != orderpb.OrderStatus_PENDING.String() {
+			return utils.ConvertToProto(doc), nil
+		}
+	}
+	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to persist order: %v", err)
 	}
+	if doc.Status == orderpb.OrderStatus_SCHEDULED.String() {
+		// order.created опубликует releaser, когда наступит scheduled_at.
+		return utils.ConvertToProto(doc), nil
+	}
 
-	if err := receiver.natsClient.PublishOrderCreated(models.OrderCreatedEvent{
+	if err := receiver.natsClient.PublishOrderCreated(ctx, models.OrderCreatedEvent{
+		TenantID:    doc.TenantID,
 		OrderID:     doc.OrderID,
 		UserID:      doc.UserID,
 		TotalAmount: doc.TotalAmount,
+		Currency:    doc.Currency,
 		CreatedAt:   doc.CreatedAt,
 	}); err != nil {
 		receiver.logger.Warn("failed to publish event on <CreateOrder> of <OrderService>",
@@ -125,9 +237,66 @@ func (receiver *OrderService) GetOrder(ctx context.Context, orderID string) (*or
 		}
 		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
 	}
+	// Чужой заказ неотличим от несуществующего.
+	if !canAccess(ctx, doc.UserID) {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
 	return utils.ConvertToProto(doc), nil
 }
 
+func (receiver *OrderService) GetOrderByNumber(ctx context.Context, number string) (*orderpb.Order, error) {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	if number == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_number is required")
+	}
+	doc, err := receiver.orderRepo.GetByNumber(ctx, number)
+	if err != nil {
+		if errors.Is(err, pj_errors.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+	if !canAccess(ctx, doc.UserID) {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return utils.ConvertToProto(doc), nil
+}
+
+// currency checks the requested currency against the currencies of the tenant.
+func (receiver *OrderService) currency(ctx context.Context, requested string) (string, error) {
+	requested = strings.ToUpper(strings.TrimSpace(requested))
+	currencies := receiver.tenants.For(tenant.FromContext(ctx)).Currencies
+	if len(currencies) == 0 {
+		return requested, nil
+	}
+	if requested == "" {
+		return strings.ToUpper(currencies[0]), nil
+	}
+	for _, currency := range currencies {
+		if strings.EqualFold(currency, requested) {
+			return requested, nil
+		}
+	}
+	return "", status.Errorf(codes.InvalidArgument, "currency %q is not accepted", requested)
+}
+
+// orderNumber takes the next number of the year of the tenant, e.g. ORD-2026-000123.
+// A number taken by an order that failed to be stored is skipped, so numbers may
+// have gaps.
+func (receiver *OrderService) orderNumber(ctx context.Context, createdAt time.Time) (string, error) {
+	year := createdAt.UTC().Year()
+	counter := fmt.Sprintf("order_number:%d", year)
+	// Счётчик витрины по умолчанию сохраняет имя, выданное до появления витрин.
+	if id := tenant.FromContext(ctx); id != tenant.Default {
+		counter = fmt.Sprintf("order_number:%s:%d", id, year)
+	}
+	sequence, err := receiver.counters.Next(ctx, counter)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ORD-%d-%06d", year, sequence), nil
+}
+
 func (receiver *OrderService) UpdateOrderStatus(ctx context.Context, orderID string, newStatus orderpb.OrderStatus) (*orderpb.Order, error) {
 	if orderID == "" {
 		return nil, status.Error(codes.InvalidArgument, "order_id is required")
@@ -135,16 +304,385 @@ func (receiver *OrderService) UpdateOrderStatus(ctx context.Context, orderID str
 	if newStatus == orderpb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
 		return nil, status.Error(codes.InvalidArgument, "status is required")
 	}
+	if newStatus == orderpb.OrderStatus_SHIPPED {
+		return nil, status.Error(codes.InvalidArgument, "use MarkShipped to ship an order")
+	}
 	if _, ok := models.AllowedStatuses[newStatus]; !ok {
 		return nil, status.Errorf(codes.InvalidArgument, "unsupported status %q", newStatus.String())
 	}
 
-	doc, err := receiver.orderRepo.UpdateStatus(ctx, orderID, newStatus.String())
-	if err != nil {
-		if errors.Is(err, pj_errors.ErrNotFound) {
-			return nil, status.Error(codes.NotFound, "order not found")
+	var (
+		doc models.Order
+		err error
+	)
+	switch newStatus {
+	case orderpb.OrderStatus_PROCESSING:
+		doc, err = receiver.orderRepo.Transition(ctx, orderID,
+			[]string{orderpb.OrderStatus_PAID.String(), orderpb.OrderStatus_PROCESSING.String()},
+			models.StatusChange{Status: newStatus.String()},
+		)
+	case orderpb.OrderStatus_DELIVERED:
+		deliveredAt := time.Now().UTC()
+		doc, err = receiver.orderRepo.Transition(ctx, orderID,
+			[]string{orderpb.OrderStatus_SHIPPED.String()},
+			models.StatusChange{Status: newStatus.String(), DeliveredAt: deliveredAt},
+		)
+		if err == nil {
+			receiver.publishDelivered(ctx, doc, deliveredAt)
 		}
-		return nil, status.Errorf(codes.Internal, "failed to update status: %v", err)
+	case orderpb.OrderStatus_PAID, orderpb.OrderStatus_FAILED:
+		doc, err = receiver.settlePayment(ctx, orderID, newStatus)
+	default:
+		doc, err = receiver.orderRepo.UpdateStatus(ctx, orderID, newStatus.String())
+	}
+	if err != nil {
+		return nil, statusUpdateError(err)
+	}
+	return utils.ConvertToProto(doc), nil
+}
+
+// settlePayment moves a PENDING order to the result of its payment. A repeated result
+// changes nothing; an order that was resolved otherwise in the meantime (expired,
+// cancelled, or got the opposite result) is not overwritten and ErrConflict is returned.
+func (receiver *OrderService) settlePayment(ctx context.Context, orderID string, result orderpb.OrderStatus) (models.Order, error) {
+	doc, err := receiver.orderRepo.Transition(ctx, orderID,
+		[]string{orderpb.OrderStatus_PENDING.String()},
+		models.StatusChange{Status: result.String()},
+	)
+	if !errors.Is(err, pj_errors.ErrConflict) {
+		return doc, err
+	}
+
+	current, getErr := receiver.orderRepo.Get(ctx, orderID)
+	if getErr != nil {
+		return models.Order{}, getErr
+	}
+	// Повторный результат оплаты, в том числе PAID для заказа, который уже собирается, ничего не меняет.
+	if current.Status == result.String() ||
+		(result == orderpb.OrderStatus_PAID && slices.Contains(models.FulfilmentStatuses, current.Status)) {
+		return current, nil
+	}
+	return models.Order{}, err
+}
+
+// MarkShipped moves a paid order to SHIPPED and records the carrier and tracking number.
+func (receiver *OrderService) MarkShipped(ctx context.Context, orderID string, carrier string, trackingNumber string) (*orderpb.Order, error) {
+	if orderID == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	if carrier == "" {
+		return nil, status.Error(codes.InvalidArgument, "carrier is required")
+	}
+	if trackingNumber == "" {
+		return nil, status.Error(codes.InvalidArgument, "tracking_number is required")
+	}
+
+	shipment := &models.Shipment{
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		ShippedAt:      time.Now().UTC(),
+	}
+	doc, err := receiver.orderRepo.Transition(ctx, orderID,
+		[]string{orderpb.OrderStatus_PAID.String(), orderpb.OrderStatus_PROCESSING.String()},
+		models.StatusChange{Status: orderpb.OrderStatus_SHIPPED.String(), Shipment: shipment},
+	)
+	if err != nil {
+		return nil, statusUpdateError(err)
+	}
+
+	if err := receiver.natsClient.PublishOrderShipped(ctx, models.OrderShippedEvent{
+		OrderID:        doc.OrderID,
+		UserID:         doc.UserID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		ShippedAt:      shipment.ShippedAt,
+	}); err != nil {
+		receiver.logger.Warn("failed to publish event on <MarkShipped> of <OrderService>",
+			zap.String("subject", "order.shipped"),
+			zap.String("order_id", doc.OrderID),
+			zap.Error(err),
+		)
 	}
 	return utils.ConvertToProto(doc), nil
 }
+
+// AmendOrder sets new quantities for the products of a PENDING order. Products that
+// are left out or have zero quantity are removed, prices stay as they were ordered.
+func (receiver *OrderService) AmendOrder(ctx context.Context, req *orderpb.AmendOrderRequest) (*orderpb.Order, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	if req.OrderId == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	quantities := make(map[string]int32, len(req.Items))
+	for _, item := range req.Items {
+		if item.ProductId == "" {
+			return nil, status.Error(codes.InvalidArgument, "product_id is required")
+		}
+		if item.Quantity < 0 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must be non-negative")
+		}
+		if _, ok := quantities[item.ProductId]; ok {
+			return nil, status.Errorf(codes.InvalidArgument, "product %q is listed twice", item.ProductId)
+		}
+		quantities[item.ProductId] = item.Quantity
+	}
+
+	doc, err := receiver.orderRepo.Get(ctx, req.OrderId)
+	if err != nil {
+		return nil, statusUpdateError(err)
+	}
+	if !canAccess(ctx, doc.UserID) {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	if doc.Status != orderpb.OrderStatus_PENDING.String() {
+		return nil, status.Error(codes.FailedPrecondition, "only PENDING orders can be amended")
+	}
+	if req.ExpectedVersion != 0 && req.ExpectedVersion != doc.Version {
+		return nil, status.Errorf(codes.Aborted, "order has version %d", doc.Version)
+	}
+
+	items, changes, err := amendItems(doc.Items, quantities)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return utils.ConvertToProto(doc), nil
+	}
+
+	amendment := models.OrderAmendment{
+		Version:       doc.Version + 1,
+		Actor:         auth.Actor(ctx),
+		Changes:       changes,
+		PreviousTotal: doc.TotalAmount,
+		At:            time.Now().UTC(),
+	}
+	for _, item := range items {
+		amendment.NewTotal += float64(item.Quantity) * item.Price
+	}
+
+	amended, err := receiver.orderRepo.Amend(ctx, doc.OrderID, doc.Version, items, amendment)
+	if errors.Is(err, pj_errors.ErrConflict) {
+		// Заказ успели оплатить или изменить параллельно.
+		return nil, status.Error(codes.Aborted, "order was changed concurrently, reload it and retry")
+	}
+	if err != nil {
+		return nil, statusUpdateError(err)
+	}
+
+	if err := receiver.natsClient.PublishOrderAmended(ctx, models.OrderAmendedEvent{
+		OrderID:       amended.OrderID,
+		UserID:        amended.UserID,
+		Version:       amendment.Version,
+		TotalAmount:   amendment.NewTotal,
+		PreviousTotal: amendment.PreviousTotal,
+		AmendedAt:     amendment.At,
+	}); err != nil {
+		receiver.logger.Warn("failed to publish event on <AmendOrder> of <OrderService>",
+			zap.String("subject", "order.amended"),
+			zap.String("order_id", amended.OrderID),
+			zap.Error(err),
+		)
+	}
+	return utils.ConvertToProto(amended), nil
+}
+
+// amendItems applies the new quantities to the ordered items. Lines of the same
+// product are merged and keep the price of the first one.
+func amendItems(ordered []models.OrderItem, quantities map[string]int32) ([]models.OrderItem, []models.ItemChange, error) {
+	var merged []models.OrderItem
+	index := make(map[string]int, len(ordered))
+	for _, item := range ordered {
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+	for productID := range quantities {
+		if _, ok := index[productID]; !ok {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "product %q is not in the order", productID)
+		}
+	}
+
+	var (
+		items   []models.OrderItem
+		changes []models.ItemChange
+	)
+	for _, item := range merged {
+		quantity := quantities[item.ProductID]
+		if quantity != item.Quantity {
+			changes = append(changes, models.ItemChange{
+				ProductID:   item.ProductID,
+				OldQuantity: item.Quantity,
+				NewQuantity: quantity,
+				Price:       item.Price,
+			})
+		}
+		if quantity > 0 {
+			items = append(items, models.OrderItem{ProductID: item.ProductID, Quantity: quantity, Price: item.Price})
+		}
+	}
+	if len(items) == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "at least one item must remain")
+	}
+	// Слияние строк без изменения количеств — тоже не изменение.
+	if len(changes) == 0 {
+		return ordered, nil, nil
+	}
+	return items, changes, nil
+}
+
+func (receiver *OrderService) publishDelivered(ctx context.Context, doc models.Order, deliveredAt time.Time) {
+	if err := receiver.natsClient.PublishOrderDelivered(ctx, models.OrderDeliveredEvent{
+		OrderID:     doc.OrderID,
+		UserID:      doc.UserID,
+		DeliveredAt: deliveredAt,
+	}); err != nil {
+		receiver.logger.Warn("failed to publish event on <UpdateOrderStatus> of <OrderService>",
+			zap.String("subject", "order.delivered"),
+			zap.String("order_id", doc.OrderID),
+			zap.Error(err),
+		)
+	}
+}
+
+func statusUpdateError(err error) error {
+	switch {
+	case errors.Is(err, pj_errors.ErrNotFound):
+		return status.Error(codes.NotFound, "order not found")
+	case errors.Is(err, pj_errors.ErrConflict):
+		return status.Error(codes.FailedPrecondition, "order status does not allow this change")
+	default:
+		return status.Errorf(codes.Internal, "failed to update status: %v", err)
+	}
+}
+
+// ShippingAddressFromProto validates the address; nil means the order has none.
+func ShippingAddressFromProto(address *orderpb.ShippingAddress) (*models.ShippingAddress, error) {
+	if address == nil {
+		return nil, nil
+	}
+	switch {
+	case address.Recipient == "":
+		return nil, status.Error(codes.InvalidArgument, "shipping_address.recipient is required")
+	case address.Line1 == "":
+		return nil, status.Error(codes.InvalidArgument, "shipping_address.line1 is required")
+	case address.City == "":
+		return nil, status.Error(codes.InvalidArgument, "shipping_address.city is required")
+	case address.Country == "":
+		return nil, status.Error(codes.InvalidArgument, "shipping_address.country is required")
+	}
+	return &models.ShippingAddress{
+		Recipient:  address.Recipient,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Phone:      address.Phone,
+	}, nil
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func (receiver *OrderService) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) ([]*orderpb.Order, string, error) {
+	if req == nil {
+		return nil, "", status.Error(codes.InvalidArgument, "request is required")
+	}
+	if req.PageSize < 0 {
+		return nil, "", status.Error(codes.InvalidArgument, "page_size must be non-negative")
+	}
+
+	userID, err := ownerFor(ctx, req.UserId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := models.ListOrdersFilter{
+		UserID: userID,
+		Limit:  int(req.PageSize),
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+	if req.Status != orderpb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		filter.Status = req.Status.String()
+	}
+	if req.PageToken != "" {
+		cursor, err := utils.DecodePageToken(req.PageToken)
+		if err != nil {
+			return nil, "", status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		filter.After = &cursor
+	}
+
+	pageSize := filter.Limit
+	// Запрашиваем на один заказ больше, чтобы понять, есть ли следующая страница.
+	filter.Limit++
+	docs, err := receiver.orderRepo.List(ctx, filter)
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "failed to list orders: %v", err)
+	}
+
+	nextPageToken := ""
+	if len(docs) > pageSize {
+		docs = docs[:pageSize]
+		last := docs[len(docs)-1]
+		nextPageToken = utils.EncodePageToken(models.OrderCursor{CreatedAt: last.CreatedAt, OrderID: last.OrderID})
+	}
+
+	orders := make([]*orderpb.Order, 0, len(docs))
+	for _, doc := range docs {
+		orders = append(orders, utils.ConvertToProto(doc))
+	}
+	return orders, nextPageToken, nil
+}
+
+// ownerFor resolves the owner of the orders a request is about. End users act only
+// on their own orders, services may act on behalf of any user.
+func ownerFor(ctx context.Context, requestedUserID string) (string, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || principal.IsService() {
+		return requestedUserID, nil
+	}
+	if requestedUserID != "" && requestedUserID != principal.Subject {
+		return "", status.Error(codes.PermissionDenied, "orders of another user are not accessible")
+	}
+	return principal.Subject, nil
+}
+
+// contactEmail lowercases the requested email; users who leave it empty get the
+// email of their token, if it is valid.
+func contactEmail(ctx context.Context, requested string) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(requested))
+	if email != "" {
+		if !validEmail(email) {
+			return "", status.Error(codes.InvalidArgument, "email is invalid")
+		}
+		return email, nil
+	}
+	if principal, ok := auth.PrincipalFrom(ctx); ok && !principal.IsService() && validEmail(principal.Email) {
+		return principal.Email, nil
+	}
+	return "", nil
+}
+
+// validEmail accepts a bare address without a display name.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+func canAccess(ctx context.Context, ownerID string) bool {
+	principal, ok := auth.PrincipalFrom(ctx)
+	return !ok || principal.IsService() || principal.Subject == ownerID
+}
@@ -0,0 +1,350 @@
+package return_service
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/auth"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/utils"
+	orderpb "order-service-system/proto/order"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ReturnService struct {
+	logger     *zap.Logger
+	returnRepo ReturnRepository
+	orderRepo  OrderReader
+	natsClient ReturnEventsPublisher
+	now        func() time.Time
+}
+
+type Deps struct {
+	Logger     *zap.Logger
+	ReturnRepo ReturnRepository
+	OrderRepo  OrderReader
+	NatsClient ReturnEventsPublisher
+	// Now is time.Now when nil.
+	Now func() time.Time
+}
+
+type ReturnRepository interface {
+	Create(ctx context.Context, ret models.Return) error
+	Get(ctx context.Context, returnID string) (models.Return, error)
+	ListByOrder(ctx context.Context, orderID string) ([]models.Return, error)
+	Transition(ctx context.Context, returnID string, from []string, entry models.ReturnHistoryEntry) (models.Return, error)
+}
+
+type OrderReader interface {
+	Get(ctx context.Context, orderID string) (models.Order, error)
+}
+
+// ReturnEventsPublisher publishes the event of the current status of the return:
+// return.approved, return.rejected or return.received.
+type ReturnEventsPublisher interface {
+	PublishReturnStatus(ctx context.Context, ret models.Return) error
+}
+
+func NewReturnService(deps Deps) *ReturnService {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewReturnService> of <ReturnService>")
+	}
+	if deps.ReturnRepo == nil {
+		panic("return repo must not be nil on <NewReturnService> of <ReturnService>")
+	}
+	if deps.OrderRepo == nil {
+		panic("order repo must not be nil on <NewReturnService> of <ReturnService>")
+	}
+	if deps.NatsClient == nil {
+		panic("nats client must not be nil on <NewReturnService> of <ReturnService>")
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &ReturnService{
+		logger:     deps.Logger,
+		returnRepo: deps.ReturnRepo,
+		orderRepo:  deps.OrderRepo,
+		natsClient: deps.NatsClient,
+		now:        now,
+	}
+}
+
+func (receiver *ReturnService) RequestReturn(ctx context.Context, req *orderpb.RequestReturnRequest) (*orderpb.Return, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	if req.OrderId == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	if req.Reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+	if len(req.Items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "items are required")
+	}
+
+	requested := map[string]int32{}
+	var products []string
+	for _, item := range req.Items {
+		if item.ProductId == "" {
+			return nil, status.Error(codes.InvalidArgument, "product_id is required")
+		}
+		if item.Quantity <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+		}
+		if _, ok := requested[item.ProductId]; !ok {
+			products = append(products, item.ProductId)
+		}
+		requested[item.ProductId] += item.Quantity
+	}
+
+	order, err := receiver.orderRepo.Get(ctx, req.OrderId)
+	if err != nil {
+		if errors.Is(err, pj_errors.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+	if !canAccess(ctx, order.UserID) {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	if order.Status != orderpb.OrderStatus_DELIVERED.String() {
+		return nil, status.Error(codes.FailedPrecondition, "only delivered orders can be returned")
+	}
+
+	existing, err := receiver.returnRepo.ListByOrder(ctx, order.OrderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list returns: %v", err)
+	}
+	returnable := returnableItems(order, existing)
+
+	var items []models.ReturnItem
+	var refund float64
+	for _, productID := range products {
+		line, ok := returnable[productID]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "product %q is not in the order", productID)
+		}
+		quantity := requested[productID]
+		if quantity > line.quantity {
+			return nil, status.Errorf(codes.FailedPrecondition, "only %d of product %q can be returned", line.quantity, productID)
+		}
+		items = append(items, models.ReturnItem{ProductID: productID, Quantity: quantity})
+		refund += float64(quantity) * line.price
+	}
+
+	now := receiver.now().UTC()
+	doc := models.Return{
+		ReturnID:     uuid.NewString(),
+		OrderID:      order.OrderID,
+		Sequence:     int64(len(existing)) + 1,
+		UserID:       order.UserID,
+		Items:        items,
+		Reason:       req.Reason,
+		Status:       orderpb.ReturnStatus_RETURN_REQUESTED.String(),
+		RefundAmount: refund,
+		History: []models.ReturnHistoryEntry{{
+			Status:  orderpb.ReturnStatus_RETURN_REQUESTED.String(),
+			Actor:   auth.Actor(ctx),
+			Comment: req.Reason,
+			At:      now,
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := receiver.returnRepo.Create(ctx, doc); err != nil {
+		if errors.Is(err, pj_errors.ErrAlreadyExists) {
+			// Другой запрос возврата проверен по тем же возвратам и сохранён первым.
+			return nil, status.Error(codes.Aborted, "another return of the order was requested concurrently, retry")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to persist return: %v", err)
+	}
+	return utils.ConvertReturnToProto(doc), nil
+}
+
+func (receiver *ReturnService) GetReturn(ctx context.Context, returnID string) (*orderpb.Return, error) {
+	if returnID == "" {
+		return nil, status.Error(codes.InvalidArgument, "return_id is required")
+	}
+	doc, err := receiver.returnRepo.Get(ctx, returnID)
+	if err != nil {
+		if errors.Is(err, pj_errors.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "return not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get return: %v", err)
+	}
+	if !canAccess(ctx, doc.UserID) {
+		return nil, status.Error(codes.NotFound, "return not found")
+	}
+	return utils.ConvertReturnToProto(doc), nil
+}
+
+func (receiver *ReturnService) ListReturns(ctx context.Context, orderID string) ([]*orderpb.Return, error) {
+	if orderID == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	order, err := receiver.orderRepo.Get(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, pj_errors.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "order not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get order: %v", err)
+	}
+	if !canAccess(ctx, order.UserID) {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+
+	docs, err := receiver.returnRepo.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list returns: %v", err)
+	}
+	returns := make([]*orderpb.Return, 0, len(docs))
+	for _, doc := range docs {
+		returns = append(returns, utils.ConvertReturnToProto(doc))
+	}
+	return returns, nil
+}
+
+func (receiver *ReturnService) ApproveReturn(ctx context.Context, returnID string, comment string) (*orderpb.Return, error) {
+	return receiver.transition(ctx, returnID,
+		[]string{orderpb.ReturnStatus_RETURN_REQUESTED.String()},
+		orderpb.ReturnStatus_RETURN_APPROVED, comment, true,
+	)
+}
+
+func (receiver *ReturnService) RejectReturn(ctx context.Context, returnID string, reason string) (*orderpb.Return, error) {
+	if reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+	return receiver.transition(ctx, returnID,
+		[]string{orderpb.ReturnStatus_RETURN_REQUESTED.String()},
+		orderpb.ReturnStatus_RETURN_REJECTED, reason, true,
+	)
+}
+
+// MarkReturnReceived confirms receipt and asks billing for the refund. Calling it
+// again for a received return repeats the refund request; billing refunds once.
+func (receiver *ReturnService) MarkReturnReceived(ctx context.Context, returnID string) (*orderpb.Return, error) {
+	ret, err := receiver.transition(ctx, returnID,
+		[]string{orderpb.ReturnStatus_RETURN_APPROVED.String()},
+		orderpb.ReturnStatus_RETURN_RECEIVED, "", true,
+	)
+	if status.Code(err) != codes.FailedPrecondition {
+		return ret, err
+	}
+
+	doc, getErr := receiver.returnRepo.Get(ctx, returnID)
+	if getErr != nil || doc.Status != orderpb.ReturnStatus_RETURN_RECEIVED.String() {
+		return nil, err
+	}
+	receiver.publish(ctx, doc)
+	return utils.ConvertReturnToProto(doc), nil
+}
+
+// RecordRefund stores the refund result reported by billing. A failed refund can be
+// recorded as refunded later; repeating the same result is a no-op.
+func (receiver *ReturnService) RecordRefund(ctx context.Context, returnID string, refunded bool, reason string) (*orderpb.Return, error) {
+	to := orderpb.ReturnStatus_RETURN_REFUND_FAILED
+	from := []string{orderpb.ReturnStatus_RETURN_RECEIVED.String()}
+	if refunded {
+		to = orderpb.ReturnStatus_RETURN_REFUNDED
+		from = append(from, orderpb.ReturnStatus_RETURN_REFUND_FAILED.String())
+	}
+
+	ret, err := receiver.transition(ctx, returnID, from, to, reason, false)
+	if status.Code(err) != codes.FailedPrecondition {
+		return ret, err
+	}
+	doc, getErr := receiver.returnRepo.Get(ctx, returnID)
+	if getErr != nil || doc.Status != to.String() {
+		return nil, err
+	}
+	return utils.ConvertReturnToProto(doc), nil
+}
+
+func (receiver *ReturnService) transition(ctx context.Context, returnID string, from []string, to orderpb.ReturnStatus, comment string, publish bool) (*orderpb.Return, error) {
+	if returnID == "" {
+		return nil, status.Error(codes.InvalidArgument, "return_id is required")
+	}
+
+	doc, err := receiver.returnRepo.Transition(ctx, returnID, from, models.ReturnHistoryEntry{
+		Status:  to.String(),
+		Actor:   auth.Actor(ctx),
+		Comment: comment,
+		At:      receiver.now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pj_errors.ErrNotFound):
+			return nil, status.Error(codes.NotFound, "return not found")
+		case errors.Is(err, pj_errors.ErrConflict):
+			return nil, status.Errorf(codes.FailedPrecondition, "return cannot be moved to %s", to.String())
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to update return: %v", err)
+		}
+	}
+
+	if publish {
+		receiver.publish(ctx, doc)
+	}
+	return utils.ConvertReturnToProto(doc), nil
+}
+
+func (receiver *ReturnService) publish(ctx context.Context, doc models.Return) {
+	if err := receiver.natsClient.PublishReturnStatus(ctx, doc); err != nil {
+		receiver.logger.Warn("failed to publish event on <publish> of <ReturnService>",
+			zap.String("return_id", doc.ReturnID),
+			zap.String("status", doc.Status),
+			zap.Error(err),
+		)
+	}
+}
+
+type returnableLine struct {
+	quantity int32
+	price    float64
+}
+
+// returnableItems is what is left of every product of the order, without cancelled
+// units, after the returns that are still open or done. The price is the average price paid for the product.
+func returnableItems(order models.Order, returns []models.Return) map[string]returnableLine {
+	amounts := map[string]float64{}
+	lines := map[string]returnableLine{}
+	for _, item := range order.Items {
+		line := lines[item.ProductID]
+		line.quantity += item.ActiveQuantity()
+		lines[item.ProductID] = line
+		amounts[item.ProductID] += float64(item.ActiveQuantity()) * item.Price
+	}
+	for productID, line := range lines {
+		if line.quantity > 0 {
+			line.price = amounts[productID] / float64(line.quantity)
+		}
+		lines[productID] = line
+	}
+
+	for _, ret := range returns {
+		if slices.Contains(models.ClosedReturnStatuses, ret.Status) {
+			continue
+		}
+		for _, item := range ret.Items {
+			line := lines[item.ProductID]
+			line.quantity -= item.Quantity
+			lines[item.ProductID] = line
+		}
+	}
+	return lines
+}
+
+func canAccess(ctx context.Context, ownerID string) bool {
+	principal, ok := auth.PrincipalFrom(ctx)
+	return !ok || principal.IsService() || principal.Subject == ownerID
+}
@@ -0,0 +1,155 @@
+package search_service
+
+import (
+	"context"
+	"errors"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/utils"
+	orderpb "order-service-system/proto/order"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// maxOffset bounds paging: support narrows the query instead of paging deep.
+	maxOffset     = 1000
+	maxTextLength = 200
+)
+
+type SearchService struct {
+	logger  *zap.Logger
+	backend Backend
+}
+
+type Deps struct {
+	Logger  *zap.Logger
+	Backend Backend
+}
+
+// Backend runs the search in the tenant of the context. The Mongo backend is used
+// by default; an external search engine can be plugged in by implementing it.
+// Backends that do not highlight matches themselves use OrderSearchQuery.Highlight.
+type Backend interface {
+	Search(ctx context.Context, query models.OrderSearchQuery) (models.OrderSearchResult, error)
+}
+
+func NewSearchService(deps Deps) *SearchService {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewSearchService> of <SearchService>")
+	}
+	if deps.Backend == nil {
+		panic("backend must not be nil on <NewSearchService> of <SearchService>")
+	}
+	return &SearchService{
+		logger:  deps.Logger,
+		backend: deps.Backend,
+	}
+}
+
+func (receiver *SearchService) SearchOrders(ctx context.Context, req *orderpb.SearchOrdersRequest) (*orderpb.SearchOrdersResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	query, err := queryFromProto(req)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := query.Limit
+	// Запрашиваем на один заказ больше, чтобы понять, есть ли следующая страница.
+	query.Limit++
+	result, err := receiver.backend.Search(ctx, query)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, status.Error(codes.DeadlineExceeded, "search takes too long, narrow the criteria")
+		}
+		receiver.logger.Error("failed to search orders on <SearchOrders> of <SearchService>", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to search orders: %v", err)
+	}
+
+	response := &orderpb.SearchOrdersResponse{Total: result.Total}
+	hits := result.Hits
+	if len(hits) > pageSize {
+		hits = hits[:pageSize]
+		if next := query.Offset + pageSize; next < maxOffset {
+			response.NextPageToken = utils.EncodeOffsetToken(next)
+		}
+	}
+	for _, hit := range hits {
+		highlights := make([]*orderpb.SearchHighlight, 0, len(hit.Highlights))
+		for _, highlight := range hit.Highlights {
+			highlights = append(highlights, &orderpb.SearchHighlight{Field: highlight.Field, Snippet: highlight.Snippet})
+		}
+		response.Hits = append(response.Hits, &orderpb.OrderSearchHit{
+			Order:      utils.ConvertToProto(hit.Order),
+			Highlights: highlights,
+			Score:      hit.Score,
+		})
+	}
+	for name, count := range result.StatusCounts {
+		value, ok := orderpb.OrderStatus_value[name]
+		if !ok || count == 0 {
+			continue
+		}
+		response.StatusFacets = append(response.StatusFacets, &orderpb.StatusFacet{Status: orderpb.OrderStatus(value), Count: count})
+	}
+	// Фасеты идут в порядке статусов в proto, а не в порядке выдачи хранилища.
+	slices.SortFunc(response.StatusFacets, func(a, b *orderpb.StatusFacet) int {
+		return int(a.Status) - int(b.Status)
+	})
+	return response, nil
+}
+
+func queryFromProto(req *orderpb.SearchOrdersRequest) (models.OrderSearchQuery, error) {
+	query := models.OrderSearchQuery{
+		Text:      strings.TrimSpace(req.Query),
+		Number:    strings.TrimSpace(req.OrderNumber),
+		ProductID: strings.TrimSpace(req.ProductId),
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+		Limit:     int(req.PageSize),
+	}
+	if query.Text == "" && query.Number == "" && query.ProductID == "" && query.Email == "" && query.MinAmount == 0 && query.MaxAmount == 0 {
+		return models.OrderSearchQuery{}, status.Error(codes.InvalidArgument, "at least one search criterion is required")
+	}
+	if len(query.Text) > maxTextLength {
+		return models.OrderSearchQuery{}, status.Errorf(codes.InvalidArgument, "query must be at most %d characters", maxTextLength)
+	}
+	if query.Text != "" && len(models.TextTerms(query.Text)) == 0 {
+		return models.OrderSearchQuery{}, status.Error(codes.InvalidArgument, "query has no words")
+	}
+	if query.MinAmount < 0 || query.MaxAmount < 0 {
+		return models.OrderSearchQuery{}, status.Error(codes.InvalidArgument, "amounts must be non-negative")
+	}
+	if query.MaxAmount > 0 && query.MinAmount > query.MaxAmount {
+		return models.OrderSearchQuery{}, status.Error(codes.InvalidArgument, "min_amount must not exceed max_amount")
+	}
+	if req.Status != orderpb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		query.Status = req.Status.String()
+	}
+
+	if req.PageSize < 0 {
+		return models.OrderSearchQuery{}, status.Error(codes.InvalidArgument, "page_size must be non-negative")
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit > maxPageSize {
+		query.Limit = maxPageSize
+	}
+	if req.PageToken != "" {
+		offset, err := utils.DecodeOffsetToken(req.PageToken)
+		if err != nil || offset >= maxOffset {
+			return models.OrderSearchQuery{}, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		query.Offset = offset
+	}
+	return query, nil
+}
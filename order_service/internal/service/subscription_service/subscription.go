@@ -0,0 +1,299 @@
+package subscription_service
+
+import (
+	"context"
+	"errors"
+	"order-service-system/common/auth"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/utils"
+	orderpb "order-service-system/proto/order"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxChangeAttempts bounds the retries of a change that lost a race with the
+// scheduler or another change of the same subscription.
+const maxChangeAttempts = 3
+
+type SubscriptionService struct {
+	logger           *zap.Logger
+	subscriptionRepo SubscriptionRepository
+	now              func() time.Time
+}
+
+type Deps struct {
+	Logger           *zap.Logger
+	SubscriptionRepo SubscriptionRepository
+	// Now is time.Now when nil.
+	Now func() time.Time
+}
+
+type SubscriptionRepository interface {
+	Create(ctx context.Context, subscription models.Subscription) error
+	Get(ctx context.Context, subscriptionID string) (models.Subscription, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Subscription, error)
+	Replace(ctx context.Context, subscription models.Subscription, expectedVersion int64) (models.Subscription, error)
+}
+
+func NewSubscriptionService(deps Deps) *SubscriptionService {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewSubscriptionService> of <SubscriptionService>")
+	}
+	if deps.SubscriptionRepo == nil {
+		panic("subscription repo must not be nil on <NewSubscriptionService> of <SubscriptionService>")
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &SubscriptionService{
+		logger:           deps.Logger,
+		subscriptionRepo: deps.SubscriptionRepo,
+		now:              now,
+	}
+}
+
+func (receiver *SubscriptionService) CreateSubscription(ctx context.Context, req *orderpb.CreateSubscriptionRequest) (*orderpb.Subscription, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is required")
+	}
+	userID, err := ownerFor(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if len(req.Items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "items are required")
+	}
+	if req.Period == orderpb.SubscriptionPeriod_SUBSCRIPTION_PERIOD_UNSPECIFIED {
+		return nil, status.Error(codes.InvalidArgument, "period is required")
+	}
+	if _, ok := orderpb.SubscriptionPeriod_name[int32(req.Period)]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported period %d", req.Period)
+	}
+	if req.IntervalCount < 0 {
+		return nil, status.Error(codes.InvalidArgument, "interval_count must not be negative")
+	}
+
+	var items []models.OrderItem
+	for _, item := range req.Items {
+		if item.ProductId == "" {
+			return nil, status.Error(codes.InvalidArgument, "product_id is required")
+		}
+		if item.Quantity <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+		}
+		if item.Price < 0 {
+			return nil, status.Error(codes.InvalidArgument, "price must be non-negative")
+		}
+		items = append(items, models.OrderItem{
+			ProductID: item.ProductId,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+	address, err := order_service.ShippingAddressFromProto(req.ShippingAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	now := receiver.now().UTC()
+	startsAt := now
+	if req.StartsAt != nil && req.StartsAt.AsTime().After(now) {
+		startsAt = req.StartsAt.AsTime().UTC()
+	}
+	var endsAt time.Time
+	if req.EndsAt != nil {
+		endsAt = req.EndsAt.AsTime().UTC()
+		if endsAt.Before(startsAt) {
+			return nil, status.Error(codes.InvalidArgument, "ends_at must not be before starts_at")
+		}
+	}
+
+	doc := models.Subscription{
+		SubscriptionID:  uuid.NewString(),
+		UserID:          userID,
+		Items:           items,
+		ShippingAddress: address,
+		Period:          req.Period.String(),
+		IntervalCount:   max(req.IntervalCount, 1),
+		StartsAt:        startsAt,
+		EndsAt:          endsAt,
+		Status:          orderpb.SubscriptionStatus_SUBSCRIPTION_ACTIVE.String(),
+		NextRunAt:       startsAt,
+		Version:         1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := receiver.subscriptionRepo.Create(ctx, doc); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to persist subscription: %v", err)
+	}
+	return utils.ConvertSubscriptionToProto(doc), nil
+}
+
+func (receiver *SubscriptionService) GetSubscription(ctx context.Context, subscriptionID string) (*orderpb.Subscription, error) {
+	doc, err := receiver.load(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return utils.ConvertSubscriptionToProto(doc), nil
+}
+
+func (receiver *SubscriptionService) ListSubscriptions(ctx context.Context, userID string) ([]*orderpb.Subscription, error) {
+	userID, err := ownerFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	docs, err := receiver.subscriptionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list subscriptions: %v", err)
+	}
+	subscriptions := make([]*orderpb.Subscription, 0, len(docs))
+	for _, doc := range docs {
+		subscriptions = append(subscriptions, utils.ConvertSubscriptionToProto(doc))
+	}
+	return subscriptions, nil
+}
+
+// PauseSubscription keeps an unpaid order of the current period; it is still
+// charged and tracked while the subscription is paused.
+func (receiver *SubscriptionService) PauseSubscription(ctx context.Context, subscriptionID string, reason string) (*orderpb.Subscription, error) {
+	return receiver.change(ctx, subscriptionID, func(doc *models.Subscription, _ time.Time) (bool, error) {
+		if doc.Status == orderpb.SubscriptionStatus_SUBSCRIPTION_PAUSED.String() {
+			return false, nil
+		}
+		if !slices.Contains(models.SchedulableSubscriptionStatuses, doc.Status) {
+			return false, status.Errorf(codes.FailedPrecondition, "%s subscription cannot be paused", doc.Status)
+		}
+		doc.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_PAUSED.String()
+		doc.StatusReason = reason
+		return true, nil
+	})
+}
+
+// ResumeSubscription bills the current period right away if its time has come;
+// periods that passed while paused are skipped. Failed payments are forgotten, so a
+// subscription paused by dunning gets a fresh set of retries.
+func (receiver *SubscriptionService) ResumeSubscription(ctx context.Context, subscriptionID string) (*orderpb.Subscription, error) {
+	return receiver.change(ctx, subscriptionID, func(doc *models.Subscription, now time.Time) (bool, error) {
+		if slices.Contains(models.SchedulableSubscriptionStatuses, doc.Status) {
+			return false, nil
+		}
+		if doc.Status != orderpb.SubscriptionStatus_SUBSCRIPTION_PAUSED.String() {
+			return false, status.Errorf(codes.FailedPrecondition, "%s subscription cannot be resumed", doc.Status)
+		}
+
+		doc.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_ACTIVE.String()
+		doc.StatusReason = ""
+		doc.FailedAttempts = 0
+		if doc.PendingOrderID == "" {
+			for !doc.RunAt(doc.CurrentPeriod + 1).After(now) {
+				doc.CurrentPeriod++
+			}
+			doc.NextRunAt = doc.RunAt(doc.CurrentPeriod)
+			if doc.NextRunAt.Before(now) {
+				doc.NextRunAt = now
+			}
+		}
+		if doc.Ended(doc.CurrentPeriod) {
+			doc.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_COMPLETED.String()
+		}
+		return true, nil
+	})
+}
+
+func (receiver *SubscriptionService) CancelSubscription(ctx context.Context, subscriptionID string, reason string) (*orderpb.Subscription, error) {
+	return receiver.change(ctx, subscriptionID, func(doc *models.Subscription, _ time.Time) (bool, error) {
+		switch doc.Status {
+		case orderpb.SubscriptionStatus_SUBSCRIPTION_CANCELLED.String():
+			return false, nil
+		case orderpb.SubscriptionStatus_SUBSCRIPTION_COMPLETED.String():
+			return false, status.Error(codes.FailedPrecondition, "subscription is completed")
+		}
+		doc.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_CANCELLED.String()
+		doc.StatusReason = reason
+		return true, nil
+	})
+}
+
+// change applies fn and stores the subscription unless fn reports no change. A
+// change that lost the race with the scheduler is applied again to the fresh copy.
+func (receiver *SubscriptionService) change(ctx context.Context, subscriptionID string, fn func(doc *models.Subscription, now time.Time) (bool, error)) (*orderpb.Subscription, error) {
+	for attempt := 1; ; attempt++ {
+		doc, err := receiver.load(ctx, subscriptionID)
+		if err != nil {
+			return nil, err
+		}
+		now := receiver.now().UTC()
+		expectedVersion := doc.Version
+		changed, err := fn(&doc, now)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			return utils.ConvertSubscriptionToProto(doc), nil
+		}
+		doc.UpdatedAt = now
+
+		saved, err := receiver.subscriptionRepo.Replace(ctx, doc, expectedVersion)
+		if errors.Is(err, pj_errors.ErrConflict) && attempt < maxChangeAttempts {
+			continue
+		}
+		if errors.Is(err, pj_errors.ErrConflict) {
+			return nil, status.Error(codes.Aborted, "subscription was changed concurrently, retry")
+		}
+		if err != nil {
+			return nil, subscriptionError(err)
+		}
+		return utils.ConvertSubscriptionToProto(saved), nil
+	}
+}
+
+func (receiver *SubscriptionService) load(ctx context.Context, subscriptionID string) (models.Subscription, error) {
+	if subscriptionID == "" {
+		return models.Subscription{}, status.Error(codes.InvalidArgument, "subscription_id is required")
+	}
+	doc, err := receiver.subscriptionRepo.Get(ctx, subscriptionID)
+	if err != nil {
+		return models.Subscription{}, subscriptionError(err)
+	}
+	if !canAccess(ctx, doc.UserID) {
+		return models.Subscription{}, status.Error(codes.NotFound, "subscription not found")
+	}
+	return doc, nil
+}
+
+func subscriptionError(err error) error {
+	if errors.Is(err, pj_errors.ErrNotFound) {
+		return status.Error(codes.NotFound, "subscription not found")
+	}
+	return status.Errorf(codes.Internal, "failed to update subscription: %v", err)
+}
+
+func ownerFor(ctx context.Context, requestedUserID string) (string, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || principal.IsService() {
+		return requestedUserID, nil
+	}
+	if requestedUserID != "" && requestedUserID != principal.Subject {
+		return "", status.Error(codes.PermissionDenied, "subscriptions of another user are not accessible")
+	}
+	return principal.Subject, nil
+}
+
+func canAccess(ctx context.Context, ownerID string) bool {
+	principal, ok := auth.PrincipalFrom(ctx)
+	return !ok || principal.IsService() || principal.Subject == ownerID
+}
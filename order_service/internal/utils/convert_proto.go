@@ -10,24 +10,238 @@ import (
 func ConvertToProto(doc models.Order) *orderpb.Order {
 	items := make([]*orderpb.OrderItem, 0, len(doc.Items))
 	for _, item := range doc.Items {
+		itemStatus, ok := orderpb.ItemStatus_value[item.Status]
+		if !ok {
+			itemStatus = int32(orderpb.ItemStatus_ITEM_ACTIVE)
+		}
 		items = append(items, &orderpb.OrderItem{
+			ProductId:         item.ProductID,
+			Quantity:          item.Quantity,
+			Price:             item.Price,
+			CancelledQuantity: item.CancelledQuantity,
+			Status:            orderpb.ItemStatus(itemStatus),
+		})
+	}
+
+	status, ok := orderpb.OrderStatus_value[doc.Status]
+	if !ok {
+		status = int32(orderpb.OrderStatus_ORDER_STATUS_UNSPECIFIED)
+	}
+
+	order := &orderpb.Order{
+		OrderId:        doc.OrderID,
+		OrderNumber:    doc.Number,
+		UserId:         doc.UserID,
+		Items:          items,
+		TotalAmount:    doc.TotalAmount,
+		Currency:       doc.Currency,
+		Email:          doc.Email,
+		Status:         orderpb.OrderStatus(status),
+		StatusReason:   doc.StatusReason,
+		CreatedAt:      timestamppb.New(doc.CreatedAt),
+		Version:        doc.Version,
+		CartId:         doc.CartID,
+		PromoCode:      doc.PromoCode,
+		Discount:       doc.Discount,
+		SubscriptionId: doc.SubscriptionID,
+	}
+	for _, amendment := range doc.Amendments {
+		changes := make([]*orderpb.ItemChange, 0, len(amendment.Changes))
+		for _, change := range amendment.Changes {
+			changes = append(changes, &orderpb.ItemChange{
+				ProductId:   change.ProductID,
+				OldQuantity: change.OldQuantity,
+				NewQuantity: change.NewQuantity,
+				Price:       change.Price,
+			})
+		}
+		order.Amendments = append(order.Amendments, &orderpb.OrderAmendment{
+			Version:       amendment.Version,
+			Actor:         amendment.Actor,
+			Changes:       changes,
+			PreviousTotal: amendment.PreviousTotal,
+			NewTotal:      amendment.NewTotal,
+			At:            timestamppb.New(amendment.At),
+		})
+	}
+	for _, cancellation := range doc.Cancellations {
+		order.Cancellations = append(order.Cancellations, ConvertCancellationToProto(cancellation))
+	}
+	order.ShippingAddress = ConvertShippingAddressToProto(doc.ShippingAddress)
+	if shipment := doc.Shipment; shipment != nil {
+		order.Shipment = &orderpb.Shipment{
+			Carrier:        shipment.Carrier,
+			TrackingNumber: shipment.TrackingNumber,
+			ShippedAt:      timestamppb.New(shipment.ShippedAt),
+		}
+		if !shipment.DeliveredAt.IsZero() {
+			order.Shipment.DeliveredAt = timestamppb.New(shipment.DeliveredAt)
+		}
+	}
+	if !doc.ScheduledAt.IsZero() {
+		order.ScheduledAt = timestamppb.New(doc.ScheduledAt)
+	}
+	if !doc.SubmittedAt.IsZero() || doc.ScheduledAt.IsZero() {
+		order.SubmittedAt = timestamppb.New(doc.PendingSince())
+	}
+	return order
+}
+
+func ConvertShippingAddressToProto(address *models.ShippingAddress) *orderpb.ShippingAddress {
+	if address == nil {
+		return nil
+	}
+	return &orderpb.ShippingAddress{
+		Recipient:  address.Recipient,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Phone:      address.Phone,
+	}
+}
+
+// ShippingAddressFromProto copies the address as is; CreateOrder validates it.
+func ShippingAddressFromProto(address *orderpb.ShippingAddress) *models.ShippingAddress {
+	if address == nil {
+		return nil
+	}
+	return &models.ShippingAddress{
+		Recipient:  address.Recipient,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Phone:      address.Phone,
+	}
+}
+
+func ConvertCancellationToProto(cancellation models.ItemCancellation) *orderpb.ItemCancellation {
+	items := make([]*orderpb.CancelledItem, 0, len(cancellation.Items))
+	for _, item := range cancellation.Items {
+		items = append(items, &orderpb.CancelledItem{
 			ProductId: item.ProductID,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 		})
 	}
+	return &orderpb.ItemCancellation{
+		CancellationId: cancellation.CancellationID,
+		Items:          items,
+		Amount:         cancellation.Amount,
+		Reason:         cancellation.Reason,
+		Actor:          cancellation.Actor,
+		At:             timestamppb.New(cancellation.At),
+	}
+}
 
-	status, ok := orderpb.OrderStatus_value[doc.Status]
+// ConvertCartToProto shows the items with their own prices and the promo code only
+// in the totals.
+func ConvertCartToProto(doc models.Cart, totals models.CartTotals) *orderpb.Cart {
+	items := make([]*orderpb.OrderItem, 0, len(doc.Items))
+	for _, item := range doc.Items {
+		items = append(items, &orderpb.OrderItem{
+			ProductId: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+
+	status, ok := orderpb.CartStatus_value[doc.Status]
 	if !ok {
-		status = int32(orderpb.OrderStatus_ORDER_STATUS_UNSPECIFIED)
+		status = int32(orderpb.CartStatus_CART_STATUS_UNSPECIFIED)
+	}
+
+	return &orderpb.Cart{
+		CartId:    doc.CartID,
+		UserId:    doc.UserID,
+		Items:     items,
+		PromoCode: doc.PromoCode,
+		Subtotal:  totals.Subtotal,
+		Discount:  totals.Discount,
+		Total:     totals.Total,
+		Status:    orderpb.CartStatus(status),
+		OrderId:   doc.OrderID,
+		Version:   doc.Version,
+		CreatedAt: timestamppb.New(doc.CreatedAt),
+		UpdatedAt: timestamppb.New(doc.UpdatedAt),
+		ExpiresAt: timestamppb.New(doc.ExpiresAt),
+	}
+}
+
+func ConvertSubscriptionToProto(doc models.Subscription) *orderpb.Subscription {
+	items := make([]*orderpb.OrderItem, 0, len(doc.Items))
+	for _, item := range doc.Items {
+		items = append(items, &orderpb.OrderItem{
+			ProductId: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+	runs := make([]*orderpb.SubscriptionRun, 0, len(doc.Runs))
+	for _, run := range doc.Runs {
+		runs = append(runs, &orderpb.SubscriptionRun{
+			Period:      run.Period,
+			Attempt:     run.Attempt,
+			OrderId:     run.OrderID,
+			OrderStatus: orderpb.OrderStatus(orderpb.OrderStatus_value[run.OrderStatus]),
+			At:          timestamppb.New(run.At),
+		})
+	}
+
+	subscription := &orderpb.Subscription{
+		SubscriptionId:  doc.SubscriptionID,
+		UserId:          doc.UserID,
+		Items:           items,
+		ShippingAddress: ConvertShippingAddressToProto(doc.ShippingAddress),
+		Period:          orderpb.SubscriptionPeriod(orderpb.SubscriptionPeriod_value[doc.Period]),
+		IntervalCount:   doc.IntervalCount,
+		StartsAt:        timestamppb.New(doc.StartsAt),
+		Status:          orderpb.SubscriptionStatus(orderpb.SubscriptionStatus_value[doc.Status]),
+		StatusReason:    doc.StatusReason,
+		NextRunAt:       timestamppb.New(doc.NextRunAt),
+		FailedAttempts:  doc.FailedAttempts,
+		Runs:            runs,
+		CreatedAt:       timestamppb.New(doc.CreatedAt),
+		UpdatedAt:       timestamppb.New(doc.UpdatedAt),
+	}
+	if !doc.EndsAt.IsZero() {
+		subscription.EndsAt = timestamppb.New(doc.EndsAt)
+	}
+	return subscription
+}
+
+func ConvertReturnToProto(doc models.Return) *orderpb.Return {
+	items := make([]*orderpb.ReturnItem, 0, len(doc.Items))
+	for _, item := range doc.Items {
+		items = append(items, &orderpb.ReturnItem{
+			ProductId: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	history := make([]*orderpb.ReturnHistoryEntry, 0, len(doc.History))
+	for _, entry := range doc.History {
+		history = append(history, &orderpb.ReturnHistoryEntry{
+			Status:  orderpb.ReturnStatus(orderpb.ReturnStatus_value[entry.Status]),
+			Actor:   entry.Actor,
+			Comment: entry.Comment,
+			At:      timestamppb.New(entry.At),
+		})
 	}
 
-	return &orderpb.Order{
-		OrderId:     doc.OrderID,
-		UserId:      doc.UserID,
-		Items:       items,
-		TotalAmount: doc.TotalAmount,
-		Status:      orderpb.OrderStatus(status),
-		CreatedAt:   timestamppb.New(doc.CreatedAt),
+	return &orderpb.Return{
+		ReturnId:     doc.ReturnID,
+		OrderId:      doc.OrderID,
+		UserId:       doc.UserID,
+		Items:        items,
+		Reason:       doc.Reason,
+		Status:       orderpb.ReturnStatus(orderpb.ReturnStatus_value[doc.Status]),
+		RefundAmount: doc.RefundAmount,
+		History:      history,
+		CreatedAt:    timestamppb.New(doc.CreatedAt),
+		UpdatedAt:    timestamppb.New(doc.UpdatedAt),
 	}
 }
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"order-service-system/order_service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidPageToken = errors.New("invalid page token")
+
+// EncodePageToken makes an opaque token from the cursor of the last order on a page.
+func EncodePageToken(cursor models.OrderCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + ":" + cursor.OrderID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodePageToken(token string) (models.OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.OrderCursor{}, errInvalidPageToken
+	}
+
+	createdAt, orderID, ok := strings.Cut(string(raw), ":")
+	if !ok || orderID == "" {
+		return models.OrderCursor{}, errInvalidPageToken
+	}
+	nanos, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return models.OrderCursor{}, errInvalidPageToken
+	}
+
+	return models.OrderCursor{CreatedAt: time.Unix(0, nanos).UTC(), OrderID: orderID}, nil
+}
+
+// EncodeOffsetToken makes an opaque token from the number of results already
+// returned, for result sets without a stable cursor such as search.
+func EncodeOffsetToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func DecodeOffsetToken(token string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errInvalidPageToken
+	}
+	value, ok := strings.CutPrefix(string(raw), "offset:")
+	if !ok {
+		return 0, errInvalidPageToken
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, errInvalidPageToken
+	}
+	return offset, nil
+}
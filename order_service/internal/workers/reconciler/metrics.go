@@ -0,0 +1,19 @@
+package reconciler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	actionPaid      = "paid"
+	actionFailed    = "failed"
+	actionExpired   = "expired"
+	actionReemitted = "reemitted"
+	actionWaiting   = "waiting"
+)
+
+var reconciledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_reconciliation_total",
+	Help: "Stuck PENDING orders handled by the reconciler by action.",
+}, []string{"action"})
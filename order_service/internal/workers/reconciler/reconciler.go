@@ -0,0 +1,222 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	orderpb "order-service-system/proto/order"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Configuration struct {
+	Interval time.Duration `env:"RECONCILE_INTERVAL" envDefault:"1m"`
+	// PendingAfter is the age after which a PENDING order is considered stuck.
+	PendingAfter time.Duration `env:"RECONCILE_PENDING_AFTER" envDefault:"5m"`
+	// ExpireAfter is the age after which an unresolved order is moved to EXPIRED.
+	ExpireAfter  time.Duration `env:"RECONCILE_EXPIRE_AFTER" envDefault:"30m"`
+	BatchSize    int           `env:"RECONCILE_BATCH_SIZE" envDefault:"100"`
+	QueryTimeout time.Duration `env:"RECONCILE_QUERY_TIMEOUT" envDefault:"2s"`
+}
+
+type OrderRepository interface {
+	ListStale(ctx context.Context, status string, submittedBefore time.Time, limit int) ([]models.Order, error)
+	TransitionStatus(ctx context.Context, orderID string, from string, to string, reason string) (bool, error)
+}
+
+type NatsClient interface {
+	PublishOrderCreated(ctx context.Context, event models.OrderCreatedEvent) error
+	PublishOrderPaid(ctx context.Context, event models.OrderPaidEvent) error
+	PublishOrderFailed(ctx context.Context, event models.OrderFailedEvent) error
+	QueryPaymentStatus(ctx context.Context, orderID string) (events.PaymentStatusReply, error)
+}
+
+// Reconciler resolves orders that stay PENDING because billing was down or an
+// event was lost: it asks billing for the payment outcome, re-emits order.created
+// when billing has never seen the order and expires orders nobody could resolve.
+// Payment results it applies are published like billing does, so consumers of
+// order.paid and order.failed see them too.
+type Reconciler struct {
+	logger     *zap.Logger
+	orderRepo  OrderRepository
+	natsClient NatsClient
+	cfg        Configuration
+	now        func() time.Time
+}
+
+type Deps struct {
+	Logger        *zap.Logger
+	OrderRepo     OrderRepository
+	NatsClient    NatsClient
+	Configuration Configuration
+	// Now is used by tests to control time.
+	Now func() time.Time
+}
+
+func NewReconciler(deps Deps) *Reconciler {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewReconciler> of <Reconciler>")
+	}
+	if deps.OrderRepo == nil {
+		panic("order repo must not be nil on <NewReconciler> of <Reconciler>")
+	}
+	if deps.NatsClient == nil {
+		panic("nats client must not be nil on <NewReconciler> of <Reconciler>")
+	}
+
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	cfg := deps.Configuration
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.ExpireAfter < cfg.PendingAfter {
+		cfg.ExpireAfter = cfg.PendingAfter
+	}
+
+	return &Reconciler{
+		logger:     deps.Logger,
+		orderRepo:  deps.OrderRepo,
+		natsClient: deps.NatsClient,
+		cfg:        cfg,
+		now:        now,
+	}
+}
+
+func (receiver *Reconciler) Start(ctx context.Context) {
+	ticker := time.NewTicker(receiver.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := receiver.Reconcile(ctx); err != nil {
+				receiver.logger.Warn("reconciliation failed on <Start> of <Reconciler>", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Reconcile handles one batch of stuck orders, oldest first.
+func (receiver *Reconciler) Reconcile(ctx context.Context) error {
+	now := receiver.now()
+	ctx = tenant.WithAllTenants(ctx)
+	stuck, err := receiver.orderRepo.ListStale(ctx, orderpb.OrderStatus_PENDING.String(), now.Add(-receiver.cfg.PendingAfter), receiver.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("list pending orders: %w", err)
+	}
+
+	for _, order := range stuck {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		receiver.reconcile(tenant.WithTenant(ctx, order.TenantID), order, now)
+	}
+	return nil
+}
+
+func (receiver *Reconciler) reconcile(ctx context.Context, order models.Order, now time.Time) {
+	// Возраст считается с отправки на оплату: запланированный заказ мог ждать своего времени долго.
+	age := now.Sub(order.PendingSince())
+	logger := receiver.logger.With(zap.String("tenant_id", tenant.FromContext(ctx)), zap.String("order_id", order.OrderID), zap.Duration("age", age))
+	expired := age >= receiver.cfg.ExpireAfter
+
+	queryCtx, cancel := context.WithTimeout(ctx, receiver.cfg.QueryTimeout)
+	reply, err := receiver.natsClient.QueryPaymentStatus(queryCtx, order.OrderID)
+	cancel()
+	if err != nil {
+		logger.Warn("billing did not answer on <reconcile> of <Reconciler>", zap.Error(err))
+		reply.Outcome = events.PaymentOutcomeUnknown
+	}
+
+	switch reply.Outcome {
+	case events.PaymentOutcomePaid:
+		receiver.transition(ctx, logger, order, orderpb.OrderStatus_PAID, "", actionPaid)
+		return
+	case events.PaymentOutcomeDeclined:
+		reason := reply.Reason
+		if reason == "" {
+			reason = "payment declined"
+		}
+		receiver.transition(ctx, logger, order, orderpb.OrderStatus_FAILED, reason, actionFailed)
+		return
+	}
+
+	if expired {
+		reason := fmt.Sprintf("payment not confirmed within %s", receiver.cfg.ExpireAfter)
+		if err != nil {
+			reason += ": billing unavailable"
+		}
+		receiver.transition(ctx, logger, order, orderpb.OrderStatus_EXPIRED, reason, actionExpired)
+		return
+	}
+
+	// Оплата ещё идёт — ждём следующего прохода.
+	if reply.Outcome == events.PaymentOutcomeProcessing {
+		reconciledTotal.WithLabelValues(actionWaiting).Inc()
+		return
+	}
+
+	// Billing не видел заказ: событие потеряно, публикуем его повторно (при ошибке оно попадёт в outbox).
+	if err := receiver.natsClient.PublishOrderCreated(ctx, models.OrderCreatedEvent{
+		TenantID:    tenant.FromContext(ctx),
+		OrderID:     order.OrderID,
+		UserID:      order.UserID,
+		TotalAmount: order.TotalAmount,
+		Currency:    order.Currency,
+		CreatedAt:   order.CreatedAt,
+	}); err != nil {
+		logger.Warn("failed to re-emit order.created on <reconcile> of <Reconciler>", zap.Error(err))
+	}
+	reconciledTotal.WithLabelValues(actionReemitted).Inc()
+	logger.Info("re-emitted order.created on <reconcile> of <Reconciler>")
+}
+
+func (receiver *Reconciler) transition(ctx context.Context, logger *zap.Logger, order models.Order, status orderpb.OrderStatus, reason string, action string) {
+	moved, err := receiver.orderRepo.TransitionStatus(ctx, order.OrderID, orderpb.OrderStatus_PENDING.String(), status.String(), reason)
+	if err != nil {
+		logger.Error("failed to update status on <transition> of <Reconciler>", zap.String("status", status.String()), zap.Error(err))
+		return
+	}
+	if !moved {
+		// Статус успели изменить параллельно (billing или другая реплика).
+		logger.Info("order already left PENDING on <transition> of <Reconciler>")
+		return
+	}
+
+	reconciledTotal.WithLabelValues(action).Inc()
+	logger.Info("order reconciled on <transition> of <Reconciler>", zap.String("status", status.String()), zap.String("reason", reason))
+
+	if err := receiver.publishResult(ctx, order, status, reason); err != nil {
+		// Заказ уже вышел из PENDING: следующий проход его не увидит.
+		logger.Error("failed to publish payment result on <transition> of <Reconciler>", zap.String("status", status.String()), zap.Error(err))
+	}
+}
+
+func (receiver *Reconciler) publishResult(ctx context.Context, order models.Order, status orderpb.OrderStatus, reason string) error {
+	switch status {
+	case orderpb.OrderStatus_PAID:
+		return receiver.natsClient.PublishOrderPaid(ctx, models.OrderPaidEvent{
+			OrderID:     order.OrderID,
+			UserID:      order.UserID,
+			TotalAmount: order.TotalAmount,
+			PaidAt:      receiver.now(),
+		})
+	case orderpb.OrderStatus_FAILED:
+		return receiver.natsClient.PublishOrderFailed(ctx, models.OrderFailedEvent{
+			OrderID:  order.OrderID,
+			UserID:   order.UserID,
+			Reason:   reason,
+			FailedAt: receiver.now(),
+		})
+	default:
+		return nil
+	}
+}
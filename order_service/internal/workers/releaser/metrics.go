@@ -0,0 +1,16 @@
+package releaser
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	resultReleased = "released"
+	resultSkipped  = "skipped"
+)
+
+var releasedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_scheduled_releases_total",
+	Help: "Scheduled orders handled by the releaser by result.",
+}, []string{"result"})
@@ -0,0 +1,146 @@
+package releaser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Configuration struct {
+	Interval  time.Duration `env:"SCHEDULED_ORDERS_INTERVAL" envDefault:"15s"`
+	BatchSize int           `env:"SCHEDULED_ORDERS_BATCH_SIZE" envDefault:"100"`
+}
+
+type OrderRepository interface {
+	ListScheduled(ctx context.Context, dueBefore time.Time, limit int) ([]models.Order, error)
+	Release(ctx context.Context, orderID string, submittedAt time.Time) (models.Order, error)
+}
+
+type NatsClient interface {
+	PublishOrderCreated(ctx context.Context, event models.OrderCreatedEvent) error
+}
+
+// Releaser submits SCHEDULED orders for payment when their time comes. The state
+// lives in Mongo, so nothing is lost on restart; the guarded SCHEDULED -> PENDING
+// update lets only one replica publish order.created. An event lost after the
+// update is re-emitted by the reconciler like for any other PENDING order.
+type Releaser struct {
+	logger     *zap.Logger
+	orderRepo  OrderRepository
+	natsClient NatsClient
+	cfg        Configuration
+	now        func() time.Time
+}
+
+type Deps struct {
+	Logger        *zap.Logger
+	OrderRepo     OrderRepository
+	NatsClient    NatsClient
+	Configuration Configuration
+	// Now is used by tests to control time.
+	Now func() time.Time
+}
+
+func NewReleaser(deps Deps) *Releaser {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewReleaser> of <Releaser>")
+	}
+	if deps.OrderRepo == nil {
+		panic("order repo must not be nil on <NewReleaser> of <Releaser>")
+	}
+	if deps.NatsClient == nil {
+		panic("nats client must not be nil on <NewReleaser> of <Releaser>")
+	}
+
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+	cfg := deps.Configuration
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Releaser{
+		logger:     deps.Logger,
+		orderRepo:  deps.OrderRepo,
+		natsClient: deps.NatsClient,
+		cfg:        cfg,
+		now:        now,
+	}
+}
+
+func (receiver *Releaser) Start(ctx context.Context) {
+	ticker := time.NewTicker(receiver.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := receiver.Release(ctx); err != nil {
+				receiver.logger.Warn("releasing scheduled orders failed on <Start> of <Releaser>", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Release submits one batch of due orders, most overdue first.
+func (receiver *Releaser) Release(ctx context.Context) error {
+	now := receiver.now().UTC()
+	ctx = tenant.WithAllTenants(ctx)
+	due, err := receiver.orderRepo.ListScheduled(ctx, now, receiver.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("list scheduled orders: %w", err)
+	}
+
+	for _, order := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		receiver.release(tenant.WithTenant(ctx, order.TenantID), order, now)
+	}
+	return nil
+}
+
+func (receiver *Releaser) release(ctx context.Context, order models.Order, now time.Time) {
+	logger := receiver.logger.With(zap.String("tenant_id", tenant.FromContext(ctx)), zap.String("order_id", order.OrderID), zap.Duration("delay", now.Sub(order.ScheduledAt)))
+
+	released, err := receiver.orderRepo.Release(ctx, order.OrderID, now)
+	if errors.Is(err, pj_errors.ErrConflict) {
+		// Заказ отправила другая реплика или его успели отменить.
+		releasedTotal.WithLabelValues(resultSkipped).Inc()
+		logger.Info("order already left SCHEDULED on <release> of <Releaser>")
+		return
+	}
+	if err != nil {
+		logger.Error("failed to release order on <release> of <Releaser>", zap.Error(err))
+		return
+	}
+
+	if err := receiver.natsClient.PublishOrderCreated(ctx, models.OrderCreatedEvent{
+		TenantID:    tenant.FromContext(ctx),
+		OrderID:     released.OrderID,
+		UserID:      released.UserID,
+		TotalAmount: released.TotalAmount,
+		Currency:    released.Currency,
+		CreatedAt:   released.CreatedAt,
+	}); err != nil {
+		logger.Warn("failed to publish event on <release> of <Releaser>",
+			zap.String("subject", "order.created"),
+			zap.Error(err),
+		)
+	}
+	releasedTotal.WithLabelValues(resultReleased).Inc()
+	logger.Info("scheduled order submitted for payment on <release> of <Releaser>")
+}
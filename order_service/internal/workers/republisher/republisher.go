@@ -48,14 +48,14 @@ func (receiver *Republisher) Start(ctx context.Context, interval time.Duration)
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			receiver.republish()
+			receiver.republish(ctx)
 		}
 	}
 }
 
-func (receiver *Republisher) republish() {
+func (receiver *Republisher) republish(ctx context.Context) {
 	if err := receiver.bboltStore.Range(func(key string, event models.OrderCreatedEvent) error {
-		if err := receiver.natsClient.PublishOrderCreated(event); err != nil {
+		if err := receiver.natsClient.PublishOrderCreated(ctx, event); err != nil {
 			receiver.logger.Warn("retry publish failed on <republish> of <Republisher>",
 				zap.String("order_id", event.OrderID),
 				zap.Error(err),
@@ -0,0 +1,18 @@
+package rollup
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	resultApplied = "applied"
+	resultSkipped = "skipped"
+	resultFailed  = "failed"
+	resultInvalid = "invalid"
+)
+
+var updatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_analytics_rollup_updates_total",
+	Help: "Order events applied to the daily analytics rollups by event type and result.",
+}, []string{"event", "result"})
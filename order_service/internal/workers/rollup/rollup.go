@@ -0,0 +1,200 @@
+package rollup
+
+import (
+	"context"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const queueRollup = "order-analytics-workers"
+
+type Store interface {
+	Order(ctx context.Context, orderID string) (models.Order, bool, error)
+	Apply(ctx context.Context, key string, delta models.RollupDelta) (bool, error)
+}
+
+// Updater keeps the daily analytics rollups up to date from order events. An order
+// is counted on the day it was created, once per kind of event, and its item
+// cancellations and refunds are taken off its revenue there once each: the rollup
+// keeps the keys of the events counted in it, so redeliveries and re-emitted events
+// are skipped on any replica.
+type Updater struct {
+	logger     *zap.Logger
+	subscriber bus.Subscriber
+	store      Store
+}
+
+type Deps struct {
+	Logger     *zap.Logger
+	Subscriber bus.Subscriber
+	Store      Store
+}
+
+func NewUpdater(deps Deps) *Updater {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewUpdater> of <Updater>")
+	}
+	if deps.Subscriber == nil {
+		panic("subscriber must not be nil on <NewUpdater> of <Updater>")
+	}
+	if deps.Store == nil {
+		panic("store must not be nil on <NewUpdater> of <Updater>")
+	}
+	return &Updater{
+		logger:     deps.Logger,
+		subscriber: deps.Subscriber,
+		store:      deps.Store,
+	}
+}
+
+func (receiver *Updater) Start(ctx context.Context) ([]bus.Subscription, error) {
+	handlers := []struct {
+		subject string
+		handler bus.Handler
+	}{
+		{events.TypeOrderCreated, receiver.handleCreated},
+		{events.TypeOrderPaid, receiver.handlePaid},
+		{events.TypeOrderFailed, receiver.handleFailed},
+		{events.TypeOrderItemsCancelled, receiver.handleItemsCancelled},
+		{events.TypeReturnRefunded, receiver.handleRefunded},
+	}
+
+	subscriptions := make([]bus.Subscription, 0, len(handlers))
+	for _, h := range handlers {
+		sub, err := receiver.subscriber.Subscribe(ctx, h.subject, queueRollup, h.handler)
+		if err != nil {
+			for _, subscribed := range subscriptions {
+				_ = subscribed.Unsubscribe()
+			}
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	receiver.logger.Info("listening for order events on <Start> of <Updater>",
+		zap.Strings("subjects", []string{events.TypeOrderCreated, events.TypeOrderPaid, events.TypeOrderFailed, events.TypeOrderItemsCancelled, events.TypeReturnRefunded}),
+		zap.String("queue", queueRollup),
+	)
+	return subscriptions, nil
+}
+
+func (receiver *Updater) handleCreated(ctx context.Context, msg *bus.Message) error {
+	envelope, err := events.Unmarshal[events.OrderCreatedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode created payload on <handleCreated> of <Updater>", zap.Error(err))
+		updatesTotal.WithLabelValues(events.TypeOrderCreated, resultInvalid).Inc()
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+	return receiver.apply(ctx, events.TypeOrderCreated, models.RollupCreated+":"+envelope.Data.OrderID, envelope.Data.OrderID,
+		fixed(models.AnalyticsMetrics{OrdersCount: 1}))
+}
+
+func (receiver *Updater) handlePaid(ctx context.Context, msg *bus.Message) error {
+	envelope, err := events.Unmarshal[events.OrderPaidPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode paid payload on <handlePaid> of <Updater>", zap.Error(err))
+		updatesTotal.WithLabelValues(events.TypeOrderPaid, resultInvalid).Inc()
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+	return receiver.apply(ctx, events.TypeOrderPaid, models.RollupPaid+":"+envelope.Data.OrderID, envelope.Data.OrderID,
+		fixed(models.AnalyticsMetrics{PaidOrders: 1, GrossRevenue: envelope.Data.TotalAmount}))
+}
+
+func (receiver *Updater) handleFailed(ctx context.Context, msg *bus.Message) error {
+	envelope, err := events.Unmarshal[events.OrderFailedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode failed payload on <handleFailed> of <Updater>", zap.Error(err))
+		updatesTotal.WithLabelValues(events.TypeOrderFailed, resultInvalid).Inc()
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+	return receiver.apply(ctx, events.TypeOrderFailed, models.RollupFailed+":"+envelope.Data.OrderID, envelope.Data.OrderID,
+		fixed(models.AnalyticsMetrics{FailedPayments: 1}))
+}
+
+// handleItemsCancelled takes the cancelled amount off the revenue. The cancellation
+// that left nothing in the order also takes it off the paid orders, like the order
+// leaves the paid statuses.
+func (receiver *Updater) handleItemsCancelled(ctx context.Context, msg *bus.Message) error {
+	envelope, err := events.Unmarshal[events.OrderItemsCancelledPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode items cancelled payload on <handleItemsCancelled> of <Updater>", zap.Error(err))
+		updatesTotal.WithLabelValues(events.TypeOrderItemsCancelled, resultInvalid).Inc()
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+	payload := envelope.Data
+	return receiver.apply(ctx, events.TypeOrderItemsCancelled, models.RollupCancelled+":"+payload.CancellationID, payload.OrderID,
+		func(order models.Order) models.AnalyticsMetrics {
+			metrics := models.AnalyticsMetrics{GrossRevenue: -payload.Amount}
+			if order.Status == "CANCELLED" && len(order.Cancellations) > 0 &&
+				order.Cancellations[len(order.Cancellations)-1].CancellationID == payload.CancellationID {
+				metrics.PaidOrders = -1
+			}
+			return metrics
+		})
+}
+
+func (receiver *Updater) handleRefunded(ctx context.Context, msg *bus.Message) error {
+	envelope, err := events.Unmarshal[events.ReturnRefundedPayload](msg.Header.Get(events.HeaderContentType), msg.Data)
+	if err != nil {
+		receiver.logger.Error("failed to decode refunded payload on <handleRefunded> of <Updater>", zap.Error(err))
+		updatesTotal.WithLabelValues(events.TypeReturnRefunded, resultInvalid).Inc()
+		return nil
+	}
+	ctx = tenant.WithTenant(ctx, envelope.TenantID)
+	return receiver.apply(ctx, events.TypeReturnRefunded, models.RollupRefunded+":"+envelope.Data.ReturnID, envelope.Data.OrderID,
+		fixed(models.AnalyticsMetrics{GrossRevenue: -envelope.Data.Amount}))
+}
+
+func fixed(metrics models.AnalyticsMetrics) func(models.Order) models.AnalyticsMetrics {
+	return func(models.Order) models.AnalyticsMetrics { return metrics }
+}
+
+// apply adds the metrics of the order to the rollup of the day it was created under
+// the key of the event. A failed update is redelivered.
+func (receiver *Updater) apply(ctx context.Context, event string, key string, orderID string, metrics func(models.Order) models.AnalyticsMetrics) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	order, found, err := receiver.store.Order(ctx, orderID)
+	if err != nil {
+		receiver.logger.Error("failed to get order on <apply> of <Updater>", zap.String("event", event), zap.String("order_id", orderID), zap.Error(err))
+		updatesTotal.WithLabelValues(event, resultFailed).Inc()
+		return err
+	}
+	if !found {
+		updatesTotal.WithLabelValues(event, resultSkipped).Inc()
+		return nil
+	}
+
+	delta := models.RollupDelta{Day: order.CreatedAt, Currency: order.Currency, Metrics: metrics(order)}
+	applied, err := receiver.store.Apply(ctx, key, delta)
+	if err != nil {
+		receiver.logger.Error("failed to update rollup on <apply> of <Updater>", zap.String("event", event), zap.String("order_id", orderID), zap.Error(err))
+		updatesTotal.WithLabelValues(event, resultFailed).Inc()
+		return err
+	}
+	if !applied {
+		updatesTotal.WithLabelValues(event, resultSkipped).Inc()
+		return nil
+	}
+	updatesTotal.WithLabelValues(event, resultApplied).Inc()
+
+	receiver.logger.Debug("order counted in rollup on <apply> of <Updater>",
+		zap.String("event", event),
+		zap.String("tenant_id", tenant.FromContext(ctx)),
+		zap.String("order_id", orderID),
+		zap.Time("day", models.DayOf(order.CreatedAt)),
+	)
+	return nil
+}
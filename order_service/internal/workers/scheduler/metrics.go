@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	resultCreated          = "created"
+	resultSettled          = "settled"
+	resultPaymentFailed    = "payment_failed"
+	resultDunningExhausted = "dunning_exhausted"
+	resultCompleted        = "completed"
+	resultRejected         = "rejected"
+)
+
+var runsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_subscription_runs_total",
+	Help: "Subscription runs handled by the scheduler by result.",
+}, []string{"result"})
@@ -0,0 +1,327 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/utils"
+	orderpb "order-service-system/proto/order"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// orderNamespace derives the ids of subscription orders, so that every replica
+// creates the same order for the same run.
+var orderNamespace = uuid.MustParse("6f1c1f5e-3c55-4b8e-9d7a-2f0f4f7a9c10")
+
+type Configuration struct {
+	Interval  time.Duration `env:"SUBSCRIPTION_SCHEDULE_INTERVAL" envDefault:"1m"`
+	BatchSize int           `env:"SUBSCRIPTION_BATCH_SIZE" envDefault:"100"`
+	// DunningRetries are the delays of the retries after a failed payment; the
+	// subscription is paused when all of them have failed.
+	DunningRetries []time.Duration `env:"SUBSCRIPTION_DUNNING_RETRIES" envSeparator:"," envDefault:"24h,72h,168h"`
+}
+
+type SubscriptionRepository interface {
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error)
+	ListAwaitingPayment(ctx context.Context, limit int) ([]models.Subscription, error)
+	Replace(ctx context.Context, subscription models.Subscription, expectedVersion int64) (models.Subscription, error)
+}
+
+// OrderCreator is the OrderService; subscription orders go through CreateOrder.
+type OrderCreator interface {
+	CreateOrderFrom(ctx context.Context, req *orderpb.CreateOrderRequest, origin models.OrderOrigin) (*orderpb.Order, error)
+}
+
+type OrderReader interface {
+	Get(ctx context.Context, orderID string) (models.Order, error)
+}
+
+// Scheduler creates the orders of due subscriptions and follows their payment.
+type Scheduler struct {
+	logger           *zap.Logger
+	subscriptionRepo SubscriptionRepository
+	orders           OrderCreator
+	orderRepo        OrderReader
+	cfg              Configuration
+	now              func() time.Time
+}
+
+type Deps struct {
+	Logger           *zap.Logger
+	SubscriptionRepo SubscriptionRepository
+	Orders           OrderCreator
+	OrderRepo        OrderReader
+	Configuration    Configuration
+	// Now is used by tests to control time.
+	Now func() time.Time
+}
+
+func NewScheduler(deps Deps) *Scheduler {
+	if deps.Logger == nil {
+		panic("logger must not be nil on <NewScheduler> of <Scheduler>")
+	}
+	if deps.SubscriptionRepo == nil {
+		panic("subscription repo must not be nil on <NewScheduler> of <Scheduler>")
+	}
+	if deps.Orders == nil {
+		panic("orders must not be nil on <NewScheduler> of <Scheduler>")
+	}
+	if deps.OrderRepo == nil {
+		panic("order repo must not be nil on <NewScheduler> of <Scheduler>")
+	}
+	now := deps.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	cfg := deps.Configuration
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Scheduler{
+		logger:           deps.Logger,
+		subscriptionRepo: deps.SubscriptionRepo,
+		orders:           deps.Orders,
+		orderRepo:        deps.OrderRepo,
+		cfg:              cfg,
+		now:              now,
+	}
+}
+
+func (receiver *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(receiver.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := receiver.Run(ctx); err != nil {
+				receiver.logger.Warn("scheduling failed on <Start> of <Scheduler>", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Run first follows the payment of created orders and then starts the due runs.
+// Every step is guarded by the version of the subscription and the order ids are
+// derived from the run, so replicas running at the same time do not double-bill.
+func (receiver *Scheduler) Run(ctx context.Context) error {
+	ctx = tenant.WithAllTenants(ctx)
+	awaiting, err := receiver.subscriptionRepo.ListAwaitingPayment(ctx, receiver.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("list subscriptions awaiting payment: %w", err)
+	}
+	for _, subscription := range awaiting {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		receiver.follow(tenant.WithTenant(ctx, subscription.TenantID), subscription)
+	}
+
+	due, err := receiver.subscriptionRepo.ListDue(ctx, receiver.now().UTC(), receiver.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("list due subscriptions: %w", err)
+	}
+	for _, subscription := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		receiver.start(tenant.WithTenant(ctx, subscription.TenantID), subscription)
+	}
+	return nil
+}
+
+// start records the run before its order exists; follow creates the order and
+// also recreates it if the scheduler stopped in between.
+func (receiver *Scheduler) start(ctx context.Context, subscription models.Subscription) {
+	now := receiver.now().UTC()
+	expectedVersion := subscription.Version
+
+	if subscription.Ended(subscription.CurrentPeriod) {
+		subscription.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_COMPLETED.String()
+		subscription.UpdatedAt = now
+		if receiver.save(ctx, subscription, expectedVersion) {
+			runsTotal.WithLabelValues(resultCompleted).Inc()
+		}
+		return
+	}
+
+	run := models.SubscriptionRun{
+		Period:      subscription.CurrentPeriod,
+		Attempt:     subscription.FailedAttempts,
+		OrderStatus: orderpb.OrderStatus_PENDING.String(),
+		At:          now,
+	}
+	// Номер запуска, а не попытки: после возобновления счётчик попыток сбрасывается.
+	run.OrderID = uuid.NewSHA1(orderNamespace, []byte(fmt.Sprintf("%s/%d", subscription.SubscriptionID, len(subscription.Runs)))).String()
+	subscription.PendingOrderID = run.OrderID
+	subscription.Runs = append(subscription.Runs, run)
+	subscription.UpdatedAt = now
+	if !receiver.save(ctx, subscription, expectedVersion) {
+		return
+	}
+	subscription.Version = expectedVersion + 1
+	receiver.follow(ctx, subscription)
+}
+
+// follow creates the pending order if it is missing and applies its payment result.
+func (receiver *Scheduler) follow(ctx context.Context, subscription models.Subscription) {
+	logger := receiver.logger.With(
+		zap.String("tenant_id", tenant.FromContext(ctx)),
+		zap.String("subscription_id", subscription.SubscriptionID),
+		zap.String("order_id", subscription.PendingOrderID),
+	)
+
+	order, err := receiver.orderRepo.Get(ctx, subscription.PendingOrderID)
+	if errors.Is(err, pj_errors.ErrNotFound) {
+		order, err = receiver.place(ctx, subscription)
+		if status.Code(err) == codes.InvalidArgument {
+			// Шаблон заказа больше не проходит валидацию — повторять бессмысленно.
+			receiver.pause(ctx, subscription, fmt.Sprintf("order rejected: %s", status.Convert(err).Message()))
+			runsTotal.WithLabelValues(resultRejected).Inc()
+			return
+		}
+		if err == nil {
+			runsTotal.WithLabelValues(resultCreated).Inc()
+			logger.Info("created subscription order on <follow> of <Scheduler>", zap.Int64("period", subscription.CurrentPeriod))
+		}
+	}
+	if err != nil {
+		logger.Warn("failed to get subscription order on <follow> of <Scheduler>", zap.Error(err))
+		return
+	}
+
+	switch order.Status {
+	case orderpb.OrderStatus_PENDING.String(), orderpb.OrderStatus_SCHEDULED.String():
+		return
+	case orderpb.OrderStatus_FAILED.String(), orderpb.OrderStatus_EXPIRED.String():
+		receiver.paymentFailed(ctx, logger, subscription, order)
+	default:
+		// Оплаченный или отменённый пользователем заказ закрывает период.
+		receiver.settle(ctx, subscription, order)
+	}
+}
+
+func (receiver *Scheduler) place(ctx context.Context, subscription models.Subscription) (models.Order, error) {
+	items := make([]*orderpb.OrderItem, 0, len(subscription.Items))
+	for _, item := range subscription.Items {
+		items = append(items, &orderpb.OrderItem{ProductId: item.ProductID, Quantity: item.Quantity, Price: item.Price})
+	}
+	created, err := receiver.orders.CreateOrderFrom(ctx, &orderpb.CreateOrderRequest{
+		UserId:          subscription.UserID,
+		Items:           items,
+		ShippingAddress: utils.ConvertShippingAddressToProto(subscription.ShippingAddress),
+	}, models.OrderOrigin{
+		OrderID:        subscription.PendingOrderID,
+		SubscriptionID: subscription.SubscriptionID,
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+	return models.Order{OrderID: created.OrderId, Status: created.Status.String()}, nil
+}
+
+func (receiver *Scheduler) settle(ctx context.Context, subscription models.Subscription, order models.Order) {
+	expectedVersion := subscription.Version
+	receiver.recordOrderStatus(&subscription, order)
+	subscription.PendingOrderID = ""
+	subscription.FailedAttempts = 0
+	subscription.CurrentPeriod++
+	subscription.NextRunAt = subscription.RunAt(subscription.CurrentPeriod)
+	subscription.UpdatedAt = receiver.now().UTC()
+	if slices.Contains(models.SchedulableSubscriptionStatuses, subscription.Status) {
+		subscription.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_ACTIVE.String()
+		if subscription.Ended(subscription.CurrentPeriod) {
+			subscription.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_COMPLETED.String()
+		}
+	}
+	if receiver.save(ctx, subscription, expectedVersion) {
+		runsTotal.WithLabelValues(resultSettled).Inc()
+	}
+}
+
+// paymentFailed schedules the next dunning retry of the period or pauses the
+// subscription once the retries are exhausted.
+func (receiver *Scheduler) paymentFailed(ctx context.Context, logger *zap.Logger, subscription models.Subscription, order models.Order) {
+	expectedVersion := subscription.Version
+	now := receiver.now().UTC()
+	receiver.recordOrderStatus(&subscription, order)
+	subscription.PendingOrderID = ""
+	subscription.FailedAttempts++
+	subscription.UpdatedAt = now
+
+	result := resultPaymentFailed
+	schedulable := slices.Contains(models.SchedulableSubscriptionStatuses, subscription.Status)
+	if int(subscription.FailedAttempts) > len(receiver.cfg.DunningRetries) {
+		result = resultDunningExhausted
+		if schedulable {
+			subscription.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_PAUSED.String()
+			subscription.StatusReason = fmt.Sprintf("payment failed %d times: %s", subscription.FailedAttempts, order.StatusReason)
+		}
+	} else {
+		subscription.NextRunAt = now.Add(receiver.cfg.DunningRetries[subscription.FailedAttempts-1])
+		if schedulable {
+			subscription.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_PAST_DUE.String()
+		}
+	}
+
+	if receiver.save(ctx, subscription, expectedVersion) {
+		runsTotal.WithLabelValues(result).Inc()
+		logger.Info("subscription payment failed on <paymentFailed> of <Scheduler>",
+			zap.Int32("failed_attempts", subscription.FailedAttempts),
+			zap.String("status", subscription.Status),
+			zap.Time("next_run_at", subscription.NextRunAt),
+		)
+	}
+}
+
+func (receiver *Scheduler) pause(ctx context.Context, subscription models.Subscription, reason string) {
+	expectedVersion := subscription.Version
+	subscription.PendingOrderID = ""
+	subscription.Status = orderpb.SubscriptionStatus_SUBSCRIPTION_PAUSED.String()
+	subscription.StatusReason = reason
+	subscription.UpdatedAt = receiver.now().UTC()
+	receiver.save(ctx, subscription, expectedVersion)
+}
+
+func (receiver *Scheduler) recordOrderStatus(subscription *models.Subscription, order models.Order) {
+	subscription.Runs = slices.Clone(subscription.Runs)
+	for i := range subscription.Runs {
+		if subscription.Runs[i].OrderID == order.OrderID {
+			subscription.Runs[i].OrderStatus = order.Status
+		}
+	}
+}
+
+// save reports whether the subscription was stored. A conflict means it was changed
+// meanwhile; the next pass works with the fresh copy.
+func (receiver *Scheduler) save(ctx context.Context, subscription models.Subscription, expectedVersion int64) bool {
+	_, err := receiver.subscriptionRepo.Replace(ctx, subscription, expectedVersion)
+	if errors.Is(err, pj_errors.ErrConflict) {
+		receiver.logger.Info("subscription changed concurrently on <save> of <Scheduler>", zap.String("subscription_id", subscription.SubscriptionID))
+		return false
+	}
+	if err != nil {
+		receiver.logger.Error("failed to save subscription on <save> of <Scheduler>",
+			zap.String("subscription_id", subscription.SubscriptionID),
+			zap.Error(err),
+		)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"order-service-system/common/auth"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/service/order_service"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAmendOrder_ChangesPendingOrder(t *testing.T) {
+	orders := map[string]models.Order{
+		"o1": {OrderID: "o1", UserID: "alice", Status: orderpb.OrderStatus_PENDING.String(), Version: 1, TotalAmount: 25, Items: []models.OrderItem{
+			{ProductID: "p1", Quantity: 2, Price: 10},
+			{ProductID: "p2", Quantity: 1, Price: 5},
+		}},
+		"o2": {OrderID: "o2", UserID: "alice", Status: orderpb.OrderStatus_PAID.String(), Version: 1, Items: []models.OrderItem{
+			{ProductID: "p1", Quantity: 1, Price: 10},
+		}},
+	}
+	publisher := &mockNatsClient{}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:     zap.NewNop(),
+		OrderRepo:  newFulfilmentRepository(orders),
+		Counters:   memoryCounters{},
+		NatsClient: publisher,
+	})
+	alice := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Type: auth.PrincipalUser})
+	bob := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "bob", Type: auth.PrincipalUser})
+	amend := func(ctx context.Context, orderID string, version int64, items ...*orderpb.OrderItem) (*orderpb.Order, error) {
+		return svc.AmendOrder(ctx, &orderpb.AmendOrderRequest{OrderId: orderID, Items: items, ExpectedVersion: version})
+	}
+
+	// p2 не указан — удаляется, p1 уменьшается до одной штуки.
+	order, err := amend(alice, "o1", 1, &orderpb.OrderItem{ProductId: "p1", Quantity: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), order.Version)
+	require.Equal(t, 10.0, order.TotalAmount)
+	require.Len(t, order.Items, 1)
+	require.Len(t, order.Amendments, 1)
+	require.Equal(t, "user:alice", order.Amendments[0].Actor)
+	require.Equal(t, 25.0, order.Amendments[0].PreviousTotal)
+	require.Equal(t, []*orderpb.ItemChange{
+		{ProductId: "p1", OldQuantity: 2, NewQuantity: 1, Price: 10},
+		{ProductId: "p2", OldQuantity: 1, NewQuantity: 0, Price: 5},
+	}, order.Amendments[0].Changes)
+	require.Equal(t, []models.OrderAmendedEvent{{
+		OrderID: "o1", UserID: "alice", Version: 2, TotalAmount: 10, PreviousTotal: 25, AmendedAt: publisher.amended[0].AmendedAt,
+	}}, publisher.amended)
+
+	// Без изменений версия не растёт и событие не публикуется.
+	order, err = amend(alice, "o1", 0, &orderpb.OrderItem{ProductId: "p1", Quantity: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), order.Version)
+	require.Len(t, publisher.amended, 1)
+
+	_, err = amend(alice, "o1", 1, &orderpb.OrderItem{ProductId: "p1", Quantity: 3})
+	require.Equal(t, codes.Aborted, status.Code(err))
+	_, err = amend(alice, "o1", 0, &orderpb.OrderItem{ProductId: "p3", Quantity: 1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = amend(alice, "o1", 0, &orderpb.OrderItem{ProductId: "p1", Quantity: 0})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = amend(bob, "o1", 0, &orderpb.OrderItem{ProductId: "p1", Quantity: 3})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = amend(alice, "o2", 0, &orderpb.OrderItem{ProductId: "p1", Quantity: 3})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Len(t, publisher.amended, 1)
+}
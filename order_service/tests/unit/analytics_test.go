@@ -0,0 +1,435 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service-system/common/auth"
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/service/analytics_service"
+	"order-service-system/order_service/internal/workers/rollup"
+	orderpb "order-service-system/proto/order"
+	"order-service-system/sdk/ordersdk"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type rollupKey struct {
+	tenantID string
+	day      time.Time
+	currency string
+}
+
+// memoryAnalyticsStore keeps orders, their refunded amounts and daily rollups with
+// the events counted in them like the Mongo repository and aggregates them the same way.
+type memoryAnalyticsStore struct {
+	mu          sync.Mutex
+	orders      []models.Order
+	refunds     map[string]float64
+	counted     map[rollupKey][]string
+	rollups     map[rollupKey]models.AnalyticsMetrics
+	failApplies int
+	queries     []models.AnalyticsQuery
+}
+
+func newMemoryAnalyticsStore(orders ...models.Order) *memoryAnalyticsStore {
+	return &memoryAnalyticsStore{
+		orders:  orders,
+		refunds: map[string]float64{},
+		counted: map[rollupKey][]string{},
+		rollups: map[rollupKey]models.AnalyticsMetrics{},
+	}
+}
+
+func (f *memoryAnalyticsStore) Order(ctx context.Context, orderID string) (models.Order, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, order := range f.orders {
+		if order.OrderID == orderID && tenant.Matches(ctx, order.TenantID) {
+			return order, true, nil
+		}
+	}
+	return models.Order{}, false, nil
+}
+
+func (f *memoryAnalyticsStore) Apply(ctx context.Context, key string, delta models.RollupDelta) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failApplies > 0 {
+		f.failApplies--
+		return false, errors.New("rollups are unavailable")
+	}
+	rollup := rollupKey{tenantID: tenant.FromContext(ctx), day: models.DayOf(delta.Day), currency: delta.Currency}
+	if slices.Contains(f.counted[rollup], key) {
+		return false, nil
+	}
+	f.counted[rollup] = append(f.counted[rollup], key)
+	f.rollups[rollup] = f.rollups[rollup].Add(delta.Metrics)
+	return true, nil
+}
+
+func (f *memoryAnalyticsStore) AggregateRollups(ctx context.Context, query models.AnalyticsQuery) (models.AnalyticsResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, query)
+	buckets := map[rollupKey]models.AnalyticsMetrics{}
+	for key, metrics := range f.rollups {
+		if !tenant.Matches(ctx, key.tenantID) || key.day.Before(query.From) || !key.day.Before(query.To) {
+			continue
+		}
+		if query.Currency != "" && key.currency != query.Currency {
+			continue
+		}
+		period := key.day
+		if query.GroupBy == models.AnalyticsByWeek {
+			period = models.WeekOf(key.day)
+		}
+		bucket := rollupKey{day: period, currency: key.currency}
+		buckets[bucket] = buckets[bucket].Add(metrics)
+	}
+	var result models.AnalyticsResult
+	for key, metrics := range buckets {
+		result.Buckets = append(result.Buckets, models.AnalyticsBucket{PeriodStart: key.day, Currency: key.currency, Metrics: metrics})
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		a, b := result.Buckets[i], result.Buckets[j]
+		if !a.PeriodStart.Equal(b.PeriodStart) {
+			return a.PeriodStart.Before(b.PeriodStart)
+		}
+		return a.Currency < b.Currency
+	})
+	result.Totals = totalsOf(result.Buckets)
+	return result, nil
+}
+
+func (f *memoryAnalyticsStore) AggregateOrders(ctx context.Context, query models.AnalyticsQuery) (models.AnalyticsResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, query)
+	buckets := map[[3]string]models.AnalyticsMetrics{}
+	for _, order := range f.orders {
+		if !tenant.Matches(ctx, order.TenantID) || order.Status == "SCHEDULED" || order.CreatedAt.Before(query.From) || !order.CreatedAt.Before(query.To) {
+			continue
+		}
+		if query.Currency != "" && order.Currency != query.Currency {
+			continue
+		}
+		metrics := models.AnalyticsMetrics{OrdersCount: 1}
+		if models.IsPaidStatus(order.Status) {
+			metrics.PaidOrders, metrics.GrossRevenue = 1, order.TotalAmount-f.refunds[order.OrderID]
+		}
+		if order.Status == "FAILED" {
+			metrics.FailedPayments = 1
+		}
+		key := [3]string{order.Status, "", order.Currency}
+		if query.GroupBy == models.AnalyticsByUser {
+			key = [3]string{"", order.UserID, order.Currency}
+		}
+		buckets[key] = buckets[key].Add(metrics)
+	}
+	var result models.AnalyticsResult
+	for key, metrics := range buckets {
+		result.Buckets = append(result.Buckets, models.AnalyticsBucket{Status: key[0], UserID: key[1], Currency: key[2], Metrics: metrics})
+	}
+	result.Totals = totalsOf(result.Buckets)
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		a, b := result.Buckets[i], result.Buckets[j]
+		if a.Metrics.GrossRevenue != b.Metrics.GrossRevenue {
+			return a.Metrics.GrossRevenue > b.Metrics.GrossRevenue
+		}
+		return a.Status+a.UserID < b.Status+b.UserID
+	})
+	if query.Limit > 0 && len(result.Buckets) > query.Limit {
+		result.Buckets = result.Buckets[:query.Limit]
+	}
+	return result, nil
+}
+
+func totalsOf(buckets []models.AnalyticsBucket) []models.AnalyticsBucket {
+	totals := map[string]models.AnalyticsMetrics{}
+	for _, bucket := range buckets {
+		totals[bucket.Currency] = totals[bucket.Currency].Add(bucket.Metrics)
+	}
+	var result []models.AnalyticsBucket
+	for currency, metrics := range totals {
+		result = append(result, models.AnalyticsBucket{Currency: currency, Metrics: metrics})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result
+}
+
+// analyticsDay is a Wednesday.
+var analyticsDay = time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+func analyticsFixture() *memoryAnalyticsStore {
+	return newMemoryAnalyticsStore(
+		models.Order{OrderID: "o1", UserID: "alice", TotalAmount: 100, Currency: "USD", Status: "PAID", CreatedAt: analyticsDay.Add(10 * time.Hour)},
+		models.Order{OrderID: "o2", UserID: "bob", TotalAmount: 50, Currency: "USD", Status: "FAILED", CreatedAt: analyticsDay.Add(23*time.Hour + 30*time.Minute)},
+		models.Order{OrderID: "o3", UserID: "alice", TotalAmount: 30, Currency: "USD", Status: "SHIPPED", CreatedAt: analyticsDay.Add(30 * time.Hour)},
+		models.Order{OrderID: "o4", UserID: "carol", TotalAmount: 20, Currency: "EUR", Status: "PAID", CreatedAt: analyticsDay.Add(12 * time.Hour)},
+		models.Order{OrderID: "o5", UserID: "carol", TotalAmount: 40, Currency: "USD", Status: "PENDING", CreatedAt: analyticsDay.Add(31 * time.Hour)},
+		models.Order{OrderID: "a1", TenantID: "acme", UserID: "dave", TotalAmount: 70, Currency: "USD", Status: "PAID", CreatedAt: analyticsDay.Add(11 * time.Hour)},
+	)
+}
+
+func newAnalyticsService(backend analytics_service.Backend) *analytics_service.AnalyticsService {
+	return analytics_service.NewAnalyticsService(analytics_service.Deps{Logger: zap.NewNop(), Backend: backend})
+}
+
+func publishOrderEvent[T events.Payload](t *testing.T, b bus.Publisher, tenantID string, payload T) {
+	t.Helper()
+	data, err := events.Encode(events.NewEnvelope("test", payload).WithTenant(tenantID))
+	require.NoError(t, err)
+	msg := bus.NewMessage(payload.EventType(), data)
+	msg.Header.Set(events.HeaderContentType, events.ContentTypeJSON)
+	require.NoError(t, b.Publish(context.Background(), msg))
+}
+
+func analyticsRequest(groupBy orderpb.AnalyticsGroupBy, from time.Time, to time.Time) *orderpb.GetOrderAnalyticsRequest {
+	return &orderpb.GetOrderAnalyticsRequest{From: timestamppb.New(from), To: timestamppb.New(to), GroupBy: groupBy}
+}
+
+func metricsOf(ordersCount int64, revenue float64, paid int64, failed int64, failureRate float64, averageOrderValue float64) *orderpb.OrderMetrics {
+	return &orderpb.OrderMetrics{
+		OrdersCount:        ordersCount,
+		GrossRevenue:       revenue,
+		PaidOrders:         paid,
+		FailedPayments:     failed,
+		PaymentFailureRate: failureRate,
+		AverageOrderValue:  averageOrderValue,
+	}
+}
+
+func TestRollupUpdater_CountsEveryOrderOncePerEvent(t *testing.T) {
+	memory := bus.NewMemory()
+	store := analyticsFixture()
+	updater := rollup.NewUpdater(rollup.Deps{Logger: zap.NewNop(), Subscriber: memory, Store: store})
+	subscriptions, err := updater.Start(context.Background())
+	require.NoError(t, err)
+
+	for _, id := range []string{"o1", "o2", "o3", "o4", "o5"} {
+		publishOrderEvent(t, memory, "", events.OrderCreatedPayload{OrderID: id})
+	}
+	publishOrderEvent(t, memory, "acme", events.OrderCreatedPayload{OrderID: "a1"})
+	publishOrderEvent(t, memory, "", events.OrderPaidPayload{OrderID: "o1", TotalAmount: 100})
+	publishOrderEvent(t, memory, "", events.OrderPaidPayload{OrderID: "o1", TotalAmount: 100})
+	publishOrderEvent(t, memory, "", events.OrderPaidPayload{OrderID: "o3", TotalAmount: 30})
+	publishOrderEvent(t, memory, "", events.OrderPaidPayload{OrderID: "o4", TotalAmount: 20})
+	publishOrderEvent(t, memory, "", events.OrderFailedPayload{OrderID: "o2", Reason: "declined"})
+	publishOrderEvent(t, memory, "", events.OrderPaidPayload{OrderID: "unknown", TotalAmount: 10})
+	// Заказ другой витрины не находится по событию без витрины.
+	publishOrderEvent(t, memory, "", events.OrderPaidPayload{OrderID: "a1", TotalAmount: 70})
+	for _, sub := range subscriptions {
+		require.NoError(t, sub.Drain())
+	}
+
+	svc := newAnalyticsService(store)
+	ctx := context.Background()
+
+	byDay, err := svc.GetOrderAnalytics(ctx, analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_DAY, analyticsDay.Add(8*time.Hour), analyticsDay.Add(36*time.Hour)))
+	require.NoError(t, err)
+	require.Len(t, byDay.Buckets, 3)
+	require.Equal(t, analyticsDay, byDay.Buckets[0].PeriodStart.AsTime())
+	require.Equal(t, "EUR", byDay.Buckets[0].Currency)
+	require.Equal(t, metricsOf(1, 20, 1, 0, 0, 20), byDay.Buckets[0].Metrics)
+	require.Equal(t, "USD", byDay.Buckets[1].Currency)
+	require.Equal(t, metricsOf(2, 100, 1, 1, 0.5, 100), byDay.Buckets[1].Metrics)
+	require.Equal(t, analyticsDay.AddDate(0, 0, 1), byDay.Buckets[2].PeriodStart.AsTime())
+	require.Equal(t, metricsOf(2, 30, 1, 0, 0, 30), byDay.Buckets[2].Metrics)
+	require.Len(t, byDay.Totals, 2)
+	require.Equal(t, metricsOf(4, 130, 2, 1, 1.0/3, 65), byDay.Totals[1].Metrics)
+
+	byWeek, err := svc.GetOrderAnalytics(ctx, analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_WEEK, analyticsDay, analyticsDay.AddDate(0, 0, 1)))
+	require.NoError(t, err)
+	require.Len(t, byWeek.Buckets, 2)
+	require.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), byWeek.Buckets[1].PeriodStart.AsTime())
+	require.Equal(t, metricsOf(4, 130, 2, 1, 1.0/3, 65), byWeek.Buckets[1].Metrics)
+
+	acme, err := svc.GetOrderAnalytics(tenant.WithTenant(ctx, "acme"), analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_DAY, analyticsDay, analyticsDay.AddDate(0, 0, 1)))
+	require.NoError(t, err)
+	require.Len(t, acme.Buckets, 1)
+	require.Equal(t, metricsOf(1, 0, 0, 0, 0, 0), acme.Buckets[0].Metrics)
+}
+
+func TestRollupUpdater_CountsRedeliveredEventWhenRollupFails(t *testing.T) {
+	memory := bus.NewMemory(bus.WithMaxDeliver(2), bus.WithRedeliveryDelay(time.Millisecond))
+	store := analyticsFixture()
+	store.failApplies = 1
+	updater := rollup.NewUpdater(rollup.Deps{Logger: zap.NewNop(), Subscriber: memory, Store: store})
+	subscriptions, err := updater.Start(context.Background())
+	require.NoError(t, err)
+
+	publishOrderEvent(t, memory, "", events.OrderPaidPayload{OrderID: "o1", TotalAmount: 100})
+	for _, sub := range subscriptions {
+		require.NoError(t, sub.Drain())
+	}
+
+	require.Equal(t, models.AnalyticsMetrics{GrossRevenue: 100, PaidOrders: 1},
+		store.rollups[rollupKey{tenantID: tenant.Default, day: analyticsDay, currency: "USD"}])
+}
+
+func TestRollupUpdater_TakesCancellationsAndRefundsOffRevenue(t *testing.T) {
+	memory := bus.NewMemory()
+	store := newMemoryAnalyticsStore(
+		models.Order{OrderID: "o1", UserID: "alice", TotalAmount: 70, Currency: "USD", Status: "PAID", CreatedAt: analyticsDay.Add(10 * time.Hour),
+			Cancellations: []models.ItemCancellation{{CancellationID: "c1", Amount: 30}}},
+		models.Order{OrderID: "o2", UserID: "bob", Currency: "USD", Status: "CANCELLED", CreatedAt: analyticsDay.Add(11 * time.Hour),
+			Cancellations: []models.ItemCancellation{{CancellationID: "c2", Amount: 20}, {CancellationID: "c3", Amount: 30}}},
+		models.Order{OrderID: "o3", UserID: "carol", TotalAmount: 40, Currency: "USD", Status: "DELIVERED", CreatedAt: analyticsDay.Add(12 * time.Hour)},
+	)
+	store.refunds["o3"] = 15
+	updater := rollup.NewUpdater(rollup.Deps{Logger: zap.NewNop(), Subscriber: memory, Store: store})
+	subscriptions, err := updater.Start(context.Background())
+	require.NoError(t, err)
+
+	for _, paid := range []events.OrderPaidPayload{{OrderID: "o1", TotalAmount: 100}, {OrderID: "o2", TotalAmount: 50}, {OrderID: "o3", TotalAmount: 40}} {
+		publishOrderEvent(t, memory, "", events.OrderCreatedPayload{OrderID: paid.OrderID})
+		publishOrderEvent(t, memory, "", paid)
+	}
+	publishOrderEvent(t, memory, "", events.OrderItemsCancelledPayload{CancellationID: "c1", OrderID: "o1", Amount: 30})
+	publishOrderEvent(t, memory, "", events.OrderItemsCancelledPayload{CancellationID: "c1", OrderID: "o1", Amount: 30})
+	publishOrderEvent(t, memory, "", events.OrderItemsCancelledPayload{CancellationID: "c2", OrderID: "o2", Amount: 20})
+	publishOrderEvent(t, memory, "", events.OrderItemsCancelledPayload{CancellationID: "c3", OrderID: "o2", Amount: 30})
+	publishOrderEvent(t, memory, "", events.ReturnRefundedPayload{ReturnID: "r1", OrderID: "o3", Amount: 15})
+	for _, sub := range subscriptions {
+		require.NoError(t, sub.Drain())
+	}
+
+	svc := newAnalyticsService(store)
+	ctx := context.Background()
+
+	// Отмена, оставившая заказ пустым, снимает и оплаченный заказ.
+	byDay, err := svc.GetOrderAnalytics(ctx, analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_DAY, analyticsDay, analyticsDay.AddDate(0, 0, 1)))
+	require.NoError(t, err)
+	require.Len(t, byDay.Buckets, 1)
+	require.Equal(t, metricsOf(3, 95, 2, 0, 0, 47.5), byDay.Buckets[0].Metrics)
+
+	byStatus, err := svc.GetOrderAnalytics(ctx, analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_STATUS, analyticsDay, analyticsDay.AddDate(0, 0, 1)))
+	require.NoError(t, err)
+	require.Equal(t, byDay.Totals, byStatus.Totals)
+}
+
+func TestGetOrderAnalytics_ByStatusAndUser(t *testing.T) {
+	store := analyticsFixture()
+	svc := newAnalyticsService(store)
+	ctx := context.Background()
+
+	byStatus, err := svc.GetOrderAnalytics(ctx, &orderpb.GetOrderAnalyticsRequest{
+		From:     timestamppb.New(analyticsDay),
+		To:       timestamppb.New(analyticsDay.AddDate(0, 0, 2)),
+		GroupBy:  orderpb.AnalyticsGroupBy_GROUP_BY_STATUS,
+		Currency: " usd ",
+	})
+	require.NoError(t, err)
+	var statuses []orderpb.OrderStatus
+	for _, bucket := range byStatus.Buckets {
+		statuses = append(statuses, bucket.Status)
+	}
+	require.Equal(t, []orderpb.OrderStatus{orderpb.OrderStatus_PENDING, orderpb.OrderStatus_PAID, orderpb.OrderStatus_FAILED, orderpb.OrderStatus_SHIPPED}, statuses)
+	require.Equal(t, "USD", store.queries[0].Currency)
+
+	byUser, err := svc.GetOrderAnalytics(ctx, &orderpb.GetOrderAnalyticsRequest{
+		From:    timestamppb.New(analyticsDay),
+		To:      timestamppb.New(analyticsDay.AddDate(0, 0, 2)),
+		GroupBy: orderpb.AnalyticsGroupBy_GROUP_BY_USER,
+		Limit:   1,
+	})
+	require.NoError(t, err)
+	require.Len(t, byUser.Buckets, 1)
+	require.Equal(t, "alice", byUser.Buckets[0].UserId)
+	require.Equal(t, metricsOf(2, 130, 2, 0, 0, 65), byUser.Buckets[0].Metrics)
+	// Итоги учитывают и пользователей, отрезанных лимитом.
+	require.Equal(t, int64(5), byUser.Totals[0].Metrics.OrdersCount+byUser.Totals[1].Metrics.OrdersCount)
+}
+
+func TestGetOrderAnalytics_Validation(t *testing.T) {
+	store := newMemoryAnalyticsStore()
+	svc := newAnalyticsService(store)
+	ctx := context.Background()
+	wednesday := analyticsDay.Add(10 * time.Hour)
+
+	for _, req := range []*orderpb.GetOrderAnalyticsRequest{
+		{},
+		{From: timestamppb.New(wednesday), GroupBy: orderpb.AnalyticsGroupBy_GROUP_BY_DAY},
+		analyticsRequest(orderpb.AnalyticsGroupBy_ANALYTICS_GROUP_BY_UNSPECIFIED, wednesday, wednesday.Add(time.Hour)),
+		analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_DAY, wednesday, wednesday),
+		analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_DAY, wednesday, wednesday.AddDate(1, 1, 0)),
+		analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_USER, wednesday, wednesday.AddDate(0, 4, 0)),
+		{From: timestamppb.New(wednesday), To: timestamppb.New(wednesday.Add(time.Hour)), GroupBy: orderpb.AnalyticsGroupBy_GROUP_BY_DAY, Currency: "dollars"},
+		{From: timestamppb.New(wednesday), To: timestamppb.New(wednesday.Add(time.Hour)), GroupBy: orderpb.AnalyticsGroupBy_GROUP_BY_USER, Limit: -1},
+	} {
+		_, err := svc.GetOrderAnalytics(ctx, req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+	require.Empty(t, store.queries)
+
+	_, err := svc.GetOrderAnalytics(ctx, analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_WEEK, wednesday, analyticsDay.AddDate(0, 0, 12)))
+	require.NoError(t, err)
+	require.Equal(t, models.AnalyticsQuery{
+		From:    time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+		GroupBy: models.AnalyticsByWeek,
+	}, store.queries[0])
+
+	_, err = svc.GetOrderAnalytics(ctx, &orderpb.GetOrderAnalyticsRequest{From: timestamppb.New(wednesday), To: timestamppb.New(wednesday.Add(time.Hour)), GroupBy: orderpb.AnalyticsGroupBy_GROUP_BY_USER, Limit: 1000})
+	require.NoError(t, err)
+	require.Equal(t, 100, store.queries[1].Limit)
+}
+
+func TestGetOrderAnalytics_RestrictedToAnalyticsServices(t *testing.T) {
+	addr := startAuthServer(t)
+	ctx := context.Background()
+	req := analyticsRequest(orderpb.AnalyticsGroupBy_GROUP_BY_STATUS, analyticsDay, analyticsDay.AddDate(0, 0, 2))
+
+	for _, client := range []*ordersdk.Client{
+		clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "alice", auth.PrincipalUser))),
+		clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "support-service", auth.PrincipalService))),
+	} {
+		_, err := client.GetOrderAnalytics(ctx, req)
+		require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+	}
+
+	finance := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "finance-service", auth.PrincipalService)))
+	response, err := finance.GetOrderAnalytics(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, response.GetBuckets(), 5)
+}
+
+func TestGateway_GetOrderAnalytics(t *testing.T) {
+	httpServer := startGateway(t, &mockOrderRepository{})
+
+	resp, err := http.Get(httpServer.URL + "/v1/analytics/orders?from=2026-03-04T00:00:00Z&to=2026-03-06T00:00:00Z&groupBy=GROUP_BY_USER&currency=USD")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		Buckets []struct {
+			UserID  string `json:"userId"`
+			Metrics struct {
+				OrdersCount        string  `json:"ordersCount"`
+				GrossRevenue       float64 `json:"grossRevenue"`
+				AverageOrderValue  float64 `json:"averageOrderValue"`
+				PaymentFailureRate float64 `json:"paymentFailureRate"`
+			} `json:"metrics"`
+		} `json:"buckets"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	_ = resp.Body.Close()
+	require.Len(t, body.Buckets, 3)
+	require.Equal(t, "alice", body.Buckets[0].UserID)
+	require.Equal(t, "2", body.Buckets[0].Metrics.OrdersCount)
+	require.Equal(t, 65.0, body.Buckets[0].Metrics.AverageOrderValue)
+}
@@ -0,0 +1,313 @@
+package unit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"order-service-system/common/auth"
+	"order-service-system/common/tlsconfig"
+	"order-service-system/order_service/internal/controllers/grpc/analytics_grpc_controller"
+	"order-service-system/order_service/internal/controllers/grpc/cart_grpc_controller"
+	"order-service-system/order_service/internal/controllers/grpc/order_grpc_controller"
+	"order-service-system/order_service/internal/controllers/grpc/subscription_grpc_controller"
+	"order-service-system/order_service/internal/initialize"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/server"
+	"order-service-system/order_service/internal/service/cart_service"
+	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/service/subscription_service"
+	orderpb "order-service-system/proto/order"
+	"order-service-system/sdk/ordersdk"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testAuthKey    = "test:c2VjcmV0LWtleS1mb3ItdGVzdHM="
+	testServiceKey = "svc:c2VydmljZS1rZXktZm9yLXRlc3Rz"
+)
+
+// tokenFor signs service tokens with the service key, like the services do.
+func tokenFor(t *testing.T, subject string, principalType auth.PrincipalType) string {
+	t.Helper()
+	key := testAuthKey
+	if principalType == auth.PrincipalService {
+		key = testServiceKey
+	}
+	kid, secret, err := auth.ParseStaticKey(key)
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{"order-service"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		PrincipalType: principalType,
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(secret)
+	require.NoError(t, err)
+	return signed
+}
+
+func startAuthServer(t *testing.T) string {
+	t.Helper()
+	verifier, err := auth.NewVerifier(auth.Configuration{
+		StaticKeys:  []string{testAuthKey},
+		ServiceKeys: []string{testServiceKey},
+		Audience:    "order-service",
+	})
+	require.NoError(t, err)
+	return startOrderServer(t, verifier, nil)
+}
+
+// startOrderServer serves the order API over a real listener; verifier and tlsConfig
+// are nil when the server runs without JWT or TLS.
+func startOrderServer(t *testing.T, verifier *auth.Verifier, tlsConfig *tls.Config) string {
+	t.Helper()
+
+	orders := map[string]models.Order{}
+	repo := &mockOrderRepository{
+		create: func(ctx context.Context, order models.Order) error {
+			orders[order.OrderID] = order
+			return nil
+		},
+		get: func(ctx context.Context, orderID string) (models.Order, error) {
+			order, ok := orders[orderID]
+			if !ok {
+				return models.Order{}, pj_errors.ErrNotFound
+			}
+			return order, nil
+		},
+		updateStatus: func(ctx context.Context, orderID string, status string) (models.Order, error) {
+			order := orders[orderID]
+			order.Status = status
+			orders[orderID] = order
+			return order, nil
+		},
+		transition: func(ctx context.Context, orderID string, from []string, change models.StatusChange) (models.Order, error) {
+			order := orders[orderID]
+			order.Status = change.Status
+			order.Shipment = change.Shipment
+			orders[orderID] = order
+			return order, nil
+		},
+		list: func(ctx context.Context, filter models.ListOrdersFilter) ([]models.Order, error) {
+			var result []models.Order
+			for _, order := range orders {
+				if filter.UserID == "" || order.UserID == filter.UserID {
+					result = append(result, order)
+				}
+			}
+			return result, nil
+		},
+	}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:    zap.NewNop(),
+		OrderRepo: repo,
+		Counters:  memoryCounters{},
+		NatsClient: &mockNatsClient{
+			publish: func(event models.OrderCreatedEvent) error { return nil },
+		},
+	})
+
+	grpcServer, err := server.NewGRPC(server.DepsGRPC{
+		Logger:             zap.NewNop(),
+		Verifier:           verifier,
+		TLS:                tlsConfig,
+		StatusUpdaters:     []string{"billing-service", "notification-service"},
+		FulfilmentServices: []string{"fulfilment-service"},
+		SupportServices:    []string{"support-service"},
+		AnalyticsServices:  []string{"finance-service"},
+	})
+	require.NoError(t, err)
+	grpcServer.Register(&initialize.RpcControllers{
+		OrderController: order_grpc_controller.NewOrderController(order_grpc_controller.Deps{
+			OrderService:  svc,
+			ReturnService: newReturnService(t, repo, &mockNatsClient{}),
+			SearchService: newSearchService(&memorySearchBackend{orders: searchFixture()}),
+		}),
+		CartController: cart_grpc_controller.NewCartController(cart_grpc_controller.Deps{
+			CartService: cart_service.NewCartService(cart_service.Deps{
+				Logger:   zap.NewNop(),
+				CartRepo: &memoryCartRepository{carts: map[string]models.Cart{}},
+				Orders:   svc,
+			}),
+		}),
+		SubscriptionController: subscription_grpc_controller.NewSubscriptionController(subscription_grpc_controller.Deps{
+			SubscriptionService: subscription_service.NewSubscriptionService(subscription_service.Deps{
+				Logger:           zap.NewNop(),
+				SubscriptionRepo: &memorySubscriptionRepository{subscriptions: map[string]models.Subscription{}},
+			}),
+		}),
+		AnalyticsController: analytics_grpc_controller.NewAnalyticsController(analytics_grpc_controller.Deps{
+			AnalyticsService: newAnalyticsService(analyticsFixture()),
+		}),
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(func() { _ = grpcServer.Stop(context.Background()) })
+
+	return listener.Addr().String()
+}
+
+func clientAs(t *testing.T, addr string, opts ...ordersdk.Option) *ordersdk.Client {
+	t.Helper()
+	client, err := ordersdk.New(addr, append([]ordersdk.Option{ordersdk.WithoutRetry()}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestAuth_Ownership(t *testing.T) {
+	addr := startAuthServer(t)
+	ctx := context.Background()
+	item := &orderpb.OrderItem{ProductId: "p1", Quantity: 1, Price: 1}
+
+	_, err := clientAs(t, addr).GetOrder(ctx, "any")
+	require.True(t, errors.Is(err, ordersdk.ErrUnauthenticated))
+
+	alice := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "alice", auth.PrincipalUser)))
+	bob := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "bob", auth.PrincipalUser)))
+
+	_, err = alice.CreateOrder(ctx, "bob", item)
+	require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+
+	created, err := alice.CreateOrder(ctx, "", item)
+	require.NoError(t, err)
+	require.Equal(t, "alice", created.GetUserId())
+
+	_, err = alice.GetOrder(ctx, created.GetOrderId())
+	require.NoError(t, err)
+	_, err = bob.GetOrder(ctx, created.GetOrderId())
+	require.True(t, errors.Is(err, ordersdk.ErrNotFound))
+
+	page, _, err := bob.ListOrders(ctx, &orderpb.ListOrdersRequest{})
+	require.NoError(t, err)
+	require.Empty(t, page)
+	_, _, err = bob.ListOrders(ctx, &orderpb.ListOrdersRequest{UserId: "alice"})
+	require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+}
+
+func TestAuth_UpdateOrderStatusRestrictedToServices(t *testing.T) {
+	addr := startAuthServer(t)
+	ctx := context.Background()
+
+	alice := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "alice", auth.PrincipalUser)))
+	created, err := alice.CreateOrder(ctx, "", &orderpb.OrderItem{ProductId: "p1", Quantity: 1, Price: 1})
+	require.NoError(t, err)
+
+	_, err = alice.UpdateOrderStatus(ctx, created.GetOrderId(), orderpb.OrderStatus_PAID)
+	require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+	_, err = alice.ApproveReturn(ctx, "r1", "")
+	require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+
+	reporting := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "reporting-service", auth.PrincipalService)))
+	_, err = reporting.UpdateOrderStatus(ctx, created.GetOrderId(), orderpb.OrderStatus_PAID)
+	require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+
+	signer, err := auth.NewSigner("billing-service", auth.SignerConfiguration{Key: testServiceKey, Audience: "order-service", TTL: time.Minute})
+	require.NoError(t, err)
+	billing := clientAs(t, addr, ordersdk.WithTokenSource(signer.Token))
+	updated, err := billing.UpdateOrderStatus(ctx, created.GetOrderId(), orderpb.OrderStatus_PAID)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_PAID, updated.GetStatus())
+
+	// Сервис видит заказы любого пользователя.
+	_, err = billing.GetOrder(ctx, created.GetOrderId())
+	require.NoError(t, err)
+
+	_, err = billing.MarkShipped(ctx, created.GetOrderId(), "dhl", "TRACK1")
+	require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+
+	fulfilment := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "fulfilment-service", auth.PrincipalService)))
+	shipped, err := fulfilment.MarkShipped(ctx, created.GetOrderId(), "dhl", "TRACK1")
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_SHIPPED, shipped.GetStatus())
+}
+
+// writeCertificate issues a certificate for the DNS name, signed by parent (self-signed
+// when parent is nil), writes it with its key into dir and returns the paths.
+func writeCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return cert, key, certFile, keyFile
+}
+
+func TestAuth_TLSWithoutTokensDeniesCallersWithoutCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caFile, _ := writeCertificate(t, dir, "test-ca", nil, nil)
+	_, _, serverCert, serverKey := writeCertificate(t, dir, "order-service", ca, caKey)
+	_, _, billingCert, billingKey := writeCertificate(t, dir, "billing-service", ca, caKey)
+
+	serverReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{
+		CertFile: serverCert, KeyFile: serverKey, CAFile: caFile, ClientAuth: tlsconfig.ClientAuthOptional,
+	})
+	require.NoError(t, err)
+	billingReloader, err := tlsconfig.NewReloader(tlsconfig.Configuration{CertFile: billingCert, KeyFile: billingKey, CAFile: caFile})
+	require.NoError(t, err)
+
+	addr := startOrderServer(t, nil, serverReloader.ServerConfig())
+	ctx := context.Background()
+
+	billing := clientAs(t, addr, ordersdk.WithTLS(billingReloader.ClientConfig("order-service")))
+	created, err := billing.CreateOrder(ctx, "alice", &orderpb.OrderItem{ProductId: "p1", Quantity: 1, Price: 1})
+	require.NoError(t, err)
+
+	// Без сертификата и без JWT вызывающий не опознан: ни служебные методы, ни чужие заказы.
+	anonymous := clientAs(t, addr, ordersdk.WithTLS(billingReloader.AnonymousClientConfig("order-service")))
+	_, err = anonymous.UpdateOrderStatus(ctx, created.GetOrderId(), orderpb.OrderStatus_PAID)
+	require.True(t, errors.Is(err, ordersdk.ErrUnauthenticated))
+	_, err = anonymous.GetOrder(ctx, created.GetOrderId())
+	require.True(t, errors.Is(err, ordersdk.ErrUnauthenticated))
+
+	updated, err := billing.UpdateOrderStatus(ctx, created.GetOrderId(), orderpb.OrderStatus_PAID)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_PAID, updated.GetStatus())
+}
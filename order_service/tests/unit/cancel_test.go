@@ -0,0 +1,76 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/service/order_service"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCancelItems_CancelsUnitsOfPaidOrder(t *testing.T) {
+	orders := map[string]models.Order{
+		"o1": {OrderID: "o1", UserID: "alice", Status: orderpb.OrderStatus_PAID.String(), Version: 1, TotalAmount: 35, Items: []models.OrderItem{
+			{ProductID: "p1", Quantity: 3, Price: 10},
+			{ProductID: "p2", Quantity: 1, Price: 5},
+		}},
+		"o2": {OrderID: "o2", UserID: "alice", Status: orderpb.OrderStatus_SHIPPED.String(), Items: []models.OrderItem{
+			{ProductID: "p1", Quantity: 1, Price: 10},
+		}},
+	}
+	publisher := &mockNatsClient{}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:     zap.NewNop(),
+		OrderRepo:  newFulfilmentRepository(orders),
+		Counters:   memoryCounters{},
+		NatsClient: publisher,
+	})
+	ctx := context.Background()
+	cancel := func(orderID string, cancellationID string, items ...*orderpb.CancelledItem) (*orderpb.Order, *orderpb.ItemCancellation, error) {
+		return svc.CancelItems(ctx, &orderpb.CancelItemsRequest{OrderId: orderID, Items: items, Reason: "changed mind", CancellationId: cancellationID})
+	}
+
+	order, cancellation, err := cancel("o1", "c1", &orderpb.CancelledItem{ProductId: "p1", Quantity: 2})
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_PAID, order.Status)
+	require.Equal(t, 15.0, order.TotalAmount)
+	require.Equal(t, int32(2), order.Items[0].CancelledQuantity)
+	require.Equal(t, orderpb.ItemStatus_ITEM_PARTIALLY_CANCELLED, order.Items[0].Status)
+	require.Equal(t, orderpb.ItemStatus_ITEM_ACTIVE, order.Items[1].Status)
+	require.Equal(t, 20.0, cancellation.Amount)
+	require.Len(t, publisher.cancelled, 1)
+	require.Equal(t, []models.CancelledItem{{ProductID: "p1", Quantity: 2, Price: 10}}, publisher.cancelled[0].Cancellation.Items)
+
+	// Повтор с тем же ключом ничего не отменяет, но снова просит billing о возврате.
+	order, _, err = cancel("o1", "c1", &orderpb.CancelledItem{ProductId: "p1", Quantity: 2})
+	require.NoError(t, err)
+	require.Equal(t, 15.0, order.TotalAmount)
+	require.Len(t, publisher.cancelled, 2)
+	require.Equal(t, "c1", publisher.cancelled[1].Cancellation.CancellationID)
+
+	_, _, err = cancel("o1", "", &orderpb.CancelledItem{ProductId: "p1", Quantity: 2})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, _, err = cancel("o1", "", &orderpb.CancelledItem{ProductId: "p3", Quantity: 1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, _, err = cancel("o2", "", &orderpb.CancelledItem{ProductId: "p1", Quantity: 1})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Отмена всех оставшихся единиц отменяет заказ целиком.
+	order, cancellation, err = cancel("o1", "",
+		&orderpb.CancelledItem{ProductId: "p1", Quantity: 1},
+		&orderpb.CancelledItem{ProductId: "p2", Quantity: 1},
+	)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_CANCELLED, order.Status)
+	require.Zero(t, order.TotalAmount)
+	require.Equal(t, 15.0, cancellation.Amount)
+	require.Equal(t, orderpb.ItemStatus_ITEM_CANCELLED, order.Items[0].Status)
+	require.Len(t, order.Cancellations, 2)
+	require.Equal(t, int64(3), order.Version)
+}
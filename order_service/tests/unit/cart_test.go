@@ -0,0 +1,222 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/service/cart_service"
+	"order-service-system/order_service/internal/service/order_service"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memoryCartRepository applies the status and version guards of the Mongo repository.
+type memoryCartRepository struct {
+	carts map[string]models.Cart
+}
+
+func (f *memoryCartRepository) Create(ctx context.Context, cart models.Cart) error {
+	f.carts[cart.CartID] = cart
+	return nil
+}
+
+func (f *memoryCartRepository) Get(ctx context.Context, cartID string) (models.Cart, error) {
+	cart, ok := f.carts[cartID]
+	if !ok {
+		return models.Cart{}, pj_errors.ErrNotFound
+	}
+	cart.Items = append([]models.OrderItem(nil), cart.Items...)
+	return cart, nil
+}
+
+func (f *memoryCartRepository) Save(ctx context.Context, cart models.Cart, expectedVersion int64) (models.Cart, error) {
+	return f.guarded(cart.CartID, orderpb.CartStatus_CART_ACTIVE, expectedVersion, "", func(doc *models.Cart) {
+		doc.Items = cart.Items
+		doc.PromoCode = cart.PromoCode
+		doc.ExpiresAt = cart.ExpiresAt
+	})
+}
+
+func (f *memoryCartRepository) StartCheckout(ctx context.Context, cartID string, expectedVersion int64, orderID string, address *models.ShippingAddress, _ time.Time) (models.Cart, error) {
+	return f.guarded(cartID, orderpb.CartStatus_CART_ACTIVE, expectedVersion, "", func(doc *models.Cart) {
+		doc.Status = orderpb.CartStatus_CART_CHECKING_OUT.String()
+		doc.OrderID = orderID
+		doc.ShippingAddress = address
+	})
+}
+
+func (f *memoryCartRepository) ReleaseCheckout(ctx context.Context, cartID string, orderID string) (models.Cart, error) {
+	return f.guarded(cartID, orderpb.CartStatus_CART_CHECKING_OUT, 0, orderID, func(doc *models.Cart) {
+		doc.Status = orderpb.CartStatus_CART_ACTIVE.String()
+		doc.OrderID = ""
+		doc.ShippingAddress = nil
+	})
+}
+
+func (f *memoryCartRepository) CompleteCheckout(ctx context.Context, cartID string, orderID string) (models.Cart, error) {
+	return f.guarded(cartID, orderpb.CartStatus_CART_CHECKING_OUT, 0, orderID, func(doc *models.Cart) {
+		doc.Status = orderpb.CartStatus_CART_CHECKED_OUT.String()
+	})
+}
+
+func (f *memoryCartRepository) guarded(cartID string, from orderpb.CartStatus, version int64, orderID string, apply func(*models.Cart)) (models.Cart, error) {
+	doc, ok := f.carts[cartID]
+	if !ok {
+		return models.Cart{}, pj_errors.ErrNotFound
+	}
+	if doc.Status != from.String() || (version != 0 && doc.Version != version) || doc.OrderID != orderID {
+		return models.Cart{}, pj_errors.ErrConflict
+	}
+	apply(&doc)
+	doc.Version++
+	f.carts[cartID] = doc
+	return doc, nil
+}
+
+func newCartService(t *testing.T, orders map[string]models.Order, create func(models.Order) error) (*cart_service.CartService, *memoryCartRepository) {
+	t.Helper()
+	promos, err := cart_service.ParsePromoCodes([]string{"welcome10:10%", "MINUS5:5"})
+	require.NoError(t, err)
+
+	repo := newFulfilmentRepository(orders)
+	repo.create = func(ctx context.Context, order models.Order) error {
+		if create != nil {
+			if err := create(order); err != nil {
+				return err
+			}
+		}
+		if _, ok := orders[order.OrderID]; ok {
+			return pj_errors.ErrAlreadyExists
+		}
+		orders[order.OrderID] = order
+		return nil
+	}
+	orderService := order_service.NewOrderService(order_service.Deps{
+		Logger:     zap.NewNop(),
+		OrderRepo:  repo,
+		Counters:   memoryCounters{},
+		NatsClient: &mockNatsClient{publish: func(models.OrderCreatedEvent) error { return nil }},
+	})
+
+	carts := &memoryCartRepository{carts: map[string]models.Cart{}}
+	return cart_service.NewCartService(cart_service.Deps{
+		Logger:   zap.NewNop(),
+		CartRepo: carts,
+		Orders:   orderService,
+		Promos:   promos,
+	}), carts
+}
+
+func TestCart_CheckoutAppliesPromoOnce(t *testing.T) {
+	orders := map[string]models.Order{}
+	svc, _ := newCartService(t, orders, nil)
+	ctx := context.Background()
+
+	cart, err := svc.CreateCart(ctx, &orderpb.CreateCartRequest{UserId: "alice", Items: []*orderpb.OrderItem{
+		{ProductId: "p1", Quantity: 1, Price: 10},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, orderpb.CartStatus_CART_ACTIVE, cart.Status)
+
+	_, err = svc.AddCartItem(ctx, cart.CartId, &orderpb.OrderItem{ProductId: "p1", Quantity: 2, Price: 10})
+	require.NoError(t, err)
+	_, err = svc.AddCartItem(ctx, cart.CartId, &orderpb.OrderItem{ProductId: "p2", Quantity: 1, Price: 5})
+	require.NoError(t, err)
+	_, err = svc.AddCartItem(ctx, cart.CartId, &orderpb.OrderItem{ProductId: "p3", Quantity: 1, Price: 7})
+	require.NoError(t, err)
+	_, err = svc.RemoveCartItem(ctx, cart.CartId, "p3")
+	require.NoError(t, err)
+	cart, err = svc.UpdateCartItem(ctx, cart.CartId, "p2", 2)
+	require.NoError(t, err)
+	require.Equal(t, 40.0, cart.Total)
+
+	_, err = svc.ApplyPromo(ctx, cart.CartId, "unknown")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = svc.ApplyPromo(ctx, cart.CartId, "Welcome10")
+	require.NoError(t, err)
+
+	cart, err = svc.GetCart(ctx, cart.CartId)
+	require.NoError(t, err)
+	require.Equal(t, "WELCOME10", cart.PromoCode)
+	require.Equal(t, 40.0, cart.Subtotal)
+	require.Equal(t, 4.0, cart.Discount)
+	require.Equal(t, 36.0, cart.Total)
+
+	order, checkedOut, err := svc.Checkout(ctx, &orderpb.CheckoutRequest{CartId: cart.CartId})
+	require.NoError(t, err)
+	require.Equal(t, orderpb.CartStatus_CART_CHECKED_OUT, checkedOut.Status)
+	require.Equal(t, order.OrderId, checkedOut.OrderId)
+	require.Equal(t, 36.0, order.TotalAmount)
+	require.Equal(t, 9.0, order.Items[0].Price)
+	require.Equal(t, cart.CartId, order.CartId)
+	require.Equal(t, "WELCOME10", order.PromoCode)
+	require.Equal(t, 4.0, order.Discount)
+
+	// Повторный checkout возвращает тот же заказ, а корзина больше не меняется.
+	again, _, err := svc.Checkout(ctx, &orderpb.CheckoutRequest{CartId: cart.CartId})
+	require.NoError(t, err)
+	require.Equal(t, order.OrderId, again.OrderId)
+	require.Len(t, orders, 1)
+
+	_, err = svc.AddCartItem(ctx, cart.CartId, &orderpb.OrderItem{ProductId: "p1", Quantity: 1, Price: 10})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestCart_CheckoutResumesAfterFailure(t *testing.T) {
+	orders := map[string]models.Order{}
+	failures := 1
+	svc, carts := newCartService(t, orders, func(models.Order) error {
+		if failures > 0 {
+			failures--
+			return errors.New("mongo is down")
+		}
+		return nil
+	})
+	ctx := context.Background()
+
+	cart, err := svc.CreateCart(ctx, &orderpb.CreateCartRequest{UserId: "alice", Items: []*orderpb.OrderItem{
+		{ProductId: "p1", Quantity: 1, Price: 10},
+	}})
+	require.NoError(t, err)
+
+	// Неверный адрес отклоняет заказ и возвращает корзину к редактированию.
+	_, _, err = svc.Checkout(ctx, &orderpb.CheckoutRequest{CartId: cart.CartId, ShippingAddress: &orderpb.ShippingAddress{Recipient: "Alice"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, orderpb.CartStatus_CART_ACTIVE.String(), carts.carts[cart.CartId].Status)
+
+	_, _, err = svc.Checkout(ctx, &orderpb.CheckoutRequest{CartId: cart.CartId})
+	require.Equal(t, codes.Internal, status.Code(err))
+	frozen := carts.carts[cart.CartId]
+	require.Equal(t, orderpb.CartStatus_CART_CHECKING_OUT.String(), frozen.Status)
+	require.Empty(t, orders)
+
+	order, checkedOut, err := svc.Checkout(ctx, &orderpb.CheckoutRequest{CartId: cart.CartId})
+	require.NoError(t, err)
+	require.Equal(t, frozen.OrderID, order.OrderId)
+	require.Equal(t, orderpb.CartStatus_CART_CHECKED_OUT, checkedOut.Status)
+	require.Len(t, orders, 1)
+}
+
+func TestParsePromoCodes(t *testing.T) {
+	promos, err := cart_service.ParsePromoCodes([]string{" sale:15% ", "minus5:5", ""})
+	require.NoError(t, err)
+	require.Equal(t, map[string]models.Promo{
+		"SALE":   {Code: "SALE", Percent: 15},
+		"MINUS5": {Code: "MINUS5", Amount: 5},
+	}, promos)
+
+	for _, spec := range []string{"sale", "sale:abc", "sale:0", "sale:120%", ":5"} {
+		_, err := cart_service.ParsePromoCodes([]string{spec})
+		require.Error(t, err, spec)
+	}
+	_, err = cart_service.ParsePromoCodes([]string{"sale:5", "SALE:10%"})
+	require.Error(t, err)
+}
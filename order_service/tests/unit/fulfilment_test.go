@@ -0,0 +1,189 @@
+package unit
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/service/order_service"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newFulfilmentRepository keeps orders in a map and applies guarded transitions the
+// way the Mongo repository does.
+func newFulfilmentRepository(orders map[string]models.Order) *mockOrderRepository {
+	guarded := func(orderID string, allowed func(string) bool, apply func(*models.Order)) (models.Order, error) {
+		doc, ok := orders[orderID]
+		if !ok {
+			return models.Order{}, pj_errors.ErrNotFound
+		}
+		if !allowed(doc.Status) {
+			return models.Order{}, pj_errors.ErrConflict
+		}
+		apply(&doc)
+		orders[orderID] = doc
+		return doc, nil
+	}
+
+	return &mockOrderRepository{
+		get: func(ctx context.Context, orderID string) (models.Order, error) {
+			doc, ok := orders[orderID]
+			if !ok {
+				return models.Order{}, pj_errors.ErrNotFound
+			}
+			return doc, nil
+		},
+		getByNumber: func(ctx context.Context, number string) (models.Order, error) {
+			for _, doc := range orders {
+				if doc.Number == number {
+					return doc, nil
+				}
+			}
+			return models.Order{}, pj_errors.ErrNotFound
+		},
+		updateStatus: func(ctx context.Context, orderID string, status string) (models.Order, error) {
+			return guarded(orderID,
+				func(current string) bool { return !slices.Contains(models.FulfilmentStatuses, current) },
+				func(doc *models.Order) { doc.Status = status },
+			)
+		},
+		transition: func(ctx context.Context, orderID string, from []string, change models.StatusChange) (models.Order, error) {
+			return guarded(orderID,
+				func(current string) bool { return slices.Contains(from, current) },
+				func(doc *models.Order) {
+					doc.Status = change.Status
+					if change.Shipment != nil {
+						doc.Shipment = change.Shipment
+					}
+					if !change.DeliveredAt.IsZero() {
+						doc.Shipment.DeliveredAt = change.DeliveredAt
+					}
+				},
+			)
+		},
+		amend: func(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, amendment models.OrderAmendment) (models.Order, error) {
+			return guarded(orderID,
+				func(current string) bool {
+					return current == orderpb.OrderStatus_PENDING.String() && orders[orderID].Version == expectedVersion
+				},
+				func(doc *models.Order) {
+					doc.Items = items
+					doc.TotalAmount = amendment.NewTotal
+					doc.Version = amendment.Version
+					doc.Amendments = append(slices.Clone(doc.Amendments), amendment)
+				},
+			)
+		},
+		cancelItems: func(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, total float64, status string, cancellation models.ItemCancellation) (models.Order, error) {
+			return guarded(orderID,
+				func(current string) bool {
+					return slices.Contains(models.CancellableStatuses, current) && orders[orderID].Version == expectedVersion
+				},
+				func(doc *models.Order) {
+					doc.Items = items
+					doc.TotalAmount = total
+					doc.Status = status
+					doc.Version++
+					doc.Cancellations = append(slices.Clone(doc.Cancellations), cancellation)
+				},
+			)
+		},
+	}
+}
+
+func TestMarkShipped_FulfilmentFlow(t *testing.T) {
+	orders := map[string]models.Order{
+		"o1": {OrderID: "o1", UserID: "u1", Status: orderpb.OrderStatus_PAID.String(), CreatedAt: time.Now()},
+		"o2": {OrderID: "o2", UserID: "u1", Status: orderpb.OrderStatus_PENDING.String(), CreatedAt: time.Now()},
+	}
+	publisher := &mockNatsClient{}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:     newTestLogger(t),
+		OrderRepo:  newFulfilmentRepository(orders),
+		Counters:   memoryCounters{},
+		NatsClient: publisher,
+	})
+	ctx := context.Background()
+
+	_, err := svc.MarkShipped(ctx, "o1", "", "TRACK1")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = svc.MarkShipped(ctx, "o2", "dhl", "TRACK2")
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = svc.MarkShipped(ctx, "missing", "dhl", "TRACK3")
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = svc.UpdateOrderStatus(ctx, "o1", orderpb.OrderStatus_SHIPPED)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	order, err := svc.UpdateOrderStatus(ctx, "o1", orderpb.OrderStatus_PROCESSING)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_PROCESSING, order.Status)
+
+	order, err = svc.MarkShipped(ctx, "o1", "dhl", "TRACK1")
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_SHIPPED, order.Status)
+	require.Equal(t, "dhl", order.Shipment.Carrier)
+	require.Equal(t, "TRACK1", order.Shipment.TrackingNumber)
+	require.Nil(t, order.Shipment.DeliveredAt)
+	require.Len(t, publisher.shipped, 1)
+	require.Equal(t, "u1", publisher.shipped[0].UserID)
+
+	// Запоздавший результат оплаты не возвращает отправленный заказ назад.
+	order, err = svc.UpdateOrderStatus(ctx, "o1", orderpb.OrderStatus_PAID)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_SHIPPED, order.Status)
+
+	_, err = svc.UpdateOrderStatus(ctx, "o1", orderpb.OrderStatus_CANCELLED)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	order, err = svc.UpdateOrderStatus(ctx, "o1", orderpb.OrderStatus_DELIVERED)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_DELIVERED, order.Status)
+	require.NotNil(t, order.Shipment.DeliveredAt)
+	require.Len(t, publisher.delivered, 1)
+	require.Equal(t, "o1", publisher.delivered[0].OrderID)
+}
+
+func TestCreateOrder_ValidatesShippingAddress(t *testing.T) {
+	var created models.Order
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:   newTestLogger(t),
+		Counters: memoryCounters{},
+		OrderRepo: &mockOrderRepository{
+			create: func(ctx context.Context, order models.Order) error {
+				created = order
+				return nil
+			},
+		},
+		NatsClient: &mockNatsClient{
+			publish: func(event models.OrderCreatedEvent) error { return nil },
+		},
+	})
+	ctx := context.Background()
+	items := []*orderpb.OrderItem{{ProductId: "p1", Quantity: 1, Price: 10}}
+
+	_, err := svc.CreateOrder(ctx, &orderpb.CreateOrderRequest{
+		UserId:          "u1",
+		Items:           items,
+		ShippingAddress: &orderpb.ShippingAddress{Recipient: "Ivan", Line1: "Lenina 1", City: "Moscow"},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	order, err := svc.CreateOrder(ctx, &orderpb.CreateOrderRequest{
+		UserId:          "u1",
+		Items:           items,
+		ShippingAddress: &orderpb.ShippingAddress{Recipient: "Ivan", Line1: "Lenina 1", City: "Moscow", Country: "RU"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "RU", created.ShippingAddress.Country)
+	require.Equal(t, "Moscow", order.ShippingAddress.City)
+}
@@ -0,0 +1,177 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service-system/order_service/internal/controllers/grpc/analytics_grpc_controller"
+	"order-service-system/order_service/internal/controllers/grpc/order_grpc_controller"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/server"
+	"order-service-system/order_service/internal/service/order_service"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func startGateway(t *testing.T, repo *mockOrderRepository) *httptest.Server {
+	t.Helper()
+
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:    zap.NewNop(),
+		OrderRepo: repo,
+		Counters:  memoryCounters{},
+		NatsClient: &mockNatsClient{
+			publish: func(event models.OrderCreatedEvent) error { return nil },
+		},
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcServer := grpc.NewServer()
+	orderpb.RegisterOrderServiceServer(grpcServer, order_grpc_controller.NewOrderController(order_grpc_controller.Deps{
+		OrderService:  svc,
+		ReturnService: newReturnService(t, repo, &mockNatsClient{}),
+		SearchService: newSearchService(&memorySearchBackend{orders: searchFixture()}),
+	}))
+	orderpb.RegisterAnalyticsServiceServer(grpcServer, analytics_grpc_controller.NewAnalyticsController(analytics_grpc_controller.Deps{
+		AnalyticsService: newAnalyticsService(analyticsFixture()),
+	}))
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	gateway, err := server.NewGateway(context.Background(), server.DepsGateway{
+		Logger:  zap.NewNop(),
+		GrpcURL: listener.Addr().String(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = gateway.Close(context.Background()) })
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", gateway)
+	mux.Handle("/openapi.json", gateway.OpenAPIHandler())
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func TestGateway_Routes(t *testing.T) {
+	created := map[string]models.Order{}
+	httpServer := startGateway(t, &mockOrderRepository{
+		create: func(ctx context.Context, order models.Order) error {
+			created[order.OrderID] = order
+			return nil
+		},
+		get: func(ctx context.Context, orderID string) (models.Order, error) {
+			order, ok := created[orderID]
+			if !ok {
+				return models.Order{}, pj_errors.ErrNotFound
+			}
+			return order, nil
+		},
+		updateStatus: func(ctx context.Context, orderID string, status string) (models.Order, error) {
+			order := created[orderID]
+			order.Status = status
+			created[orderID] = order
+			return order, nil
+		},
+		list: func(ctx context.Context, filter models.ListOrdersFilter) ([]models.Order, error) {
+			var orders []models.Order
+			for _, order := range created {
+				if order.UserID == filter.UserID {
+					orders = append(orders, order)
+				}
+			}
+			return orders, nil
+		},
+	})
+
+	resp, err := http.Post(httpServer.URL+"/v1/orders", "application/json",
+		strings.NewReader(`{"userId":"user1","items":[{"productId":"p1","quantity":2,"price":5}]}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var createResp struct {
+		Order struct {
+			OrderID     string  `json:"orderId"`
+			TotalAmount float64 `json:"totalAmount"`
+			Status      string  `json:"status"`
+		} `json:"order"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&createResp))
+	_ = resp.Body.Close()
+	require.Equal(t, 10.0, createResp.Order.TotalAmount)
+	require.Equal(t, "PENDING", createResp.Order.Status)
+
+	resp, err = http.Get(httpServer.URL + "/v1/orders/" + createResp.Order.OrderID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	req, err := http.NewRequest(http.MethodPatch, httpServer.URL+"/v1/orders/"+createResp.Order.OrderID+"/status", strings.NewReader(`{"status":"CANCELLED"}`))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	resp, err = http.Get(httpServer.URL + "/v1/orders?userId=user1&pageSize=10")
+	require.NoError(t, err)
+	var listResp struct {
+		Orders []struct {
+			Status string `json:"status"`
+		} `json:"orders"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listResp))
+	_ = resp.Body.Close()
+	require.Len(t, listResp.Orders, 1)
+	require.Equal(t, "CANCELLED", listResp.Orders[0].Status)
+}
+
+func TestGateway_ErrorBody(t *testing.T) {
+	httpServer := startGateway(t, &mockOrderRepository{
+		get: func(ctx context.Context, orderID string) (models.Order, error) {
+			return models.Order{}, pj_errors.ErrNotFound
+		},
+	})
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	for path, expected := range map[string]server.ErrorStatus{
+		"/v1/orders/missing":       {Code: http.StatusNotFound, Status: "NOT_FOUND", Message: "order not found"},
+		"/v1/orders?pageSize=-1":   {Code: http.StatusBadRequest, Status: "INVALID_ARGUMENT", Message: "page_size must be non-negative"},
+		"/v1/unknown/route/at/all": {Code: http.StatusNotFound, Status: "NOT_FOUND", Message: "Not Found"},
+	} {
+		resp, err := client.Get(httpServer.URL + path)
+		require.NoError(t, err)
+
+		var body server.ErrorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		_ = resp.Body.Close()
+
+		require.Equal(t, expected.Code, resp.StatusCode, path)
+		require.Equal(t, expected, body.Error, path)
+	}
+}
+
+func TestGateway_ServesOpenAPI(t *testing.T) {
+	httpServer := startGateway(t, &mockOrderRepository{})
+
+	resp, err := http.Get(httpServer.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var doc struct {
+		Paths map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	require.Contains(t, doc.Paths, "/v1/orders")
+	require.Contains(t, doc.Paths, "/v1/orders/{orderId}")
+}
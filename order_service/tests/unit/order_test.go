@@ -3,10 +3,12 @@ package unit
 import (
 	"context"
 	"errors"
+	"fmt"
 	"order-service-system/order_service/internal/service/order_service"
 	"testing"
 	"time"
 
+	"order-service-system/common/auth"
 	"order-service-system/order_service/internal/models"
 	"order-service-system/order_service/internal/pj_errors"
 	orderpb "order-service-system/proto/order"
@@ -20,7 +22,12 @@ import (
 type mockOrderRepository struct {
 	create       func(ctx context.Context, order models.Order) error
 	get          func(ctx context.Context, orderID string) (models.Order, error)
+	getByNumber  func(ctx context.Context, number string) (models.Order, error)
 	updateStatus func(ctx context.Context, orderID string, status string) (models.Order, error)
+	list         func(ctx context.Context, filter models.ListOrdersFilter) ([]models.Order, error)
+	transition   func(ctx context.Context, orderID string, from []string, change models.StatusChange) (models.Order, error)
+	amend        func(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, amendment models.OrderAmendment) (models.Order, error)
+	cancelItems  func(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, total float64, status string, cancellation models.ItemCancellation) (models.Order, error)
 }
 
 func (f *mockOrderRepository) Create(ctx context.Context, order models.Order) error {
@@ -31,18 +38,68 @@ func (f *mockOrderRepository) Get(ctx context.Context, orderID string) (models.O
 	return f.get(ctx, orderID)
 }
 
+func (f *mockOrderRepository) GetByNumber(ctx context.Context, number string) (models.Order, error) {
+	return f.getByNumber(ctx, number)
+}
+
 func (f *mockOrderRepository) UpdateStatus(ctx context.Context, orderID string, status string) (models.Order, error) {
 	return f.updateStatus(ctx, orderID, status)
 }
 
+func (f *mockOrderRepository) List(ctx context.Context, filter models.ListOrdersFilter) ([]models.Order, error) {
+	return f.list(ctx, filter)
+}
+
+func (f *mockOrderRepository) Transition(ctx context.Context, orderID string, from []string, change models.StatusChange) (models.Order, error) {
+	return f.transition(ctx, orderID, from, change)
+}
+
+func (f *mockOrderRepository) Amend(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, amendment models.OrderAmendment) (models.Order, error) {
+	return f.amend(ctx, orderID, expectedVersion, items, amendment)
+}
+
+func (f *mockOrderRepository) CancelItems(ctx context.Context, orderID string, expectedVersion int64, items []models.OrderItem, total float64, status string, cancellation models.ItemCancellation) (models.Order, error) {
+	return f.cancelItems(ctx, orderID, expectedVersion, items, total, status, cancellation)
+}
+
 type mockNatsClient struct {
-	publish func(event models.OrderCreatedEvent) error
+	publish   func(event models.OrderCreatedEvent) error
+	shipped   []models.OrderShippedEvent
+	delivered []models.OrderDeliveredEvent
+	returns   []models.Return
+	amended   []models.OrderAmendedEvent
+	cancelled []models.OrderItemsCancelledEvent
 }
 
-func (f *mockNatsClient) PublishOrderCreated(event models.OrderCreatedEvent) error {
+func (f *mockNatsClient) PublishOrderCreated(ctx context.Context, event models.OrderCreatedEvent) error {
 	return f.publish(event)
 }
 
+func (f *mockNatsClient) PublishOrderShipped(ctx context.Context, event models.OrderShippedEvent) error {
+	f.shipped = append(f.shipped, event)
+	return nil
+}
+
+func (f *mockNatsClient) PublishOrderDelivered(ctx context.Context, event models.OrderDeliveredEvent) error {
+	f.delivered = append(f.delivered, event)
+	return nil
+}
+
+func (f *mockNatsClient) PublishOrderAmended(ctx context.Context, event models.OrderAmendedEvent) error {
+	f.amended = append(f.amended, event)
+	return nil
+}
+
+func (f *mockNatsClient) PublishOrderItemsCancelled(ctx context.Context, event models.OrderItemsCancelledEvent) error {
+	f.cancelled = append(f.cancelled, event)
+	return nil
+}
+
+func (f *mockNatsClient) PublishReturnStatus(ctx context.Context, ret models.Return) error {
+	f.returns = append(f.returns, ret)
+	return nil
+}
+
 func newTestLogger(t *testing.T) *zap.Logger {
 	t.Helper()
 	logger, err := zap.NewDevelopment()
@@ -58,14 +115,16 @@ func TestNewOrderService_PanicsOnNilDeps(t *testing.T) {
 	})
 	require.Panics(t, func() {
 		order_service.NewOrderService(order_service.Deps{
-			Logger:    logger,
-			OrderRepo: &mockOrderRepository{},
+			Logger:     logger,
+			OrderRepo:  &mockOrderRepository{},
+			NatsClient: &mockNatsClient{},
 		})
 	})
 	require.Panics(t, func() {
 		order_service.NewOrderService(order_service.Deps{
 			Logger:     logger,
 			OrderRepo:  &mockOrderRepository{},
+			Counters:   memoryCounters{},
 			NatsClient: nil,
 		})
 	})
@@ -74,6 +133,7 @@ func TestNewOrderService_PanicsOnNilDeps(t *testing.T) {
 		_ = order_service.NewOrderService(order_service.Deps{
 			Logger:     logger,
 			OrderRepo:  &mockOrderRepository{},
+			Counters:   memoryCounters{},
 			NatsClient: &mockNatsClient{},
 		})
 	})
@@ -83,7 +143,8 @@ func TestCreateOrder_ValidationErrors(t *testing.T) {
 	logger := newTestLogger(t)
 
 	svc := order_service.NewOrderService(order_service.Deps{
-		Logger: logger,
+		Logger:   logger,
+		Counters: memoryCounters{},
 		OrderRepo: &mockOrderRepository{
 			create: func(ctx context.Context, order models.Order) error {
 				t.Fatalf("Create should not be called on validation error")
@@ -139,7 +200,8 @@ func TestCreateOrder_SuccessAndNatsErrorIsNonFatal(t *testing.T) {
 	natsCalled := 0
 
 	svc := order_service.NewOrderService(order_service.Deps{
-		Logger: logger,
+		Logger:   logger,
+		Counters: memoryCounters{},
 		OrderRepo: &mockOrderRepository{
 			create: func(ctx context.Context, order models.Order) error {
 				repoCalled++
@@ -180,7 +242,8 @@ func TestCreateOrder_RepoErrorReturnsInternal(t *testing.T) {
 	logger := newTestLogger(t)
 
 	svc := order_service.NewOrderService(order_service.Deps{
-		Logger: logger,
+		Logger:   logger,
+		Counters: memoryCounters{},
 		OrderRepo: &mockOrderRepository{
 			create: func(ctx context.Context, order models.Order) error {
 				return errors.New("db error")
@@ -214,7 +277,8 @@ func TestGetOrder_ValidationAndErrorMapping(t *testing.T) {
 
 	var requestID string
 	svc := order_service.NewOrderService(order_service.Deps{
-		Logger: logger,
+		Logger:   logger,
+		Counters: memoryCounters{},
 		OrderRepo: &mockOrderRepository{
 			get: func(ctx context.Context, orderID string) (models.Order, error) {
 				requestID = orderID
@@ -264,15 +328,53 @@ func TestGetOrder_ValidationAndErrorMapping(t *testing.T) {
 	require.Equal(t, "order123", resp.OrderId)
 }
 
+func TestGetOrderByNumber_SequentialNumbers(t *testing.T) {
+	orders := map[string]models.Order{}
+	repo := newFulfilmentRepository(orders)
+	repo.create = func(ctx context.Context, order models.Order) error {
+		orders[order.OrderID] = order
+		return nil
+	}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:     zap.NewNop(),
+		OrderRepo:  repo,
+		Counters:   memoryCounters{},
+		NatsClient: &mockNatsClient{publish: func(models.OrderCreatedEvent) error { return nil }},
+	})
+	alice := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Type: auth.PrincipalUser})
+	bob := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "bob", Type: auth.PrincipalUser})
+	items := []*orderpb.OrderItem{{ProductId: "p1", Quantity: 1, Price: 10}}
+
+	first, err := svc.CreateOrder(alice, &orderpb.CreateOrderRequest{Items: items})
+	require.NoError(t, err)
+	second, err := svc.CreateOrder(alice, &orderpb.CreateOrderRequest{Items: items})
+	require.NoError(t, err)
+	year := time.Now().UTC().Year()
+	require.Equal(t, fmt.Sprintf("ORD-%d-000001", year), first.OrderNumber)
+	require.Equal(t, fmt.Sprintf("ORD-%d-000002", year), second.OrderNumber)
+
+	found, err := svc.GetOrderByNumber(alice, fmt.Sprintf(" ord-%d-000002 ", year))
+	require.NoError(t, err)
+	require.Equal(t, second.OrderId, found.OrderId)
+
+	_, err = svc.GetOrderByNumber(bob, second.OrderNumber)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.GetOrderByNumber(alice, "ORD-1999-000001")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.GetOrderByNumber(alice, " ")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestUpdateOrderStatus_ValidationAndErrorMapping(t *testing.T) {
 	logger := newTestLogger(t)
 
 	var getStatus string
 	svc := order_service.NewOrderService(order_service.Deps{
-		Logger: logger,
+		Logger:   logger,
+		Counters: memoryCounters{},
 		OrderRepo: &mockOrderRepository{
-			updateStatus: func(ctx context.Context, orderID string, status string) (models.Order, error) {
-				getStatus = status
+			transition: func(ctx context.Context, orderID string, from []string, change models.StatusChange) (models.Order, error) {
+				getStatus = change.Status
 				if orderID == "not_found" {
 					return models.Order{}, pj_errors.ErrNotFound
 				}
@@ -281,7 +383,7 @@ func TestUpdateOrderStatus_ValidationAndErrorMapping(t *testing.T) {
 				}
 				return models.Order{
 					OrderID:   orderID,
-					Status:    status,
+					Status:    change.Status,
 					CreatedAt: time.Now(),
 				}, nil
 			},
@@ -330,3 +432,89 @@ func TestUpdateOrderStatus_ValidationAndErrorMapping(t *testing.T) {
 	require.Equal(t, "order1", resp.OrderId)
 	require.Equal(t, orderpb.OrderStatus_PAID, resp.Status)
 }
+
+func TestListOrders_PaginationAndFilters(t *testing.T) {
+	logger := newTestLogger(t)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var stored []models.Order
+	for i := 0; i < 5; i++ {
+		stored = append(stored, models.Order{
+			OrderID:   string(rune('e' - i)),
+			UserID:    "user1",
+			Status:    orderpb.OrderStatus_PENDING.String(),
+			CreatedAt: base.Add(time.Duration(5-i) * time.Minute),
+		})
+	}
+
+	var filters []models.ListOrdersFilter
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:   logger,
+		Counters: memoryCounters{},
+		OrderRepo: &mockOrderRepository{
+			list: func(ctx context.Context, filter models.ListOrdersFilter) ([]models.Order, error) {
+				filters = append(filters, filter)
+				var page []models.Order
+				for _, doc := range stored {
+					if filter.After != nil && !doc.CreatedAt.Before(filter.After.CreatedAt) {
+						continue
+					}
+					page = append(page, doc)
+				}
+				if len(page) > filter.Limit {
+					page = page[:filter.Limit]
+				}
+				return page, nil
+			},
+		},
+		NatsClient: &mockNatsClient{
+			publish: func(event models.OrderCreatedEvent) error { return nil },
+		},
+	})
+
+	ctx := context.Background()
+
+	_, _, err := svc.ListOrders(ctx, &orderpb.ListOrdersRequest{PageSize: -1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, _, err = svc.ListOrders(ctx, &orderpb.ListOrdersRequest{PageToken: "???"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	first, token, err := svc.ListOrders(ctx, &orderpb.ListOrdersRequest{
+		UserId:   "user1",
+		Status:   orderpb.OrderStatus_PENDING,
+		PageSize: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	require.NotEmpty(t, token)
+	require.Equal(t, "user1", filters[0].UserID)
+	require.Equal(t, "PENDING", filters[0].Status)
+	require.Equal(t, 3, filters[0].Limit)
+
+	var ids []string
+	for _, order := range first {
+		ids = append(ids, order.OrderId)
+	}
+	for token != "" {
+		var page []*orderpb.Order
+		page, token, err = svc.ListOrders(ctx, &orderpb.ListOrdersRequest{UserId: "user1", PageSize: 2, PageToken: token})
+		require.NoError(t, err)
+		for _, order := range page {
+			ids = append(ids, order.OrderId)
+		}
+	}
+	require.Equal(t, []string{"e", "d", "c", "b", "a"}, ids)
+
+	_, _, err = svc.ListOrders(ctx, &orderpb.ListOrdersRequest{PageSize: 1000})
+	require.NoError(t, err)
+	require.Equal(t, 101, filters[len(filters)-1].Limit)
+}
+
+// memoryCounters issues sequence numbers like the Mongo counter collection.
+type memoryCounters map[string]int64
+
+func (f memoryCounters) Next(ctx context.Context, name string) (int64, error) {
+	f[name]++
+	return f[name], nil
+}
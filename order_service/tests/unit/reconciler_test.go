@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service-system/common/bus"
+	"order-service-system/common/events"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/workers/reconciler"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stuckOrderRepository struct {
+	orders      map[string]models.Order
+	transitions map[string]string
+}
+
+func (f *stuckOrderRepository) ListStale(_ context.Context, status string, submittedBefore time.Time, limit int) ([]models.Order, error) {
+	var stale []models.Order
+	for _, order := range f.orders {
+		if order.Status == status && order.PendingSince().Before(submittedBefore) && len(stale) < limit {
+			stale = append(stale, order)
+		}
+	}
+	return stale, nil
+}
+
+func (f *stuckOrderRepository) TransitionStatus(_ context.Context, orderID string, from string, to string, reason string) (bool, error) {
+	order := f.orders[orderID]
+	if order.Status != from {
+		return false, nil
+	}
+	order.Status, order.StatusReason = to, reason
+	f.orders[orderID] = order
+	f.transitions[orderID] = to + ": " + reason
+	return true, nil
+}
+
+type reconcileNatsClient struct {
+	outcomes  map[string]string
+	err       error
+	reemitted []string
+	results   []string
+}
+
+func (f *reconcileNatsClient) PublishOrderCreated(_ context.Context, event models.OrderCreatedEvent) error {
+	f.reemitted = append(f.reemitted, event.OrderID)
+	return nil
+}
+
+func (f *reconcileNatsClient) PublishOrderPaid(_ context.Context, event models.OrderPaidEvent) error {
+	f.results = append(f.results, event.OrderID+": paid")
+	return nil
+}
+
+func (f *reconcileNatsClient) PublishOrderFailed(_ context.Context, event models.OrderFailedEvent) error {
+	f.results = append(f.results, event.OrderID+": "+event.Reason)
+	return nil
+}
+
+func (f *reconcileNatsClient) QueryPaymentStatus(_ context.Context, orderID string) (events.PaymentStatusReply, error) {
+	if f.err != nil {
+		return events.PaymentStatusReply{}, f.err
+	}
+	outcome, ok := f.outcomes[orderID]
+	if !ok {
+		outcome = events.PaymentOutcomeUnknown
+	}
+	return events.PaymentStatusReply{OrderID: orderID, Outcome: outcome}, nil
+}
+
+func TestReconciler_ResolvesStuckOrders(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	pending := func(id string, age time.Duration) models.Order {
+		return models.Order{OrderID: id, UserID: "u1", Status: "PENDING", CreatedAt: now.Add(-age)}
+	}
+	repo := &stuckOrderRepository{transitions: map[string]string{}, orders: map[string]models.Order{
+		"fresh":      pending("fresh", time.Minute),
+		"paid":       pending("paid", 10*time.Minute),
+		"declined":   pending("declined", 10*time.Minute),
+		"lost":       pending("lost", 10*time.Minute),
+		"processing": pending("processing", 10*time.Minute),
+		"abandoned":  pending("abandoned", time.Hour),
+		// Запланированный заказ создан давно, но на оплату ушёл недавно.
+		"released":  {OrderID: "released", UserID: "u1", Status: "PENDING", CreatedAt: now.Add(-48 * time.Hour), SubmittedAt: now.Add(-10 * time.Minute)},
+		"scheduled": {OrderID: "scheduled", UserID: "u1", Status: "SCHEDULED", CreatedAt: now.Add(-48 * time.Hour)},
+	}}
+	natsClient := &reconcileNatsClient{outcomes: map[string]string{
+		"paid":       events.PaymentOutcomePaid,
+		"declined":   events.PaymentOutcomeDeclined,
+		"processing": events.PaymentOutcomeProcessing,
+	}}
+
+	worker := reconciler.NewReconciler(reconciler.Deps{
+		Logger:     zap.NewNop(),
+		OrderRepo:  repo,
+		NatsClient: natsClient,
+		Configuration: reconciler.Configuration{
+			PendingAfter: 5 * time.Minute,
+			ExpireAfter:  30 * time.Minute,
+			QueryTimeout: time.Second,
+		},
+		Now: func() time.Time { return now },
+	})
+	require.NoError(t, worker.Reconcile(context.Background()))
+
+	require.Equal(t, map[string]string{
+		"paid":      "PAID: ",
+		"declined":  "FAILED: payment declined",
+		"abandoned": "EXPIRED: payment not confirmed within 30m0s",
+	}, repo.transitions)
+	require.ElementsMatch(t, []string{"lost", "released"}, natsClient.reemitted)
+	// Истечение не является исходом оплаты и не публикуется.
+	require.ElementsMatch(t, []string{"paid: paid", "declined: payment declined"}, natsClient.results)
+	require.Equal(t, "PENDING", repo.orders["processing"].Status)
+}
+
+func TestReconciler_ExpiresWhenBillingIsUnavailable(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := &stuckOrderRepository{transitions: map[string]string{}, orders: map[string]models.Order{
+		"recent": {OrderID: "recent", Status: "PENDING", CreatedAt: now.Add(-10 * time.Minute)},
+		"old":    {OrderID: "old", Status: "PENDING", CreatedAt: now.Add(-time.Hour)},
+	}}
+	natsClient := &reconcileNatsClient{err: bus.ErrNoResponders}
+
+	worker := reconciler.NewReconciler(reconciler.Deps{
+		Logger:        zap.NewNop(),
+		OrderRepo:     repo,
+		NatsClient:    natsClient,
+		Configuration: reconciler.Configuration{PendingAfter: 5 * time.Minute, ExpireAfter: 30 * time.Minute, QueryTimeout: time.Second},
+		Now:           func() time.Time { return now },
+	})
+	require.NoError(t, worker.Reconcile(context.Background()))
+
+	require.Equal(t, map[string]string{"old": "EXPIRED: payment not confirmed within 30m0s: billing unavailable"}, repo.transitions)
+	require.Equal(t, []string{"recent"}, natsClient.reemitted)
+	require.Empty(t, natsClient.results)
+}
+
+func TestUpdateOrderStatus_LatePaymentResultDoesNotReopenOrder(t *testing.T) {
+	orders := map[string]models.Order{
+		"expired":   {OrderID: "expired", Status: orderpb.OrderStatus_EXPIRED.String()},
+		"cancelled": {OrderID: "cancelled", Status: orderpb.OrderStatus_CANCELLED.String()},
+		"failed":    {OrderID: "failed", Status: orderpb.OrderStatus_FAILED.String()},
+		"pending":   {OrderID: "pending", Status: orderpb.OrderStatus_PENDING.String()},
+	}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:     zap.NewNop(),
+		OrderRepo:  newFulfilmentRepository(orders),
+		Counters:   memoryCounters{},
+		NatsClient: &mockNatsClient{},
+	})
+	ctx := context.Background()
+
+	// Заказ, который сверка уже закрыла, не возвращается в PAID/FAILED.
+	for _, orderID := range []string{"expired", "cancelled"} {
+		for _, result := range []orderpb.OrderStatus{orderpb.OrderStatus_PAID, orderpb.OrderStatus_FAILED} {
+			_, err := svc.UpdateOrderStatus(ctx, orderID, result)
+			require.Equal(t, codes.FailedPrecondition, status.Code(err), "%s -> %s", orderID, result)
+		}
+	}
+	_, err := svc.UpdateOrderStatus(ctx, "failed", orderpb.OrderStatus_PAID)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, orderpb.OrderStatus_EXPIRED.String(), orders["expired"].Status)
+	require.Equal(t, orderpb.OrderStatus_CANCELLED.String(), orders["cancelled"].Status)
+	require.Equal(t, orderpb.OrderStatus_FAILED.String(), orders["failed"].Status)
+
+	// Повторный результат оплаты ничего не меняет.
+	paid, err := svc.UpdateOrderStatus(ctx, "pending", orderpb.OrderStatus_PAID)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_PAID, paid.Status)
+	paid, err = svc.UpdateOrderStatus(ctx, "pending", orderpb.OrderStatus_PAID)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_PAID, paid.Status)
+	failed, err := svc.UpdateOrderStatus(ctx, "failed", orderpb.OrderStatus_FAILED)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_FAILED, failed.Status)
+}
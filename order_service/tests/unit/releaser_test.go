@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/workers/releaser"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// scheduledOrderRepository applies the SCHEDULED guard of the Mongo repository.
+type scheduledOrderRepository struct {
+	orders map[string]models.Order
+}
+
+func (f *scheduledOrderRepository) ListScheduled(_ context.Context, dueBefore time.Time, limit int) ([]models.Order, error) {
+	var due []models.Order
+	for _, order := range f.orders {
+		if order.Status == orderpb.OrderStatus_SCHEDULED.String() && !order.ScheduledAt.After(dueBefore) && len(due) < limit {
+			due = append(due, order)
+		}
+	}
+	return due, nil
+}
+
+func (f *scheduledOrderRepository) Release(_ context.Context, orderID string, submittedAt time.Time) (models.Order, error) {
+	order, ok := f.orders[orderID]
+	if !ok {
+		return models.Order{}, pj_errors.ErrNotFound
+	}
+	if order.Status != orderpb.OrderStatus_SCHEDULED.String() {
+		return models.Order{}, pj_errors.ErrConflict
+	}
+	order.Status = orderpb.OrderStatus_PENDING.String()
+	order.SubmittedAt = submittedAt
+	f.orders[orderID] = order
+	return order, nil
+}
+
+func TestCreateOrder_ScheduledAtDefersOrderCreated(t *testing.T) {
+	orders := map[string]models.Order{}
+	repo := newFulfilmentRepository(orders)
+	repo.create = func(ctx context.Context, order models.Order) error {
+		orders[order.OrderID] = order
+		return nil
+	}
+	var published []string
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:    zap.NewNop(),
+		OrderRepo: repo,
+		Counters:  memoryCounters{},
+		NatsClient: &mockNatsClient{publish: func(event models.OrderCreatedEvent) error {
+			published = append(published, event.OrderID)
+			return nil
+		}},
+	})
+	items := []*orderpb.OrderItem{{ProductId: "p1", Quantity: 1, Price: 10}}
+
+	scheduledAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	scheduled, err := svc.CreateOrder(context.Background(), &orderpb.CreateOrderRequest{
+		UserId: "alice", Items: items, ScheduledAt: timestamppb.New(scheduledAt),
+	})
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_SCHEDULED, scheduled.Status)
+	require.True(t, scheduledAt.Equal(scheduled.ScheduledAt.AsTime()))
+	require.Nil(t, scheduled.SubmittedAt)
+	require.Empty(t, published)
+
+	// Время в прошлом не откладывает заказ.
+	immediate, err := svc.CreateOrder(context.Background(), &orderpb.CreateOrderRequest{
+		UserId: "alice", Items: items, ScheduledAt: timestamppb.New(time.Now().Add(-time.Minute)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, orderpb.OrderStatus_PENDING, immediate.Status)
+	require.Equal(t, []string{immediate.OrderId}, published)
+
+	_, err = svc.CreateOrder(context.Background(), &orderpb.CreateOrderRequest{
+		UserId: "alice", Items: items, ScheduledAt: &timestamppb.Timestamp{Nanos: -1},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestReleaser_PublishesDueOrdersOnce(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	scheduled := func(id string, at time.Time) models.Order {
+		return models.Order{OrderID: id, UserID: "u1", Status: orderpb.OrderStatus_SCHEDULED.String(), CreatedAt: now.Add(-72 * time.Hour), ScheduledAt: at}
+	}
+	repo := &scheduledOrderRepository{orders: map[string]models.Order{
+		"due":       scheduled("due", now.Add(-time.Minute)),
+		"on-time":   scheduled("on-time", now),
+		"later":     scheduled("later", now.Add(time.Hour)),
+		"cancelled": {OrderID: "cancelled", Status: orderpb.OrderStatus_CANCELLED.String(), ScheduledAt: now.Add(-time.Hour)},
+	}}
+	var published []string
+	natsClient := &mockNatsClient{publish: func(event models.OrderCreatedEvent) error {
+		published = append(published, event.OrderID)
+		return errors.New("nats down")
+	}}
+
+	due, err := repo.ListScheduled(context.Background(), now, 10)
+	require.NoError(t, err)
+	newReleaser := func(orderRepo releaser.OrderRepository) *releaser.Releaser {
+		return releaser.NewReleaser(releaser.Deps{
+			Logger:     zap.NewNop(),
+			OrderRepo:  orderRepo,
+			NatsClient: natsClient,
+			Now:        func() time.Time { return now },
+		})
+	}
+	require.NoError(t, newReleaser(repo).Release(context.Background()))
+	// Вторая реплика прочитала тот же список до первой, но отправить заказы ей не удаётся.
+	require.NoError(t, newReleaser(&staleListRepository{scheduledOrderRepository: repo, due: due}).Release(context.Background()))
+
+	slices.Sort(published)
+	require.Equal(t, []string{"due", "on-time"}, published)
+	require.Equal(t, orderpb.OrderStatus_PENDING.String(), repo.orders["due"].Status)
+	require.Equal(t, now, repo.orders["due"].SubmittedAt)
+	require.Equal(t, orderpb.OrderStatus_SCHEDULED.String(), repo.orders["later"].Status)
+}
+
+// staleListRepository returns a list read before another replica released the orders.
+type staleListRepository struct {
+	*scheduledOrderRepository
+	due []models.Order
+}
+
+func (f *staleListRepository) ListScheduled(context.Context, time.Time, int) ([]models.Order, error) {
+	return f.due, nil
+}
@@ -0,0 +1,248 @@
+package unit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service-system/common/auth"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/service/return_service"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type memoryReturnRepository struct {
+	mu      sync.Mutex
+	returns []models.Return
+}
+
+func (f *memoryReturnRepository) Create(ctx context.Context, ret models.Return) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, stored := range f.returns {
+		if stored.OrderID == ret.OrderID && stored.Sequence == ret.Sequence {
+			return pj_errors.ErrAlreadyExists
+		}
+	}
+	f.returns = append(f.returns, ret)
+	return nil
+}
+
+func (f *memoryReturnRepository) Get(ctx context.Context, returnID string) (models.Return, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ret := range f.returns {
+		if ret.ReturnID == returnID {
+			return ret, nil
+		}
+	}
+	return models.Return{}, pj_errors.ErrNotFound
+}
+
+func (f *memoryReturnRepository) ListByOrder(ctx context.Context, orderID string) ([]models.Return, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []models.Return
+	for _, ret := range f.returns {
+		if ret.OrderID == orderID {
+			result = append(result, ret)
+		}
+	}
+	return result, nil
+}
+
+func (f *memoryReturnRepository) Transition(ctx context.Context, returnID string, from []string, entry models.ReturnHistoryEntry) (models.Return, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, ret := range f.returns {
+		if ret.ReturnID != returnID {
+			continue
+		}
+		if !slices.Contains(from, ret.Status) {
+			return models.Return{}, pj_errors.ErrConflict
+		}
+		ret.Status = entry.Status
+		ret.UpdatedAt = entry.At
+		ret.History = append(slices.Clone(ret.History), entry)
+		f.returns[i] = ret
+		return ret, nil
+	}
+	return models.Return{}, pj_errors.ErrNotFound
+}
+
+func newReturnService(t *testing.T, orders return_service.OrderReader, publisher *mockNatsClient) *return_service.ReturnService {
+	t.Helper()
+	return return_service.NewReturnService(return_service.Deps{
+		Logger:     zap.NewNop(),
+		ReturnRepo: &memoryReturnRepository{},
+		OrderRepo:  orders,
+		NatsClient: publisher,
+	})
+}
+
+func ordersRepository(orders ...models.Order) *mockOrderRepository {
+	return &mockOrderRepository{
+		get: func(ctx context.Context, orderID string) (models.Order, error) {
+			for _, order := range orders {
+				if order.OrderID == orderID {
+					return order, nil
+				}
+			}
+			return models.Order{}, pj_errors.ErrNotFound
+		},
+	}
+}
+
+func TestReturns_Workflow(t *testing.T) {
+	publisher := &mockNatsClient{}
+	svc := newReturnService(t, ordersRepository(
+		models.Order{OrderID: "o1", UserID: "alice", Status: orderpb.OrderStatus_DELIVERED.String(), Items: []models.OrderItem{
+			{ProductID: "p1", Quantity: 2, Price: 10},
+			{ProductID: "p2", Quantity: 1, Price: 5},
+		}},
+		models.Order{OrderID: "o2", UserID: "alice", Status: orderpb.OrderStatus_SHIPPED.String()},
+	), publisher)
+
+	alice := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Type: auth.PrincipalUser})
+	bob := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "bob", Type: auth.PrincipalUser})
+	support := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "support-service", Type: auth.PrincipalService})
+	items := func(quantity int32) []*orderpb.ReturnItem {
+		return []*orderpb.ReturnItem{{ProductId: "p1", Quantity: quantity}}
+	}
+
+	_, err := svc.RequestReturn(alice, &orderpb.RequestReturnRequest{OrderId: "o2", Items: items(1), Reason: "broken"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = svc.RequestReturn(bob, &orderpb.RequestReturnRequest{OrderId: "o1", Items: items(1), Reason: "broken"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.RequestReturn(alice, &orderpb.RequestReturnRequest{OrderId: "o1", Items: items(3), Reason: "broken"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	ret, err := svc.RequestReturn(alice, &orderpb.RequestReturnRequest{OrderId: "o1", Items: items(2), Reason: "broken"})
+	require.NoError(t, err)
+	require.Equal(t, orderpb.ReturnStatus_RETURN_REQUESTED, ret.Status)
+	require.Equal(t, 20.0, ret.RefundAmount)
+
+	// Все p1 уже в возврате.
+	_, err = svc.RequestReturn(alice, &orderpb.RequestReturnRequest{OrderId: "o1", Items: items(1), Reason: "again"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = svc.MarkReturnReceived(support, ret.ReturnId)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	ret, err = svc.ApproveReturn(support, ret.ReturnId, "ok")
+	require.NoError(t, err)
+	require.Equal(t, orderpb.ReturnStatus_RETURN_APPROVED, ret.Status)
+
+	ret, err = svc.MarkReturnReceived(support, ret.ReturnId)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.ReturnStatus_RETURN_RECEIVED, ret.Status)
+
+	// Повторное подтверждение получения снова просит billing о возврате денег.
+	_, err = svc.MarkReturnReceived(support, ret.ReturnId)
+	require.NoError(t, err)
+	require.Len(t, publisher.returns, 3)
+	require.Equal(t, orderpb.ReturnStatus_RETURN_RECEIVED.String(), publisher.returns[2].Status)
+	require.Equal(t, 20.0, publisher.returns[2].RefundAmount)
+
+	ret, err = svc.RecordRefund(support, ret.ReturnId, true, "")
+	require.NoError(t, err)
+	require.Equal(t, orderpb.ReturnStatus_RETURN_REFUNDED, ret.Status)
+	_, err = svc.RecordRefund(support, ret.ReturnId, true, "")
+	require.NoError(t, err)
+	_, err = svc.RecordRefund(support, ret.ReturnId, false, "declined")
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	ret, err = svc.GetReturn(alice, ret.ReturnId)
+	require.NoError(t, err)
+	var history []orderpb.ReturnStatus
+	for _, entry := range ret.History {
+		history = append(history, entry.Status)
+	}
+	require.Equal(t, []orderpb.ReturnStatus{
+		orderpb.ReturnStatus_RETURN_REQUESTED,
+		orderpb.ReturnStatus_RETURN_APPROVED,
+		orderpb.ReturnStatus_RETURN_RECEIVED,
+		orderpb.ReturnStatus_RETURN_REFUNDED,
+	}, history)
+	require.Equal(t, "user:alice", ret.History[0].Actor)
+	require.Equal(t, "service:support-service", ret.History[1].Actor)
+
+	_, err = svc.GetReturn(bob, ret.ReturnId)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestReturns_RejectedItemsCanBeRequestedAgain(t *testing.T) {
+	publisher := &mockNatsClient{}
+	svc := newReturnService(t, ordersRepository(models.Order{
+		OrderID: "o1", UserID: "alice", Status: orderpb.OrderStatus_DELIVERED.String(), CreatedAt: time.Now(),
+		Items: []models.OrderItem{{ProductID: "p1", Quantity: 1, Price: 10}},
+	}), publisher)
+	ctx := context.Background()
+	req := &orderpb.RequestReturnRequest{OrderId: "o1", Items: []*orderpb.ReturnItem{{ProductId: "p1", Quantity: 1}}, Reason: "wrong size"}
+
+	ret, err := svc.RequestReturn(ctx, req)
+	require.NoError(t, err)
+
+	_, err = svc.RejectReturn(ctx, ret.ReturnId, "")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = svc.RejectReturn(ctx, ret.ReturnId, "used item")
+	require.NoError(t, err)
+	require.Equal(t, "used item", publisher.returns[0].History[1].Comment)
+
+	_, err = svc.RequestReturn(ctx, req)
+	require.NoError(t, err)
+
+	returns, err := svc.ListReturns(ctx, "o1")
+	require.NoError(t, err)
+	require.Len(t, returns, 2)
+}
+
+// racingReturnRepository lets both requests read the returns before either stores its own.
+type racingReturnRepository struct {
+	*memoryReturnRepository
+	listed sync.WaitGroup
+}
+
+func (f *racingReturnRepository) ListByOrder(ctx context.Context, orderID string) ([]models.Return, error) {
+	returns, err := f.memoryReturnRepository.ListByOrder(ctx, orderID)
+	f.listed.Done()
+	f.listed.Wait()
+	return returns, err
+}
+
+func TestReturns_ConcurrentRequestsDoNotOverReturn(t *testing.T) {
+	repo := &racingReturnRepository{memoryReturnRepository: &memoryReturnRepository{}}
+	repo.listed.Add(2)
+	svc := return_service.NewReturnService(return_service.Deps{
+		Logger:     zap.NewNop(),
+		ReturnRepo: repo,
+		OrderRepo: ordersRepository(models.Order{
+			OrderID: "o1", UserID: "alice", Status: orderpb.OrderStatus_DELIVERED.String(),
+			Items: []models.OrderItem{{ProductID: "p1", Quantity: 2, Price: 10}},
+		}),
+		NatsClient: &mockNatsClient{},
+	})
+	req := &orderpb.RequestReturnRequest{OrderId: "o1", Items: []*orderpb.ReturnItem{{ProductId: "p1", Quantity: 2}}, Reason: "broken"}
+
+	codesCh := make(chan codes.Code, 2)
+	for range 2 {
+		go func() {
+			_, err := svc.RequestReturn(context.Background(), req)
+			codesCh <- status.Code(err)
+		}()
+	}
+	got := []codes.Code{<-codesCh, <-codesCh}
+	require.ElementsMatch(t, []codes.Code{codes.OK, codes.Aborted}, got)
+
+	returns, err := repo.memoryReturnRepository.ListByOrder(context.Background(), "o1")
+	require.NoError(t, err)
+	require.Len(t, returns, 1)
+}
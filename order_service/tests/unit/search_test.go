@@ -0,0 +1,265 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service-system/common/auth"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/service/search_service"
+	orderpb "order-service-system/proto/order"
+	"order-service-system/sdk/ordersdk"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memorySearchBackend matches orders like the Mongo backend, with the text query
+// matching whole words of numbers, product ids and emails.
+type memorySearchBackend struct {
+	orders  []models.Order
+	queries []models.OrderSearchQuery
+}
+
+func (f *memorySearchBackend) Search(ctx context.Context, query models.OrderSearchQuery) (models.OrderSearchResult, error) {
+	f.queries = append(f.queries, query)
+	result := models.OrderSearchResult{StatusCounts: map[string]int64{}}
+	var hits []models.OrderSearchHit
+	for _, order := range f.orders {
+		if !searchMatches(order, query) {
+			continue
+		}
+		result.StatusCounts[order.Status]++
+		if query.Status != "" && order.Status != query.Status {
+			continue
+		}
+		hits = append(hits, models.OrderSearchHit{Order: order, Highlights: query.Highlight(order)})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Order.CreatedAt.After(hits[j].Order.CreatedAt) })
+	result.Total = int64(len(hits))
+	if query.Offset < len(hits) {
+		hits = hits[query.Offset:]
+	} else {
+		hits = nil
+	}
+	if len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	result.Hits = hits
+	return result, nil
+}
+
+func searchMatches(order models.Order, query models.OrderSearchQuery) bool {
+	if query.Number != "" && !strings.Contains(order.Number, strings.ToUpper(query.Number)) {
+		return false
+	}
+	if query.Email != "" && order.Email != query.Email {
+		return false
+	}
+	if order.TotalAmount < query.MinAmount || (query.MaxAmount > 0 && order.TotalAmount > query.MaxAmount) {
+		return false
+	}
+	products := map[string]bool{}
+	words := models.TextTerms(order.Number + " " + order.Email)
+	for _, item := range order.Items {
+		products[item.ProductID] = true
+		words = append(words, models.TextTerms(item.ProductID)...)
+	}
+	if query.ProductID != "" && !products[query.ProductID] {
+		return false
+	}
+	if query.Text == "" {
+		return true
+	}
+	for _, term := range models.TextTerms(query.Text) {
+		for _, word := range words {
+			if strings.EqualFold(word, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func searchFixture() []models.Order {
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	return []models.Order{
+		{OrderID: "o1", Number: "ORD-2026-000123", UserID: "alice", Email: "alice@example.com", TotalAmount: 30, Status: "PAID", CreatedAt: created,
+			Items: []models.OrderItem{{ProductID: "sku-1", Quantity: 1, Price: 30}}},
+		{OrderID: "o2", Number: "ORD-2026-000124", UserID: "bob", Email: "bob@example.com", TotalAmount: 120, Status: "PENDING", CreatedAt: created.Add(time.Hour),
+			Items: []models.OrderItem{{ProductID: "sku-1", Quantity: 2, Price: 50}, {ProductID: "sku-2", Quantity: 1, Price: 20}}},
+		{OrderID: "o3", Number: "ORD-2026-000200", UserID: "alice", Email: "alice@example.com", TotalAmount: 15, Status: "PAID", CreatedAt: created.Add(2 * time.Hour),
+			Items: []models.OrderItem{{ProductID: "sku-3", Quantity: 1, Price: 15}}},
+	}
+}
+
+func newSearchService(backend search_service.Backend) *search_service.SearchService {
+	return search_service.NewSearchService(search_service.Deps{Logger: zap.NewNop(), Backend: backend})
+}
+
+func TestSearchOrders_CriteriaHighlightsAndFacets(t *testing.T) {
+	svc := newSearchService(&memorySearchBackend{orders: searchFixture()})
+	ctx := context.Background()
+
+	found, err := svc.SearchOrders(ctx, &orderpb.SearchOrdersRequest{OrderNumber: "ord-2026-0001"})
+	require.NoError(t, err)
+	require.Len(t, found.Hits, 2)
+	require.Equal(t, "o2", found.Hits[0].Order.OrderId)
+	require.Equal(t, []*orderpb.SearchHighlight{{Field: "order_number", Snippet: "<em>ORD-2026-0001</em>24"}}, found.Hits[0].Highlights)
+
+	found, err = svc.SearchOrders(ctx, &orderpb.SearchOrdersRequest{ProductId: "sku-1", MinAmount: 100})
+	require.NoError(t, err)
+	require.Len(t, found.Hits, 1)
+	require.Equal(t, "o2", found.Hits[0].Order.OrderId)
+	require.Equal(t, []*orderpb.SearchHighlight{{Field: "product_id", Snippet: "<em>sku-1</em>"}}, found.Hits[0].Highlights)
+
+	// Фасеты считаются без фильтра по статусу, а выдача и total — с ним.
+	found, err = svc.SearchOrders(ctx, &orderpb.SearchOrdersRequest{Email: " Alice@Example.com ", Status: orderpb.OrderStatus_PAID, MaxAmount: 20})
+	require.NoError(t, err)
+	require.Len(t, found.Hits, 1)
+	require.Equal(t, "o3", found.Hits[0].Order.OrderId)
+	require.Equal(t, "<em>alice@example.com</em>", found.Hits[0].Highlights[0].Snippet)
+
+	found, err = svc.SearchOrders(ctx, &orderpb.SearchOrdersRequest{Query: "000123 bob"})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), found.Total)
+	require.Equal(t, []*orderpb.StatusFacet{
+		{Status: orderpb.OrderStatus_PENDING, Count: 1},
+		{Status: orderpb.OrderStatus_PAID, Count: 1},
+	}, found.StatusFacets)
+	require.Equal(t, []*orderpb.SearchHighlight{{Field: "email", Snippet: "<em>bob</em>@example.com"}}, found.Hits[0].Highlights)
+	require.Equal(t, []*orderpb.SearchHighlight{{Field: "order_number", Snippet: "ORD-2026-<em>000123</em>"}}, found.Hits[1].Highlights)
+
+	found, err = svc.SearchOrders(ctx, &orderpb.SearchOrdersRequest{Query: "000123", Status: orderpb.OrderStatus_PENDING})
+	require.NoError(t, err)
+	require.Empty(t, found.Hits)
+	require.Equal(t, int64(0), found.Total)
+	require.Equal(t, []*orderpb.StatusFacet{{Status: orderpb.OrderStatus_PAID, Count: 1}}, found.StatusFacets)
+}
+
+func TestSearchOrders_Paging(t *testing.T) {
+	backend := &memorySearchBackend{orders: searchFixture()}
+	svc := newSearchService(backend)
+	ctx := context.Background()
+
+	first, err := svc.SearchOrders(ctx, &orderpb.SearchOrdersRequest{Email: "alice@example.com", PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, first.Hits, 1)
+	require.Equal(t, "o3", first.Hits[0].Order.OrderId)
+	require.NotEmpty(t, first.NextPageToken)
+
+	second, err := svc.SearchOrders(ctx, &orderpb.SearchOrdersRequest{Email: "alice@example.com", PageSize: 1, PageToken: first.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, second.Hits, 1)
+	require.Equal(t, "o1", second.Hits[0].Order.OrderId)
+	require.Empty(t, second.NextPageToken)
+	require.Equal(t, models.OrderSearchQuery{Email: "alice@example.com", Offset: 1, Limit: 2}, backend.queries[1])
+}
+
+func TestSearchOrders_Validation(t *testing.T) {
+	svc := newSearchService(&memorySearchBackend{})
+	ctx := context.Background()
+
+	for _, req := range []*orderpb.SearchOrdersRequest{
+		{},
+		{Status: orderpb.OrderStatus_PAID},
+		{Query: "--"},
+		{Query: strings.Repeat("a", 201)},
+		{MinAmount: -1},
+		{MinAmount: 50, MaxAmount: 10},
+		{ProductId: "sku-1", PageSize: -1},
+		{ProductId: "sku-1", PageToken: "not-a-token"},
+	} {
+		_, err := svc.SearchOrders(ctx, req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+}
+
+type slowSearchBackend struct{}
+
+func (slowSearchBackend) Search(ctx context.Context, query models.OrderSearchQuery) (models.OrderSearchResult, error) {
+	return models.OrderSearchResult{}, fmt.Errorf("%w: operation exceeded time limit", context.DeadlineExceeded)
+}
+
+func TestSearchOrders_TooSlowSearchAsksToNarrow(t *testing.T) {
+	_, err := newSearchService(slowSearchBackend{}).SearchOrders(context.Background(), &orderpb.SearchOrdersRequest{OrderNumber: "1"})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestCreateOrder_StoresContactEmail(t *testing.T) {
+	var stored []models.Order
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:   zap.NewNop(),
+		Counters: memoryCounters{},
+		OrderRepo: &mockOrderRepository{create: func(ctx context.Context, order models.Order) error {
+			stored = append(stored, order)
+			return nil
+		}},
+		NatsClient: &mockNatsClient{publish: func(models.OrderCreatedEvent) error { return nil }},
+	})
+	alice := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Type: auth.PrincipalUser, Email: "alice@example.com"})
+	items := []*orderpb.OrderItem{{ProductId: "p1", Quantity: 1, Price: 10}}
+
+	created, err := svc.CreateOrder(alice, &orderpb.CreateOrderRequest{Items: items, Email: " Alice.Work@Example.com "})
+	require.NoError(t, err)
+	require.Equal(t, "alice.work@example.com", created.Email)
+
+	created, err = svc.CreateOrder(alice, &orderpb.CreateOrderRequest{Items: items})
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com", created.Email)
+	require.Equal(t, "alice@example.com", stored[1].Email)
+
+	_, err = svc.CreateOrder(alice, &orderpb.CreateOrderRequest{Items: items, Email: "Alice <alice@example.com>"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSearchOrders_RestrictedToSupportServices(t *testing.T) {
+	addr := startAuthServer(t)
+	ctx := context.Background()
+	req := &orderpb.SearchOrdersRequest{ProductId: "sku-1"}
+
+	alice := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "alice", auth.PrincipalUser)))
+	_, err := alice.SearchOrders(ctx, req)
+	require.True(t, errors.Is(err, ordersdk.ErrPermissionDenied))
+
+	support := clientAs(t, addr, ordersdk.WithToken(tokenFor(t, "support-service", auth.PrincipalService)))
+	found, err := support.SearchOrders(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, found.GetHits(), 2)
+}
+
+func TestGateway_SearchOrders(t *testing.T) {
+	httpServer := startGateway(t, &mockOrderRepository{})
+
+	resp, err := http.Get(httpServer.URL + "/v1/orders:search?orderNumber=000200&status=PAID")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		Hits []struct {
+			Order struct {
+				OrderID string `json:"orderId"`
+			} `json:"order"`
+			Highlights []struct {
+				Field   string `json:"field"`
+				Snippet string `json:"snippet"`
+			} `json:"highlights"`
+		} `json:"hits"`
+		Total string `json:"total"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	_ = resp.Body.Close()
+	require.Len(t, body.Hits, 1)
+	require.Equal(t, "o3", body.Hits[0].Order.OrderID)
+	require.Equal(t, "ORD-2026-<em>000200</em>", body.Hits[0].Highlights[0].Snippet)
+	require.Equal(t, "1", body.Total)
+}
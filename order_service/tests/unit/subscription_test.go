@@ -0,0 +1,241 @@
+package unit
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/service/order_service"
+	"order-service-system/order_service/internal/service/subscription_service"
+	"order-service-system/order_service/internal/workers/scheduler"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// memorySubscriptionRepository applies the version guard and the filters of the Mongo repository.
+type memorySubscriptionRepository struct {
+	subscriptions map[string]models.Subscription
+}
+
+func (f *memorySubscriptionRepository) Create(ctx context.Context, subscription models.Subscription) error {
+	f.subscriptions[subscription.SubscriptionID] = subscription
+	return nil
+}
+
+func (f *memorySubscriptionRepository) Get(ctx context.Context, subscriptionID string) (models.Subscription, error) {
+	doc, ok := f.subscriptions[subscriptionID]
+	if !ok {
+		return models.Subscription{}, pj_errors.ErrNotFound
+	}
+	doc.Runs = slices.Clone(doc.Runs)
+	return doc, nil
+}
+
+func (f *memorySubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]models.Subscription, error) {
+	return f.filter(func(doc models.Subscription) bool { return doc.UserID == userID }), nil
+}
+
+func (f *memorySubscriptionRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	return f.filter(func(doc models.Subscription) bool {
+		return slices.Contains(models.SchedulableSubscriptionStatuses, doc.Status) && doc.PendingOrderID == "" && !doc.NextRunAt.After(now)
+	}), nil
+}
+
+func (f *memorySubscriptionRepository) ListAwaitingPayment(ctx context.Context, limit int) ([]models.Subscription, error) {
+	return f.filter(func(doc models.Subscription) bool { return doc.PendingOrderID != "" }), nil
+}
+
+func (f *memorySubscriptionRepository) Replace(ctx context.Context, subscription models.Subscription, expectedVersion int64) (models.Subscription, error) {
+	doc, ok := f.subscriptions[subscription.SubscriptionID]
+	if !ok {
+		return models.Subscription{}, pj_errors.ErrNotFound
+	}
+	if doc.Version != expectedVersion {
+		return models.Subscription{}, pj_errors.ErrConflict
+	}
+	subscription.Version = expectedVersion + 1
+	f.subscriptions[subscription.SubscriptionID] = subscription
+	return subscription, nil
+}
+
+func (f *memorySubscriptionRepository) filter(match func(models.Subscription) bool) []models.Subscription {
+	var docs []models.Subscription
+	for id := range f.subscriptions {
+		if doc, _ := f.Get(context.Background(), id); match(doc) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+type subscriptionFixture struct {
+	svc       *subscription_service.SubscriptionService
+	scheduler *scheduler.Scheduler
+	repo      *memorySubscriptionRepository
+	orders    map[string]models.Order
+	now       time.Time
+}
+
+func newSubscriptionFixture(t *testing.T, retries ...time.Duration) *subscriptionFixture {
+	t.Helper()
+	f := &subscriptionFixture{
+		repo:   &memorySubscriptionRepository{subscriptions: map[string]models.Subscription{}},
+		orders: map[string]models.Order{},
+		now:    time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC),
+	}
+	now := func() time.Time { return f.now }
+
+	orderRepo := newFulfilmentRepository(f.orders)
+	orderRepo.create = func(ctx context.Context, order models.Order) error {
+		if _, ok := f.orders[order.OrderID]; ok {
+			return pj_errors.ErrAlreadyExists
+		}
+		f.orders[order.OrderID] = order
+		return nil
+	}
+	orderService := order_service.NewOrderService(order_service.Deps{
+		Logger:     zap.NewNop(),
+		OrderRepo:  orderRepo,
+		Counters:   memoryCounters{},
+		NatsClient: &mockNatsClient{publish: func(models.OrderCreatedEvent) error { return nil }},
+	})
+
+	f.svc = subscription_service.NewSubscriptionService(subscription_service.Deps{
+		Logger:           zap.NewNop(),
+		SubscriptionRepo: f.repo,
+		Now:              now,
+	})
+	f.scheduler = scheduler.NewScheduler(scheduler.Deps{
+		Logger:           zap.NewNop(),
+		SubscriptionRepo: f.repo,
+		Orders:           orderService,
+		OrderRepo:        orderRepo,
+		Configuration:    scheduler.Configuration{DunningRetries: retries},
+		Now:              now,
+	})
+	return f
+}
+
+func (f *subscriptionFixture) run(t *testing.T, subscriptionID string) models.Subscription {
+	t.Helper()
+	require.NoError(t, f.scheduler.Run(context.Background()))
+	return f.repo.subscriptions[subscriptionID]
+}
+
+func (f *subscriptionFixture) setOrderStatus(orderID string, status orderpb.OrderStatus) {
+	order := f.orders[orderID]
+	order.Status = status.String()
+	f.orders[orderID] = order
+}
+
+func (f *subscriptionFixture) create(t *testing.T) *orderpb.Subscription {
+	t.Helper()
+	subscription, err := f.svc.CreateSubscription(context.Background(), &orderpb.CreateSubscriptionRequest{
+		UserId: "alice",
+		Items:  []*orderpb.OrderItem{{ProductId: "coffee", Quantity: 2, Price: 12.5}},
+		Period: orderpb.SubscriptionPeriod_MONTHLY,
+		EndsAt: timestamppb.New(f.now.AddDate(0, 2, -1)),
+	})
+	require.NoError(t, err)
+	return subscription
+}
+
+func TestSubscription_DunningRetriesThenNextPeriod(t *testing.T) {
+	f := newSubscriptionFixture(t, 24*time.Hour, 72*time.Hour)
+	start := f.now
+	created := f.create(t)
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_ACTIVE, created.Status)
+
+	doc := f.run(t, created.SubscriptionId)
+	first := doc.PendingOrderID
+	require.NotEmpty(t, first)
+	require.Equal(t, created.SubscriptionId, f.orders[first].SubscriptionID)
+	require.Equal(t, 25.0, f.orders[first].TotalAmount)
+
+	// Пока заказ не оплачен, новый не создаётся.
+	doc = f.run(t, created.SubscriptionId)
+	require.Equal(t, first, doc.PendingOrderID)
+	require.Len(t, f.orders, 1)
+
+	f.setOrderStatus(first, orderpb.OrderStatus_FAILED)
+	doc = f.run(t, created.SubscriptionId)
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_PAST_DUE.String(), doc.Status)
+	require.Equal(t, int32(1), doc.FailedAttempts)
+	require.Empty(t, doc.PendingOrderID)
+	require.Equal(t, start.Add(24*time.Hour), doc.NextRunAt)
+	require.Equal(t, orderpb.OrderStatus_FAILED.String(), doc.Runs[0].OrderStatus)
+
+	f.now = f.now.Add(24 * time.Hour)
+	doc = f.run(t, created.SubscriptionId)
+	retry := doc.PendingOrderID
+	require.NotEqual(t, first, retry)
+	require.Equal(t, int32(1), doc.Runs[1].Attempt)
+
+	f.setOrderStatus(retry, orderpb.OrderStatus_PAID)
+	doc = f.run(t, created.SubscriptionId)
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_ACTIVE.String(), doc.Status)
+	require.Equal(t, int64(1), doc.CurrentPeriod)
+	require.Zero(t, doc.FailedAttempts)
+	require.Equal(t, time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC), doc.NextRunAt)
+
+	f.now = doc.NextRunAt
+	doc = f.run(t, created.SubscriptionId)
+	f.setOrderStatus(doc.PendingOrderID, orderpb.OrderStatus_PAID)
+	doc = f.run(t, created.SubscriptionId)
+	require.Equal(t, int64(2), doc.CurrentPeriod)
+	// Третий период начинается после ends_at.
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_COMPLETED.String(), doc.Status)
+
+	f.now = doc.NextRunAt
+	f.run(t, created.SubscriptionId)
+	require.Len(t, f.orders, 3)
+}
+
+func TestSubscription_ExhaustedDunningPausesUntilResumed(t *testing.T) {
+	f := newSubscriptionFixture(t, time.Hour)
+	ctx := context.Background()
+	created := f.create(t)
+
+	doc := f.run(t, created.SubscriptionId)
+	f.setOrderStatus(doc.PendingOrderID, orderpb.OrderStatus_FAILED)
+	doc = f.run(t, created.SubscriptionId)
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_PAST_DUE.String(), doc.Status)
+
+	f.now = f.now.Add(time.Hour)
+	doc = f.run(t, created.SubscriptionId)
+	f.setOrderStatus(doc.PendingOrderID, orderpb.OrderStatus_EXPIRED)
+	doc = f.run(t, created.SubscriptionId)
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_PAUSED.String(), doc.Status)
+	require.Contains(t, doc.StatusReason, "payment failed 2 times")
+
+	f.now = f.now.Add(time.Hour)
+	doc = f.run(t, created.SubscriptionId)
+	require.Empty(t, doc.PendingOrderID)
+	require.Len(t, f.orders, 2)
+
+	resumed, err := f.svc.ResumeSubscription(ctx, created.SubscriptionId)
+	require.NoError(t, err)
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_ACTIVE, resumed.Status)
+	require.Zero(t, resumed.FailedAttempts)
+
+	doc = f.run(t, created.SubscriptionId)
+	require.NotEmpty(t, doc.PendingOrderID)
+	require.Equal(t, orderpb.OrderStatus_PENDING.String(), f.orders[doc.PendingOrderID].Status)
+	require.Len(t, f.orders, 3)
+
+	_, err = f.svc.PauseSubscription(ctx, created.SubscriptionId, "vacation")
+	require.NoError(t, err)
+	cancelled, err := f.svc.CancelSubscription(ctx, created.SubscriptionId, "")
+	require.NoError(t, err)
+	require.Equal(t, orderpb.SubscriptionStatus_SUBSCRIPTION_CANCELLED, cancelled.Status)
+	_, err = f.svc.ResumeSubscription(ctx, created.SubscriptionId)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
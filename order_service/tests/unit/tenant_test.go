@@ -0,0 +1,122 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"order-service-system/common/tenant"
+	"order-service-system/order_service/internal/models"
+	"order-service-system/order_service/internal/pj_errors"
+	"order-service-system/order_service/internal/repository/order_repository"
+	"order-service-system/order_service/internal/service/order_service"
+	orderpb "order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateOrder_StampsTenantAndCurrency(t *testing.T) {
+	var stored []models.Order
+	var published []models.OrderCreatedEvent
+	counters := memoryCounters{}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:   zap.NewNop(),
+		Counters: counters,
+		OrderRepo: &mockOrderRepository{create: func(ctx context.Context, order models.Order) error {
+			stored = append(stored, order)
+			return nil
+		}},
+		NatsClient: &mockNatsClient{publish: func(event models.OrderCreatedEvent) error {
+			published = append(published, event)
+			return nil
+		}},
+		Tenants: tenant.NewSettings(order_service.TenantSettings{}, map[string]order_service.TenantSettings{
+			"shop-a": {Currencies: []string{"EUR", "USD"}},
+		}),
+	})
+	shopA := tenant.WithTenant(context.Background(), "shop-a")
+	items := []*orderpb.OrderItem{{ProductId: "p1", Quantity: 1, Price: 10}}
+
+	first, err := svc.CreateOrder(shopA, &orderpb.CreateOrderRequest{UserId: "u1", Items: items})
+	require.NoError(t, err)
+	require.Equal(t, "EUR", first.Currency)
+
+	second, err := svc.CreateOrder(shopA, &orderpb.CreateOrderRequest{UserId: "u1", Items: items, Currency: "usd"})
+	require.NoError(t, err)
+	require.Equal(t, "USD", second.Currency)
+
+	_, err = svc.CreateOrder(shopA, &orderpb.CreateOrderRequest{UserId: "u1", Items: items, Currency: "GBP"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	legacy, err := svc.CreateOrder(context.Background(), &orderpb.CreateOrderRequest{UserId: "u1", Items: items, Currency: "gbp"})
+	require.NoError(t, err)
+	require.Equal(t, "GBP", legacy.Currency)
+
+	require.Len(t, stored, 3)
+	require.Equal(t, []string{"shop-a", "shop-a", tenant.Default},
+		[]string{stored[0].TenantID, stored[1].TenantID, stored[2].TenantID})
+	require.Len(t, published, 3)
+	require.Equal(t, "shop-a", published[0].TenantID)
+	require.Equal(t, "EUR", published[0].Currency)
+
+	// Номера заказов у каждой витрины свои, витрина по умолчанию продолжает прежний счётчик.
+	year := time.Now().UTC().Year()
+	require.Equal(t, fmt.Sprintf("ORD-%d-000001", year), first.OrderNumber)
+	require.Equal(t, fmt.Sprintf("ORD-%d-000001", year), legacy.OrderNumber)
+	require.Equal(t, int64(2), counters[fmt.Sprintf("order_number:shop-a:%d", year)])
+	require.Equal(t, int64(1), counters[fmt.Sprintf("order_number:%d", year)])
+}
+
+func TestCreateOrder_SameNumberInTwoTenants(t *testing.T) {
+	// Заказы хранятся с уникальным индексом (tenant_id, number), как в Mongo.
+	numbers := map[[2]string]bool{}
+	svc := order_service.NewOrderService(order_service.Deps{
+		Logger:   zap.NewNop(),
+		Counters: memoryCounters{},
+		OrderRepo: &mockOrderRepository{create: func(ctx context.Context, order models.Order) error {
+			key := [2]string{order.TenantID, order.Number}
+			if numbers[key] {
+				return pj_errors.ErrAlreadyExists
+			}
+			numbers[key] = true
+			return nil
+		}},
+		NatsClient: &mockNatsClient{publish: func(event models.OrderCreatedEvent) error { return nil }},
+	})
+	items := []*orderpb.OrderItem{{ProductId: "p1", Quantity: 1, Price: 10}}
+
+	legacy, err := svc.CreateOrder(context.Background(), &orderpb.CreateOrderRequest{UserId: "u1", Items: items})
+	require.NoError(t, err)
+	shopA, err := svc.CreateOrder(tenant.WithTenant(context.Background(), "shop-a"), &orderpb.CreateOrderRequest{UserId: "u1", Items: items})
+	require.NoError(t, err)
+	require.Equal(t, legacy.OrderNumber, shopA.OrderNumber)
+}
+
+func TestIsGlobalNumberIndex(t *testing.T) {
+	unique, notUnique := true, false
+	keys := func(doc bson.D) bson.Raw {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		return raw
+	}
+
+	// Индекс до появления витрин удаляется, остальные индексы по номеру остаются.
+	require.True(t, order_repository.IsGlobalNumberIndex(mongo.IndexSpecification{
+		Name: "number_1", KeysDocument: keys(bson.D{{Key: "number", Value: 1}}), Unique: &unique,
+	}))
+	require.False(t, order_repository.IsGlobalNumberIndex(mongo.IndexSpecification{
+		Name: "tenant_id_1_number_1", KeysDocument: keys(bson.D{{Key: "tenant_id", Value: 1}, {Key: "number", Value: 1}}), Unique: &unique,
+	}))
+	require.False(t, order_repository.IsGlobalNumberIndex(mongo.IndexSpecification{
+		Name: "number_1", KeysDocument: keys(bson.D{{Key: "number", Value: 1}}), Unique: &notUnique,
+	}))
+	require.False(t, order_repository.IsGlobalNumberIndex(mongo.IndexSpecification{
+		Name: "order_id_1", KeysDocument: keys(bson.D{{Key: "order_id", Value: 1}}), Unique: &unique,
+	}))
+}
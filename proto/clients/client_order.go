@@ -2,58 +2,103 @@ package clients
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"order-service-system/common/metrics"
+	"order-service-system/common/tenant"
 	"order-service-system/proto/order"
+	"order-service-system/sdk/ordersdk"
+	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+type OrderClientConfig struct {
+	CallTimeout     time.Duration `env:"ORDER_CLIENT_CALL_TIMEOUT" envDefault:"3s"`
+	MaxAttempts     int           `env:"ORDER_CLIENT_MAX_ATTEMPTS" envDefault:"3"`
+	BackoffBase     time.Duration `env:"ORDER_CLIENT_BACKOFF_BASE" envDefault:"100ms"`
+	BackoffMax      time.Duration `env:"ORDER_CLIENT_BACKOFF_MAX" envDefault:"2s"`
+	BreakerFailures int           `env:"ORDER_CLIENT_BREAKER_FAILURES" envDefault:"5"`
+	BreakerCooldown time.Duration `env:"ORDER_CLIENT_BREAKER_COOLDOWN" envDefault:"10s"`
+}
+
 type OrderClientDeps struct {
-	Logger           *zap.Logger
+	Logger *zap.Logger
+	// OrderServiceHost is a comma separated list of order_service addresses.
 	OrderServiceHost string
+	Config           OrderClientConfig
+	// TokenSource issues the service token; nil sends calls without a token.
+	TokenSource ordersdk.TokenSource
+	// TLS is nil for plaintext connections.
+	TLS *tls.Config
 }
 
+// OrderClient is the order_service client of billing and notification. It is a
+// thin wrapper over ordersdk that adds metrics, tracing and logging.
 type OrderClient struct {
-	logger           *zap.Logger
-	orderServiceHost string
+	logger *zap.Logger
+	client *ordersdk.Client
 }
 
-func NewOrderClient(deps OrderClientDeps) *OrderClient {
+// ErrCircuitOpen is returned without calling the server while the breaker is open.
+var ErrCircuitOpen = ordersdk.ErrCircuitOpen
+
+func NewOrderClient(deps OrderClientDeps) (*OrderClient, error) {
 	if deps.Logger == nil {
-		panic("logger must not be nil")
+		return nil, errors.New("logger must not be nil on <NewOrderClient>")
 	}
-	if deps.OrderServiceHost == "" {
-		panic("orderServiceHost must not be empty")
+
+	cfg := deps.Config
+	options := []ordersdk.Option{
+		ordersdk.WithTimeout(cfg.CallTimeout),
+		ordersdk.WithRetry(cfg.MaxAttempts, cfg.BackoffBase, cfg.BackoffMax),
+		ordersdk.WithCircuitBreaker(max(cfg.BreakerFailures, 1), cfg.BreakerCooldown),
+		ordersdk.WithUnaryInterceptor(metrics.UnaryClientInterceptor()),
+		// Вызов идёт от имени витрины обрабатываемого события.
+		ordersdk.WithUnaryInterceptor(tenant.UnaryClientInterceptor()),
+		ordersdk.WithDialOptions(grpc.WithStatsHandler(otelgrpc.NewClientHandler())),
 	}
-	return &OrderClient{
-		logger:           deps.Logger,
-		orderServiceHost: deps.OrderServiceHost,
+	if deps.TokenSource != nil {
+		options = append(options, ordersdk.WithTokenSource(deps.TokenSource))
+	}
+	if deps.TLS != nil {
+		options = append(options, ordersdk.WithTLS(deps.TLS))
+	}
+
+	client, err := ordersdk.New(deps.OrderServiceHost, options...)
+	if err != nil {
+		return nil, fmt.Errorf("create order service client: %w", err)
 	}
+
+	return &OrderClient{
+		logger: deps.Logger,
+		client: client,
+	}, nil
 }
 
 func (receiver *OrderClient) UpdateOrderStatus(ctx context.Context, orderID string, status order.OrderStatus) (*order.UpdateOrderStatusResponse, error) {
-	connection, err := grpc.Dial(receiver.orderServiceHost, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	updated, err := receiver.client.UpdateOrderStatus(ctx, orderID, status)
 	if err != nil {
-		receiver.logger.Error("failed to connect on <UpdateOrderStatus> of <OrderClient>", zap.Error(err), zap.String("order host", receiver.orderServiceHost))
+		receiver.logger.Error("failed Update Order Status on <UpdateOrderStatus> of <OrderClient>", zap.Error(err))
 		return nil, err
 	}
-	defer func() {
-		if closeErr := connection.Close(); closeErr != nil {
-			receiver.logger.Error("failed to close connection on <UpdateOrderStatus> of <OrderClient>", zap.Error(closeErr))
-		}
-	}()
-
-	client := order.NewOrderServiceClient(connection)
-
-	response, err := client.UpdateOrderStatus(ctx, &order.UpdateOrderStatusRequest{
-		OrderId: orderID,
-		Status:  status,
-	})
+
+	return &order.UpdateOrderStatusResponse{Order: updated}, nil
+}
+
+func (receiver *OrderClient) RecordRefund(ctx context.Context, returnID string, refunded bool, reason string) (*order.RecordRefundResponse, error) {
+	ret, err := receiver.client.RecordRefund(ctx, returnID, refunded, reason)
 	if err != nil {
-		receiver.logger.Error("failed Update Order Status on <UpdateOrderStatus> of <OrderClient>", zap.Error(err))
+		receiver.logger.Error("failed Record Refund on <RecordRefund> of <OrderClient>", zap.Error(err))
 		return nil, err
 	}
 
-	return response, nil
+	return &order.RecordRefundResponse{Return: ret}, nil
+}
+
+func (receiver *OrderClient) Close(_ context.Context) error {
+	return receiver.client.Close()
 }
@@ -0,0 +1,7 @@
+// Package openapi embeds the OpenAPI document generated from the HTTP annotations of order.proto.
+package openapi
+
+import _ "embed"
+
+//go:embed order.swagger.json
+var OrderService []byte
@@ -0,0 +1,135 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"order-service-system/proto/clients"
+	"order-service-system/proto/order"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeOrderServer struct {
+	order.UnimplementedOrderServiceServer
+	calls   atomic.Int32
+	respond func(call int32) error
+}
+
+func (f *fakeOrderServer) UpdateOrderStatus(_ context.Context, req *order.UpdateOrderStatusRequest) (*order.UpdateOrderStatusResponse, error) {
+	call := f.calls.Add(1)
+	if f.respond != nil {
+		if err := f.respond(call); err != nil {
+			return nil, err
+		}
+	}
+	return &order.UpdateOrderStatusResponse{Order: &order.Order{OrderId: req.OrderId, Status: req.Status}}, nil
+}
+
+func startOrderServer(t *testing.T, impl *fakeOrderServer) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	order.RegisterOrderServiceServer(server, impl)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func newOrderClient(t *testing.T, hosts string, cfg clients.OrderClientConfig) *clients.OrderClient {
+	t.Helper()
+
+	client, err := clients.NewOrderClient(clients.OrderClientDeps{
+		Logger:           zap.NewNop(),
+		OrderServiceHost: hosts,
+		Config:           cfg,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+	return client
+}
+
+func testConfig() clients.OrderClientConfig {
+	return clients.OrderClientConfig{
+		CallTimeout:     time.Second,
+		MaxAttempts:     3,
+		BackoffBase:     time.Millisecond,
+		BackoffMax:      5 * time.Millisecond,
+		BreakerFailures: 5,
+		BreakerCooldown: time.Minute,
+	}
+}
+
+func TestOrderClient_RoundRobin(t *testing.T) {
+	first, second := &fakeOrderServer{}, &fakeOrderServer{}
+	hosts := startOrderServer(t, first) + ", " + startOrderServer(t, second)
+	client := newOrderClient(t, hosts, testConfig())
+
+	require.Eventually(t, func() bool {
+		_, err := client.UpdateOrderStatus(context.Background(), "order-1", order.OrderStatus_PAID)
+		require.NoError(t, err)
+		return first.calls.Load() > 0 && second.calls.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestOrderClient_RetriesTransientErrors(t *testing.T) {
+	server := &fakeOrderServer{respond: func(call int32) error {
+		if call < 3 {
+			return status.Error(codes.Unavailable, "restarting")
+		}
+		return nil
+	}}
+	client := newOrderClient(t, startOrderServer(t, server), testConfig())
+
+	response, err := client.UpdateOrderStatus(context.Background(), "order-1", order.OrderStatus_PAID)
+	require.NoError(t, err)
+	require.Equal(t, "order-1", response.GetOrder().GetOrderId())
+	require.Equal(t, int32(3), server.calls.Load())
+}
+
+func TestOrderClient_DoesNotRetryBusinessErrors(t *testing.T) {
+	server := &fakeOrderServer{respond: func(int32) error {
+		return status.Error(codes.NotFound, "order not found")
+	}}
+	client := newOrderClient(t, startOrderServer(t, server), testConfig())
+
+	_, err := client.UpdateOrderStatus(context.Background(), "missing", order.OrderStatus_PAID)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, int32(1), server.calls.Load())
+}
+
+func TestOrderClient_CircuitBreakerOpens(t *testing.T) {
+	server := &fakeOrderServer{respond: func(int32) error {
+		return status.Error(codes.Unavailable, "down")
+	}}
+	cfg := testConfig()
+	cfg.MaxAttempts = 1
+	cfg.BreakerFailures = 2
+	client := newOrderClient(t, startOrderServer(t, server), cfg)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.UpdateOrderStatus(context.Background(), "order-1", order.OrderStatus_PAID)
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	}
+
+	_, err := client.UpdateOrderStatus(context.Background(), "order-1", order.OrderStatus_PAID)
+	require.True(t, errors.Is(err, clients.ErrCircuitOpen))
+	require.Equal(t, int32(2), server.calls.Load())
+}
+
+func TestOrderClient_RequiresHost(t *testing.T) {
+	_, err := clients.NewOrderClient(clients.OrderClientDeps{Logger: zap.NewNop(), OrderServiceHost: " , "})
+	require.Error(t, err)
+}
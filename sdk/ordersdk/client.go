@@ -0,0 +1,339 @@
+// Package ordersdk is the Go client for OrderService.
+//
+//	client, err := ordersdk.New("orders:50051", ordersdk.WithToken(token))
+//	order, err := client.GetOrder(ctx, id)
+//	if errors.Is(err, ordersdk.ErrNotFound) { ... }
+//
+// Package ordersdktest provides an in-process fake server for unit tests.
+package ordersdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"order-service-system/proto/order"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const roundRobinServiceConfig = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+type Client struct {
+	connection *grpc.ClientConn
+	rpc        order.OrderServiceClient
+	analytics  order.AnalyticsServiceClient
+}
+
+// New creates a client with a long-lived connection. target is an address or a
+// comma separated list of addresses balanced round-robin.
+func New(target string, opts ...Option) (*Client, error) {
+	addresses := splitAddresses(target)
+	if len(addresses) == 0 {
+		return nil, errors.New("ordersdk: target must not be empty")
+	}
+
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxAttempts < 1 {
+		o.maxAttempts = 1
+	}
+
+	builder := manual.NewBuilderWithScheme("ordersdk")
+	builder.InitialState(resolver.State{Addresses: addresses})
+
+	transport := insecure.NewCredentials()
+	if o.tlsConfig != nil {
+		transport = credentials.NewTLS(o.tlsConfig)
+	}
+
+	interceptors := append([]grpc.UnaryClientInterceptor(nil), o.interceptors...)
+	if o.tokenSource != nil {
+		interceptors = append(interceptors, tokenInterceptor(o.tokenSource))
+	}
+	if o.tenantID != "" {
+		interceptors = append(interceptors, tenantInterceptor(o.tenantID))
+	}
+	if o.breakerFailures > 0 {
+		interceptors = append(interceptors, newBreaker(o.breakerFailures, o.breakerCooldown).unaryInterceptor())
+	}
+	interceptors = append(interceptors, retrier{
+		maxAttempts: o.maxAttempts,
+		callTimeout: o.callTimeout,
+		backoffBase: o.backoffBase,
+		backoffMax:  o.backoffMax,
+	}.unaryInterceptor())
+
+	dialOptions := append([]grpc.DialOption{
+		grpc.WithResolvers(builder),
+		grpc.WithDefaultServiceConfig(roundRobinServiceConfig),
+		grpc.WithTransportCredentials(transport),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}, o.dialOptions...)
+
+	connection, err := grpc.NewClient(builder.Scheme()+":///order-service", dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("ordersdk: create connection: %w", err)
+	}
+	connection.Connect()
+
+	return &Client{
+		connection: connection,
+		rpc:        order.NewOrderServiceClient(connection),
+		analytics:  order.NewAnalyticsServiceClient(connection),
+	}, nil
+}
+
+func (c *Client) Close() error {
+	return c.connection.Close()
+}
+
+func (c *Client) CreateOrder(ctx context.Context, userID string, items ...*order.OrderItem) (*order.Order, error) {
+	response, err := c.rpc.CreateOrder(ctx, &order.CreateOrderRequest{
+		UserId: userID,
+		Items:  items,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+// CreateOrderWithShipping creates an order that is delivered to the address.
+func (c *Client) CreateOrderWithShipping(ctx context.Context, userID string, address *order.ShippingAddress, items ...*order.OrderItem) (*order.Order, error) {
+	response, err := c.rpc.CreateOrder(ctx, &order.CreateOrderRequest{
+		UserId:          userID,
+		Items:           items,
+		ShippingAddress: address,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+// ScheduleOrder places an order that stays SCHEDULED and is submitted for payment at
+// scheduledAt. address may be nil.
+func (c *Client) ScheduleOrder(ctx context.Context, userID string, scheduledAt time.Time, address *order.ShippingAddress, items ...*order.OrderItem) (*order.Order, error) {
+	response, err := c.rpc.CreateOrder(ctx, &order.CreateOrderRequest{
+		UserId:          userID,
+		Items:           items,
+		ShippingAddress: address,
+		ScheduledAt:     timestamppb.New(scheduledAt),
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+func (c *Client) GetOrder(ctx context.Context, orderID string) (*order.Order, error) {
+	response, err := c.rpc.GetOrder(ctx, &order.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+// GetOrderByNumber finds an order by its human-readable number, e.g. ORD-2026-000123.
+func (c *Client) GetOrderByNumber(ctx context.Context, orderNumber string) (*order.Order, error) {
+	response, err := c.rpc.GetOrderByNumber(ctx, &order.GetOrderByNumberRequest{OrderNumber: orderNumber})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+// ListOrders returns one page of orders, newest first, and the token of the next
+// page, which is empty on the last page.
+func (c *Client) ListOrders(ctx context.Context, req *order.ListOrdersRequest) ([]*order.Order, string, error) {
+	response, err := c.rpc.ListOrders(ctx, req)
+	if err != nil {
+		return nil, "", toError(err)
+	}
+	return response.GetOrders(), response.GetNextPageToken(), nil
+}
+
+// SearchOrders finds orders of any user of the tenant with highlighted matches
+// and counts by status; only support services may call it.
+func (c *Client) SearchOrders(ctx context.Context, req *order.SearchOrdersRequest) (*order.SearchOrdersResponse, error) {
+	response, err := c.rpc.SearchOrders(ctx, req)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response, nil
+}
+
+func (c *Client) UpdateOrderStatus(ctx context.Context, orderID string, status order.OrderStatus) (*order.Order, error) {
+	response, err := c.rpc.UpdateOrderStatus(ctx, &order.UpdateOrderStatusRequest{
+		OrderId: orderID,
+		Status:  status,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+// MarkShipped records the shipment of a paid order; only fulfilment services may call it.
+func (c *Client) MarkShipped(ctx context.Context, orderID string, carrier string, trackingNumber string) (*order.Order, error) {
+	response, err := c.rpc.MarkShipped(ctx, &order.MarkShippedRequest{
+		OrderId:        orderID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+// AmendOrder sets new quantities for the products of a PENDING order; products left
+// out are removed. With a non-zero expectedVersion a concurrent change fails with
+// ErrAborted instead of being overwritten.
+func (c *Client) AmendOrder(ctx context.Context, orderID string, expectedVersion int64, items ...*order.OrderItem) (*order.Order, error) {
+	response, err := c.rpc.AmendOrder(ctx, &order.AmendOrderRequest{
+		OrderId:         orderID,
+		Items:           items,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetOrder(), nil
+}
+
+// CancelItems cancels units of a paid order and returns the updated order with the
+// cancellation. The call carries a generated cancellation id, so retries never cancel
+// twice.
+func (c *Client) CancelItems(ctx context.Context, orderID string, reason string, items ...*order.CancelledItem) (*order.Order, *order.ItemCancellation, error) {
+	response, err := c.rpc.CancelItems(ctx, &order.CancelItemsRequest{
+		OrderId:        orderID,
+		Items:          items,
+		Reason:         reason,
+		CancellationId: uuid.NewString(),
+	})
+	if err != nil {
+		return nil, nil, toError(err)
+	}
+	return response.GetOrder(), response.GetCancellation(), nil
+}
+
+func (c *Client) RequestReturn(ctx context.Context, orderID string, reason string, items ...*order.ReturnItem) (*order.Return, error) {
+	response, err := c.rpc.RequestReturn(ctx, &order.RequestReturnRequest{
+		OrderId: orderID,
+		Items:   items,
+		Reason:  reason,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetReturn(), nil
+}
+
+func (c *Client) GetReturn(ctx context.Context, returnID string) (*order.Return, error) {
+	response, err := c.rpc.GetReturn(ctx, &order.GetReturnRequest{ReturnId: returnID})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetReturn(), nil
+}
+
+// ListReturns returns the returns of the order, oldest first.
+func (c *Client) ListReturns(ctx context.Context, orderID string) ([]*order.Return, error) {
+	response, err := c.rpc.ListReturns(ctx, &order.ListReturnsRequest{OrderId: orderID})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetReturns(), nil
+}
+
+func (c *Client) ApproveReturn(ctx context.Context, returnID string, comment string) (*order.Return, error) {
+	response, err := c.rpc.ApproveReturn(ctx, &order.ApproveReturnRequest{ReturnId: returnID, Comment: comment})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetReturn(), nil
+}
+
+func (c *Client) RejectReturn(ctx context.Context, returnID string, reason string) (*order.Return, error) {
+	response, err := c.rpc.RejectReturn(ctx, &order.RejectReturnRequest{ReturnId: returnID, Reason: reason})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetReturn(), nil
+}
+
+func (c *Client) MarkReturnReceived(ctx context.Context, returnID string) (*order.Return, error) {
+	response, err := c.rpc.MarkReturnReceived(ctx, &order.MarkReturnReceivedRequest{ReturnId: returnID})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetReturn(), nil
+}
+
+// RecordRefund reports the result of a refund; only billing may call it.
+func (c *Client) RecordRefund(ctx context.Context, returnID string, refunded bool, reason string) (*order.Return, error) {
+	response, err := c.rpc.RecordRefund(ctx, &order.RecordRefundRequest{
+		ReturnId: returnID,
+		Refunded: refunded,
+		Reason:   reason,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response.GetReturn(), nil
+}
+
+// GetOrderAnalytics returns order metrics of the tenant grouped by day, week, status
+// or user; only analytics services may call it.
+func (c *Client) GetOrderAnalytics(ctx context.Context, req *order.GetOrderAnalyticsRequest) (*order.GetOrderAnalyticsResponse, error) {
+	response, err := c.analytics.GetOrderAnalytics(ctx, req)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return response, nil
+}
+
+func tokenInterceptor(source TokenSource) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		token, err := source(ctx)
+		if err != nil {
+			return &Error{Code: ErrUnauthenticated.Code, Message: fmt.Sprintf("resolve token: %v", err)}
+		}
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func tenantInterceptor(tenantID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get(TenantMetadataKey)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, TenantMetadataKey, tenantID)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func splitAddresses(target string) []resolver.Address {
+	var addresses []resolver.Address
+	for _, host := range strings.Split(target, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			addresses = append(addresses, resolver.Address{Addr: host})
+		}
+	}
+	return addresses
+}
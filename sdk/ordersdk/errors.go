@@ -0,0 +1,83 @@
+package ordersdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error is returned by every Client method when the call fails. It keeps the gRPC
+// status, so status.Code(err) keeps working, and matches the sentinel errors below
+// with errors.Is by code.
+type Error struct {
+	Code    codes.Code
+	Message string
+}
+
+var (
+	ErrInvalidArgument    = &Error{Code: codes.InvalidArgument}
+	ErrNotFound           = &Error{Code: codes.NotFound}
+	ErrAlreadyExists      = &Error{Code: codes.AlreadyExists}
+	ErrFailedPrecondition = &Error{Code: codes.FailedPrecondition}
+	ErrAborted            = &Error{Code: codes.Aborted}
+	ErrUnauthenticated    = &Error{Code: codes.Unauthenticated}
+	ErrPermissionDenied   = &Error{Code: codes.PermissionDenied}
+	ErrResourceExhausted  = &Error{Code: codes.ResourceExhausted}
+	ErrUnavailable        = &Error{Code: codes.Unavailable}
+	ErrDeadlineExceeded   = &Error{Code: codes.DeadlineExceeded}
+	ErrCanceled           = &Error{Code: codes.Canceled}
+	ErrInternal           = &Error{Code: codes.Internal}
+
+	// ErrCircuitOpen is returned without calling the server while the breaker is open.
+	ErrCircuitOpen = &Error{Code: codes.Unavailable, Message: "order service circuit breaker is open"}
+)
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ordersdk: %s", e.Code)
+	}
+	return fmt.Sprintf("ordersdk: %s: %s", e.Code, e.Message)
+}
+
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Code, e.Message)
+}
+
+// Is matches sentinel errors (without a message) by code, and other errors by identity.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.Message == "" {
+		return t.Code == e.Code
+	}
+	return t == e
+}
+
+// toError maps an error returned by the gRPC stack to *Error.
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sdkErr *Error
+	if errors.As(err, &sdkErr) {
+		return sdkErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Code: codes.DeadlineExceeded, Message: err.Error()}
+	}
+	if errors.Is(err, context.Canceled) {
+		return &Error{Code: codes.Canceled, Message: err.Error()}
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return &Error{Code: codes.Unknown, Message: err.Error()}
+	}
+	return &Error{Code: st.Code(), Message: st.Message()}
+}
@@ -0,0 +1,117 @@
+package ordersdk
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// TokenSource returns the bearer token sent with every call.
+type TokenSource func(ctx context.Context) (string, error)
+
+// TenantMetadataKey is the metadata key of the storefront the calls act in.
+const TenantMetadataKey = "x-tenant-id"
+
+type options struct {
+	tokenSource     TokenSource
+	tenantID        string
+	callTimeout     time.Duration
+	maxAttempts     int
+	backoffBase     time.Duration
+	backoffMax      time.Duration
+	breakerFailures int
+	breakerCooldown time.Duration
+	tlsConfig       *tls.Config
+	dialOptions     []grpc.DialOption
+	interceptors    []grpc.UnaryClientInterceptor
+}
+
+func defaultOptions() options {
+	return options{
+		callTimeout: 5 * time.Second,
+		maxAttempts: 3,
+		backoffBase: 100 * time.Millisecond,
+		backoffMax:  2 * time.Second,
+	}
+}
+
+type Option func(*options)
+
+// WithToken sends a static bearer token in the authorization metadata.
+func WithToken(token string) Option {
+	return WithTokenSource(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// WithTokenSource resolves the bearer token per call, e.g. to refresh expiring tokens.
+func WithTokenSource(source TokenSource) Option {
+	return func(o *options) {
+		o.tokenSource = source
+	}
+}
+
+// WithTenant sends every call on behalf of the storefront, unless the call already
+// carries the x-tenant-id metadata. Without it calls act in the default tenant.
+func WithTenant(tenantID string) Option {
+	return func(o *options) {
+		o.tenantID = tenantID
+	}
+}
+
+// WithTimeout sets the deadline of a single attempt. Zero disables it and leaves
+// the deadline to the caller's context.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.callTimeout = timeout
+	}
+}
+
+// WithRetry repeats calls failed with Unavailable or DeadlineExceeded up to
+// maxAttempts times with exponential backoff between base and max.
+func WithRetry(maxAttempts int, base time.Duration, max time.Duration) Option {
+	return func(o *options) {
+		o.maxAttempts = maxAttempts
+		o.backoffBase = base
+		o.backoffMax = max
+	}
+}
+
+// WithoutRetry makes every call a single attempt.
+func WithoutRetry() Option {
+	return func(o *options) {
+		o.maxAttempts = 1
+	}
+}
+
+// WithCircuitBreaker fails calls fast with ErrCircuitOpen after the given number
+// of consecutive transient failures, until the cooldown passes.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.breakerFailures = failures
+		o.breakerCooldown = cooldown
+	}
+}
+
+// WithTLS enables transport security. Without it the connection is plaintext.
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithUnaryInterceptor adds an interceptor that sees every logical call before retries.
+func WithUnaryInterceptor(interceptor grpc.UnaryClientInterceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptor)
+	}
+}
+
+// WithDialOptions passes raw options to grpc.NewClient.
+func WithDialOptions(dialOptions ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, dialOptions...)
+	}
+}
@@ -0,0 +1,162 @@
+package ordersdktest
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"order-service-system/proto/order"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Returns of the fake follow the status rules of the real service but do not check
+// the quantities already returned by earlier requests.
+
+func (s *Server) RequestReturn(_ context.Context, req *order.RequestReturnRequest) (*order.RequestReturnResponse, error) {
+	if err := s.nextFailure("RequestReturn"); err != nil {
+		return nil, err
+	}
+	if req.GetOrderId() == "" || req.GetReason() == "" || len(req.GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_id, reason and items are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.orders[req.GetOrderId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	if found.GetStatus() != order.OrderStatus_DELIVERED {
+		return nil, status.Error(codes.FailedPrecondition, "only delivered orders can be returned")
+	}
+
+	var amount float64
+	for _, item := range req.GetItems() {
+		index := slices.IndexFunc(found.GetItems(), func(line *order.OrderItem) bool { return line.GetProductId() == item.GetProductId() })
+		if index < 0 || item.GetQuantity() <= 0 || item.GetQuantity() > found.GetItems()[index].GetQuantity() {
+			return nil, status.Errorf(codes.InvalidArgument, "product %q cannot be returned", item.GetProductId())
+		}
+		amount += float64(item.GetQuantity()) * found.GetItems()[index].GetPrice()
+	}
+
+	now := timestamppb.New(time.Now())
+	created := &order.Return{
+		ReturnId:     uuid.NewString(),
+		OrderId:      found.GetOrderId(),
+		UserId:       found.GetUserId(),
+		Items:        req.GetItems(),
+		Reason:       req.GetReason(),
+		Status:       order.ReturnStatus_RETURN_REQUESTED,
+		RefundAmount: amount,
+		History:      []*order.ReturnHistoryEntry{{Status: order.ReturnStatus_RETURN_REQUESTED, Comment: req.GetReason(), At: now}},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	s.returns[created.GetReturnId()] = created
+	return &order.RequestReturnResponse{Return: proto.Clone(created).(*order.Return)}, nil
+}
+
+func (s *Server) GetReturn(_ context.Context, req *order.GetReturnRequest) (*order.GetReturnResponse, error) {
+	if err := s.nextFailure("GetReturn"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.returns[req.GetReturnId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "return not found")
+	}
+	return &order.GetReturnResponse{Return: proto.Clone(found).(*order.Return)}, nil
+}
+
+func (s *Server) ListReturns(_ context.Context, req *order.ListReturnsRequest) (*order.ListReturnsResponse, error) {
+	if err := s.nextFailure("ListReturns"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := &order.ListReturnsResponse{}
+	for _, ret := range s.returns {
+		if ret.GetOrderId() == req.GetOrderId() {
+			response.Returns = append(response.Returns, proto.Clone(ret).(*order.Return))
+		}
+	}
+	slices.SortFunc(response.Returns, func(a, b *order.Return) int {
+		return a.GetCreatedAt().AsTime().Compare(b.GetCreatedAt().AsTime())
+	})
+	return response, nil
+}
+
+func (s *Server) ApproveReturn(_ context.Context, req *order.ApproveReturnRequest) (*order.ApproveReturnResponse, error) {
+	ret, err := s.transitionReturn("ApproveReturn", req.GetReturnId(), order.ReturnStatus_RETURN_APPROVED, req.GetComment(), order.ReturnStatus_RETURN_REQUESTED)
+	if err != nil {
+		return nil, err
+	}
+	return &order.ApproveReturnResponse{Return: ret}, nil
+}
+
+func (s *Server) RejectReturn(_ context.Context, req *order.RejectReturnRequest) (*order.RejectReturnResponse, error) {
+	if req.GetReason() == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+	ret, err := s.transitionReturn("RejectReturn", req.GetReturnId(), order.ReturnStatus_RETURN_REJECTED, req.GetReason(), order.ReturnStatus_RETURN_REQUESTED)
+	if err != nil {
+		return nil, err
+	}
+	return &order.RejectReturnResponse{Return: ret}, nil
+}
+
+func (s *Server) MarkReturnReceived(_ context.Context, req *order.MarkReturnReceivedRequest) (*order.MarkReturnReceivedResponse, error) {
+	ret, err := s.transitionReturn("MarkReturnReceived", req.GetReturnId(), order.ReturnStatus_RETURN_RECEIVED, "", order.ReturnStatus_RETURN_APPROVED)
+	if err != nil {
+		return nil, err
+	}
+	return &order.MarkReturnReceivedResponse{Return: ret}, nil
+}
+
+func (s *Server) RecordRefund(_ context.Context, req *order.RecordRefundRequest) (*order.RecordRefundResponse, error) {
+	to, from := order.ReturnStatus_RETURN_REFUND_FAILED, []order.ReturnStatus{order.ReturnStatus_RETURN_RECEIVED}
+	if req.GetRefunded() {
+		to, from = order.ReturnStatus_RETURN_REFUNDED, append(from, order.ReturnStatus_RETURN_REFUND_FAILED, order.ReturnStatus_RETURN_REFUNDED)
+	}
+	ret, err := s.transitionReturn("RecordRefund", req.GetReturnId(), to, req.GetReason(), from...)
+	if err != nil {
+		return nil, err
+	}
+	return &order.RecordRefundResponse{Return: ret}, nil
+}
+
+func (s *Server) transitionReturn(method string, returnID string, to order.ReturnStatus, comment string, from ...order.ReturnStatus) (*order.Return, error) {
+	if err := s.nextFailure(method); err != nil {
+		return nil, err
+	}
+	if returnID == "" {
+		return nil, status.Error(codes.InvalidArgument, "return_id is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.returns[returnID]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "return not found")
+	}
+	if !slices.Contains(from, found.GetStatus()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "return cannot be moved to %s", to)
+	}
+	now := timestamppb.New(time.Now())
+	found.Status = to
+	found.UpdatedAt = now
+	found.History = append(found.History, &order.ReturnHistoryEntry{Status: to, Comment: comment, At: now})
+	return proto.Clone(found).(*order.Return), nil
+}
@@ -0,0 +1,421 @@
+// Package ordersdktest runs an in-memory OrderService for unit tests of code that
+// uses ordersdk, without Mongo, NATS or network listeners.
+package ordersdktest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"order-service-system/proto/order"
+	"order-service-system/sdk/ordersdk"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const bufferSize = 1 << 20
+
+// Server is a fake OrderService with the validation rules of the real one.
+type Server struct {
+	order.UnimplementedOrderServiceServer
+
+	listener *bufconn.Listener
+	grpc     *grpc.Server
+
+	mu       sync.Mutex
+	orders   map[string]*order.Order
+	returns  map[string]*order.Return
+	failures map[string][]error
+	numbers  int
+}
+
+// NewServer starts the fake server; stop it with Close.
+func NewServer() *Server {
+	s := &Server{
+		listener: bufconn.Listen(bufferSize),
+		grpc:     grpc.NewServer(),
+		orders:   map[string]*order.Order{},
+		returns:  map[string]*order.Return{},
+		failures: map[string][]error{},
+	}
+	order.RegisterOrderServiceServer(s.grpc, s)
+	go func() { _ = s.grpc.Serve(s.listener) }()
+	return s
+}
+
+func (s *Server) Close() {
+	s.grpc.Stop()
+}
+
+// Client returns an SDK client connected to the fake server. Retries are disabled
+// unless opts enable them again.
+func (s *Server) Client(opts ...ordersdk.Option) (*ordersdk.Client, error) {
+	defaults := []ordersdk.Option{
+		ordersdk.WithoutRetry(),
+		ordersdk.WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		})),
+	}
+	return ordersdk.New("bufconn", append(defaults, opts...)...)
+}
+
+// FailNext makes the next calls of the method ("CreateOrder", "GetOrder",
+// "ListOrders", "UpdateOrderStatus") return the given errors, one per call.
+func (s *Server) FailNext(method string, errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], errs...)
+}
+
+// Put stores the order as is, e.g. to prepare a GetOrder fixture.
+func (s *Server) Put(o *order.Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[o.GetOrderId()] = proto.Clone(o).(*order.Order)
+}
+
+// Orders returns copies of all stored orders sorted by creation time.
+func (s *Server) Orders() []*order.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*order.Order, 0, len(s.orders))
+	for _, o := range s.orders {
+		result = append(result, proto.Clone(o).(*order.Order))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetCreatedAt().AsTime().Before(result[j].GetCreatedAt().AsTime())
+	})
+	return result
+}
+
+func (s *Server) CreateOrder(_ context.Context, req *order.CreateOrderRequest) (*order.CreateOrderResponse, error) {
+	if err := s.nextFailure("CreateOrder"); err != nil {
+		return nil, err
+	}
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if len(req.GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "items are required")
+	}
+
+	var total float64
+	for _, item := range req.GetItems() {
+		if item.GetProductId() == "" {
+			return nil, status.Error(codes.InvalidArgument, "product_id is required")
+		}
+		if item.GetQuantity() <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+		}
+		if item.GetPrice() < 0 {
+			return nil, status.Error(codes.InvalidArgument, "price must be non-negative")
+		}
+		total += float64(item.GetQuantity()) * item.GetPrice()
+	}
+
+	now := timestamppb.New(time.Now())
+	created := &order.Order{
+		OrderId:         uuid.NewString(),
+		UserId:          req.GetUserId(),
+		Items:           req.GetItems(),
+		TotalAmount:     total,
+		Status:          order.OrderStatus_PENDING,
+		CreatedAt:       now,
+		SubmittedAt:     now,
+		ShippingAddress: req.GetShippingAddress(),
+		Version:         1,
+	}
+	if scheduledAt := req.GetScheduledAt(); scheduledAt != nil && scheduledAt.AsTime().After(now.AsTime()) {
+		created.Status = order.OrderStatus_SCHEDULED
+		created.ScheduledAt = scheduledAt
+		created.SubmittedAt = nil
+	}
+	s.mu.Lock()
+	s.numbers++
+	created.OrderNumber = fmt.Sprintf("ORD-%d-%06d", now.AsTime().Year(), s.numbers)
+	s.mu.Unlock()
+	s.Put(created)
+	return &order.CreateOrderResponse{Order: created}, nil
+}
+
+func (s *Server) GetOrder(_ context.Context, req *order.GetOrderRequest) (*order.GetOrderResponse, error) {
+	if err := s.nextFailure("GetOrder"); err != nil {
+		return nil, err
+	}
+	if req.GetOrderId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.orders[req.GetOrderId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return &order.GetOrderResponse{Order: proto.Clone(found).(*order.Order)}, nil
+}
+
+func (s *Server) GetOrderByNumber(_ context.Context, req *order.GetOrderByNumberRequest) (*order.GetOrderByNumberResponse, error) {
+	if err := s.nextFailure("GetOrderByNumber"); err != nil {
+		return nil, err
+	}
+	number := strings.ToUpper(strings.TrimSpace(req.GetOrderNumber()))
+	if number == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_number is required")
+	}
+
+	for _, o := range s.Orders() {
+		if o.GetOrderNumber() == number {
+			return &order.GetOrderByNumberResponse{Order: o}, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "order not found")
+}
+
+func (s *Server) ListOrders(_ context.Context, req *order.ListOrdersRequest) (*order.ListOrdersResponse, error) {
+	if err := s.nextFailure("ListOrders"); err != nil {
+		return nil, err
+	}
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must be non-negative")
+	}
+
+	offset := 0
+	if req.GetPageToken() != "" {
+		var err error
+		if offset, err = strconv.Atoi(req.GetPageToken()); err != nil || offset < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+	pageSize := int(req.GetPageSize())
+	if pageSize == 0 {
+		pageSize = 20
+	}
+
+	var matched []*order.Order
+	all := s.Orders()
+	for i := len(all) - 1; i >= 0; i-- {
+		o := all[i]
+		if req.GetUserId() != "" && o.GetUserId() != req.GetUserId() {
+			continue
+		}
+		if req.GetStatus() != order.OrderStatus_ORDER_STATUS_UNSPECIFIED && o.GetStatus() != req.GetStatus() {
+			continue
+		}
+		matched = append(matched, o)
+	}
+
+	response := &order.ListOrdersResponse{}
+	if offset >= len(matched) {
+		return response, nil
+	}
+	end := min(offset+pageSize, len(matched))
+	response.Orders = matched[offset:end]
+	if end < len(matched) {
+		response.NextPageToken = strconv.Itoa(end)
+	}
+	return response, nil
+}
+
+func (s *Server) UpdateOrderStatus(_ context.Context, req *order.UpdateOrderStatusRequest) (*order.UpdateOrderStatusResponse, error) {
+	if err := s.nextFailure("UpdateOrderStatus"); err != nil {
+		return nil, err
+	}
+	if req.GetOrderId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	if req.GetStatus() == order.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		return nil, status.Error(codes.InvalidArgument, "status is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.orders[req.GetOrderId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	found.Status = req.GetStatus()
+	found.UpdatedAt = timestamppb.New(time.Now())
+	return &order.UpdateOrderStatusResponse{Order: proto.Clone(found).(*order.Order)}, nil
+}
+
+func (s *Server) MarkShipped(_ context.Context, req *order.MarkShippedRequest) (*order.MarkShippedResponse, error) {
+	if err := s.nextFailure("MarkShipped"); err != nil {
+		return nil, err
+	}
+	if req.GetOrderId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+	if req.GetCarrier() == "" || req.GetTrackingNumber() == "" {
+		return nil, status.Error(codes.InvalidArgument, "carrier and tracking_number are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.orders[req.GetOrderId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	if found.Status != order.OrderStatus_PAID && found.Status != order.OrderStatus_PROCESSING {
+		return nil, status.Error(codes.FailedPrecondition, "order status does not allow this change")
+	}
+	now := timestamppb.New(time.Now())
+	found.Status = order.OrderStatus_SHIPPED
+	found.Shipment = &order.Shipment{
+		Carrier:        req.GetCarrier(),
+		TrackingNumber: req.GetTrackingNumber(),
+		ShippedAt:      now,
+	}
+	found.UpdatedAt = now
+	return &order.MarkShippedResponse{Order: proto.Clone(found).(*order.Order)}, nil
+}
+
+func (s *Server) AmendOrder(_ context.Context, req *order.AmendOrderRequest) (*order.AmendOrderResponse, error) {
+	if err := s.nextFailure("AmendOrder"); err != nil {
+		return nil, err
+	}
+	if req.GetOrderId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_id is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.orders[req.GetOrderId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	if found.Status != order.OrderStatus_PENDING {
+		return nil, status.Error(codes.FailedPrecondition, "only PENDING orders can be amended")
+	}
+	if req.GetExpectedVersion() != 0 && req.GetExpectedVersion() != found.Version {
+		return nil, status.Errorf(codes.Aborted, "order has version %d", found.Version)
+	}
+
+	quantities := map[string]int32{}
+	for _, item := range req.GetItems() {
+		quantities[item.GetProductId()] = item.GetQuantity()
+	}
+	var (
+		items []*order.OrderItem
+		total float64
+	)
+	for _, item := range found.Items {
+		quantity, ok := quantities[item.GetProductId()]
+		if !ok || quantity <= 0 {
+			continue
+		}
+		items = append(items, &order.OrderItem{ProductId: item.GetProductId(), Quantity: quantity, Price: item.GetPrice()})
+		total += float64(quantity) * item.GetPrice()
+	}
+	if len(items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one item must remain")
+	}
+
+	found.Items = items
+	found.TotalAmount = total
+	found.Version++
+	found.UpdatedAt = timestamppb.New(time.Now())
+	return &order.AmendOrderResponse{Order: proto.Clone(found).(*order.Order)}, nil
+}
+
+func (s *Server) CancelItems(_ context.Context, req *order.CancelItemsRequest) (*order.CancelItemsResponse, error) {
+	if err := s.nextFailure("CancelItems"); err != nil {
+		return nil, err
+	}
+	if req.GetOrderId() == "" || len(req.GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_id and items are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found, ok := s.orders[req.GetOrderId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	for _, cancellation := range found.Cancellations {
+		if req.GetCancellationId() != "" && cancellation.GetCancellationId() == req.GetCancellationId() {
+			return &order.CancelItemsResponse{Order: proto.Clone(found).(*order.Order), Cancellation: proto.Clone(cancellation).(*order.ItemCancellation)}, nil
+		}
+	}
+	if found.Status != order.OrderStatus_PAID && found.Status != order.OrderStatus_PROCESSING {
+		return nil, status.Error(codes.FailedPrecondition, "only PAID or PROCESSING orders can be cancelled partially")
+	}
+
+	updated := proto.Clone(found).(*order.Order)
+	cancellation := &order.ItemCancellation{CancellationId: req.GetCancellationId(), Reason: req.GetReason(), At: timestamppb.New(time.Now())}
+	if cancellation.CancellationId == "" {
+		cancellation.CancellationId = uuid.NewString()
+	}
+	for _, requested := range req.GetItems() {
+		left := requested.GetQuantity()
+		for _, item := range updated.Items {
+			if item.GetProductId() != requested.GetProductId() || left <= 0 {
+				continue
+			}
+			cancelled := min(left, item.GetQuantity()-item.GetCancelledQuantity())
+			if cancelled <= 0 {
+				continue
+			}
+			item.CancelledQuantity += cancelled
+			left -= cancelled
+			cancellation.Items = append(cancellation.Items, &order.CancelledItem{ProductId: item.GetProductId(), Quantity: cancelled, Price: item.GetPrice()})
+			cancellation.Amount += float64(cancelled) * item.GetPrice()
+		}
+		if left > 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "not enough units of product %q to cancel", requested.GetProductId())
+		}
+	}
+
+	var remaining float64
+	active := false
+	for _, item := range updated.Items {
+		switch {
+		case item.GetCancelledQuantity() == 0:
+			item.Status = order.ItemStatus_ITEM_ACTIVE
+		case item.GetCancelledQuantity() == item.GetQuantity():
+			item.Status = order.ItemStatus_ITEM_CANCELLED
+		default:
+			item.Status = order.ItemStatus_ITEM_PARTIALLY_CANCELLED
+		}
+		remaining += float64(item.GetQuantity()-item.GetCancelledQuantity()) * item.GetPrice()
+		active = active || item.GetCancelledQuantity() < item.GetQuantity()
+	}
+	if !active {
+		updated.Status = order.OrderStatus_CANCELLED
+	}
+	updated.TotalAmount = remaining
+	updated.Version++
+	updated.Cancellations = append(updated.Cancellations, cancellation)
+	updated.UpdatedAt = cancellation.At
+	s.orders[updated.GetOrderId()] = updated
+	return &order.CancelItemsResponse{Order: proto.Clone(updated).(*order.Order), Cancellation: proto.Clone(cancellation).(*order.ItemCancellation)}, nil
+}
+
+func (s *Server) nextFailure(method string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errs := s.failures[method]
+	if len(errs) == 0 {
+		return nil
+	}
+	s.failures[method] = errs[1:]
+	return errs[0]
+}
@@ -0,0 +1,91 @@
+package ordersdk
+
+import (
+	"context"
+	"math/rand"
+	"order-service-system/proto/order"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// isTransient reports whether the call may succeed when repeated. Business errors
+// such as NotFound or InvalidArgument are never retried.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// nonIdempotent calls may have been applied on the server when they hit their
+// deadline; repeating them would create a duplicate or fail on the changed status.
+var nonIdempotent = map[string]bool{
+	order.OrderService_CreateOrder_FullMethodName:        true,
+	order.OrderService_MarkShipped_FullMethodName:        true,
+	order.OrderService_AmendOrder_FullMethodName:         true,
+	order.OrderService_RequestReturn_FullMethodName:      true,
+	order.OrderService_ApproveReturn_FullMethodName:      true,
+	order.OrderService_RejectReturn_FullMethodName:       true,
+	order.OrderService_MarkReturnReceived_FullMethodName: true,
+}
+
+// retryable additionally protects non-idempotent calls: only Unavailable is repeated
+// for them.
+func retryable(method string, err error) bool {
+	if nonIdempotent[method] {
+		return status.Code(err) == codes.Unavailable
+	}
+	return isTransient(err)
+}
+
+type retrier struct {
+	maxAttempts int
+	callTimeout time.Duration
+	backoffBase time.Duration
+	backoffMax  time.Duration
+}
+
+// unaryInterceptor applies a deadline to every attempt and repeats transient
+// failures with exponential backoff and full jitter.
+func (r retrier) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = r.invoke(ctx, method, req, reply, cc, invoker, opts...)
+			if !retryable(method, err) || attempt >= r.maxAttempts || ctx.Err() != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(r.backoff(attempt)):
+			}
+		}
+	}
+}
+
+func (r retrier) invoke(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if r.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.callTimeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (r retrier) backoff(attempt int) time.Duration {
+	delay := r.backoffBase << (attempt - 1)
+	if delay <= 0 || delay > r.backoffMax {
+		delay = r.backoffMax
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
@@ -0,0 +1,185 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service-system/proto/order"
+	"order-service-system/sdk/ordersdk"
+	"order-service-system/sdk/ordersdk/ordersdktest"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newFake(t *testing.T, opts ...ordersdk.Option) (*ordersdktest.Server, *ordersdk.Client) {
+	t.Helper()
+
+	server := ordersdktest.NewServer()
+	t.Cleanup(server.Close)
+
+	client, err := server.Client(opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return server, client
+}
+
+func TestClient_OrderLifecycle(t *testing.T) {
+	server, client := newFake(t)
+	ctx := context.Background()
+
+	created, err := client.CreateOrder(ctx, "user-1",
+		&order.OrderItem{ProductId: "p-1", Quantity: 2, Price: 10},
+		&order.OrderItem{ProductId: "p-2", Quantity: 1, Price: 5.5},
+	)
+	require.NoError(t, err)
+	require.Equal(t, order.OrderStatus_PENDING, created.GetStatus())
+	require.Equal(t, 25.5, created.GetTotalAmount())
+
+	fetched, err := client.GetOrder(ctx, created.GetOrderId())
+	require.NoError(t, err)
+	require.Equal(t, "user-1", fetched.GetUserId())
+
+	byNumber, err := client.GetOrderByNumber(ctx, created.GetOrderNumber())
+	require.NoError(t, err)
+	require.Equal(t, created.GetOrderId(), byNumber.GetOrderId())
+
+	amended, err := client.AmendOrder(ctx, created.GetOrderId(), 1, &order.OrderItem{ProductId: "p-1", Quantity: 1})
+	require.NoError(t, err)
+	require.Equal(t, 10.0, amended.GetTotalAmount())
+	require.Equal(t, int64(2), amended.GetVersion())
+	_, err = client.AmendOrder(ctx, created.GetOrderId(), 1, &order.OrderItem{ProductId: "p-1", Quantity: 2})
+	require.True(t, errors.Is(err, ordersdk.ErrAborted))
+
+	updated, err := client.UpdateOrderStatus(ctx, created.GetOrderId(), order.OrderStatus_PAID)
+	require.NoError(t, err)
+	require.Equal(t, order.OrderStatus_PAID, updated.GetStatus())
+	require.Len(t, server.Orders(), 1)
+
+	shipped, err := client.MarkShipped(ctx, created.GetOrderId(), "dhl", "TRACK-1")
+	require.NoError(t, err)
+	require.Equal(t, order.OrderStatus_SHIPPED, shipped.GetStatus())
+	require.Equal(t, "TRACK-1", shipped.GetShipment().GetTrackingNumber())
+
+	_, err = client.MarkShipped(ctx, created.GetOrderId(), "dhl", "TRACK-1")
+	require.True(t, errors.Is(err, ordersdk.ErrFailedPrecondition))
+
+	_, err = client.CreateOrder(ctx, "user-2", &order.OrderItem{ProductId: "p-3", Quantity: 1, Price: 1})
+	require.NoError(t, err)
+
+	page, next, err := client.ListOrders(ctx, &order.ListOrdersRequest{UserId: "user-1", PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, created.GetOrderId(), page[0].GetOrderId())
+	require.Empty(t, next)
+}
+
+func TestClient_CancelItems(t *testing.T) {
+	server, client := newFake(t)
+	ctx := context.Background()
+	server.Put(&order.Order{OrderId: "o1", UserId: "user-1", Status: order.OrderStatus_PAID, TotalAmount: 25, Items: []*order.OrderItem{
+		{ProductId: "p-1", Quantity: 2, Price: 10},
+		{ProductId: "p-2", Quantity: 1, Price: 5},
+	}})
+
+	updated, cancellation, err := client.CancelItems(ctx, "o1", "changed mind", &order.CancelledItem{ProductId: "p-1", Quantity: 1})
+	require.NoError(t, err)
+	require.Equal(t, 10.0, cancellation.GetAmount())
+	require.Equal(t, 15.0, updated.GetTotalAmount())
+	require.Equal(t, order.ItemStatus_ITEM_PARTIALLY_CANCELLED, updated.GetItems()[0].GetStatus())
+
+	_, _, err = client.CancelItems(ctx, "o1", "", &order.CancelledItem{ProductId: "p-2", Quantity: 2})
+	require.True(t, errors.Is(err, ordersdk.ErrFailedPrecondition))
+}
+
+func TestClient_ScheduleOrder(t *testing.T) {
+	_, client := newFake(t)
+	ctx := context.Background()
+	at := time.Now().Add(24 * time.Hour).UTC()
+
+	scheduled, err := client.ScheduleOrder(ctx, "user-1", at, nil, &order.OrderItem{ProductId: "p-1", Quantity: 1, Price: 10})
+	require.NoError(t, err)
+	require.Equal(t, order.OrderStatus_SCHEDULED, scheduled.GetStatus())
+	require.True(t, at.Equal(scheduled.GetScheduledAt().AsTime()))
+	require.Nil(t, scheduled.GetSubmittedAt())
+
+	immediate, err := client.ScheduleOrder(ctx, "user-1", time.Now().Add(-time.Minute), nil, &order.OrderItem{ProductId: "p-1", Quantity: 1, Price: 10})
+	require.NoError(t, err)
+	require.Equal(t, order.OrderStatus_PENDING, immediate.GetStatus())
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	_, client := newFake(t)
+	ctx := context.Background()
+
+	_, err := client.GetOrder(ctx, "missing")
+	require.True(t, errors.Is(err, ordersdk.ErrNotFound))
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	var sdkErr *ordersdk.Error
+	require.True(t, errors.As(err, &sdkErr))
+	require.Equal(t, "order not found", sdkErr.Message)
+
+	_, err = client.CreateOrder(ctx, "user-1")
+	require.True(t, errors.Is(err, ordersdk.ErrInvalidArgument))
+	require.False(t, errors.Is(err, ordersdk.ErrNotFound))
+}
+
+func TestClient_RetriesUnavailable(t *testing.T) {
+	server, client := newFake(t, ordersdk.WithRetry(3, time.Millisecond, time.Millisecond))
+	server.FailNext("GetOrder", status.Error(codes.Unavailable, "restarting"), status.Error(codes.Unavailable, "restarting"))
+	server.Put(&order.Order{OrderId: "order-1", Status: order.OrderStatus_PENDING})
+
+	fetched, err := client.GetOrder(context.Background(), "order-1")
+	require.NoError(t, err)
+	require.Equal(t, "order-1", fetched.GetOrderId())
+}
+
+func TestClient_DoesNotRetryCreateOnDeadline(t *testing.T) {
+	server, client := newFake(t, ordersdk.WithRetry(3, time.Millisecond, time.Millisecond))
+	server.FailNext("CreateOrder", status.Error(codes.DeadlineExceeded, "slow"))
+
+	_, err := client.CreateOrder(context.Background(), "user-1", &order.OrderItem{ProductId: "p-1", Quantity: 1, Price: 1})
+	require.True(t, errors.Is(err, ordersdk.ErrDeadlineExceeded))
+	require.Empty(t, server.Orders())
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	server, client := newFake(t, ordersdk.WithCircuitBreaker(1, time.Minute))
+	server.FailNext("GetOrder", status.Error(codes.Unavailable, "down"))
+
+	_, err := client.GetOrder(context.Background(), "order-1")
+	require.True(t, errors.Is(err, ordersdk.ErrUnavailable))
+
+	_, err = client.GetOrder(context.Background(), "order-1")
+	require.True(t, errors.Is(err, ordersdk.ErrCircuitOpen))
+}
+
+func TestClient_SendsTokenAndTenant(t *testing.T) {
+	var authorization, tenant []string
+	_, client := newFake(t,
+		ordersdk.WithToken("secret"),
+		ordersdk.WithTenant("shop-a"),
+		ordersdk.WithDialOptions(grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			authorization = md.Get("authorization")
+			tenant = md.Get(ordersdk.TenantMetadataKey)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})),
+	)
+
+	_, _ = client.GetOrder(context.Background(), "order-1")
+	require.Equal(t, []string{"Bearer secret"}, authorization)
+	require.Equal(t, []string{"shop-a"}, tenant)
+}
+
+func TestNew_RequiresTarget(t *testing.T) {
+	_, err := ordersdk.New(" ")
+	require.Error(t, err)
+}